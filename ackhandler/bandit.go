@@ -0,0 +1,316 @@
+package ackhandler
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// A BanditAlgorithm is the algorithm the fluctuation monitor uses to choose alpha
+type BanditAlgorithm string
+
+// The bandit algorithms
+const (
+	// BanditUCB is UCB1 with discounted rewards
+	BanditUCB BanditAlgorithm = "ucb"
+	// BanditSlidingWindowUCB is UCB1 on the last Window rewards
+	BanditSlidingWindowUCB BanditAlgorithm = "sw-ucb"
+	// BanditThompson is Gaussian Thompson sampling
+	BanditThompson BanditAlgorithm = "thompson"
+	// BanditEXP3 is EXP3, for adversarial rewards
+	BanditEXP3 BanditAlgorithm = "exp3"
+)
+
+// The defaults of the BanditConfig
+const (
+	DefaultBanditGamma       = 0.5
+	DefaultBanditHistoryLen  = 10
+	DefaultBanditExploration = 2.0
+	DefaultBanditWindow      = 50
+	DefaultBanditBatchSize   = 6
+)
+
+// DefaultBanditArms are the alphas the fluctuation monitor chooses from by default
+var DefaultBanditArms = []float32{1.0, 1.1, 1.2}
+
+// A RewardFunc calculates the reward of an alpha.
+// meetRatio is the deadline meet ratio of the packets sent with this alpha in the last HistoryLen ACKs,
+// notSent the number of packets of the last batch of the peer that were not sent, and batchSize the length of that batch.
+type RewardFunc func(meetRatio float32, notSent uint16, batchSize int) float32
+
+// DefaultReward is the meet ratio, minus the fraction of the batch that was not sent
+func DefaultReward(meetRatio float32, notSent uint16, batchSize int) float32 {
+	return meetRatio - float32(notSent)/float32(batchSize)
+}
+
+// BanditConfig configures the fluctuation monitor, the bandit that chooses the deadline stringency factor alpha of a path.
+// Zero values are replaced by the defaults.
+type BanditConfig struct {
+	// Algorithm is one of the BanditAlgorithms. Unknown algorithms fall back to BanditUCB.
+	Algorithm BanditAlgorithm
+	// Arms are the alphas to choose from. They are sent in tenths in the public header, so they are rounded to one decimal.
+	// They must be between 0.1 and 25.5.
+	Arms []float32
+	// Gamma is the discount factor of the rewards of BanditUCB, in (0, 1]
+	Gamma float64
+	// HistoryLen is the number of ACKs the meet ratio of an alpha is calculated over
+	HistoryLen int
+	// Exploration scales the exploration: the confidence bound of the UCB algorithms,
+	// the standard deviation of Thompson sampling, and the uniform share of EXP3 (capped at 1)
+	Exploration float64
+	// Window is the number of rewards BanditSlidingWindowUCB remembers
+	Window int
+	// Reward calculates the rewards. If nil, DefaultReward is used.
+	Reward RewardFunc
+	// BatchSize is passed to the RewardFunc when the peer didn't report the length of its last batch
+	BatchSize int
+	// Seed seeds the randomized algorithms. If zero, they are seeded with the current time.
+	Seed int64
+}
+
+// populate returns a copy of the config with the defaults filled in
+func (c *BanditConfig) populate() *BanditConfig {
+	config := &BanditConfig{}
+	if c != nil {
+		*config = *c
+	}
+	switch config.Algorithm {
+	case BanditUCB, BanditSlidingWindowUCB, BanditThompson, BanditEXP3:
+	default:
+		config.Algorithm = BanditUCB
+	}
+	arms := make([]float32, 0, len(config.Arms))
+	for _, a := range config.Arms {
+		if a = float32(math.Round(float64(a)*10) / 10); a >= 0.1 && a <= 25.5 {
+			arms = append(arms, a)
+		}
+	}
+	if len(arms) == 0 {
+		arms = append(arms, DefaultBanditArms...)
+	}
+	config.Arms = arms
+	if config.Gamma <= 0 || config.Gamma > 1 {
+		config.Gamma = DefaultBanditGamma
+	}
+	if config.HistoryLen <= 0 {
+		config.HistoryLen = DefaultBanditHistoryLen
+	}
+	if config.Exploration <= 0 {
+		config.Exploration = DefaultBanditExploration
+	}
+	if config.Window <= 0 {
+		config.Window = DefaultBanditWindow
+	}
+	if config.Reward == nil {
+		config.Reward = DefaultReward
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultBanditBatchSize
+	}
+	if config.Seed == 0 {
+		config.Seed = time.Now().UnixNano()
+	}
+	return config
+}
+
+// A bandit chooses one of the arms of the BanditConfig
+type bandit interface {
+	// selectArm returns the index of the arm to play next
+	selectArm() int
+	// update reports the reward of an arm
+	update(arm int, reward float32)
+}
+
+func newBandit(config *BanditConfig) bandit {
+	n := len(config.Arms)
+	switch config.Algorithm {
+	case BanditSlidingWindowUCB:
+		return &slidingWindowUCB{exploration: config.Exploration, window: config.Window, arms: n}
+	case BanditThompson:
+		return &thompsonSampling{
+			sigma:       config.Exploration,
+			totalReward: make([]float64, n),
+			numPlay:     make([]int, n),
+			rand:        rand.New(rand.NewSource(config.Seed)),
+		}
+	case BanditEXP3:
+		weights := make([]float64, n)
+		for i := range weights {
+			weights[i] = 1
+		}
+		return &exp3{
+			eta:     math.Min(config.Exploration, 1),
+			weights: weights,
+			probs:   make([]float64, n),
+			rand:    rand.New(rand.NewSource(config.Seed)),
+		}
+	}
+	return &discountedUCB{
+		gamma:       float32(config.Gamma),
+		exploration: config.Exploration,
+		armsNumPlay: make([]int, n),
+		totalReward: make([]float32, n),
+	}
+}
+
+// untriedArmUCB is the UCB of an arm that was never played
+const untriedArmUCB = 2
+
+// discountedUCB is UCB1 with discounted rewards.
+// The discounted sum is still divided by the number of plays, so with Gamma < 1 the exploration term dominates over time.
+type discountedUCB struct {
+	gamma       float32
+	exploration float64
+
+	armsNumPlay  []int
+	totalNumPlay int
+	totalReward  []float32
+	curArmIndex  int
+}
+
+func (b *discountedUCB) update(arm int, reward float32) {
+	b.totalReward[arm] = b.gamma*b.totalReward[arm] + reward
+	// the play is counted for the arm that is currently in use
+	b.armsNumPlay[b.curArmIndex]++
+	b.totalNumPlay++
+}
+
+func (b *discountedUCB) selectArm() int {
+	ucbs := make([]float32, len(b.armsNumPlay))
+	for i := range ucbs {
+		if b.armsNumPlay[i] == 0 {
+			ucbs[i] = untriedArmUCB
+		} else {
+			aveReward := b.totalReward[i] / float32(b.armsNumPlay[i])
+			delta := math.Sqrt(b.exploration * math.Log(float64(b.totalNumPlay+1)) / float64(b.armsNumPlay[i]))
+			ucbs[i] = aveReward + float32(delta)
+		}
+	}
+	b.curArmIndex = selectBestArm(ucbs)
+	return b.curArmIndex
+}
+
+type armReward struct {
+	arm    int
+	reward float32
+}
+
+// slidingWindowUCB is UCB1 on the last rewards, so it forgets arms that got better or worse
+type slidingWindowUCB struct {
+	exploration float64
+	window      int
+	arms        int
+
+	history []armReward
+}
+
+func (b *slidingWindowUCB) update(arm int, reward float32) {
+	b.history = append(b.history, armReward{arm: arm, reward: reward})
+	if len(b.history) > b.window {
+		b.history = b.history[len(b.history)-b.window:]
+	}
+}
+
+func (b *slidingWindowUCB) selectArm() int {
+	numPlay := make([]int, b.arms)
+	totalReward := make([]float32, b.arms)
+	for _, r := range b.history {
+		numPlay[r.arm]++
+		totalReward[r.arm] += r.reward
+	}
+	ucbs := make([]float32, b.arms)
+	for i := range ucbs {
+		if numPlay[i] == 0 {
+			ucbs[i] = untriedArmUCB
+		} else {
+			delta := math.Sqrt(b.exploration * math.Log(float64(len(b.history)+1)) / float64(numPlay[i]))
+			ucbs[i] = totalReward[i]/float32(numPlay[i]) + float32(delta)
+		}
+	}
+	return selectBestArm(ucbs)
+}
+
+// thompsonSampling samples the mean reward of every arm from a normal distribution around the observed mean
+type thompsonSampling struct {
+	sigma float64
+
+	totalReward []float64
+	numPlay     []int
+	rand        *rand.Rand
+}
+
+func (b *thompsonSampling) update(arm int, reward float32) {
+	b.totalReward[arm] += float64(reward)
+	b.numPlay[arm]++
+}
+
+func (b *thompsonSampling) selectArm() int {
+	samples := make([]float32, len(b.numPlay))
+	for i := range samples {
+		var mean float64
+		if b.numPlay[i] > 0 {
+			mean = b.totalReward[i] / float64(b.numPlay[i])
+		}
+		samples[i] = float32(mean + b.rand.NormFloat64()*b.sigma/math.Sqrt(float64(b.numPlay[i]+1)))
+	}
+	return selectBestArm(samples)
+}
+
+// exp3 makes no assumption about the distribution of the rewards.
+// The rewards are mapped from [-1, 1] to [0, 1].
+type exp3 struct {
+	eta float64
+
+	weights []float64
+	probs   []float64
+	rand    *rand.Rand
+}
+
+func (b *exp3) updateProbs() {
+	var sum float64
+	for _, w := range b.weights {
+		sum += w
+	}
+	k := float64(len(b.weights))
+	for i, w := range b.weights {
+		b.probs[i] = (1-b.eta)*w/sum + b.eta/k
+	}
+}
+
+func (b *exp3) update(arm int, reward float32) {
+	b.updateProbs()
+	x := math.Max(0, math.Min(1, (float64(reward)+1)/2))
+	b.weights[arm] *= math.Exp(b.eta * x / b.probs[arm] / float64(len(b.weights)))
+	// normalize, so that the weights don't overflow
+	max := 0.0
+	for _, w := range b.weights {
+		max = math.Max(max, w)
+	}
+	for i := range b.weights {
+		b.weights[i] /= max
+	}
+}
+
+func (b *exp3) selectArm() int {
+	b.updateProbs()
+	r := b.rand.Float64()
+	for i, p := range b.probs {
+		if r < p {
+			return i
+		}
+		r -= p
+	}
+	return len(b.probs) - 1
+}
+
+func selectBestArm(ucbs []float32) int {
+	bestArm := 0
+	maxUcb := ucbs[0]
+	for i := 0; i < len(ucbs); i++ {
+		if ucbs[i] > maxUcb {
+			bestArm = i
+			maxUcb = ucbs[i]
+		}
+	}
+	return bestArm
+}
@@ -0,0 +1,148 @@
+package ackhandler
+
+import (
+	"github.com/lucas-clemente/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Fluctuation monitor", func() {
+	Context("config", func() {
+		It("uses the defaults", func() {
+			config := (*BanditConfig)(nil).populate()
+			Expect(config.Algorithm).To(Equal(BanditUCB))
+			Expect(config.Arms).To(Equal(DefaultBanditArms))
+			Expect(config.Gamma).To(Equal(DefaultBanditGamma))
+			Expect(config.HistoryLen).To(Equal(DefaultBanditHistoryLen))
+			Expect(config.Exploration).To(Equal(DefaultBanditExploration))
+			Expect(config.Window).To(Equal(DefaultBanditWindow))
+			Expect(config.BatchSize).To(Equal(DefaultBanditBatchSize))
+			Expect(config.Reward).ToNot(BeNil())
+			Expect(config.Seed).ToNot(BeZero())
+		})
+
+		It("rounds the arms to tenths and drops the ones that can't be sent", func() {
+			config := (&BanditConfig{Arms: []float32{0.04, 1.04, 1.46, 30}}).populate()
+			Expect(config.Arms).To(Equal([]float32{1, 1.5}))
+		})
+
+		It("falls back to UCB for unknown algorithms", func() {
+			config := (&BanditConfig{Algorithm: "foobar"}).populate()
+			Expect(config.Algorithm).To(Equal(BanditUCB))
+			Expect(newBandit(config)).To(BeAssignableToTypeOf(&discountedUCB{}))
+		})
+
+		It("doesn't modify the config", func() {
+			arms := []float32{1.04}
+			c := &BanditConfig{Arms: arms}
+			c.populate()
+			Expect(c.Arms).To(Equal([]float32{1.04}))
+			Expect(c.Gamma).To(BeZero())
+		})
+	})
+
+	Context("algorithms", func() {
+		// play pulls the arms and rewards arm 2 only
+		play := func(b bandit, rounds int) []int {
+			counts := make([]int, 3)
+			for i := 0; i < rounds; i++ {
+				arm := b.selectArm()
+				counts[arm]++
+				if arm == 2 {
+					b.update(arm, 1)
+				} else {
+					b.update(arm, -0.5)
+				}
+			}
+			return counts
+		}
+
+		for _, algorithm := range []BanditAlgorithm{BanditUCB, BanditSlidingWindowUCB, BanditThompson, BanditEXP3} {
+			algorithm := algorithm
+
+			It("finds the best arm with "+string(algorithm), func() {
+				config := (&BanditConfig{Algorithm: algorithm, Gamma: 1, Seed: 1, Exploration: 0.5}).populate()
+				counts := play(newBandit(config), 500)
+				Expect(counts[2]).To(BeNumerically(">", 300))
+			})
+		}
+
+		It("tries the arms that were never played with UCB", func() {
+			b := newBandit((&BanditConfig{}).populate())
+			Expect(b.selectArm()).To(Equal(0))
+			b.update(0, -1)
+			Expect(b.selectArm()).To(Equal(1))
+			b.update(1, -1)
+			Expect(b.selectArm()).To(Equal(2))
+		})
+
+		It("forgets old rewards with sliding-window UCB", func() {
+			b := newBandit((&BanditConfig{Algorithm: BanditSlidingWindowUCB, Window: 4}).populate()).(*slidingWindowUCB)
+			for i := 0; i < 10; i++ {
+				b.update(i%3, 1)
+			}
+			Expect(b.history).To(HaveLen(4))
+		})
+	})
+
+	Context("in the sent packet handler", func() {
+		ackWithDeadlines := func(alpha uint16) *wire.AckFrame {
+			return &wire.AckFrame{
+				HasDeadlineInformation: true,
+				DeadlineSamples:        []wire.DeadlineSample{{PacketNumber: 1, Lateness: -1}, {PacketNumber: 2, Lateness: -1}, {PacketNumber: 3}, {PacketNumber: 4, Lateness: -1}},
+				Alpha:                  alpha,
+				CurNotSent:             2,
+			}
+		}
+
+		It("starts with the first arm", func() {
+			handler := NewSentPacketHandler(nil, nil, nil, &BanditConfig{Arms: []float32{2, 3}}).(*sentPacketHandler)
+			Expect(handler.GetPathAlpha()).To(Equal(float32(2)))
+		})
+
+		It("chooses from the configured arms with the configured reward", func() {
+			var rewards []float32
+			config := &BanditConfig{
+				Arms:       []float32{2, 3},
+				HistoryLen: 2,
+				BatchSize:  8,
+				Reward: func(meetRatio float32, notSent uint16, batchSize int) float32 {
+					Expect(notSent).To(Equal(uint16(2)))
+					Expect(batchSize).To(Equal(8))
+					rewards = append(rewards, meetRatio)
+					return meetRatio
+				},
+			}
+			handler := NewSentPacketHandler(nil, nil, nil, config).(*sentPacketHandler)
+			handler.updateDeadlineInformation(ackWithDeadlines(20))
+			Expect(handler.GetPathAlpha()).To(Equal(float32(3)))
+			handler.updateDeadlineInformation(ackWithDeadlines(20))
+			Expect(rewards).To(Equal([]float32{0, 0.75}))
+		})
+
+		It("normalises by the batch length echoed by the peer", func() {
+			var batchSizes []int
+			config := &BanditConfig{
+				Arms:      []float32{2},
+				BatchSize: 8,
+				Reward: func(meetRatio float32, notSent uint16, batchSize int) float32 {
+					batchSizes = append(batchSizes, batchSize)
+					return meetRatio
+				},
+			}
+			handler := NewSentPacketHandler(nil, nil, nil, config).(*sentPacketHandler)
+			ack := ackWithDeadlines(20)
+			ack.BatchLength = 3
+			handler.updateDeadlineInformation(ack)
+			Expect(batchSizes).To(Equal([]int{3}))
+			Expect(DefaultReward(1, ack.CurNotSent, batchSizes[0])).To(BeNumerically("~", 1.0/3, 1e-6))
+		})
+
+		It("ignores ACKs without deadline information", func() {
+			handler := NewSentPacketHandler(nil, nil, nil, nil).(*sentPacketHandler)
+			handler.updateDeadlineInformation(&wire.AckFrame{})
+			Expect(handler.changePDInfo.historicalHasDeadlines[0]).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,70 @@
+package ackhandler
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/congestion"
+)
+
+// clockOffsetWindow is the time after which old samples are forgotten, so that the estimation follows clock drift
+const clockOffsetWindow = 10 * time.Second
+
+// A clockOffsetEstimator maps the timestamps of the peer to the local clock.
+// Every packet carries its send time, relative to an epoch of the peer. The receive time minus this timestamp
+// is the local time of the peer's epoch, plus the one-way delay of the packet. The smallest of these samples
+// only contains the minimal one-way delay, which is estimated as half the minimal RTT measured with ACKs.
+// A peer that mostly receives has no RTT sample of its own, so it uses the RTT the peer reports in PATHS frames.
+type clockOffsetEstimator struct {
+	rttStats *congestion.RTTStats
+	// remoteRTT is the RTT of the path measured by the peer
+	remoteRTT time.Duration
+
+	// peerEpoch is the earliest local time of the peer's epoch seen in the current window
+	peerEpoch     time.Time
+	prevPeerEpoch time.Time
+	windowStart   time.Time
+}
+
+func newClockOffsetEstimator(rttStats *congestion.RTTStats) *clockOffsetEstimator {
+	return &clockOffsetEstimator{rttStats: rttStats}
+}
+
+// OnPacketReceived adds a sample
+func (e *clockOffsetEstimator) OnPacketReceived(timestamp time.Duration, rcvTime time.Time) {
+	if rcvTime.Sub(e.windowStart) > clockOffsetWindow {
+		e.prevPeerEpoch = e.peerEpoch
+		e.peerEpoch = time.Time{}
+		e.windowStart = rcvTime
+	}
+	epoch := rcvTime.Add(-timestamp)
+	if e.peerEpoch.IsZero() || epoch.Before(e.peerEpoch) {
+		e.peerEpoch = epoch
+	}
+}
+
+// SetRemoteRTT sets the RTT of the path measured by the peer
+func (e *clockOffsetEstimator) SetRemoteRTT(rtt time.Duration) {
+	e.remoteRTT = rtt
+}
+
+// rtt returns the RTT used for the one-way delay: the minimal RTT of the path, or else the RTT measured by the peer
+func (e *clockOffsetEstimator) rtt() time.Duration {
+	if e.rttStats != nil && e.rttStats.MinRTT() != 0 {
+		return e.rttStats.MinRTT()
+	}
+	return e.remoteRTT
+}
+
+// ToLocalTime converts a timestamp of the peer to the local clock. It must only be called after a sample was added.
+// ok is false as long as there is no RTT estimate, since the one-way delay is unknown.
+func (e *clockOffsetEstimator) ToLocalTime(timestamp time.Duration) (t time.Time, ok bool) {
+	rtt := e.rtt()
+	if rtt == 0 {
+		return time.Time{}, false
+	}
+	epoch := e.peerEpoch
+	if !e.prevPeerEpoch.IsZero() && e.prevPeerEpoch.Before(epoch) {
+		epoch = e.prevPeerEpoch
+	}
+	return epoch.Add(-rtt / 2).Add(timestamp), true
+}
@@ -0,0 +1,69 @@
+package ackhandler
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/congestion"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Clock offset estimation", func() {
+	var (
+		estimator *clockOffsetEstimator
+		rttStats  *congestion.RTTStats
+		base      time.Time
+	)
+
+	BeforeEach(func() {
+		base = time.Now()
+		rttStats = &congestion.RTTStats{}
+		estimator = newClockOffsetEstimator(rttStats)
+	})
+
+	toLocalTime := func(timestamp time.Duration) time.Time {
+		t, ok := estimator.ToLocalTime(timestamp)
+		Expect(ok).To(BeTrue())
+		return t
+	}
+
+	It("doesn't convert timestamps without an RTT estimate", func() {
+		estimator.OnPacketReceived(time.Second, base.Add(20*time.Millisecond))
+		_, ok := estimator.ToLocalTime(time.Second)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("uses the smallest delay", func() {
+		rttStats.UpdateRTT(20*time.Millisecond, 0, base)
+		estimator.OnPacketReceived(time.Second, base.Add(40*time.Millisecond))
+		estimator.OnPacketReceived(2*time.Second, base.Add(time.Second+20*time.Millisecond))
+		Expect(toLocalTime(3 * time.Second)).To(Equal(base.Add(2*time.Second + 10*time.Millisecond)))
+	})
+
+	It("subtracts half the minimal RTT", func() {
+		rttStats.UpdateRTT(40*time.Millisecond, 0, base)
+		estimator.OnPacketReceived(time.Second, base.Add(20*time.Millisecond))
+		Expect(toLocalTime(time.Second)).To(Equal(base))
+	})
+
+	It("uses the RTT measured by the peer as long as the path has none", func() {
+		estimator.SetRemoteRTT(60 * time.Millisecond)
+		estimator.OnPacketReceived(time.Second, base.Add(30*time.Millisecond))
+		Expect(toLocalTime(time.Second)).To(Equal(base))
+		rttStats.UpdateRTT(40*time.Millisecond, 0, base)
+		Expect(toLocalTime(time.Second)).To(Equal(base.Add(10 * time.Millisecond)))
+	})
+
+	It("forgets old samples", func() {
+		rttStats.UpdateRTT(20*time.Millisecond, 0, base)
+		estimator.OnPacketReceived(time.Second, base.Add(10*time.Millisecond))
+		// the clock of the peer is 5ms slower in the next windows
+		t := base.Add(clockOffsetWindow + 11*time.Millisecond)
+		estimator.OnPacketReceived(time.Second+clockOffsetWindow+time.Millisecond-5*time.Millisecond, t)
+		Expect(toLocalTime(time.Second)).To(Equal(base))
+		t = t.Add(clockOffsetWindow + time.Millisecond)
+		estimator.OnPacketReceived(time.Second+2*clockOffsetWindow+2*time.Millisecond-5*time.Millisecond, t)
+		Expect(toLocalTime(time.Second)).To(Equal(base.Add(5 * time.Millisecond)))
+	})
+})
@@ -37,6 +37,11 @@ type SentPacketHandler interface {
 
 	GetPathAlpha() float32
 
+	// GetDeadlineStatistics returns the number of packets with a deadline the peer reported on, and of those that met it
+	GetDeadlineStatistics() (uint64, uint64)
+	// DeadlineMisses returns the packets the peer reported late since the last call
+	DeadlineMisses() []DeadlineMiss
+
 	// czy
 	CalculateMeetRatio() float32
 	CalculateInstantMeetRatio() float32
@@ -56,8 +61,10 @@ type ReceivedPacketHandler interface {
 	GetStatistics() (uint64, uint64, uint64)
 
 	StatisticPacketMeet(hdr *wire.PublicHeader, rcvTime time.Time) error
+	// SetRemoteRTT sets the RTT of the path measured by the peer, as sent in PATHS frames
+	SetRemoteRTT(rtt time.Duration)
 
 	//czy
-	UpdateCurNotSent(curNotSent uint16)
+	UpdateCurNotSent(curNotSent uint16, batchLength uint16)
 	UpdateAlpha(alpha uint16)
 }
@@ -28,6 +28,9 @@ func (p *Packet) GetFramesForRetransmission() []wire.Frame {
 			continue
 		case *wire.StopWaitingFrame:
 			continue
+		case *wire.DatagramFrame:
+			// datagrams are unreliable
+			continue
 		}
 		fs = append(fs, frame)
 	}
@@ -53,6 +56,11 @@ func (p *Packet) IsRetransmittable() bool {
 			return true
 		case *wire.PathsFrame:
 			return true
+		case *wire.PathPriorityFrame:
+			return true
+		case *wire.DatagramFrame:
+			// not retransmitted, but it keeps the RTO armed, so that a lost datagram doesn't stay in flight
+			return true
 		}
 	}
 	return false
@@ -47,5 +47,11 @@ var _ = Describe("Packet", func() {
 			Expect(fs).ToNot(ContainElement(ackFrame))
 		})
 
+		It("never retransmits datagrams", func() {
+			packet := &Packet{Frames: []wire.Frame{&wire.DatagramFrame{Data: []byte{0x42}}}}
+			Expect(packet.IsRetransmittable()).To(BeTrue())
+			Expect(packet.GetFramesForRetransmission()).To(BeEmpty())
+		})
+
 	})
 })
@@ -4,12 +4,18 @@ import (
 	"errors"
 	"time"
 
+	"github.com/lucas-clemente/quic-go/congestion"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 )
 
 var errInvalidPacketNumber = errors.New("ReceivedPacketHandler: Invalid packet number")
 
+// maxPendingDeadlineSamples is the maximum number of DeadlineSamples waiting to be sent in an ACK.
+// If more packets with a deadline arrive, the oldest samples are dropped.
+const maxPendingDeadlineSamples = 8 * wire.MaxDeadlineSamples
+
 type receivedPacketHandler struct {
 	largestObserved             protocol.PacketNumber
 	lowerLimit                  protocol.PacketNumber
@@ -33,19 +39,24 @@ type receivedPacketHandler struct {
 	packetsHasDeadline  uint64
 	packetsMeetDeadline uint64
 
-	packetsMeetDeadlineSinceLastAck   uint16
-	packetNotMeetDeadlineSinceLastAck uint16
+	// deadlineSamples are the samples not sent in an ACK yet
+	deadlineSamples []wire.DeadlineSample
+
+	curNotSent  uint16
+	batchLength uint16
+	alpha       uint16
 
-	curNotSent uint16
-	alpha      uint16
+	clockOffset *clockOffsetEstimator
 }
 
-// NewReceivedPacketHandler creates a new receivedPacketHandler
-func NewReceivedPacketHandler(version protocol.VersionNumber) ReceivedPacketHandler {
+// NewReceivedPacketHandler creates a new receivedPacketHandler.
+// The RTT of the path is used to estimate the clock offset to the peer, and the RTT measured by the peer as long as it has none.
+func NewReceivedPacketHandler(version protocol.VersionNumber, rttStats *congestion.RTTStats) ReceivedPacketHandler {
 	return &receivedPacketHandler{
 		packetHistory: newReceivedPacketHistory(),
 		ackSendDelay:  protocol.AckSendDelay,
 		version:       version,
+		clockOffset:   newClockOffsetEstimator(rttStats),
 	}
 }
 
@@ -142,11 +153,16 @@ func (h *receivedPacketHandler) GetAckFrame() *wire.AckFrame {
 		LargestAcked:       h.largestObserved,
 		LowestAcked:        ackRanges[len(ackRanges)-1].First,
 		PacketReceivedTime: h.largestObservedReceivedTime,
-		NumMeetDeadline:    h.packetsMeetDeadlineSinceLastAck,
-		NumHasDeadline:     h.packetNotMeetDeadlineSinceLastAck,
 		CurNotSent:         h.curNotSent,
+		BatchLength:        h.batchLength,
 		Alpha:              h.alpha,
 	}
+	// the samples that don't fit are sent with the next ACK
+	if n := len(h.deadlineSamples); n > 0 {
+		n = utils.Min(n, wire.MaxDeadlineSamples)
+		ack.DeadlineSamples = h.deadlineSamples[:n:n]
+		h.deadlineSamples = h.deadlineSamples[n:]
+	}
 
 	if len(ackRanges) > 1 {
 		ack.AckRanges = ackRanges
@@ -157,8 +173,6 @@ func (h *receivedPacketHandler) GetAckFrame() *wire.AckFrame {
 	h.ackQueued = false
 	h.packetsReceivedSinceLastAck = 0
 	h.retransmittablePacketsReceivedSinceLastAck = 0
-	h.packetsMeetDeadlineSinceLastAck = 0
-	h.packetNotMeetDeadlineSinceLastAck = 0
 
 	return ack
 }
@@ -180,25 +194,40 @@ func (h *receivedPacketHandler) GetClosePathFrame() *wire.ClosePathFrame {
 func (h *receivedPacketHandler) GetAlarmTimeout() time.Time { return h.ackAlarm }
 
 func (h *receivedPacketHandler) StatisticPacketMeet(hdr *wire.PublicHeader, rcvTime time.Time) error {
-	if !hdr.Deadline.IsZero() {
-		meetTime := hdr.Deadline.Sub(rcvTime) //Deadline - RcvTime
-		if meetTime > 0 {
-			//meet deadline
-			h.packetsHasDeadline++
-			h.packetsMeetDeadline++
-
-			h.packetsMeetDeadlineSinceLastAck++
-			h.packetNotMeetDeadlineSinceLastAck++
-		} else { //not meet deadline
-			h.packetsHasDeadline++
-			h.packetNotMeetDeadlineSinceLastAck++
-		}
+	h.clockOffset.OnPacketReceived(hdr.Timestamp, rcvTime)
+	if hdr.DeadlineTTL == 0 {
+		return nil
+	}
+	// The deadline is relative to the send time, in the clock of the peer.
+	// Without an RTT estimate, the lateness can't be told, so the packet is not reported.
+	sendTime, ok := h.clockOffset.ToLocalTime(hdr.Timestamp)
+	if !ok {
+		return nil
 	}
+	deadline := sendTime.Add(hdr.DeadlineTTL)
+	sample := wire.DeadlineSample{PacketNumber: hdr.PacketNumber, Lateness: rcvTime.Sub(deadline)}
+	h.packetsHasDeadline++
+	if sample.MetDeadline() {
+		h.packetsMeetDeadline++
+	}
+	// packets below the lower limit are not acked anymore
+	if hdr.PacketNumber <= h.lowerLimit {
+		return nil
+	}
+	if len(h.deadlineSamples) >= maxPendingDeadlineSamples {
+		h.deadlineSamples = h.deadlineSamples[1:]
+	}
+	h.deadlineSamples = append(h.deadlineSamples, sample)
 	return nil
 }
 
-func (h *receivedPacketHandler) UpdateCurNotSent(curNotSent uint16) {
+func (h *receivedPacketHandler) SetRemoteRTT(rtt time.Duration) {
+	h.clockOffset.SetRemoteRTT(rtt)
+}
+
+func (h *receivedPacketHandler) UpdateCurNotSent(curNotSent uint16, batchLength uint16) {
 	h.curNotSent = curNotSent
+	h.batchLength = batchLength
 }
 
 func (h *receivedPacketHandler) UpdateAlpha(alpha uint16) {
@@ -3,6 +3,7 @@ package ackhandler
 import (
 	"time"
 
+	"github.com/lucas-clemente/quic-go/congestion"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 
@@ -16,7 +17,7 @@ var _ = Describe("receivedPacketHandler", func() {
 	)
 
 	BeforeEach(func() {
-		handler = NewReceivedPacketHandler(protocol.VersionWhatever).(*receivedPacketHandler)
+		handler = NewReceivedPacketHandler(protocol.VersionWhatever, &congestion.RTTStats{}).(*receivedPacketHandler)
 	})
 
 	Context("accepting packets", func() {
@@ -320,4 +321,90 @@ var _ = Describe("receivedPacketHandler", func() {
 			})
 		})
 	})
+
+	Context("deadline statistics", func() {
+		var base time.Time
+
+		BeforeEach(func() {
+			base = time.Now()
+			rttStats := &congestion.RTTStats{}
+			rttStats.UpdateRTT(20*time.Millisecond, 0, base)
+			handler.clockOffset.rttStats = rttStats
+		})
+
+		It("doesn't report packets without an RTT estimate", func() {
+			handler.clockOffset.rttStats = &congestion.RTTStats{}
+			hdr := &wire.PublicHeader{PacketNumber: 1, Timestamp: time.Hour + 100*time.Millisecond, DeadlineTTL: 20 * time.Millisecond}
+			Expect(handler.StatisticPacketMeet(hdr, base.Add(110*time.Millisecond))).To(Succeed())
+			_, hasDeadline, _ := handler.GetStatistics()
+			Expect(hasDeadline).To(BeZero())
+			Expect(handler.deadlineSamples).To(BeEmpty())
+			// the RTT measured by the peer is enough
+			handler.SetRemoteRTT(20 * time.Millisecond)
+			hdr = &wire.PublicHeader{PacketNumber: 2, Timestamp: time.Hour + 100*time.Millisecond, DeadlineTTL: 20 * time.Millisecond}
+			Expect(handler.StatisticPacketMeet(hdr, base.Add(110*time.Millisecond))).To(Succeed())
+			Expect(handler.deadlineSamples).To(Equal([]wire.DeadlineSample{{PacketNumber: 2, Lateness: -10 * time.Millisecond}}))
+		})
+
+		It("echoes the packets not sent and the length of the last batch", func() {
+			Expect(handler.ReceivedPacket(1, true)).To(Succeed())
+			handler.UpdateCurNotSent(2, 6)
+			ack := handler.GetAckFrame()
+			Expect(ack.CurNotSent).To(BeEquivalentTo(2))
+			Expect(ack.BatchLength).To(BeEquivalentTo(6))
+		})
+
+		It("ignores packets without a deadline", func() {
+			hdr := &wire.PublicHeader{Timestamp: 100 * time.Millisecond}
+			Expect(handler.StatisticPacketMeet(hdr, base.Add(110*time.Millisecond))).To(Succeed())
+			_, hasDeadline, _ := handler.GetStatistics()
+			Expect(hasDeadline).To(BeZero())
+		})
+
+		It("compares the deadline to the receive time, independently of the peer's clock", func() {
+			// the one-way delay is 10ms, half the RTT
+			hdr := &wire.PublicHeader{PacketNumber: 1, Timestamp: time.Hour + 100*time.Millisecond, DeadlineTTL: 20 * time.Millisecond}
+			Expect(handler.StatisticPacketMeet(hdr, base.Add(110*time.Millisecond))).To(Succeed())
+			// this packet is delayed by 40ms
+			hdr = &wire.PublicHeader{PacketNumber: 2, Timestamp: time.Hour + 200*time.Millisecond, DeadlineTTL: 20 * time.Millisecond}
+			Expect(handler.StatisticPacketMeet(hdr, base.Add(240*time.Millisecond))).To(Succeed())
+			_, hasDeadline, meetDeadline := handler.GetStatistics()
+			Expect(hasDeadline).To(BeEquivalentTo(2))
+			Expect(meetDeadline).To(BeEquivalentTo(1))
+			Expect(handler.deadlineSamples).To(Equal([]wire.DeadlineSample{
+				{PacketNumber: 1, Lateness: -10 * time.Millisecond},
+				{PacketNumber: 2, Lateness: 20 * time.Millisecond},
+			}))
+		})
+
+		It("reports the samples in the next ACKs", func() {
+			for i := 1; i <= wire.MaxDeadlineSamples+3; i++ {
+				pn := protocol.PacketNumber(i)
+				Expect(handler.ReceivedPacket(pn, true)).To(Succeed())
+				hdr := &wire.PublicHeader{PacketNumber: pn, Timestamp: time.Hour, DeadlineTTL: time.Second}
+				Expect(handler.StatisticPacketMeet(hdr, base)).To(Succeed())
+			}
+			handler.ackQueued = true
+			ack := handler.GetAckFrame()
+			Expect(ack.DeadlineSamples).To(HaveLen(wire.MaxDeadlineSamples))
+			Expect(ack.DeadlineSamples[0].PacketNumber).To(Equal(protocol.PacketNumber(1)))
+			Expect(ack.DeadlineSamples[0].MetDeadline()).To(BeTrue())
+			// the samples that didn't fit are sent with the next ACK
+			handler.ackQueued = true
+			ack = handler.GetAckFrame()
+			Expect(ack.DeadlineSamples).To(HaveLen(3))
+			Expect(ack.DeadlineSamples[0].PacketNumber).To(Equal(protocol.PacketNumber(wire.MaxDeadlineSamples + 1)))
+			handler.ackQueued = true
+			Expect(handler.GetAckFrame().DeadlineSamples).To(BeEmpty())
+		})
+
+		It("keeps a bounded number of samples", func() {
+			for i := 1; i <= maxPendingDeadlineSamples+5; i++ {
+				hdr := &wire.PublicHeader{PacketNumber: protocol.PacketNumber(i), Timestamp: time.Hour, DeadlineTTL: time.Second}
+				Expect(handler.StatisticPacketMeet(hdr, base)).To(Succeed())
+			}
+			Expect(handler.deadlineSamples).To(HaveLen(maxPendingDeadlineSamples))
+			Expect(handler.deadlineSamples[0].PacketNumber).To(Equal(protocol.PacketNumber(6)))
+		})
+	})
 })
@@ -33,10 +33,8 @@ const (
 	minRetransmissionTime = 200 * time.Millisecond
 	// Minimum tail loss probe time in ms
 	minTailLossProbeTimeout = 10 * time.Millisecond
-	// czy: discount reward factor gamma
-	gamma      = 0.5
-	batch      = 6
-	historyLen = 10
+	// The maximum number of DeadlineMisses kept until they are collected
+	maxDeadlineMisses = 256
 )
 
 var (
@@ -99,6 +97,20 @@ type sentPacketHandler struct {
 	changePDInfo ChangePointDetectionHandler
 
 	curNotSent uint8 // save the current Not Sent
+
+	deadlineSamples    uint64
+	deadlineSamplesMet uint64
+	deadlineMisses     []DeadlineMiss
+}
+
+// A DeadlineMiss is a packet the peer reported to have arrived after its deadline
+type DeadlineMiss struct {
+	PacketNumber protocol.PacketNumber
+	// Lateness is how long after the deadline the packet arrived
+	Lateness time.Duration
+	// StreamIDs are the streams with data in the packet.
+	// They are unknown if the packet was already acked or declared lost.
+	StreamIDs []protocol.StreamID
 }
 
 type ChangePointDetectionHandler struct {
@@ -106,22 +118,18 @@ type ChangePointDetectionHandler struct {
 	totalHasDeadline        uint16
 	curMeetDeadline         uint16
 	curHasDeadline          uint16
-	alpha                   float32           // RTT discount factor, every RTT has an alpha
-	historicalMeetDeadlines [][]uint16        // history curMeetDeadline
-	historicalHasDeadlines  [][]uint16        // history curHasDeadline
-	banditInformation       BanditInformation // Bandit Information
-}
+	alpha                   float32    // RTT discount factor, every RTT has an alpha
+	historicalMeetDeadlines [][]uint16 // history curMeetDeadline
+	historicalHasDeadlines  [][]uint16 // history curHasDeadline
 
-type BanditInformation struct {
-	armsAlpha    []float32
-	armsNumPlay  []int
-	totalNumPlay int
-	totalReward  []float32
-	curArmIndex  int
+	config      *BanditConfig
+	bandit      bandit
+	curArmIndex int
 }
 
-// NewSentPacketHandler creates a new sentPacketHandler
-func NewSentPacketHandler(rttStats *congestion.RTTStats, cong congestion.SendAlgorithm, onRTOCallback func(time.Time) bool) SentPacketHandler {
+// NewSentPacketHandler creates a new sentPacketHandler.
+// If banditConfig is nil, the fluctuation monitor uses the default configuration.
+func NewSentPacketHandler(rttStats *congestion.RTTStats, cong congestion.SendAlgorithm, onRTOCallback func(time.Time) bool, banditConfig *BanditConfig) SentPacketHandler {
 	var congestionControl congestion.SendAlgorithm
 
 	if cong != nil {
@@ -136,8 +144,7 @@ func NewSentPacketHandler(rttStats *congestion.RTTStats, cong congestion.SendAlg
 		)
 	}
 
-	// initial BanditInformation
-	bandit := NewBanditInformation()
+	banditConfig = banditConfig.populate()
 
 	return &sentPacketHandler{
 		packetHistory:      NewPacketList(),
@@ -146,25 +153,15 @@ func NewSentPacketHandler(rttStats *congestion.RTTStats, cong congestion.SendAlg
 		congestion:         congestionControl,
 		onRTOCallback:      onRTOCallback,
 		changePDInfo: ChangePointDetectionHandler{
-			alpha:                   1.0,
-			banditInformation:       bandit,
-			historicalMeetDeadlines: make([][]uint16, len(bandit.armsAlpha)),
-			historicalHasDeadlines:  make([][]uint16, len(bandit.armsAlpha)),
+			alpha:                   banditConfig.Arms[0],
+			historicalMeetDeadlines: make([][]uint16, len(banditConfig.Arms)),
+			historicalHasDeadlines:  make([][]uint16, len(banditConfig.Arms)),
+			config:                  banditConfig,
+			bandit:                  newBandit(banditConfig),
 		},
 	}
 }
 
-// NewBanditInformation creates a new BanditInformation
-func NewBanditInformation() BanditInformation {
-	var bandit BanditInformation
-	bandit.armsAlpha = []float32{1.0, 1.1, 1.2} // initial alpha
-	bandit.armsNumPlay = []int{0, 0, 0}         // initial num is zeros
-	bandit.totalNumPlay = 0
-	bandit.totalReward = []float32{0.0, 0.0, 0.0} // initial total reward is zeros
-	bandit.curArmIndex = 0
-	return bandit
-}
-
 func (h *sentPacketHandler) GetStatistics() (uint64, uint64, uint64) {
 	return h.packets, h.retransmissions, h.losses
 }
@@ -181,7 +178,7 @@ func (h *sentPacketHandler) GetLastPackets() uint64 {
 }
 
 func (h *sentPacketHandler) GetPathAlpha() float32 {
-	return h.changePDInfo.banditInformation.armsAlpha[h.changePDInfo.banditInformation.curArmIndex]
+	return h.changePDInfo.config.Arms[h.changePDInfo.curArmIndex]
 }
 
 func (h *sentPacketHandler) ShouldSendRetransmittablePacket() bool {
@@ -248,6 +245,8 @@ func (h *sentPacketHandler) ReceivedAck(ackFrame *wire.AckFrame, withPacketNumbe
 		return errAckForUnsentPacket
 	}
 
+	// every sample is reported in a single ACK, so this is also done for out-of-order ACKs,
+	// and before the acked packets are removed from the history
 	h.updateDeadlineInformation(ackFrame)
 
 	// duplicate or out-of-order ACK
@@ -713,36 +712,87 @@ func (h *sentPacketHandler) garbageCollectSkippedPackets() {
 }
 
 func (h *sentPacketHandler) updateDeadlineInformation(ackFrame *wire.AckFrame) {
-	h.changePDInfo.curMeetDeadline = ackFrame.NumMeetDeadline
-	h.changePDInfo.curHasDeadline = ackFrame.NumHasDeadline
+	// A peer without the deadline extension doesn't report anything
+	if !ackFrame.HasDeadlineInformation {
+		return
+	}
+	h.changePDInfo.curMeetDeadline = 0
+	h.changePDInfo.curHasDeadline = 0
+	var misses []DeadlineMiss
+	for _, sample := range ackFrame.DeadlineSamples {
+		h.changePDInfo.curHasDeadline++
+		if sample.MetDeadline() {
+			h.changePDInfo.curMeetDeadline++
+		} else {
+			misses = append(misses, DeadlineMiss{PacketNumber: sample.PacketNumber, Lateness: sample.Lateness})
+		}
+	}
+	h.deadlineSamples += uint64(h.changePDInfo.curHasDeadline)
+	h.deadlineSamplesMet += uint64(h.changePDInfo.curMeetDeadline)
+	h.addDeadlineMisses(misses)
 
 	// Find arm Index of alpha
 	alphaTrue := float32(ackFrame.Alpha) / float32(10)
-	armIndex := findIndexOfAlpha(alphaTrue, h.changePDInfo.banditInformation.armsAlpha)
+	armIndex := findIndexOfAlpha(alphaTrue, h.changePDInfo.config.Arms)
 
 	// Update history Deadline Information
 	h.changePDInfo.updateHistoricalData(armIndex)
 
 	// Update Bandit Information
-	reward := h.CalculateHistoryMeetRatio(armIndex)
-	reward = reward - float32(ackFrame.CurNotSent)/float32(batch)
-	h.changePDInfo.updateBanditInfo(reward, armIndex)
+	// The peer echoes the length of the batch CurNotSent belongs to
+	batchSize := int(ackFrame.BatchLength)
+	if batchSize == 0 {
+		batchSize = h.changePDInfo.config.BatchSize
+	}
+	reward := h.changePDInfo.config.Reward(h.CalculateHistoryMeetRatio(armIndex), ackFrame.CurNotSent, batchSize)
+	h.changePDInfo.bandit.update(armIndex, reward)
 
 	// Update alpha
 	h.changePDInfo.updateAlpha()
 
 	// Update total Deadline Information
-	h.changePDInfo.totalMeetDeadline = h.changePDInfo.totalMeetDeadline + ackFrame.NumMeetDeadline
-	h.changePDInfo.totalHasDeadline = h.changePDInfo.totalHasDeadline + ackFrame.NumHasDeadline
+	h.changePDInfo.totalMeetDeadline = h.changePDInfo.totalMeetDeadline + h.changePDInfo.curMeetDeadline
+	h.changePDInfo.totalHasDeadline = h.changePDInfo.totalHasDeadline + h.changePDInfo.curHasDeadline
 }
 
-func (cpd *ChangePointDetectionHandler) updateBanditInfo(reward float32, armIndex int) {
-	//update discount reward
-	cpd.banditInformation.totalReward[armIndex] =
-		gamma*cpd.banditInformation.totalReward[armIndex] + reward
-	// update numPlays
-	cpd.banditInformation.armsNumPlay[cpd.banditInformation.curArmIndex]++
-	cpd.banditInformation.totalNumPlay++
+// addDeadlineMisses adds the missed packets, with the streams of those that are still in the history
+func (h *sentPacketHandler) addDeadlineMisses(misses []DeadlineMiss) {
+	if len(misses) == 0 {
+		return
+	}
+	missIndex := make(map[protocol.PacketNumber]int, len(misses))
+	for i, miss := range misses {
+		missIndex[miss.PacketNumber] = i
+	}
+	for el := h.packetHistory.Front(); el != nil; el = el.Next() {
+		i, ok := missIndex[el.Value.PacketNumber]
+		if !ok {
+			continue
+		}
+		for _, f := range el.Value.Frames {
+			if sf, ok := f.(*wire.StreamFrame); ok {
+				misses[i].StreamIDs = append(misses[i].StreamIDs, sf.StreamID)
+			}
+		}
+	}
+	h.deadlineMisses = append(h.deadlineMisses, misses...)
+	// if nobody collects them, only keep the latest misses
+	if len(h.deadlineMisses) > maxDeadlineMisses {
+		h.deadlineMisses = h.deadlineMisses[len(h.deadlineMisses)-maxDeadlineMisses:]
+	}
+}
+
+// GetDeadlineStatistics returns the number of sent packets with a deadline the peer reported on,
+// and the number of those that arrived in time
+func (h *sentPacketHandler) GetDeadlineStatistics() (uint64, uint64) {
+	return h.deadlineSamples, h.deadlineSamplesMet
+}
+
+// DeadlineMisses returns the packets reported late since the last call
+func (h *sentPacketHandler) DeadlineMisses() []DeadlineMiss {
+	misses := h.deadlineMisses
+	h.deadlineMisses = nil
+	return misses
 }
 
 func findIndexOfAlpha(alpha float32, arm []float32) int {
@@ -762,42 +812,8 @@ func isEqualFloat32(a, b float32) bool {
 }
 
 func (cpd *ChangePointDetectionHandler) updateAlpha() {
-	// computeUCB
-	ucbs := cpd.banditInformation.computeUCB()
-
-	//select best alpha
-	bestArm := selectBestArm(ucbs)
-	bestAlpha := cpd.banditInformation.armsAlpha[bestArm]
-	cpd.banditInformation.curArmIndex = bestArm
-	cpd.alpha = bestAlpha
-
-}
-
-func (bandit *BanditInformation) computeUCB() []float32 {
-	ucbs := make([]float32, len(bandit.armsAlpha))
-	for i := 0; i < len(bandit.armsAlpha); i++ {
-		if bandit.armsNumPlay[i] == 0 {
-			//ucbs[i] = float32(math.Inf(1))
-			ucbs[i] = 2
-		} else {
-			aveReward := bandit.totalReward[i] / float32(bandit.armsNumPlay[i])
-			delta := math.Sqrt(2 * math.Log(float64(bandit.totalNumPlay+1)) / float64(bandit.armsNumPlay[i]))
-			ucbs[i] = aveReward + float32(delta)
-		}
-	}
-	return ucbs
-}
-
-func selectBestArm(ucbs []float32) int {
-	bestArm := 0
-	maxUcb := ucbs[0]
-	for i := 0; i < len(ucbs); i++ {
-		if ucbs[i] > maxUcb {
-			bestArm = i
-			maxUcb = ucbs[i]
-		}
-	}
-	return bestArm
+	cpd.curArmIndex = cpd.bandit.selectArm()
+	cpd.alpha = cpd.config.Arms[cpd.curArmIndex]
 }
 
 func (cpd *ChangePointDetectionHandler) updateHistoricalData(armIndex int) {
@@ -805,12 +821,12 @@ func (cpd *ChangePointDetectionHandler) updateHistoricalData(armIndex int) {
 	cpd.historicalHasDeadlines[armIndex] = append(cpd.historicalHasDeadlines[armIndex], cpd.curHasDeadline)
 
 	//check slice is not more history len
-	if len(cpd.historicalMeetDeadlines[armIndex]) > historyLen {
-		cpd.historicalMeetDeadlines[armIndex] = cpd.historicalMeetDeadlines[armIndex][len(cpd.historicalMeetDeadlines[armIndex])-historyLen:]
+	if len(cpd.historicalMeetDeadlines[armIndex]) > cpd.config.HistoryLen {
+		cpd.historicalMeetDeadlines[armIndex] = cpd.historicalMeetDeadlines[armIndex][len(cpd.historicalMeetDeadlines[armIndex])-cpd.config.HistoryLen:]
 	}
 
-	if len(cpd.historicalHasDeadlines[armIndex]) > historyLen {
-		cpd.historicalHasDeadlines[armIndex] = cpd.historicalHasDeadlines[armIndex][len(cpd.historicalHasDeadlines[armIndex])-historyLen:]
+	if len(cpd.historicalHasDeadlines[armIndex]) > cpd.config.HistoryLen {
+		cpd.historicalHasDeadlines[armIndex] = cpd.historicalHasDeadlines[armIndex][len(cpd.historicalHasDeadlines[armIndex])-cpd.config.HistoryLen:]
 	}
 }
 
@@ -834,13 +850,13 @@ func (h *sentPacketHandler) CalculateInstantMeetRatio() float32 {
 
 // CalculateHistoryMeetRatio calculate history meet ratio
 func (h *sentPacketHandler) CalculateHistoryMeetRatio(armIndex int) float32 {
-	if len(h.changePDInfo.historicalMeetDeadlines[armIndex]) < historyLen ||
-		len(h.changePDInfo.historicalHasDeadlines[armIndex]) < historyLen {
+	if len(h.changePDInfo.historicalMeetDeadlines[armIndex]) < h.changePDInfo.config.HistoryLen ||
+		len(h.changePDInfo.historicalHasDeadlines[armIndex]) < h.changePDInfo.config.HistoryLen {
 		return 0
 	}
 
 	var meetSum, hasSum uint16
-	for i := len(h.changePDInfo.historicalMeetDeadlines[armIndex]) - historyLen; i < len(h.changePDInfo.historicalMeetDeadlines[armIndex]); i++ {
+	for i := len(h.changePDInfo.historicalMeetDeadlines[armIndex]) - h.changePDInfo.config.HistoryLen; i < len(h.changePDInfo.historicalMeetDeadlines[armIndex]); i++ {
 		meetSum += h.changePDInfo.historicalMeetDeadlines[armIndex][i]
 		hasSum += h.changePDInfo.historicalHasDeadlines[armIndex][i]
 	}
@@ -74,7 +74,7 @@ var _ = Describe("SentPacketHandler", func() {
 
 	BeforeEach(func() {
 		rttStats := &congestion.RTTStats{}
-		handler = NewSentPacketHandler(rttStats, nil, nil).(*sentPacketHandler)
+		handler = NewSentPacketHandler(rttStats, nil, nil, nil).(*sentPacketHandler)
 		streamFrame = wire.StreamFrame{
 			StreamID: 5,
 			Data:     []byte{0x13, 0x37},
@@ -562,6 +562,65 @@ var _ = Describe("SentPacketHandler", func() {
 				Expect(handler.rttStats.LatestRTT()).To(BeNumerically("~", 5*time.Minute, 1*time.Second))
 			})
 		})
+
+		Context("deadline feedback", func() {
+			var ack *wire.AckFrame
+
+			BeforeEach(func() {
+				ack = &wire.AckFrame{
+					LargestAcked:           3,
+					LowestAcked:            1,
+					HasDeadlineInformation: true,
+					DeadlineSamples: []wire.DeadlineSample{
+						{PacketNumber: 1, Lateness: -time.Millisecond},
+						{PacketNumber: 2, Lateness: 5 * time.Millisecond},
+						{PacketNumber: 3, Lateness: -time.Millisecond},
+					},
+				}
+			})
+
+			It("counts the samples", func() {
+				Expect(handler.ReceivedAck(ack, 1, time.Now())).To(Succeed())
+				reported, met := handler.GetDeadlineStatistics()
+				Expect(reported).To(BeEquivalentTo(3))
+				Expect(met).To(BeEquivalentTo(2))
+				Expect(handler.changePDInfo.curHasDeadline).To(BeEquivalentTo(3))
+				Expect(handler.changePDInfo.curMeetDeadline).To(BeEquivalentTo(2))
+			})
+
+			It("counts the samples of reordered ACKs", func() {
+				Expect(handler.ReceivedAck(ack, 2, time.Now())).To(Succeed())
+				reordered := &wire.AckFrame{
+					LargestAcked:           1,
+					LowestAcked:            1,
+					HasDeadlineInformation: true,
+					DeadlineSamples:        []wire.DeadlineSample{{PacketNumber: 4, Lateness: 3 * time.Millisecond}},
+				}
+				Expect(handler.ReceivedAck(reordered, 1, time.Now())).To(MatchError(ErrDuplicateOrOutOfOrderAck))
+				reported, met := handler.GetDeadlineStatistics()
+				Expect(reported).To(BeEquivalentTo(4))
+				Expect(met).To(BeEquivalentTo(2))
+				Expect(handler.changePDInfo.totalHasDeadline).To(BeEquivalentTo(4))
+				Expect(handler.DeadlineMisses()).To(HaveLen(2))
+			})
+
+			It("reports the missed packets with their streams", func() {
+				Expect(handler.ReceivedAck(ack, 1, time.Now())).To(Succeed())
+				Expect(handler.DeadlineMisses()).To(Equal([]DeadlineMiss{
+					{PacketNumber: 2, Lateness: 5 * time.Millisecond, StreamIDs: []protocol.StreamID{5}},
+				}))
+				Expect(handler.DeadlineMisses()).To(BeEmpty())
+			})
+
+			It("reports misses of packets that were already acked without streams", func() {
+				Expect(handler.ReceivedAck(&wire.AckFrame{LargestAcked: 3, LowestAcked: 1}, 1, time.Now())).To(Succeed())
+				Expect(handler.ReceivedAck(ack, 2, time.Now())).To(Succeed())
+				misses := handler.DeadlineMisses()
+				Expect(misses).To(HaveLen(1))
+				Expect(misses[0].PacketNumber).To(Equal(protocol.PacketNumber(2)))
+				Expect(misses[0].StreamIDs).To(BeEmpty())
+			})
+		})
 	})
 
 	Context("Retransmission handling", func() {
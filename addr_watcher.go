@@ -0,0 +1,22 @@
+package quic
+
+import "net"
+
+// An addressEvent reports a change of the local addresses
+type addressEvent struct {
+	// Interface is the name of the interface
+	Interface string
+	// IP is the address that was added or removed. It is nil if the whole interface went up or down.
+	IP      net.IP
+	Removed bool
+}
+
+// An addressWatcher reports changes of the local addresses.
+// On Linux, it is implemented with rtnetlink. Elsewhere, the pconnManager only polls the interfaces.
+type addressWatcher interface {
+	Events() <-chan addressEvent
+	Close() error
+}
+
+// newAddressWatcher creates the addressWatcher of a client. It can be replaced in tests.
+var newAddressWatcher = newSystemAddressWatcher
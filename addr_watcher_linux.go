@@ -0,0 +1,182 @@
+//go:build linux
+// +build linux
+
+package quic
+
+import (
+	"bytes"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+// netlinkReadTimeout is how often the netlinkAddressWatcher checks if it was closed
+const netlinkReadTimeout = 500 * time.Millisecond
+
+// The rtnetlink multicast groups, which the syscall package doesn't define
+const (
+	rtmgrpLink       = 0x1
+	rtmgrpIPv4IfAddr = 0x10
+	rtmgrpIPv6IfAddr = 0x100
+)
+
+// netlinkAddressWatcher listens to the address and link notifications of rtnetlink
+type netlinkAddressWatcher struct {
+	fd int
+
+	events    chan addressEvent
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+var _ addressWatcher = &netlinkAddressWatcher{}
+
+func newSystemAddressWatcher() (addressWatcher, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, syscall.NETLINK_ROUTE)
+	if err != nil {
+		return nil, err
+	}
+	sa := &syscall.SockaddrNetlink{
+		Family: syscall.AF_NETLINK,
+		Groups: rtmgrpLink | rtmgrpIPv4IfAddr | rtmgrpIPv6IfAddr,
+	}
+	if err := syscall.Bind(fd, sa); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	tv := syscall.NsecToTimeval(int64(netlinkReadTimeout))
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &tv); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	w := &netlinkAddressWatcher{
+		fd:     fd,
+		events: make(chan addressEvent, 16),
+		closed: make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+func (w *netlinkAddressWatcher) run() {
+	defer close(w.events)
+	defer syscall.Close(w.fd)
+
+	buf := make([]byte, 1<<16)
+	for {
+		select {
+		case <-w.closed:
+			return
+		default:
+		}
+		n, _, err := syscall.Recvfrom(w.fd, buf, 0)
+		if err != nil {
+			switch err {
+			case syscall.EAGAIN, syscall.EINTR:
+			case syscall.ENOBUFS:
+				// Notifications were dropped, so let the pconnManager check all interfaces
+				w.send(addressEvent{})
+			default:
+				utils.Errorf("address watcher: %v", err)
+				return
+			}
+			continue
+		}
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			utils.Errorf("address watcher: %v", err)
+			continue
+		}
+		for i := range msgs {
+			if ev, ok := parseNetlinkAddressEvent(&msgs[i], interfaceName); ok {
+				w.send(ev)
+			}
+		}
+	}
+}
+
+func (w *netlinkAddressWatcher) send(ev addressEvent) {
+	select {
+	case w.events <- ev:
+	case <-w.closed:
+	}
+}
+
+func (w *netlinkAddressWatcher) Events() <-chan addressEvent {
+	return w.events
+}
+
+func (w *netlinkAddressWatcher) Close() error {
+	w.closeOnce.Do(func() { close(w.closed) })
+	return nil
+}
+
+// parseNetlinkAddressEvent converts RTM_NEWADDR, RTM_DELADDR, RTM_NEWLINK and RTM_DELLINK messages
+func parseNetlinkAddressEvent(m *syscall.NetlinkMessage, interfaceName func(index int) string) (addressEvent, bool) {
+	switch m.Header.Type {
+	case syscall.RTM_NEWADDR, syscall.RTM_DELADDR:
+		if len(m.Data) < syscall.SizeofIfAddrmsg {
+			return addressEvent{}, false
+		}
+		ifa := (*syscall.IfAddrmsg)(unsafe.Pointer(&m.Data[0]))
+		attrs, err := syscall.ParseNetlinkRouteAttr(m)
+		if err != nil {
+			return addressEvent{}, false
+		}
+		var ip net.IP
+		for _, a := range attrs {
+			switch a.Attr.Type {
+			case syscall.IFA_LOCAL:
+				// On point-to-point links, IFA_ADDRESS is the address of the peer
+				ip = append(net.IP(nil), a.Value...)
+			case syscall.IFA_ADDRESS:
+				if ip == nil {
+					ip = append(net.IP(nil), a.Value...)
+				}
+			}
+		}
+		if ip == nil {
+			return addressEvent{}, false
+		}
+		return addressEvent{
+			Interface: interfaceName(int(ifa.Index)),
+			IP:        ip,
+			Removed:   m.Header.Type == syscall.RTM_DELADDR,
+		}, true
+	case syscall.RTM_NEWLINK, syscall.RTM_DELLINK:
+		if len(m.Data) < syscall.SizeofIfInfomsg {
+			return addressEvent{}, false
+		}
+		ifi := (*syscall.IfInfomsg)(unsafe.Pointer(&m.Data[0]))
+		attrs, err := syscall.ParseNetlinkRouteAttr(m)
+		if err != nil {
+			return addressEvent{}, false
+		}
+		var name string
+		for _, a := range attrs {
+			if a.Attr.Type == syscall.IFLA_IFNAME {
+				name = string(bytes.TrimRight(a.Value, "\x00"))
+			}
+		}
+		if name == "" {
+			name = interfaceName(int(ifi.Index))
+		}
+		return addressEvent{
+			Interface: name,
+			Removed:   m.Header.Type == syscall.RTM_DELLINK || ifi.Flags&syscall.IFF_UP == 0,
+		}, true
+	}
+	return addressEvent{}, false
+}
+
+func interfaceName(index int) string {
+	iface, err := net.InterfaceByIndex(index)
+	if err != nil {
+		return ""
+	}
+	return iface.Name
+}
@@ -0,0 +1,101 @@
+//go:build linux
+// +build linux
+
+package quic
+
+import (
+	"net"
+	"syscall"
+	"unsafe"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("netlink address watcher", func() {
+	interfaceName := func(index int) string {
+		Expect(index).To(Equal(3))
+		return "wlan0"
+	}
+
+	rtattr := func(typ uint16, value []byte) []byte {
+		b := make([]byte, (syscall.SizeofRtAttr+len(value)+3)&^3)
+		a := (*syscall.RtAttr)(unsafe.Pointer(&b[0]))
+		a.Len = uint16(syscall.SizeofRtAttr + len(value))
+		a.Type = typ
+		copy(b[syscall.SizeofRtAttr:], value)
+		return b
+	}
+
+	addrMessage := func(typ uint16, attrs ...[]byte) *syscall.NetlinkMessage {
+		data := make([]byte, syscall.SizeofIfAddrmsg)
+		(*syscall.IfAddrmsg)(unsafe.Pointer(&data[0])).Index = 3
+		for _, a := range attrs {
+			data = append(data, a...)
+		}
+		return &syscall.NetlinkMessage{Header: syscall.NlMsghdr{Type: typ}, Data: data}
+	}
+
+	linkMessage := func(typ uint16, flags uint32, attrs ...[]byte) *syscall.NetlinkMessage {
+		data := make([]byte, syscall.SizeofIfInfomsg)
+		ifi := (*syscall.IfInfomsg)(unsafe.Pointer(&data[0]))
+		ifi.Index = 3
+		ifi.Flags = flags
+		for _, a := range attrs {
+			data = append(data, a...)
+		}
+		return &syscall.NetlinkMessage{Header: syscall.NlMsghdr{Type: typ}, Data: data}
+	}
+
+	It("parses new addresses", func() {
+		ev, ok := parseNetlinkAddressEvent(addrMessage(syscall.RTM_NEWADDR, rtattr(syscall.IFA_ADDRESS, net.IPv4(10, 0, 0, 1).To4())), interfaceName)
+		Expect(ok).To(BeTrue())
+		Expect(ev.Interface).To(Equal("wlan0"))
+		Expect(ev.IP.Equal(net.IPv4(10, 0, 0, 1))).To(BeTrue())
+		Expect(ev.Removed).To(BeFalse())
+	})
+
+	It("prefers the local address of point-to-point links", func() {
+		ev, ok := parseNetlinkAddressEvent(addrMessage(syscall.RTM_DELADDR,
+			rtattr(syscall.IFA_ADDRESS, net.IPv4(10, 0, 0, 2).To4()),
+			rtattr(syscall.IFA_LOCAL, net.IPv4(10, 0, 0, 1).To4()),
+		), interfaceName)
+		Expect(ok).To(BeTrue())
+		Expect(ev.IP.Equal(net.IPv4(10, 0, 0, 1))).To(BeTrue())
+		Expect(ev.Removed).To(BeTrue())
+	})
+
+	It("parses IPv6 addresses", func() {
+		ip := net.ParseIP("2001:db8::1")
+		ev, ok := parseNetlinkAddressEvent(addrMessage(syscall.RTM_NEWADDR, rtattr(syscall.IFA_ADDRESS, ip)), interfaceName)
+		Expect(ok).To(BeTrue())
+		Expect(ev.IP.Equal(ip)).To(BeTrue())
+	})
+
+	It("reports interfaces going down", func() {
+		ev, ok := parseNetlinkAddressEvent(linkMessage(syscall.RTM_NEWLINK, 0, rtattr(syscall.IFLA_IFNAME, []byte("wwan0\x00"))), interfaceName)
+		Expect(ok).To(BeTrue())
+		Expect(ev).To(Equal(addressEvent{Interface: "wwan0", Removed: true}))
+		ev, ok = parseNetlinkAddressEvent(linkMessage(syscall.RTM_NEWLINK, syscall.IFF_UP), interfaceName)
+		Expect(ok).To(BeTrue())
+		Expect(ev).To(Equal(addressEvent{Interface: "wlan0"}))
+	})
+
+	It("ignores other messages", func() {
+		_, ok := parseNetlinkAddressEvent(&syscall.NetlinkMessage{Header: syscall.NlMsghdr{Type: syscall.RTM_NEWROUTE}}, interfaceName)
+		Expect(ok).To(BeFalse())
+		_, ok = parseNetlinkAddressEvent(addrMessage(syscall.RTM_NEWADDR), interfaceName)
+		Expect(ok).To(BeFalse())
+		_, ok = parseNetlinkAddressEvent(&syscall.NetlinkMessage{Header: syscall.NlMsghdr{Type: syscall.RTM_NEWADDR}}, interfaceName)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("can be closed", func() {
+		w, err := newSystemAddressWatcher()
+		if err != nil {
+			Skip("netlink is not available: " + err.Error())
+		}
+		Expect(w.Close()).To(Succeed())
+		Eventually(w.Events(), 2).Should(BeClosed())
+	})
+})
@@ -0,0 +1,9 @@
+//go:build !linux
+// +build !linux
+
+package quic
+
+// newSystemAddressWatcher returns nil, so that the pconnManager only polls the interfaces
+func newSystemAddressWatcher() (addressWatcher, error) {
+	return nil, nil
+}
@@ -0,0 +1,134 @@
+package quic
+
+import (
+	"context"
+	"net"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type fakeAddressWatcher struct {
+	events chan addressEvent
+	closed chan struct{}
+}
+
+func (w *fakeAddressWatcher) Events() <-chan addressEvent { return w.events }
+
+func (w *fakeAddressWatcher) Close() error {
+	close(w.closed)
+	return nil
+}
+
+var _ = Describe("Address watching", func() {
+	var (
+		pcm   *pconnManager
+		pconn net.PacketConn
+	)
+
+	listen := func() net.PacketConn {
+		c, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		return c
+	}
+
+	BeforeEach(func() {
+		pcm = &pconnManager{
+			pconnAny:    listen(),
+			pconns:      make(map[string]net.PacketConn),
+			labels:      make(map[string]string),
+			retired:     make(map[net.PacketConn]bool),
+			changePaths: make(chan struct{}, 1),
+			errorConn:   make(chan error, 1),
+		}
+		pconn = listen()
+		addr := *pconn.LocalAddr().(*net.UDPAddr)
+		pcm.pconns[addr.String()] = pconn
+		pcm.localAddrs = []net.UDPAddr{addr}
+		pcm.labels[addr.IP.String()] = "wifi"
+	})
+
+	AfterEach(func() {
+		pcm.pconnAny.Close()
+		pconn.Close()
+	})
+
+	It("closes the pconn of an address that disappeared", func() {
+		pcm.handleAddressEvent(addressEvent{Interface: "wlan0", IP: net.IPv4(127, 0, 0, 1), Removed: true})
+		Expect(pcm.pconns).To(BeEmpty())
+		Expect(pcm.localAddrs).To(BeEmpty())
+		Expect(pcm.labels).To(BeEmpty())
+		Expect(pcm.retired).To(HaveKey(pconn))
+		Expect(pcm.changePaths).To(Receive())
+		_, err := pconn.WriteTo([]byte("foobar"), pcm.pconnAny.LocalAddr())
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("keeps the other addresses", func() {
+		pcm.handleAddressEvent(addressEvent{Interface: "wlan0", IP: net.IPv4(10, 0, 0, 1), Removed: true})
+		Expect(pcm.pconns).To(HaveLen(1))
+		Expect(pcm.changePaths).ToNot(Receive())
+	})
+
+	It("doesn't report the read error of a closed pconn", func() {
+		done := make(chan struct{})
+		go func() {
+			pcm.listen(pconn)
+			close(done)
+		}()
+		pcm.removeAddrs(func(net.IP) bool { return true })
+		Eventually(done).Should(BeClosed())
+		Expect(pcm.errorConn).ToNot(Receive())
+	})
+
+	It("watches the addresses of a client", func() {
+		origNewAddressWatcher := newAddressWatcher
+		defer func() { newAddressWatcher = origNewAddressWatcher }()
+		watcher := &fakeAddressWatcher{events: make(chan addressEvent), closed: make(chan struct{})}
+		newAddressWatcher = func() (addressWatcher, error) { return watcher, nil }
+
+		client := &pconnManager{
+			perspective: protocol.PerspectiveClient,
+			// don't open sockets on the interfaces of the host
+			policy: &InterfacePolicy{Deny: []InterfaceRule{{}}},
+		}
+		Expect(client.setup(nil, nil)).To(Succeed())
+		Eventually(watcher.events).Should(BeSent(addressEvent{Interface: "wlan0", IP: net.IPv4(10, 0, 0, 1), Removed: true}))
+		client.closeConns <- struct{}{}
+		Eventually(watcher.closed).Should(BeClosed())
+		Eventually(client.closed).Should(BeClosed())
+	})
+
+	It("closes the paths whose local address disappeared", func() {
+		sess := &session{
+			paths:             make(map[protocol.PathID]*path),
+			closePathRequests: make(chan protocol.PathID, 4),
+		}
+		sess.ctx, sess.ctxCancel = context.WithCancel(context.Background())
+		defer sess.ctxCancel()
+		newPath := func(pathID protocol.PathID, pconn net.PacketConn) {
+			pth := &path{pathID: pathID, sess: sess, conn: &conn{pconn: pconn}}
+			pth.open.Set(true)
+			sess.paths[pathID] = pth
+		}
+		newPath(0, pcm.pconnAny)
+		newPath(1, pconn)
+		newPath(3, listen())
+		defer sess.paths[3].conn.Close()
+		pm := &pathManager{pconnMgr: pcm, sess: sess, advertisedLocAddrs: map[string]bool{pconn.LocalAddr().String(): true}}
+
+		pm.closeStalePaths()
+		Expect(sess.closePathRequests).To(Receive(Equal(protocol.PathID(3))))
+		Expect(sess.closePathRequests).ToNot(Receive())
+
+		pcm.removeAddrs(func(net.IP) bool { return true })
+		pm.closeStalePaths()
+		var first, second protocol.PathID
+		Expect(sess.closePathRequests).To(Receive(&first))
+		Expect(sess.closePathRequests).To(Receive(&second))
+		Expect([]protocol.PathID{first, second}).To(ConsistOf(protocol.PathID(1), protocol.PathID(3)))
+		Expect(pm.advertisedLocAddrs).To(BeEmpty())
+	})
+})
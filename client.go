@@ -52,7 +52,7 @@ func DialAddr(addr string, tlsConf *tls.Config, config *Config) (Session, error)
 		return nil, err
 	}
 	// Create the pconnManager here. It will be used to manage UDP connections
-	pconnMgr := &pconnManager{perspective: protocol.PerspectiveClient}
+	pconnMgr := &pconnManager{perspective: protocol.PerspectiveClient, policy: interfacePolicy(config)}
 	err = pconnMgr.setup(nil, nil)
 	if err != nil {
 		return nil, err
@@ -72,7 +72,7 @@ func DialAddrNonFWSecure(
 		return nil, err
 	}
 	// Create the pconnManager here. It will be used to manage UDP connections
-	pconnMgr := &pconnManager{perspective: protocol.PerspectiveClient}
+	pconnMgr := &pconnManager{perspective: protocol.PerspectiveClient, policy: interfacePolicy(config)}
 	err = pconnMgr.setup(nil, nil)
 	if err != nil {
 		return nil, err
@@ -110,7 +110,7 @@ func DialNonFWSecure(
 	var pconnMgr *pconnManager
 
 	if pconnMgrArg == nil {
-		pconnMgr = &pconnManager{perspective: protocol.PerspectiveClient}
+		pconnMgr = &pconnManager{perspective: protocol.PerspectiveClient, policy: interfacePolicy(config)}
 		err := pconnMgr.setup(pconn, nil)
 		if err != nil {
 			return nil, err
@@ -199,6 +199,20 @@ func populateClientConfig(config *Config) *Config {
 		KeepAlive:                             config.KeepAlive,
 		CacheHandshake:                        config.CacheHandshake,
 		CreatePaths:                           config.CreatePaths,
+		CostPolicy:                            config.CostPolicy,
+		PartialReliability:                    config.PartialReliability,
+		LinUCBStore:                           config.LinUCBStore,
+		BatchSize:                             config.BatchSize,
+		Tracer:                                config.Tracer,
+		PathCreation:                          config.PathCreation,
+		PathHealth:                            config.PathHealth,
+		StreamOrder:                           config.StreamOrder,
+		Redundancy:                            config.Redundancy,
+		CongestionControl:                     config.CongestionControl,
+		InterfacePolicy:                       config.InterfacePolicy,
+		PrimaryPathLabel:                      config.PrimaryPathLabel,
+		SecondPathLabel:                       config.SecondPathLabel,
+		Bandit:                                config.Bandit,
 	}
 }
 
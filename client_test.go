@@ -35,7 +35,7 @@ var _ = Describe("Client", func() {
 			ConnectionID:    connID,
 			PacketNumber:    1,
 			PacketNumberLen: 1,
-		}).Write(b, protocol.VersionWhatever, protocol.PerspectiveServer)
+		}).Write(b, protocol.SupportedVersions[0], protocol.PerspectiveServer)
 		Expect(err).ToNot(HaveOccurred())
 		return b.Bytes()
 	}
@@ -317,7 +317,7 @@ var _ = Describe("Client", func() {
 					ConnectionID:    0x1337,
 				}
 				b := &bytes.Buffer{}
-				err := ph.Write(b, protocol.VersionWhatever, protocol.PerspectiveServer)
+				err := ph.Write(b, cl.version, protocol.PerspectiveServer)
 				Expect(err).ToNot(HaveOccurred())
 				cl.handlePacket(&receivedRawPacket{remoteAddr: nil, data: b.Bytes()})
 				Expect(cl.versionNegotiated).To(BeTrue())
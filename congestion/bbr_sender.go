@@ -0,0 +1,383 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+// The modes of the BBR sender
+type bbrMode uint8
+
+const (
+	// bbrStartup doubles the window every round trip, until the bandwidth stops growing
+	bbrStartup bbrMode = iota
+	// bbrDrain empties the queue built during startup
+	bbrDrain
+	// bbrProbeBandwidth cycles the gain to probe for more bandwidth and drain the queue again
+	bbrProbeBandwidth
+	// bbrProbeRTT shrinks the window to measure the minimum RTT again
+	bbrProbeRTT
+)
+
+const (
+	// bbrHighGain is 2/ln(2), the smallest gain that doubles the delivery rate every round trip
+	bbrHighGain = 2.885
+	// bbrBandwidthWindow is the number of round trips the maximum bandwidth is taken over
+	bbrBandwidthWindow = 10
+	// bbrMinRTTWindow is how long a minimum RTT sample is valid
+	bbrMinRTTWindow = 10 * time.Second
+	// bbrProbeRTTDuration is the minimum time spent in bbrProbeRTT
+	bbrProbeRTTDuration = 200 * time.Millisecond
+	// bbrStartupGrowthTarget is the bandwidth growth per round trip below which the pipe is considered full
+	bbrStartupGrowthTarget = 1.25
+	// bbrStartupFullRounds is the number of round trips without growth that end the startup
+	bbrStartupFullRounds = 3
+	// bbrMinCongestionWindow is the window in packets during bbrProbeRTT, and the minimum window
+	bbrMinCongestionWindow protocol.PacketNumber = 4
+	// bbrAckHeadroom is added to the window, in packets, to leave room for the ACKs that are delayed
+	bbrAckHeadroom protocol.PacketNumber = 3
+)
+
+// bbrGainCycle are the gains of bbrProbeBandwidth, each used for one minimum RTT
+var bbrGainCycle = []float64{1.25, 0.75, 1, 1, 1, 1, 1, 1}
+
+// bbrSentPacket is the delivery state when a packet was sent, used to calculate the delivery rate once it is acked
+type bbrSentPacket struct {
+	sentTime      time.Time
+	delivered     protocol.ByteCount
+	deliveredTime time.Time
+}
+
+// bbrBandwidthSample is the maximum bandwidth measured during a round trip
+type bbrBandwidthSample struct {
+	round     uint64
+	bandwidth Bandwidth
+}
+
+type bbrSender struct {
+	clock    Clock
+	rttStats *RTTStats
+
+	mode bbrMode
+
+	// sentPackets are the packets in flight
+	sentPackets map[protocol.PacketNumber]*bbrSentPacket
+	// delivered is the number of bytes acked, deliveredTime when the last one was acked
+	delivered     protocol.ByteCount
+	deliveredTime time.Time
+
+	// round counts the round trips. A round trip ends when a packet sent after its start is acked.
+	round              uint64
+	nextRoundDelivered protocol.ByteCount
+	roundStart         bool
+
+	// maxBandwidth holds the highest bandwidth of each of the last bbrBandwidthWindow round trips
+	maxBandwidth []bbrBandwidthSample
+
+	minRTT          time.Duration
+	minRTTTimestamp time.Time
+	// minRTTExpired is set if the minimum RTT was older than bbrMinRTTWindow when the last sample was taken
+	minRTTExpired bool
+
+	// fullBandwidth is the bandwidth when the startup last saw it grow
+	fullBandwidth      Bandwidth
+	fullBandwidthCount int
+
+	// gain is the multiple of the bandwidth-delay product the window is set to
+	gain       float64
+	cycleIndex int
+	cycleStart time.Time
+
+	probeRTTDone        time.Time
+	probeRTTRoundDone   bool
+	probeRTTRoundTarget uint64
+
+	congestionWindow protocol.PacketNumber
+
+	initialCongestionWindow    protocol.PacketNumber
+	initialMaxCongestionWindow protocol.PacketNumber
+}
+
+// NewBBRSender creates a delay-based sender in the style of BBR.
+// It estimates the bottleneck bandwidth and the minimum RTT of the path, and limits the data in flight to a multiple of their product,
+// so that it keeps the queues short instead of filling them until packets are lost.
+// Since the session doesn't pace its packets, the gains that BBR applies to the pacing rate are applied to the window.
+func NewBBRSender(clock Clock, rttStats *RTTStats, initialCongestionWindow, initialMaxCongestionWindow protocol.PacketNumber) SendAlgorithmWithDebugInfo {
+	b := &bbrSender{
+		clock:                      clock,
+		rttStats:                   rttStats,
+		initialCongestionWindow:    initialCongestionWindow,
+		initialMaxCongestionWindow: initialMaxCongestionWindow,
+	}
+	b.reset()
+	return b
+}
+
+func (b *bbrSender) reset() {
+	b.mode = bbrStartup
+	b.gain = bbrHighGain
+	b.sentPackets = make(map[protocol.PacketNumber]*bbrSentPacket)
+	b.delivered = 0
+	b.deliveredTime = time.Time{}
+	b.round = 0
+	b.nextRoundDelivered = 0
+	b.maxBandwidth = nil
+	b.minRTT = 0
+	b.minRTTTimestamp = time.Time{}
+	b.minRTTExpired = false
+	b.fullBandwidth = 0
+	b.fullBandwidthCount = 0
+	b.congestionWindow = b.initialCongestionWindow
+}
+
+func (b *bbrSender) TimeUntilSend(now time.Time, bytesInFlight protocol.ByteCount) time.Duration {
+	if b.GetCongestionWindow() > bytesInFlight {
+		return 0
+	}
+	return utils.InfDuration
+}
+
+func (b *bbrSender) OnPacketSent(sentTime time.Time, bytesInFlight protocol.ByteCount, packetNumber protocol.PacketNumber, bytes protocol.ByteCount, isRetransmittable bool) bool {
+	// Only update bytesInFlight for data packets.
+	if !isRetransmittable {
+		return false
+	}
+	if bytesInFlight <= bytes {
+		// The connection was idle, so the delivery rate starts from now
+		b.deliveredTime = sentTime
+	}
+	deliveredTime := b.deliveredTime
+	if deliveredTime.IsZero() {
+		deliveredTime = sentTime
+	}
+	b.sentPackets[packetNumber] = &bbrSentPacket{sentTime: sentTime, delivered: b.delivered, deliveredTime: deliveredTime}
+	return true
+}
+
+// bandwidth is the maximum bandwidth of the last bbrBandwidthWindow round trips
+func (b *bbrSender) bandwidth() Bandwidth {
+	var bw Bandwidth
+	for _, s := range b.maxBandwidth {
+		if s.bandwidth > bw {
+			bw = s.bandwidth
+		}
+	}
+	return bw
+}
+
+// bdp is the bandwidth-delay product in bytes, or 0 if no bandwidth or RTT was measured yet
+func (b *bbrSender) bdp() protocol.ByteCount {
+	bw := b.bandwidth()
+	if bw == 0 || b.minRTT == 0 {
+		return 0
+	}
+	return protocol.ByteCount(uint64(bw) * uint64(b.minRTT) / uint64(time.Second) / uint64(BytesPerSecond))
+}
+
+func (b *bbrSender) GetCongestionWindow() protocol.ByteCount {
+	return protocol.ByteCount(b.congestionWindow) * protocol.DefaultTCPMSS
+}
+
+// MaybeExitSlowStart does nothing, the startup ends when the bandwidth stops growing
+func (b *bbrSender) MaybeExitSlowStart() {}
+
+func (b *bbrSender) OnPacketAcked(number protocol.PacketNumber, ackedBytes protocol.ByteCount, bytesInFlight protocol.ByteCount) {
+	now := b.clock.Now()
+	packet, ok := b.sentPackets[number]
+	if !ok {
+		return
+	}
+	delete(b.sentPackets, number)
+
+	b.delivered += ackedBytes
+	b.deliveredTime = now
+
+	b.roundStart = false
+	if packet.delivered >= b.nextRoundDelivered {
+		b.nextRoundDelivered = b.delivered
+		b.round++
+		b.roundStart = true
+	}
+
+	if interval := now.Sub(packet.deliveredTime); interval > 0 {
+		b.updateBandwidth(BandwidthFromDelta(b.delivered-packet.delivered, interval))
+	}
+	b.updateMinRTT(now)
+	b.checkFullPipe()
+	b.updateMode(now, bytesInFlight)
+	b.updateCongestionWindow()
+}
+
+func (b *bbrSender) updateBandwidth(sample Bandwidth) {
+	if n := len(b.maxBandwidth); n > 0 && b.maxBandwidth[n-1].round == b.round {
+		if sample > b.maxBandwidth[n-1].bandwidth {
+			b.maxBandwidth[n-1].bandwidth = sample
+		}
+	} else {
+		b.maxBandwidth = append(b.maxBandwidth, bbrBandwidthSample{round: b.round, bandwidth: sample})
+	}
+	for len(b.maxBandwidth) > 0 && b.maxBandwidth[0].round+bbrBandwidthWindow <= b.round {
+		b.maxBandwidth = b.maxBandwidth[1:]
+	}
+}
+
+func (b *bbrSender) updateMinRTT(now time.Time) {
+	rtt := b.rttStats.LatestRTT()
+	if rtt == 0 {
+		return
+	}
+	b.minRTTExpired = b.minRTT != 0 && now.Sub(b.minRTTTimestamp) > bbrMinRTTWindow
+	if b.minRTT == 0 || rtt <= b.minRTT || b.minRTTExpired {
+		b.minRTT = rtt
+		b.minRTTTimestamp = now
+	}
+}
+
+// checkFullPipe ends the startup once the bandwidth didn't grow by 25% for 3 round trips
+func (b *bbrSender) checkFullPipe() {
+	if b.mode != bbrStartup || !b.roundStart {
+		return
+	}
+	bw := b.bandwidth()
+	if float64(bw) >= float64(b.fullBandwidth)*bbrStartupGrowthTarget {
+		b.fullBandwidth = bw
+		b.fullBandwidthCount = 0
+		return
+	}
+	b.fullBandwidthCount++
+	if b.fullBandwidthCount >= bbrStartupFullRounds {
+		b.mode = bbrDrain
+		b.gain = 1
+	}
+}
+
+func (b *bbrSender) updateMode(now time.Time, bytesInFlight protocol.ByteCount) {
+	if b.mode == bbrDrain && bytesInFlight <= protocol.ByteCount(b.targetWindow(1))*protocol.DefaultTCPMSS {
+		b.enterProbeBandwidth(now)
+	}
+	if b.mode == bbrProbeBandwidth && now.Sub(b.cycleStart) > b.minRTT {
+		b.cycleIndex = (b.cycleIndex + 1) % len(bbrGainCycle)
+		b.cycleStart = now
+		b.gain = bbrGainCycle[b.cycleIndex]
+	}
+	if b.mode != bbrProbeRTT && b.minRTTExpired {
+		b.mode = bbrProbeRTT
+		b.probeRTTDone = time.Time{}
+		b.probeRTTRoundDone = false
+	}
+	if b.mode == bbrProbeRTT {
+		if b.probeRTTDone.IsZero() && bytesInFlight <= protocol.ByteCount(bbrMinCongestionWindow)*protocol.DefaultTCPMSS {
+			b.probeRTTDone = now.Add(bbrProbeRTTDuration)
+			b.probeRTTRoundTarget = b.round + 1
+		} else if !b.probeRTTDone.IsZero() {
+			if b.round >= b.probeRTTRoundTarget {
+				b.probeRTTRoundDone = true
+			}
+			if b.probeRTTRoundDone && now.After(b.probeRTTDone) {
+				b.minRTTTimestamp = now
+				if b.fullBandwidthCount >= bbrStartupFullRounds {
+					b.enterProbeBandwidth(now)
+				} else {
+					b.mode = bbrStartup
+					b.gain = bbrHighGain
+				}
+			}
+		}
+	}
+}
+
+func (b *bbrSender) enterProbeBandwidth(now time.Time) {
+	b.mode = bbrProbeBandwidth
+	// don't start with the phase that drains the queue
+	b.cycleIndex = 2
+	b.cycleStart = now
+	b.gain = bbrGainCycle[b.cycleIndex]
+}
+
+// targetWindow is the window in packets for a gain
+func (b *bbrSender) targetWindow(gain float64) protocol.PacketNumber {
+	return protocol.PacketNumber(float64(b.bdp())*gain)/protocol.PacketNumber(protocol.DefaultTCPMSS) + bbrAckHeadroom
+}
+
+func (b *bbrSender) updateCongestionWindow() {
+	if b.mode == bbrProbeRTT {
+		b.congestionWindow = bbrMinCongestionWindow
+		return
+	}
+	if b.bdp() == 0 {
+		return
+	}
+	target := b.targetWindow(b.gain)
+	if b.mode == bbrStartup {
+		// the window never shrinks during the startup
+		target = utils.MaxPacketNumber(target, b.congestionWindow)
+	}
+	b.congestionWindow = utils.MinPacketNumber(utils.MaxPacketNumber(target, bbrMinCongestionWindow), b.initialMaxCongestionWindow)
+}
+
+// OnPacketLost doesn't change the window, since BBR doesn't consider losses as a congestion signal
+func (b *bbrSender) OnPacketLost(number protocol.PacketNumber, lostBytes protocol.ByteCount, bytesInFlight protocol.ByteCount) {
+	delete(b.sentPackets, number)
+}
+
+// SetNumEmulatedConnections does nothing, BBR doesn't emulate several connections
+func (b *bbrSender) SetNumEmulatedConnections(n int) {}
+
+// OnRetransmissionTimeout is called on an retransmission timeout
+func (b *bbrSender) OnRetransmissionTimeout(packetsRetransmitted bool) {
+	if !packetsRetransmitted {
+		return
+	}
+	// The packets in flight are retransmitted, so they won't be acked anymore
+	b.sentPackets = make(map[protocol.PacketNumber]*bbrSentPacket)
+	b.congestionWindow = bbrMinCongestionWindow
+}
+
+func (b *bbrSender) OnConnectionMigration() {
+	b.reset()
+}
+
+// RetransmissionDelay gives the RTO retransmission time
+func (b *bbrSender) RetransmissionDelay() time.Duration {
+	if b.rttStats.SmoothedRTT() == 0 {
+		return 0
+	}
+	return b.rttStats.SmoothedRTT() + b.rttStats.MeanDeviation()*4
+}
+
+func (b *bbrSender) SmoothedRTT() time.Duration {
+	return b.rttStats.SmoothedRTT()
+}
+
+// SetSlowStartLargeReduction does nothing, BBR has no slow start
+func (b *bbrSender) SetSlowStartLargeReduction(enabled bool) {}
+
+// BandwidthEstimate is the maximum delivery rate of the last round trips
+func (b *bbrSender) BandwidthEstimate() Bandwidth {
+	return b.bandwidth()
+}
+
+// HybridSlowStart returns nil, BBR has no slow start
+func (b *bbrSender) HybridSlowStart() *HybridSlowStart {
+	return nil
+}
+
+// SlowstartThreshold is infinite during the startup, and the window afterwards
+func (b *bbrSender) SlowstartThreshold() protocol.PacketNumber {
+	if b.mode == bbrStartup {
+		return b.initialMaxCongestionWindow
+	}
+	return b.congestionWindow
+}
+
+// RenoBeta is 1, BBR doesn't back off after a loss
+func (b *bbrSender) RenoBeta() float32 {
+	return 1
+}
+
+// InRecovery is false, BBR doesn't recover from losses
+func (b *bbrSender) InRecovery() bool {
+	return false
+}
@@ -0,0 +1,154 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BBR Sender", func() {
+	const (
+		// the bottleneck delivers a packet every 5 ms, the base RTT is 100 ms
+		bottleneckInterval = 5 * time.Millisecond
+		baseRTT            = 100 * time.Millisecond
+		// bottleneckBDP is the bandwidth-delay product of the bottleneck in packets
+		bottleneckBDP = protocol.PacketNumber(baseRTT / bottleneckInterval)
+	)
+
+	var (
+		sender        SendAlgorithmWithDebugInfo
+		clock         mockClock
+		rttStats      *RTTStats
+		bytesInFlight protocol.ByteCount
+		packetNumber  protocol.PacketNumber
+		// inFlight are the send times of the packets in flight, in order
+		inFlight []time.Time
+		// linkFree is when the bottleneck finished sending the last packet
+		linkFree time.Time
+		// extraDelay is added to the base RTT
+		extraDelay time.Duration
+	)
+
+	bottleneckBandwidth := BandwidthFromDelta(protocol.DefaultTCPMSS, bottleneckInterval)
+
+	BeforeEach(func() {
+		clock = mockClock{}
+		rttStats = NewRTTStats()
+		bytesInFlight = 0
+		packetNumber = 1
+		inFlight = nil
+		linkFree = time.Time{}
+		extraDelay = 0
+		sender = NewBBRSender(&clock, rttStats, initialCongestionWindowPackets, MaxCongestionWindow)
+	})
+
+	// SendAvailableSendWindow sends as long as the window allows
+	SendAvailableSendWindow := func() int {
+		packetsSent := 0
+		for bytesInFlight < sender.GetCongestionWindow() {
+			sender.OnPacketSent(clock.Now(), bytesInFlight, packetNumber, protocol.DefaultTCPMSS, true)
+			packetNumber++
+			packetsSent++
+			bytesInFlight += protocol.DefaultTCPMSS
+			inFlight = append(inFlight, clock.Now())
+		}
+		return packetsSent
+	}
+
+	// RunRound sends the window through the bottleneck, and acks every packet that arrived within the next interval
+	RunRound := func(interval time.Duration) {
+		SendAvailableSendWindow()
+		end := clock.Now().Add(interval)
+		for len(inFlight) > 0 {
+			sent := inFlight[0]
+			// the packet waits until the bottleneck is free, and the ACK takes the base RTT
+			start := sent
+			if linkFree.After(start) {
+				start = linkFree
+			}
+			ackTime := start.Add(baseRTT + extraDelay)
+			if ackTime.After(end) {
+				break
+			}
+			linkFree = start.Add(bottleneckInterval)
+			clock = mockClock(ackTime)
+			rttStats.UpdateRTT(ackTime.Sub(sent), 0, ackTime)
+			ackedNumber := packetNumber - protocol.PacketNumber(len(inFlight))
+			inFlight = inFlight[1:]
+			bytesInFlight -= protocol.DefaultTCPMSS
+			sender.OnPacketAcked(ackedNumber, protocol.DefaultTCPMSS, bytesInFlight)
+			SendAvailableSendWindow()
+		}
+		clock = mockClock(end)
+	}
+
+	It("starts with the initial window", func() {
+		Expect(sender.GetCongestionWindow()).To(Equal(defaultWindowTCP))
+		Expect(sender.TimeUntilSend(clock.Now(), 0)).To(BeZero())
+		Expect(SendAvailableSendWindow()).To(Equal(int(initialCongestionWindowPackets)))
+		Expect(sender.TimeUntilSend(clock.Now(), bytesInFlight)).To(Equal(utils.InfDuration))
+		Expect(sender.BandwidthEstimate()).To(BeZero())
+	})
+
+	It("finds the bandwidth of the bottleneck and keeps the queue short", func() {
+		for i := 0; i < 30; i++ {
+			RunRound(baseRTT)
+		}
+		Expect(sender.BandwidthEstimate()).To(BeNumerically("~", bottleneckBandwidth, bottleneckBandwidth/10))
+		Expect(sender.(*bbrSender).mode).To(Equal(bbrProbeBandwidth))
+		// the window is about twice the bandwidth-delay product, far below the maximum window
+		Expect(sender.GetCongestionWindow()).To(BeNumerically("<=", protocol.ByteCount(2*bottleneckBDP+8)*protocol.DefaultTCPMSS))
+		Expect(rttStats.LatestRTT()).To(BeNumerically("<", 3*baseRTT))
+	})
+
+	It("doesn't reduce the window on losses", func() {
+		for i := 0; i < 30; i++ {
+			RunRound(baseRTT)
+		}
+		cwnd := sender.GetCongestionWindow()
+		sender.OnPacketLost(packetNumber-1, protocol.DefaultTCPMSS, bytesInFlight)
+		Expect(sender.GetCongestionWindow()).To(Equal(cwnd))
+		Expect(sender.InRecovery()).To(BeFalse())
+		Expect(sender.RenoBeta()).To(Equal(float32(1)))
+	})
+
+	It("probes the RTT if it didn't see the minimum RTT for 10 seconds", func() {
+		for i := 0; i < 30; i++ {
+			RunRound(baseRTT)
+		}
+		// the route changes, and the RTT increases
+		extraDelay = 50 * time.Millisecond
+		var rounds int
+		for ; rounds < 150 && sender.(*bbrSender).mode != bbrProbeRTT; rounds++ {
+			RunRound(baseRTT)
+		}
+		// the minimum RTT was last seen during the first 30 rounds of 100 ms
+		Expect(rounds).To(BeNumerically(">=", 70))
+		Expect(sender.(*bbrSender).mode).To(Equal(bbrProbeRTT))
+		Expect(sender.GetCongestionWindow()).To(Equal(protocol.ByteCount(bbrMinCongestionWindow) * protocol.DefaultTCPMSS))
+		for i := 0; i < 5; i++ {
+			RunRound(baseRTT)
+		}
+		Expect(sender.(*bbrSender).mode).To(Equal(bbrProbeBandwidth))
+		Expect(sender.(*bbrSender).minRTT).To(Equal(baseRTT + extraDelay))
+		Expect(sender.GetCongestionWindow()).To(BeNumerically(">", protocol.ByteCount(bbrMinCongestionWindow)*protocol.DefaultTCPMSS))
+	})
+
+	It("restarts with the minimum window after an RTO", func() {
+		for i := 0; i < 30; i++ {
+			RunRound(baseRTT)
+		}
+		sender.OnRetransmissionTimeout(true)
+		Expect(sender.GetCongestionWindow()).To(Equal(protocol.ByteCount(bbrMinCongestionWindow) * protocol.DefaultTCPMSS))
+	})
+
+	It("starts over after a connection migration", func() {
+		RunRound(baseRTT)
+		sender.OnConnectionMigration()
+		Expect(sender.GetCongestionWindow()).To(Equal(defaultWindowTCP))
+		Expect(sender.BandwidthEstimate()).To(BeZero())
+	})
+})
@@ -0,0 +1,407 @@
+package congestion
+
+import (
+	"math"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+// A CoupledGroup holds the senders of the paths of a connection, so that OLIA, LIA and BALIA can couple their congestion windows.
+// A group must not be shared by several connections.
+type CoupledGroup struct {
+	senders     map[protocol.PathID]*CoupledSender
+	oliaSenders map[protocol.PathID]*OliaSender
+}
+
+// NewCoupledGroup creates an empty group
+func NewCoupledGroup() *CoupledGroup {
+	return &CoupledGroup{
+		senders:     make(map[protocol.PathID]*CoupledSender),
+		oliaSenders: make(map[protocol.PathID]*OliaSender),
+	}
+}
+
+// Remove stops coupling the sender of a closed path with the other paths
+func (g *CoupledGroup) Remove(pathID protocol.PathID) {
+	delete(g.senders, pathID)
+	delete(g.oliaSenders, pathID)
+}
+
+// coupledAlgorithm calculates the window changes of a CoupledSender in congestion avoidance
+type coupledAlgorithm interface {
+	// increase returns by how many packets the window grows when a packet is acked
+	increase(c *CoupledSender) float64
+	// decrease returns the window after a loss
+	decrease(c *CoupledSender) protocol.PacketNumber
+}
+
+// A CoupledSender is a Reno sender whose congestion avoidance is coupled with the other paths of its CoupledGroup
+type CoupledSender struct {
+	hybridSlowStart HybridSlowStart
+	prr             PrrSender
+	rttStats        *RTTStats
+	stats           connectionStats
+	algorithm       coupledAlgorithm
+	group           *CoupledGroup
+
+	// Track the largest packet that has been sent.
+	largestSentPacketNumber protocol.PacketNumber
+
+	// Track the largest packet that has been acked.
+	largestAckedPacketNumber protocol.PacketNumber
+
+	// Track the largest packet number outstanding when a CWND cutback occurs.
+	largestSentAtLastCutback protocol.PacketNumber
+
+	// Congestion window in packets.
+	congestionWindow protocol.PacketNumber
+
+	// Slow start congestion window in packets, aka ssthresh.
+	slowstartThreshold protocol.PacketNumber
+
+	// Whether the last loss event caused us to exit slowstart.
+	// Used for stats collection of slowstartPacketsLost
+	lastCutbackExitedSlowstart bool
+
+	// When true, exit slow start with large cutback of congestion window.
+	slowStartLargeReduction bool
+
+	// Minimum congestion window in packets.
+	minCongestionWindow protocol.PacketNumber
+
+	// Maximum number of outstanding packets for tcp.
+	maxTCPCongestionWindow protocol.PacketNumber
+
+	// Number of connections to simulate.
+	numConnections int
+
+	// Fraction of a packet the window grew by in congestion avoidance.
+	congestionWindowFraction float64
+
+	initialCongestionWindow    protocol.PacketNumber
+	initialMaxCongestionWindow protocol.PacketNumber
+}
+
+// NewLiaSender creates a sender that uses the Linked Increases Algorithm of RFC 6356, and adds it to the group
+func NewLiaSender(group *CoupledGroup, pathID protocol.PathID, rttStats *RTTStats, initialCongestionWindow, initialMaxCongestionWindow protocol.PacketNumber) SendAlgorithmWithDebugInfo {
+	return newCoupledSender(liaAlgorithm{}, group, pathID, rttStats, initialCongestionWindow, initialMaxCongestionWindow)
+}
+
+// NewBaliaSender creates a sender that uses the Balanced Linked Adaptation algorithm of Peng et al., and adds it to the group
+func NewBaliaSender(group *CoupledGroup, pathID protocol.PathID, rttStats *RTTStats, initialCongestionWindow, initialMaxCongestionWindow protocol.PacketNumber) SendAlgorithmWithDebugInfo {
+	return newCoupledSender(baliaAlgorithm{}, group, pathID, rttStats, initialCongestionWindow, initialMaxCongestionWindow)
+}
+
+func newCoupledSender(algorithm coupledAlgorithm, group *CoupledGroup, pathID protocol.PathID, rttStats *RTTStats, initialCongestionWindow, initialMaxCongestionWindow protocol.PacketNumber) *CoupledSender {
+	c := &CoupledSender{
+		rttStats:                   rttStats,
+		algorithm:                  algorithm,
+		group:                      group,
+		initialCongestionWindow:    initialCongestionWindow,
+		initialMaxCongestionWindow: initialMaxCongestionWindow,
+		congestionWindow:           initialCongestionWindow,
+		minCongestionWindow:        defaultMinimumCongestionWindow,
+		slowstartThreshold:         initialMaxCongestionWindow,
+		maxTCPCongestionWindow:     initialMaxCongestionWindow,
+		numConnections:             defaultNumConnections,
+	}
+	group.senders[pathID] = c
+	return c
+}
+
+func (c *CoupledSender) TimeUntilSend(now time.Time, bytesInFlight protocol.ByteCount) time.Duration {
+	if c.InRecovery() {
+		// PRR is used when in recovery.
+		return c.prr.TimeUntilSend(c.GetCongestionWindow(), bytesInFlight, c.GetSlowStartThreshold())
+	}
+	if c.GetCongestionWindow() > bytesInFlight {
+		return 0
+	}
+	return utils.InfDuration
+}
+
+func (c *CoupledSender) OnPacketSent(sentTime time.Time, bytesInFlight protocol.ByteCount, packetNumber protocol.PacketNumber, bytes protocol.ByteCount, isRetransmittable bool) bool {
+	// Only update bytesInFlight for data packets.
+	if !isRetransmittable {
+		return false
+	}
+	if c.InRecovery() {
+		// PRR is used when in recovery.
+		c.prr.OnPacketSent(bytes)
+	}
+	c.largestSentPacketNumber = packetNumber
+	c.hybridSlowStart.OnPacketSent(packetNumber)
+	return true
+}
+
+func (c *CoupledSender) GetCongestionWindow() protocol.ByteCount {
+	return protocol.ByteCount(c.congestionWindow) * protocol.DefaultTCPMSS
+}
+
+func (c *CoupledSender) GetSlowStartThreshold() protocol.ByteCount {
+	return protocol.ByteCount(c.slowstartThreshold) * protocol.DefaultTCPMSS
+}
+
+func (c *CoupledSender) ExitSlowstart() {
+	c.slowstartThreshold = c.congestionWindow
+}
+
+func (c *CoupledSender) MaybeExitSlowStart() {
+	if c.InSlowStart() && c.hybridSlowStart.ShouldExitSlowStart(c.rttStats.LatestRTT(), c.rttStats.MinRTT(), c.GetCongestionWindow()/protocol.DefaultTCPMSS) {
+		c.ExitSlowstart()
+	}
+}
+
+func (c *CoupledSender) isCwndLimited(bytesInFlight protocol.ByteCount) bool {
+	congestionWindow := c.GetCongestionWindow()
+	if bytesInFlight >= congestionWindow {
+		return true
+	}
+	availableBytes := congestionWindow - bytesInFlight
+	slowStartLimited := c.InSlowStart() && bytesInFlight > congestionWindow/2
+	return slowStartLimited || availableBytes <= maxBurstBytes
+}
+
+// rtt is the RTT the coupling uses for this path, or 0 if it is not known yet
+func (c *CoupledSender) rtt() time.Duration {
+	if srtt := c.rttStats.SmoothedRTT(); srtt != 0 {
+		return srtt
+	}
+	return c.rttStats.LatestRTT()
+}
+
+func (c *CoupledSender) maybeIncreaseCwnd(bytesInFlight protocol.ByteCount) {
+	// Do not increase the congestion window unless the sender is close to using
+	// the current window.
+	if !c.isCwndLimited(bytesInFlight) {
+		return
+	}
+	if c.congestionWindow >= c.maxTCPCongestionWindow {
+		return
+	}
+	if c.InSlowStart() {
+		// TCP slow start, exponential growth, increase by one for each ACK.
+		c.congestionWindow++
+		return
+	}
+	var increase float64
+	if c.rtt() == 0 {
+		// Without an RTT, the path can't be coupled yet, so behave like Reno
+		increase = 1 / float64(c.congestionWindow)
+	} else {
+		increase = c.algorithm.increase(c)
+	}
+	// add a little, so that cwnd increases of 1/cwnd add up to a packet despite the rounding
+	c.congestionWindowFraction += increase
+	if c.congestionWindowFraction+1e-9 >= 1 {
+		grow := protocol.PacketNumber(c.congestionWindowFraction + 1e-9)
+		c.congestionWindowFraction -= float64(grow)
+		c.congestionWindow = utils.MinPacketNumber(c.maxTCPCongestionWindow, c.congestionWindow+grow)
+	}
+}
+
+func (c *CoupledSender) OnPacketAcked(ackedPacketNumber protocol.PacketNumber, ackedBytes protocol.ByteCount, bytesInFlight protocol.ByteCount) {
+	c.largestAckedPacketNumber = utils.MaxPacketNumber(ackedPacketNumber, c.largestAckedPacketNumber)
+	if c.InRecovery() {
+		// PRR is used when in recovery
+		c.prr.OnPacketAcked(ackedBytes)
+		return
+	}
+	c.maybeIncreaseCwnd(bytesInFlight)
+	if c.InSlowStart() {
+		c.hybridSlowStart.OnPacketAcked(ackedPacketNumber)
+	}
+}
+
+func (c *CoupledSender) OnPacketLost(packetNumber protocol.PacketNumber, lostBytes protocol.ByteCount, bytesInFlight protocol.ByteCount) {
+	// TCP NewReno (RFC6582) says that once a loss occurs, any losses in packets
+	// already sent should be treated as a single loss event, since it's expected.
+	if packetNumber <= c.largestSentAtLastCutback {
+		if c.lastCutbackExitedSlowstart {
+			c.stats.slowstartPacketsLost++
+			c.stats.slowstartBytesLost += lostBytes
+			if c.slowStartLargeReduction {
+				if c.stats.slowstartPacketsLost == 1 || (c.stats.slowstartBytesLost/protocol.DefaultTCPMSS) > (c.stats.slowstartBytesLost-lostBytes)/protocol.DefaultTCPMSS {
+					// Reduce congestion window by 1 for every mss of bytes lost.
+					c.congestionWindow = utils.MaxPacketNumber(c.congestionWindow-1, c.minCongestionWindow)
+				}
+				c.slowstartThreshold = c.congestionWindow
+			}
+		}
+		return
+	}
+	c.lastCutbackExitedSlowstart = c.InSlowStart()
+	if c.InSlowStart() {
+		c.stats.slowstartPacketsLost++
+	}
+
+	c.prr.OnPacketLost(bytesInFlight)
+
+	if c.slowStartLargeReduction && c.InSlowStart() {
+		c.congestionWindow = c.congestionWindow - 1
+	} else {
+		c.congestionWindow = c.algorithm.decrease(c)
+	}
+	// Enforce a minimum congestion window.
+	if c.congestionWindow < c.minCongestionWindow {
+		c.congestionWindow = c.minCongestionWindow
+	}
+	c.slowstartThreshold = c.congestionWindow
+	c.largestSentAtLastCutback = c.largestSentPacketNumber
+	// reset packet count from congestion avoidance mode. We start
+	// counting again when we're out of recovery.
+	c.congestionWindowFraction = 0
+}
+
+func (c *CoupledSender) SetNumEmulatedConnections(n int) {
+	c.numConnections = utils.Max(n, 1)
+}
+
+// OnRetransmissionTimeout is called on an retransmission timeout
+func (c *CoupledSender) OnRetransmissionTimeout(packetsRetransmitted bool) {
+	c.largestSentAtLastCutback = 0
+	if !packetsRetransmitted {
+		return
+	}
+	c.hybridSlowStart.Restart()
+	c.slowstartThreshold = c.congestionWindow / 2
+	c.congestionWindow = c.minCongestionWindow
+	c.congestionWindowFraction = 0
+}
+
+func (c *CoupledSender) OnConnectionMigration() {
+	c.hybridSlowStart.Restart()
+	c.prr = PrrSender{}
+	c.largestSentPacketNumber = 0
+	c.largestAckedPacketNumber = 0
+	c.largestSentAtLastCutback = 0
+	c.lastCutbackExitedSlowstart = false
+	c.congestionWindowFraction = 0
+	c.congestionWindow = c.initialCongestionWindow
+	c.slowstartThreshold = c.initialMaxCongestionWindow
+	c.maxTCPCongestionWindow = c.initialMaxCongestionWindow
+}
+
+// RetransmissionDelay gives the RTO retransmission time
+func (c *CoupledSender) RetransmissionDelay() time.Duration {
+	if c.rttStats.SmoothedRTT() == 0 {
+		return 0
+	}
+	return c.rttStats.SmoothedRTT() + c.rttStats.MeanDeviation()*4
+}
+
+func (c *CoupledSender) SmoothedRTT() time.Duration {
+	return c.rttStats.SmoothedRTT()
+}
+
+func (c *CoupledSender) SetSlowStartLargeReduction(enabled bool) {
+	c.slowStartLargeReduction = enabled
+}
+
+func (c *CoupledSender) BandwidthEstimate() Bandwidth {
+	srtt := c.rttStats.SmoothedRTT()
+	if srtt == 0 {
+		// If we haven't measured an rtt, the bandwidth estimate is unknown.
+		return 0
+	}
+	return BandwidthFromDelta(c.GetCongestionWindow(), srtt)
+}
+
+// HybridSlowStart returns the hybrid slow start instance for testing
+func (c *CoupledSender) HybridSlowStart() *HybridSlowStart {
+	return &c.hybridSlowStart
+}
+
+func (c *CoupledSender) SlowstartThreshold() protocol.PacketNumber {
+	return c.slowstartThreshold
+}
+
+func (c *CoupledSender) RenoBeta() float32 {
+	// kNConnectionBeta is the backoff factor after loss for our N-connection
+	// emulation, which emulates the effective backoff of an ensemble of N
+	// TCP-Reno connections on a single loss event. The effective multiplier is
+	// computed as:
+	return (float32(c.numConnections) - 1. + renoBeta) / float32(c.numConnections)
+}
+
+func (c *CoupledSender) InRecovery() bool {
+	return c.largestAckedPacketNumber <= c.largestSentAtLastCutback && c.largestAckedPacketNumber != 0
+}
+
+func (c *CoupledSender) InSlowStart() bool {
+	return c.GetCongestionWindow() < c.GetSlowStartThreshold()
+}
+
+// liaAlgorithm is the Linked Increases Algorithm of RFC 6356
+type liaAlgorithm struct{}
+
+// increase is min(alpha / cwnd_total, 1 / cwnd), with
+// alpha = cwnd_total * max(cwnd_i / rtt_i^2) / (sum(cwnd_i / rtt_i))^2
+func (liaAlgorithm) increase(c *CoupledSender) float64 {
+	var total, maxRatio, sumRate float64
+	for _, s := range c.group.senders {
+		rtt := s.rtt().Seconds()
+		if rtt == 0 {
+			continue
+		}
+		cwnd := float64(s.congestionWindow)
+		total += cwnd
+		maxRatio = math.Max(maxRatio, cwnd/(rtt*rtt))
+		sumRate += cwnd / rtt
+	}
+	own := 1 / float64(c.congestionWindow)
+	if sumRate == 0 {
+		return own
+	}
+	alpha := total * maxRatio / (sumRate * sumRate)
+	return math.Min(alpha/total, own)
+}
+
+// decrease backs off like the Reno sender
+func (liaAlgorithm) decrease(c *CoupledSender) protocol.PacketNumber {
+	return protocol.PacketNumber(float32(c.congestionWindow) * c.RenoBeta())
+}
+
+// baliaAlgorithm is the Balanced Linked Adaptation algorithm of
+// Peng et al., "Multipath TCP: Analysis, Design, and Implementation"
+type baliaAlgorithm struct{}
+
+// baliaRates returns the rate x_r = cwnd_r / rtt_r of the path, the sum and the maximum of the rates of the group
+func baliaRates(c *CoupledSender) (rate, sum, max float64) {
+	for _, s := range c.group.senders {
+		rtt := s.rtt().Seconds()
+		if rtt == 0 {
+			continue
+		}
+		x := float64(s.congestionWindow) / rtt
+		sum += x
+		max = math.Max(max, x)
+	}
+	if rtt := c.rtt().Seconds(); rtt != 0 {
+		rate = float64(c.congestionWindow) / rtt
+	}
+	return
+}
+
+// increase is (x_r / rtt_r) / (sum x_k)^2 * (1 + alpha_r) / 2 * (4 + alpha_r) / 5, with alpha_r = max x_k / x_r
+func (baliaAlgorithm) increase(c *CoupledSender) float64 {
+	rate, sum, max := baliaRates(c)
+	if rate == 0 || sum == 0 {
+		return 1 / float64(c.congestionWindow)
+	}
+	alpha := max / rate
+	return rate / c.rtt().Seconds() / (sum * sum) * (1 + alpha) / 2 * (4 + alpha) / 5
+}
+
+// decrease removes cwnd_r / 2 * min(alpha_r, 1.5) from the window
+func (baliaAlgorithm) decrease(c *CoupledSender) protocol.PacketNumber {
+	rate, _, max := baliaRates(c)
+	alpha := 1.0
+	if rate != 0 {
+		alpha = max / rate
+	}
+	cwnd := float64(c.congestionWindow)
+	return protocol.PacketNumber(math.Max(0, cwnd-cwnd/2*math.Min(alpha, 1.5)))
+}
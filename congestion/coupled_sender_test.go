@@ -0,0 +1,154 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Coupled senders", func() {
+	var (
+		group *CoupledGroup
+		clock mockClock
+	)
+
+	BeforeEach(func() {
+		group = NewCoupledGroup()
+		clock = mockClock{}
+	})
+
+	// newPath creates a sender in congestion avoidance with the given window and RTT
+	newPath := func(newSender func(*CoupledGroup, protocol.PathID, *RTTStats, protocol.PacketNumber, protocol.PacketNumber) SendAlgorithmWithDebugInfo, pathID protocol.PathID, cwnd protocol.PacketNumber, rtt time.Duration) *CoupledSender {
+		rttStats := NewRTTStats()
+		rttStats.UpdateRTT(rtt, 0, clock.Now())
+		sender := newSender(group, pathID, rttStats, cwnd, MaxCongestionWindow).(*CoupledSender)
+		sender.ExitSlowstart()
+		return sender
+	}
+
+	// ackWindow sends and acks a full window, and returns the new window
+	ackWindow := func(sender *CoupledSender) protocol.PacketNumber {
+		cwnd := sender.congestionWindow
+		packetNumber := sender.largestSentPacketNumber
+		bytesInFlight := sender.GetCongestionWindow()
+		for i := protocol.PacketNumber(1); i <= cwnd; i++ {
+			sender.OnPacketSent(clock.Now(), bytesInFlight, packetNumber+i, protocol.DefaultTCPMSS, true)
+		}
+		for i := protocol.PacketNumber(1); i <= cwnd; i++ {
+			sender.OnPacketAcked(packetNumber+i, protocol.DefaultTCPMSS, bytesInFlight)
+		}
+		return sender.congestionWindow
+	}
+
+	Context("LIA", func() {
+		It("starts like Reno", func() {
+			sender := NewLiaSender(group, 1, NewRTTStats(), initialCongestionWindowPackets, MaxCongestionWindow)
+			Expect(sender.GetCongestionWindow()).To(Equal(defaultWindowTCP))
+			Expect(sender.TimeUntilSend(clock.Now(), 0)).To(BeZero())
+			Expect(sender.TimeUntilSend(clock.Now(), defaultWindowTCP)).ToNot(BeZero())
+			Expect(sender.(*CoupledSender).InSlowStart()).To(BeTrue())
+		})
+
+		It("grows like Reno on a single path", func() {
+			sender := newPath(NewLiaSender, 1, 10, 100*time.Millisecond)
+			Expect(liaAlgorithm{}.increase(sender)).To(BeNumerically("~", 0.1, 1e-9))
+			Expect(ackWindow(sender)).To(Equal(protocol.PacketNumber(11)))
+			Expect(ackWindow(sender)).To(Equal(protocol.PacketNumber(12)))
+		})
+
+		It("couples the increase of the paths", func() {
+			path1 := newPath(NewLiaSender, 1, 10, 100*time.Millisecond)
+			path3 := newPath(NewLiaSender, 3, 10, 100*time.Millisecond)
+			// alpha = 20 * 1000 / 200^2 = 0.5, so both paths grow by 0.5 / 20 per ACK
+			Expect(liaAlgorithm{}.increase(path1)).To(BeNumerically("~", 0.025, 1e-9))
+			Expect(liaAlgorithm{}.increase(path3)).To(BeNumerically("~", 0.025, 1e-9))
+			for i := 0; i < 5; i++ {
+				ackWindow(path1)
+			}
+			// together, the paths grow by half a packet per round trip
+			Expect(path1.congestionWindow).To(Equal(protocol.PacketNumber(11)))
+		})
+
+		It("never grows faster than Reno on a path", func() {
+			path1 := newPath(NewLiaSender, 1, 20, 100*time.Millisecond)
+			path3 := newPath(NewLiaSender, 3, 10, 200*time.Millisecond)
+			// alpha = 30 * 2000 / 250^2 = 0.96
+			Expect(liaAlgorithm{}.increase(path1)).To(BeNumerically("~", 0.032, 1e-9))
+			Expect(liaAlgorithm{}.increase(path3)).To(BeNumerically("~", 0.032, 1e-9))
+			// alpha = 110 * 100000 / 2000^2 = 2.75, but path 1 grows by at most 1 / 100
+			path1.congestionWindow = 100
+			path3.congestionWindow = 10
+			path3.rttStats = NewRTTStats()
+			path3.rttStats.UpdateRTT(10*time.Millisecond, 0, clock.Now())
+			Expect(liaAlgorithm{}.increase(path1)).To(BeNumerically("~", 0.01, 1e-9))
+			Expect(liaAlgorithm{}.increase(path3)).To(BeNumerically("~", 0.025, 1e-9))
+		})
+
+		It("backs off like Reno", func() {
+			sender := newPath(NewLiaSender, 1, 20, 100*time.Millisecond)
+			sender.OnPacketSent(clock.Now(), 0, 1, protocol.DefaultTCPMSS, true)
+			sender.OnPacketLost(1, protocol.DefaultTCPMSS, 0)
+			Expect(sender.congestionWindow).To(Equal(protocol.PacketNumber(20 * sender.RenoBeta())))
+			Expect(sender.InRecovery()).To(BeFalse())
+			Expect(sender.SlowstartThreshold()).To(Equal(sender.congestionWindow))
+		})
+
+		It("stops coupling closed paths", func() {
+			path1 := newPath(NewLiaSender, 1, 10, 100*time.Millisecond)
+			newPath(NewLiaSender, 3, 10, 100*time.Millisecond)
+			group.Remove(3)
+			Expect(liaAlgorithm{}.increase(path1)).To(BeNumerically("~", 0.1, 1e-9))
+		})
+
+		It("behaves like Reno until the path has an RTT", func() {
+			path1 := newPath(NewLiaSender, 1, 10, 100*time.Millisecond)
+			path3 := NewLiaSender(group, 3, NewRTTStats(), 10, MaxCongestionWindow).(*CoupledSender)
+			path3.ExitSlowstart()
+			Expect(liaAlgorithm{}.increase(path1)).To(BeNumerically("~", 0.1, 1e-9))
+			Expect(ackWindow(path3)).To(Equal(protocol.PacketNumber(11)))
+		})
+
+		It("resets on an RTO", func() {
+			sender := newPath(NewLiaSender, 1, 20, 100*time.Millisecond)
+			sender.OnRetransmissionTimeout(true)
+			Expect(sender.congestionWindow).To(Equal(defaultMinimumCongestionWindow))
+			Expect(sender.SlowstartThreshold()).To(Equal(protocol.PacketNumber(10)))
+		})
+	})
+
+	Context("BALIA", func() {
+		It("grows like Reno on a single path", func() {
+			sender := newPath(NewBaliaSender, 1, 10, 100*time.Millisecond)
+			Expect(baliaAlgorithm{}.increase(sender)).To(BeNumerically("~", 0.1, 1e-9))
+			Expect(ackWindow(sender)).To(Equal(protocol.PacketNumber(11)))
+		})
+
+		It("halves the window on a single path", func() {
+			sender := newPath(NewBaliaSender, 1, 20, 100*time.Millisecond)
+			sender.OnPacketSent(clock.Now(), 0, 1, protocol.DefaultTCPMSS, true)
+			sender.OnPacketLost(1, protocol.DefaultTCPMSS, 0)
+			Expect(sender.congestionWindow).To(Equal(protocol.PacketNumber(10)))
+		})
+
+		It("balances the increase between the paths", func() {
+			path1 := newPath(NewBaliaSender, 1, 20, 100*time.Millisecond)
+			path3 := newPath(NewBaliaSender, 3, 10, 100*time.Millisecond)
+			// x_1 = 200, x_3 = 100, alpha_1 = 1, alpha_3 = 2
+			Expect(baliaAlgorithm{}.increase(path1)).To(BeNumerically("~", 2000.0/90000, 1e-9))
+			Expect(baliaAlgorithm{}.increase(path3)).To(BeNumerically("~", 1000.0/90000*1.5*1.2, 1e-9))
+		})
+
+		It("backs off more on the slower path", func() {
+			path1 := newPath(NewBaliaSender, 1, 20, 100*time.Millisecond)
+			path3 := newPath(NewBaliaSender, 3, 12, 100*time.Millisecond)
+			Expect(baliaAlgorithm{}.decrease(path1)).To(Equal(protocol.PacketNumber(10)))
+			// alpha_3 = 20 / 12 is capped at 1.5
+			Expect(baliaAlgorithm{}.decrease(path3)).To(Equal(protocol.PacketNumber(3)))
+			path3.OnPacketSent(clock.Now(), 0, 1, protocol.DefaultTCPMSS, true)
+			path3.OnPacketLost(1, protocol.DefaultTCPMSS, 0)
+			Expect(path3.congestionWindow).To(Equal(protocol.PacketNumber(3)))
+		})
+	})
+})
@@ -53,8 +53,9 @@ type OliaSender struct {
 	initialMaxCongestionWindow protocol.PacketNumber
 }
 
-func NewOliaSender(oliaSenders map[protocol.PathID]*OliaSender, rttStats *RTTStats, initialCongestionWindow, initialMaxCongestionWindow protocol.PacketNumber) SendAlgorithmWithDebugInfo {
-	return &OliaSender{
+// NewOliaSender creates a sender that uses OLIA, and adds it to the group
+func NewOliaSender(group *CoupledGroup, pathID protocol.PathID, rttStats *RTTStats, initialCongestionWindow, initialMaxCongestionWindow protocol.PacketNumber) SendAlgorithmWithDebugInfo {
+	o := &OliaSender{
 		rttStats:                   rttStats,
 		initialCongestionWindow:    initialCongestionWindow,
 		initialMaxCongestionWindow: initialMaxCongestionWindow,
@@ -64,8 +65,10 @@ func NewOliaSender(oliaSenders map[protocol.PathID]*OliaSender, rttStats *RTTSta
 		maxTCPCongestionWindow:     initialMaxCongestionWindow,
 		numConnections:             defaultNumConnections,
 		olia:                       NewOlia(0),
-		oliaSenders:                oliaSenders,
+		oliaSenders:                group.oliaSenders,
 	}
+	group.oliaSenders[pathID] = o
+	return o
 }
 
 func (o *OliaSender) TimeUntilSend(now time.Time, bytesInFlight protocol.ByteCount) time.Duration {
@@ -0,0 +1,66 @@
+package quic
+
+import (
+	"github.com/lucas-clemente/quic-go/congestion"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// A CongestionControlFactory creates the congestion controller of a path.
+// It is called once for every path of a connection, including the initial path.
+// The group is shared by the paths of the connection, so that coupled controllers see each other.
+// If it returns nil, the path uses Cubic.
+type CongestionControlFactory func(pathID PathID, rttStats *congestion.RTTStats, group *congestion.CoupledGroup) congestion.SendAlgorithm
+
+// The congestion controllers of the congestion package.
+// The coupled controllers leave the initial path to Cubic, since it only carries the handshake once other paths are open.
+var (
+	// CongestionControlCubic uses Cubic on every path
+	CongestionControlCubic CongestionControlFactory = func(pathID PathID, rttStats *congestion.RTTStats, group *congestion.CoupledGroup) congestion.SendAlgorithm {
+		return congestion.NewCubicSender(congestion.DefaultClock{}, rttStats, false, protocol.InitialCongestionWindow, protocol.DefaultMaxCongestionWindow)
+	}
+	// CongestionControlOLIA couples the paths with the Opportunistic Linked Increases Algorithm.
+	// It is used if the Config has no CongestionControl and the version supports multipath.
+	CongestionControlOLIA CongestionControlFactory = func(pathID PathID, rttStats *congestion.RTTStats, group *congestion.CoupledGroup) congestion.SendAlgorithm {
+		if pathID == protocol.InitialPathID {
+			return nil
+		}
+		return congestion.NewOliaSender(group, pathID, rttStats, protocol.InitialCongestionWindow, protocol.DefaultMaxCongestionWindow)
+	}
+	// CongestionControlLIA couples the paths with the Linked Increases Algorithm of RFC 6356
+	CongestionControlLIA CongestionControlFactory = func(pathID PathID, rttStats *congestion.RTTStats, group *congestion.CoupledGroup) congestion.SendAlgorithm {
+		if pathID == protocol.InitialPathID {
+			return nil
+		}
+		return congestion.NewLiaSender(group, pathID, rttStats, protocol.InitialCongestionWindow, protocol.DefaultMaxCongestionWindow)
+	}
+	// CongestionControlBALIA couples the paths with the Balanced Linked Adaptation algorithm
+	CongestionControlBALIA CongestionControlFactory = func(pathID PathID, rttStats *congestion.RTTStats, group *congestion.CoupledGroup) congestion.SendAlgorithm {
+		if pathID == protocol.InitialPathID {
+			return nil
+		}
+		return congestion.NewBaliaSender(group, pathID, rttStats, protocol.InitialCongestionWindow, protocol.DefaultMaxCongestionWindow)
+	}
+	// CongestionControlBBR uses the delay-based BBR-style sender on every path, which keeps the queues, and thus the delays, short
+	CongestionControlBBR CongestionControlFactory = func(pathID PathID, rttStats *congestion.RTTStats, group *congestion.CoupledGroup) congestion.SendAlgorithm {
+		return congestion.NewBBRSender(congestion.DefaultClock{}, rttStats, protocol.InitialCongestionWindow, protocol.DefaultMaxCongestionWindow)
+	}
+)
+
+// congestionControl creates the congestion controller of the path, or returns nil for Cubic
+func (p *path) congestionControl(group *congestion.CoupledGroup) congestion.SendAlgorithm {
+	var factory CongestionControlFactory
+	if p.sess.config != nil {
+		factory = p.sess.config.CongestionControl
+	}
+	if factory == nil {
+		// without a path manager, the session has a single path
+		if p.sess.version < protocol.VersionMP || group == nil {
+			return nil
+		}
+		factory = CongestionControlOLIA
+	}
+	if group == nil {
+		group = congestion.NewCoupledGroup()
+	}
+	return factory(p.pathID, p.rttStats, group)
+}
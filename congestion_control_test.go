@@ -0,0 +1,60 @@
+package quic
+
+import (
+	"github.com/lucas-clemente/quic-go/congestion"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Congestion control", func() {
+	var (
+		sess  *session
+		group *congestion.CoupledGroup
+	)
+
+	newPath := func(pathID protocol.PathID) *path {
+		return &path{pathID: pathID, sess: sess, rttStats: congestion.NewRTTStats()}
+	}
+
+	BeforeEach(func() {
+		sess = &session{config: &Config{}, version: protocol.VersionMP}
+		group = congestion.NewCoupledGroup()
+	})
+
+	It("uses OLIA on the paths other than the initial one by default", func() {
+		Expect(newPath(1).congestionControl(group)).To(BeAssignableToTypeOf(&congestion.OliaSender{}))
+		Expect(newPath(0).congestionControl(group)).To(BeNil())
+	})
+
+	It("uses Cubic with a single path", func() {
+		Expect(newPath(1).congestionControl(nil)).To(BeNil())
+		sess.version = protocol.VersionWhatever
+		Expect(newPath(1).congestionControl(group)).To(BeNil())
+	})
+
+	It("uses the factory of the config", func() {
+		sess.config.CongestionControl = CongestionControlLIA
+		Expect(newPath(1).congestionControl(group)).To(BeAssignableToTypeOf(&congestion.CoupledSender{}))
+		Expect(newPath(0).congestionControl(group)).To(BeNil())
+		sess.config.CongestionControl = CongestionControlBALIA
+		Expect(newPath(3).congestionControl(group)).To(BeAssignableToTypeOf(&congestion.CoupledSender{}))
+		sess.config.CongestionControl = CongestionControlBBR
+		Expect(newPath(0).congestionControl(group)).ToNot(BeNil())
+		Expect(newPath(5).congestionControl(nil)).ToNot(BeNil())
+		sess.config.CongestionControl = CongestionControlCubic
+		Expect(newPath(0).congestionControl(group)).ToNot(BeNil())
+	})
+
+	It("passes the PathID, RTT stats and group to the factory", func() {
+		pth := newPath(3)
+		sess.config.CongestionControl = func(pathID PathID, rttStats *congestion.RTTStats, g *congestion.CoupledGroup) congestion.SendAlgorithm {
+			Expect(pathID).To(Equal(PathID(3)))
+			Expect(rttStats).To(BeIdenticalTo(pth.rttStats))
+			Expect(g).To(BeIdenticalTo(group))
+			return nil
+		}
+		Expect(pth.congestionControl(group)).To(BeNil())
+	})
+})
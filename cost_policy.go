@@ -0,0 +1,156 @@
+package quic
+
+import (
+	"math"
+	"net"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// A CostUnit is the unit the price of a path is charged in
+type CostUnit uint8
+
+const (
+	// CostPerPacket charges the price for every packet sent
+	CostPerPacket CostUnit = iota
+	// CostPerByte charges the price for every byte sent
+	CostPerByte
+)
+
+// A PathCost is the price of sending on the paths of a local interface or address
+type PathCost struct {
+	// Label is the label the InterfacePolicy gave the interface, e.g. "cellular"
+	Label string
+	// Interface is the name of a local network interface, e.g. "wlan0". It is only used if Label is empty.
+	Interface string
+	// Address is a local IP address. It is only used if Label and Interface are empty.
+	Address net.IP
+	// Price is charged per packet or per byte, depending on the Unit of the CostPolicy
+	Price float64
+}
+
+// A CostPolicy prices the paths of a connection and limits how much it may spend.
+// Costs only apply on the client: the paths of a server all use its socket listening on the wildcard address,
+// so their local interface is unknown, and they are all charged the DefaultPrice.
+type CostPolicy struct {
+	// Costs are matched against the local address of a path. The first match is used.
+	Costs []PathCost
+	// DefaultPrice is charged on paths that match none of the Costs
+	DefaultPrice float64
+	Unit         CostUnit
+	// Budget is the maximal cost of the connection, or of every BudgetWindow if it is set.
+	// If nil, the cost is not limited. A zero Budget allows to send only on the paths that are free.
+	Budget       *float64
+	BudgetWindow time.Duration
+	// BatchBudget is the maximal cost of a single batch scheduled by CEDA-MPS.
+	// A zero BatchBudget means that only the Budget applies.
+	BatchBudget float64
+}
+
+// interfaceAddrs returns the addresses of a local interface. It can be replaced in tests.
+var interfaceAddrs = func(name string) ([]net.Addr, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, err
+	}
+	return iface.Addrs()
+}
+
+// price returns the price of a path with the given local address and interface label.
+// An unspecified address, like the one of a server socket, matches none of the Costs.
+func (p *CostPolicy) price(local net.Addr, label string) float64 {
+	if p == nil {
+		return 0
+	}
+	var ip net.IP
+	switch addr := local.(type) {
+	case *net.UDPAddr:
+		ip = addr.IP
+	case *net.IPAddr:
+		ip = addr.IP
+	}
+	if ip.IsUnspecified() {
+		ip = nil
+	}
+	for _, c := range p.Costs {
+		if c.Label != "" {
+			if c.Label == label {
+				return c.Price
+			}
+			continue
+		}
+		if ip == nil {
+			continue
+		}
+		if c.Interface == "" {
+			if c.Address.Equal(ip) {
+				return c.Price
+			}
+			continue
+		}
+		addrs, err := interfaceAddrs(c.Interface)
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			if ipNet, ok := a.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+				return c.Price
+			}
+		}
+	}
+	return p.DefaultPrice
+}
+
+// charge returns the cost of sending a packet of the given length at the given price
+func (p *CostPolicy) charge(price float64, length protocol.ByteCount) float64 {
+	if p != nil && p.Unit == CostPerByte {
+		return price * float64(length)
+	}
+	return price
+}
+
+// costTracker accounts the cost spent by a connection against the budget of the CostPolicy
+type costTracker struct {
+	policy *CostPolicy
+
+	spent       float64
+	windowStart time.Time
+}
+
+func newCostTracker(policy *CostPolicy) *costTracker {
+	return &costTracker{policy: policy, windowStart: time.Now()}
+}
+
+func (t *costTracker) maybeStartWindow(now time.Time) {
+	if t.policy == nil || t.policy.BudgetWindow == 0 {
+		return
+	}
+	if now.Sub(t.windowStart) >= t.policy.BudgetWindow {
+		t.spent = 0
+		t.windowStart = now
+	}
+}
+
+func (t *costTracker) add(cost float64, now time.Time) {
+	t.maybeStartWindow(now)
+	t.spent += cost
+}
+
+// remainingBudget returns the cost that may still be spent, or +Inf if there is no budget
+func (t *costTracker) remainingBudget(now time.Time) float64 {
+	if t.policy == nil || t.policy.Budget == nil {
+		return math.Inf(1)
+	}
+	t.maybeStartWindow(now)
+	return math.Max(*t.policy.Budget-t.spent, 0)
+}
+
+// batchBudget returns the cost the next batch may spend, or +Inf if there is no limit
+func (t *costTracker) batchBudget(now time.Time) float64 {
+	budget := t.remainingBudget(now)
+	if t.policy != nil && t.policy.BatchBudget != 0 {
+		budget = math.Min(budget, t.policy.BatchBudget)
+	}
+	return budget
+}
@@ -0,0 +1,140 @@
+package quic
+
+import (
+	"errors"
+	"math"
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cost Policy", func() {
+	var policy *CostPolicy
+
+	BeforeEach(func() {
+		policy = &CostPolicy{
+			Costs: []PathCost{
+				{Address: net.IPv4(10, 0, 0, 1), Price: 1.5},
+				{Interface: "wlan0", Price: 0.5},
+			},
+			DefaultPrice: 1,
+		}
+	})
+
+	Context("pricing paths", func() {
+		var origInterfaceAddrs func(string) ([]net.Addr, error)
+
+		BeforeEach(func() {
+			origInterfaceAddrs = interfaceAddrs
+			interfaceAddrs = func(name string) ([]net.Addr, error) {
+				if name != "wlan0" {
+					return nil, errors.New("no such interface")
+				}
+				return []net.Addr{&net.IPNet{IP: net.IPv4(192, 168, 1, 2), Mask: net.CIDRMask(24, 32)}}, nil
+			}
+		})
+
+		AfterEach(func() {
+			interfaceAddrs = origInterfaceAddrs
+		})
+
+		It("matches the local address", func() {
+			Expect(policy.price(&net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 4433}, "")).To(Equal(1.5))
+		})
+
+		It("matches the addresses of an interface", func() {
+			Expect(policy.price(&net.UDPAddr{IP: net.IPv4(192, 168, 1, 2), Port: 4433}, "")).To(Equal(0.5))
+		})
+
+		It("uses the default price if nothing matches", func() {
+			Expect(policy.price(&net.UDPAddr{IP: net.IPv4(172, 16, 0, 1), Port: 4433}, "")).To(Equal(1.0))
+		})
+
+		It("uses the default price for the wildcard address of a server", func() {
+			policy.Costs = append(policy.Costs, PathCost{Address: net.IPv4zero, Price: 7})
+			Expect(policy.price(&net.UDPAddr{IP: net.IPv4zero, Port: 4433}, "")).To(Equal(1.0))
+			Expect(policy.price(&net.UDPAddr{IP: net.IPv6unspecified, Port: 4433}, "")).To(Equal(1.0))
+		})
+
+		It("uses the first match", func() {
+			policy.Costs = append([]PathCost{{Address: net.IPv4(192, 168, 1, 2), Price: 3}}, policy.Costs...)
+			Expect(policy.price(&net.UDPAddr{IP: net.IPv4(192, 168, 1, 2), Port: 4433}, "")).To(Equal(3.0))
+		})
+
+		It("matches the label of the interface", func() {
+			policy.Costs = append(policy.Costs, PathCost{Label: "cellular", Price: 4})
+			Expect(policy.price(&net.UDPAddr{IP: net.IPv4(172, 16, 0, 1), Port: 4433}, "cellular")).To(Equal(4.0))
+			Expect(policy.price(&net.UDPAddr{IP: net.IPv4(172, 16, 0, 1), Port: 4433}, "wifi")).To(Equal(1.0))
+		})
+
+		It("makes all paths free without a policy", func() {
+			policy = nil
+			Expect(policy.price(&net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 4433}, "")).To(BeZero())
+		})
+	})
+
+	Context("charging", func() {
+		It("charges per packet", func() {
+			Expect(policy.charge(1.5, 1000)).To(Equal(1.5))
+		})
+
+		It("charges per byte", func() {
+			policy.Unit = CostPerByte
+			Expect(policy.charge(0.01, 1000)).To(Equal(10.0))
+		})
+	})
+
+	Context("tracking the budget", func() {
+		var now time.Time
+
+		budget := 10.0
+
+		BeforeEach(func() {
+			now = time.Now()
+		})
+
+		It("has no limit without a budget", func() {
+			t := newCostTracker(policy)
+			t.add(100, now)
+			Expect(math.IsInf(t.remainingBudget(now), 1)).To(BeTrue())
+			Expect(math.IsInf(t.batchBudget(now), 1)).To(BeTrue())
+		})
+
+		It("tracks the budget of the connection", func() {
+			policy.Budget = &budget
+			t := newCostTracker(policy)
+			t.add(4, now)
+			Expect(t.remainingBudget(now)).To(Equal(6.0))
+			t.add(8, now)
+			Expect(t.remainingBudget(now)).To(BeZero())
+		})
+
+		It("limits the budget of a batch", func() {
+			policy.Budget = &budget
+			policy.BatchBudget = 4
+			t := newCostTracker(policy)
+			Expect(t.batchBudget(now)).To(Equal(4.0))
+			t.add(8, now)
+			Expect(t.batchBudget(now)).To(Equal(2.0))
+		})
+
+		It("allows to spend nothing", func() {
+			zero := 0.0
+			policy.Budget = &zero
+			t := newCostTracker(policy)
+			Expect(t.remainingBudget(now)).To(BeZero())
+			Expect(t.batchBudget(now)).To(BeZero())
+		})
+
+		It("resets the budget every window", func() {
+			policy.Budget = &budget
+			policy.BudgetWindow = time.Second
+			t := newCostTracker(policy)
+			t.add(8, now)
+			Expect(t.remainingBudget(now)).To(Equal(2.0))
+			Expect(t.remainingBudget(now.Add(2 * time.Second))).To(Equal(10.0))
+		})
+	})
+})
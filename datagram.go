@@ -0,0 +1,61 @@
+package quic
+
+import (
+	"errors"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+)
+
+var (
+	errDatagramTooLarge      = errors.New("datagram too large")
+	errDatagramsNotSupported = errors.New("datagrams need the deadline extension")
+)
+
+// SendDatagram queues a datagram. It is sent in a single DATAGRAM frame on the path chosen by the scheduler, and never retransmitted.
+// If the deadline is not zero, the datagram is dropped once it expired without being sent.
+func (s *session) SendDatagram(p []byte, deadline time.Time) error {
+	if !s.version.UsesDeadlines() {
+		return errDatagramsNotSupported
+	}
+	if protocol.ByteCount(len(p)) > protocol.MaxDatagramSize {
+		return errDatagramTooLarge
+	}
+	frame := &wire.DatagramFrame{Data: make([]byte, len(p)), Deadline: deadline}
+	copy(frame.Data, p)
+	select {
+	case s.datagramRequests <- frame:
+		return nil
+	case <-s.ctx.Done():
+		return errSessionClosed
+	}
+}
+
+// ReceiveDatagram returns the next datagram received, blocking until one is available
+func (s *session) ReceiveDatagram() ([]byte, error) {
+	select {
+	case p := <-s.receivedDatagrams:
+		return p, nil
+	case <-s.ctx.Done():
+		return nil, errSessionClosed
+	}
+}
+
+// handleDatagramFrame queues the data of a DATAGRAM frame for the application.
+// If the application doesn't keep up, the oldest datagram is dropped.
+func (s *session) handleDatagramFrame(frame *wire.DatagramFrame) {
+	for {
+		select {
+		case s.receivedDatagrams <- frame.Data:
+			return
+		default:
+		}
+		select {
+		case <-s.receivedDatagrams:
+			utils.Debugf("Dropping a received datagram, the queue is full")
+		default:
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package quic
+
+import (
+	"context"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Datagrams", func() {
+	var sess *session
+
+	BeforeEach(func() {
+		sess = &session{
+			version:           protocol.VersionDeadline,
+			streamFramer:      newStreamFramer(nil, nil),
+			datagramRequests:  make(chan *wire.DatagramFrame, 1),
+			receivedDatagrams: make(chan []byte, protocol.MaxDatagramQueueLen),
+		}
+		sess.ctx, sess.ctxCancel = context.WithCancel(context.Background())
+	})
+
+	AfterEach(func() {
+		sess.ctxCancel()
+	})
+
+	It("hands a copy of the datagram to the run loop", func() {
+		deadline := time.Now().Add(time.Second)
+		p := []byte("foobar")
+		Expect(sess.SendDatagram(p, deadline)).To(Succeed())
+		p[0] = 'g'
+		var f *wire.DatagramFrame
+		Eventually(sess.datagramRequests).Should(Receive(&f))
+		Expect(f.Data).To(Equal([]byte("foobar")))
+		Expect(f.Deadline).To(Equal(deadline))
+	})
+
+	It("rejects too large datagrams", func() {
+		Expect(sess.SendDatagram(make([]byte, protocol.MaxDatagramSize+1), time.Time{})).To(MatchError(errDatagramTooLarge))
+	})
+
+	It("needs the deadline extension", func() {
+		sess.version = protocol.VersionMP
+		Expect(sess.SendDatagram([]byte("foobar"), time.Time{})).To(MatchError(errDatagramsNotSupported))
+	})
+
+	It("receives datagrams", func() {
+		sess.handleDatagramFrame(&wire.DatagramFrame{Data: []byte("foo")})
+		sess.handleDatagramFrame(&wire.DatagramFrame{Data: []byte("bar")})
+		Expect(sess.ReceiveDatagram()).To(Equal([]byte("foo")))
+		Expect(sess.ReceiveDatagram()).To(Equal([]byte("bar")))
+	})
+
+	It("drops the oldest received datagram if the application doesn't read them", func() {
+		for i := 0; i <= protocol.MaxDatagramQueueLen; i++ {
+			sess.handleDatagramFrame(&wire.DatagramFrame{Data: []byte{byte(i)}})
+		}
+		Expect(sess.ReceiveDatagram()).To(Equal([]byte{1}))
+	})
+
+	It("fails once the session is closed", func() {
+		sess.ctxCancel()
+		_, err := sess.ReceiveDatagram()
+		Expect(err).To(MatchError(errSessionClosed))
+		sess.datagramRequests = make(chan *wire.DatagramFrame)
+		Expect(sess.SendDatagram([]byte("foobar"), time.Time{})).To(MatchError(errSessionClosed))
+	})
+
+	It("uses the deadline of datagrams for the packet", func() {
+		deadline := time.Now().Add(time.Second)
+		frames := []wire.Frame{
+			&wire.StreamFrame{Deadline: deadline.Add(time.Millisecond)},
+			&wire.DatagramFrame{Deadline: deadline},
+		}
+		Expect(earliestDeadline(frames)).To(Equal(deadline))
+	})
+})
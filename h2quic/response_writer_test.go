@@ -48,6 +48,11 @@ func (s *mockStream) SetReadDeadline(time.Time) error              { panic("not
 func (s *mockStream) SetWriteDeadline(time.Time) error             { panic("not implemented") }
 func (s *mockStream) GetBytesSent() (protocol.ByteCount, error)    { panic("not implemented") }
 func (s *mockStream) GetBytesRetrans() (protocol.ByteCount, error) { panic("not implemented") }
+func (s *mockStream) SetDataDeadline(time.Duration)                { panic("not implemented") }
+func (s *mockStream) SetPriority(uint8, bool)                      { panic("not implemented") }
+func (s *mockStream) WriteWithDeadline(p []byte, t time.Time) (int, error) {
+	panic("not implemented")
+}
 
 func (s *mockStream) Read(p []byte) (int, error) {
 	n, _ := s.dataToRead.Read(p)
@@ -71,6 +71,30 @@ func (s *mockSession) RemoteAddr() net.Addr {
 func (s *mockSession) Context() context.Context {
 	return s.ctx
 }
+func (s *mockSession) PathStats() []quic.PathStats {
+	panic("not implemented")
+}
+func (s *mockSession) ConnectionStats() quic.ConnectionStats {
+	panic("not implemented")
+}
+func (s *mockSession) SetPathPriority(quic.PathID, quic.PathPriority) error {
+	panic("not implemented")
+}
+func (s *mockSession) Paths() []quic.PathState {
+	panic("not implemented")
+}
+func (s *mockSession) OpenPath(net.Addr, net.Addr) (quic.PathID, error) {
+	panic("not implemented")
+}
+func (s *mockSession) ClosePath(quic.PathID) error {
+	panic("not implemented")
+}
+func (s *mockSession) SendDatagram([]byte, time.Time) error {
+	panic("not implemented")
+}
+func (s *mockSession) ReceiveDatagram() ([]byte, error) {
+	panic("not implemented")
+}
 
 var _ = Describe("H2 server", func() {
 	var (
@@ -6,6 +6,7 @@ import (
 	"net"
 	"time"
 
+	"github.com/lucas-clemente/quic-go/ackhandler"
 	"github.com/lucas-clemente/quic-go/internal/handshake"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 )
@@ -13,12 +14,42 @@ import (
 // The StreamID is the ID of a QUIC stream.
 type StreamID = protocol.StreamID
 
+// The PathID is the ID of a path of a multipath QUIC connection.
+type PathID = protocol.PathID
+
+// A PathPriority tells the schedulers how to use a path.
+type PathPriority = protocol.PathPriority
+
+// The priorities of a path
+const (
+	// PathPriorityActive paths are used by all schedulers. It is the priority of new paths.
+	PathPriorityActive = protocol.PathPriorityActive
+	// PathPriorityBackup paths are only used if no active path can send, or if the active paths can't meet a deadline.
+	PathPriorityBackup = protocol.PathPriorityBackup
+	// PathPriorityDisabled paths stay open, but no data is sent on them.
+	PathPriorityDisabled = protocol.PathPriorityDisabled
+)
+
 // A VersionNumber is a QUIC version number.
 type VersionNumber = protocol.VersionNumber
 
 // A Cookie can be used to verify the ownership of the client address.
 type Cookie = handshake.Cookie
 
+// A BanditConfig configures the bandit that chooses the deadline stringency factor alpha of every path.
+type BanditConfig = ackhandler.BanditConfig
+
+// A BanditAlgorithm is the algorithm of the bandit that chooses alpha.
+type BanditAlgorithm = ackhandler.BanditAlgorithm
+
+// The bandit algorithms that can be selected in the BanditConfig
+const (
+	BanditUCB              = ackhandler.BanditUCB
+	BanditSlidingWindowUCB = ackhandler.BanditSlidingWindowUCB
+	BanditThompson         = ackhandler.BanditThompson
+	BanditEXP3             = ackhandler.BanditEXP3
+)
+
 // Stream is the interface implemented by QUIC streams
 type Stream interface {
 	// Read reads data from the stream.
@@ -51,6 +82,18 @@ type Stream interface {
 	// with the connection. It is equivalent to calling both
 	// SetReadDeadline and SetWriteDeadline.
 	SetDeadline(t time.Time) error
+	// SetDataDeadline sets a relative delivery deadline for the data passed to future Write calls.
+	// Every Write is then due d after it was called.
+	// A zero value for d means the data has no delivery deadline.
+	SetDataDeadline(d time.Duration)
+	// WriteWithDeadline writes data to the stream that should be delivered to the peer by t.
+	// It overrides the relative deadline set by SetDataDeadline for this call only.
+	// A zero value for t means the data has no delivery deadline.
+	WriteWithDeadline(p []byte, t time.Time) (int, error)
+	// SetPriority sets the priority of the stream. Streams with a higher priority are sent first if the StreamOrder is StreamOrderPriority.
+	// Urgent streams are sent before all other streams if the StreamOrder is StreamOrderEDF or StreamOrderPriority.
+	// By default, streams have priority 0 and aren't urgent.
+	SetPriority(priority uint8, urgent bool)
 	// GetBytesSent returns the number of bytes of the stream that were sent to the peer
 	GetBytesSent() (protocol.ByteCount, error)
 	// GetBytesRetrans returns the number of bytes of the stream that were retransmitted to the peer
@@ -78,6 +121,25 @@ type Session interface {
 	// The context is cancelled when the session is closed.
 	// Warning: This API should not be considered stable and might change soon.
 	Context() context.Context
+	// PathStats returns a snapshot of the statistics of every path.
+	PathStats() []PathStats
+	// ConnectionStats returns a snapshot of the statistics of the connection, including all paths.
+	ConnectionStats() ConnectionStats
+	// SetPathPriority changes the priority of an open path. With the deadline extension, the peer is asked to use the path the same way.
+	SetPathPriority(pathID PathID, priority PathPriority) error
+	// Paths returns the state of every path of the connection, including the closed ones.
+	Paths() []PathState
+	// OpenPath opens a path between a local address of the connection and a remote address.
+	// It can only be called once the handshake is complete.
+	OpenPath(local, remote net.Addr) (PathID, error)
+	// ClosePath closes a path, and tells the peer with a CLOSE_PATH frame.
+	ClosePath(pathID PathID) error
+	// SendDatagram sends p unreliably in a DATAGRAM frame, which needs the deadline extension.
+	// The datagram is never retransmitted, and dropped if the deadline passes before it is sent.
+	SendDatagram(p []byte, deadline time.Time) error
+	// ReceiveDatagram returns the next datagram received, blocking until one is available.
+	// If the application doesn't read them, older datagrams are dropped.
+	ReceiveDatagram() ([]byte, error)
 }
 
 // A NonFWSession is a QUIC connection between two peers half-way through the handshake.
@@ -124,12 +186,57 @@ type Config struct {
 	// Should the host try to create new paths, if possible?
 	CreatePaths bool
 	//Arguments for agent
+	// SchedulerName selects the PathScheduler, see RegisterPathScheduler.
+	// If empty or unknown, the lowest RTT scheduler is used.
 	SchedulerName string
 	WeightsFile   string
 	Training      bool
 	Epsilon       float64
 	AllowedCongestion	int
 	DumpExperiences		bool
+	// CostPolicy prices the paths and limits the cost of the connection.
+	// It is used by CEDA-MPS. If nil, all paths are free. Its Costs are matched on the client only,
+	// the server charges the DefaultPrice on all paths.
+	// Its Budget is a pointer: nil means no limit, while a zero Budget keeps the connection on the free paths.
+	CostPolicy *CostPolicy
+	// PartialReliability allows to abandon stream data that missed its deadline instead of retransmitting it.
+	// The peer is notified of the gap with a STREAM_GAP frame. It requires the deadline extension (VersionDeadline).
+	PartialReliability bool
+	// LinUCBStore persists the state of the linUCB bandit used by the lowband and peek schedulers between connections.
+	// It is only loaded if one of these schedulers is selected. If nil, the state is kept in memory.
+	LinUCBStore LinUCBStore
+	// BatchSize is the number of packets scheduled at once by batch schedulers like BatchLinOpt.
+	// If zero, 6 packets are scheduled at once.
+	BatchSize int
+	// Bandit configures the fluctuation monitor that chooses the alpha of every path.
+	// If nil, UCB chooses from the alphas 1.0, 1.1 and 1.2. If its BatchSize is zero, the BatchSize of this Config is used.
+	Bandit *BanditConfig
+	// InterfacePolicy selects the local interfaces and addresses a client opens paths on, and labels them.
+	// If nil, the DefaultInterfacePolicy is used.
+	InterfacePolicy *InterfacePolicy
+	// PrimaryPathLabel makes the primary and BatchPrimary schedulers send on the first path with this label, instead of on path 1.
+	PrimaryPathLabel string
+	// SecondPathLabel makes the secondPath scheduler send on the first path with this label, instead of on path 3.
+	SecondPathLabel string
+	// PathCreation decides between which local and remote addresses paths are created, if CreatePaths is set.
+	// If nil, the client creates a full mesh and the server creates no paths.
+	// If set on the server, it also opens paths toward the addresses advertised by the client.
+	PathCreation *PathCreationPolicy
+	// CongestionControl creates the congestion controller of every path, e.g. CongestionControlLIA, CongestionControlBALIA or CongestionControlBBR.
+	// If nil, OLIA couples the paths other than the initial one, and Cubic is used otherwise.
+	CongestionControl CongestionControlFactory
+	// PathHealth configures the failure detection of the paths: how paths that stopped working are probed, and when they are used again.
+	// If nil, the defaults of the PathHealthConfig are used.
+	PathHealth *PathHealthConfig
+	// StreamOrder is the order in which the data of the streams is packed, e.g. StreamOrderEDF or StreamOrderPriority.
+	// If not set, the streams are served round-robin.
+	StreamOrder StreamOrder
+	// Redundancy configures the redundant scheduler, see RedundancyConfig.
+	// If nil, the defaults of the RedundancyConfig are used.
+	Redundancy *RedundancyConfig
+	// Tracer receives structured events about the scheduling decisions and deadline outcomes of the connection.
+	// If nil, no events are traced.
+	Tracer Tracer
 }
 
 // A Listener for incoming QUIC connections
@@ -0,0 +1,95 @@
+package quic
+
+import (
+	"net"
+	"path/filepath"
+)
+
+// An InterfaceRule matches local addresses by interface, address family and network.
+// Empty fields match everything.
+type InterfaceRule struct {
+	// Interface is the name of a local network interface. It may contain the wildcards of filepath.Match, e.g. "wl*".
+	Interface string
+	// Network is "ip4" or "ip6"
+	Network string
+	// CIDRs are the networks the address must be in, e.g. 192.168.0.0/16
+	CIDRs []*net.IPNet
+	// Label names the kind of the interface, e.g. "wifi" or "cellular".
+	// Paths on the addresses allowed by this rule carry this label.
+	Label string
+}
+
+func (r *InterfaceRule) matches(iface string, ip net.IP) bool {
+	if r.Interface != "" {
+		if ok, err := filepath.Match(r.Interface, iface); err != nil || !ok {
+			return false
+		}
+	}
+	switch r.Network {
+	case "ip4":
+		if ip.To4() == nil {
+			return false
+		}
+	case "ip6":
+		if ip.To4() != nil {
+			return false
+		}
+	}
+	if len(r.CIDRs) == 0 {
+		return true
+	}
+	for _, n := range r.CIDRs {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// An InterfacePolicy selects the local addresses a client opens paths on
+type InterfacePolicy struct {
+	// Allow lists the addresses to use. If it is empty, all global unicast addresses are used.
+	// The label of the first matching rule is used.
+	Allow []InterfaceRule
+	// Deny lists the addresses never to use. It takes precedence over Allow.
+	Deny []InterfaceRule
+}
+
+// DefaultInterfacePolicy is used if the Config has no InterfacePolicy.
+// It allows the Ethernet, cellular and Wi-Fi interfaces of Linux and Android.
+var DefaultInterfacePolicy = InterfacePolicy{
+	Allow: []InterfaceRule{
+		{Interface: "*eth*", Label: "ethernet"},
+		{Interface: "*rmnet*", Label: "cellular"},
+		{Interface: "*wlan*", Label: "wifi"},
+	},
+}
+
+// allows returns if an address of a local interface may be used, and its label
+func (p *InterfacePolicy) allows(iface string, ip net.IP) (string, bool) {
+	if p == nil {
+		p = &DefaultInterfacePolicy
+	}
+	for i := range p.Deny {
+		if p.Deny[i].matches(iface, ip) {
+			return "", false
+		}
+	}
+	if len(p.Allow) == 0 {
+		return "", true
+	}
+	for i := range p.Allow {
+		if p.Allow[i].matches(iface, ip) {
+			return p.Allow[i].Label, true
+		}
+	}
+	return "", false
+}
+
+// interfacePolicy returns the InterfacePolicy of a Config, which may be nil
+func interfacePolicy(config *Config) *InterfacePolicy {
+	if config == nil {
+		return nil
+	}
+	return config.InterfacePolicy
+}
@@ -0,0 +1,81 @@
+package quic
+
+import (
+	"net"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Interface Policy", func() {
+	mustParseCIDR := func(s string) *net.IPNet {
+		_, n, err := net.ParseCIDR(s)
+		Expect(err).ToNot(HaveOccurred())
+		return n
+	}
+
+	It("allows the Ethernet, cellular and Wi-Fi interfaces by default", func() {
+		ip := net.ParseIP("10.0.0.1")
+		label, ok := (*InterfacePolicy)(nil).allows("wlan0", ip)
+		Expect(ok).To(BeTrue())
+		Expect(label).To(Equal("wifi"))
+		label, ok = (*InterfacePolicy)(nil).allows("rmnet_data0", ip)
+		Expect(ok).To(BeTrue())
+		Expect(label).To(Equal("cellular"))
+		_, ok = (*InterfacePolicy)(nil).allows("enp0s3", ip)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("allows everything without Allow rules", func() {
+		label, ok := (&InterfacePolicy{}).allows("wwan0", net.ParseIP("10.0.0.1"))
+		Expect(ok).To(BeTrue())
+		Expect(label).To(BeEmpty())
+	})
+
+	It("matches interface names with wildcards", func() {
+		policy := &InterfacePolicy{Allow: []InterfaceRule{{Interface: "wl*", Label: "wifi"}, {Interface: "wwan*", Label: "cellular"}}}
+		label, ok := policy.allows("wlp2s0", net.ParseIP("10.0.0.1"))
+		Expect(ok).To(BeTrue())
+		Expect(label).To(Equal("wifi"))
+		label, ok = policy.allows("wwan0", net.ParseIP("10.0.0.1"))
+		Expect(ok).To(BeTrue())
+		Expect(label).To(Equal("cellular"))
+		_, ok = policy.allows("veth1234", net.ParseIP("10.0.0.1"))
+		Expect(ok).To(BeFalse())
+	})
+
+	It("matches address families and networks", func() {
+		policy := &InterfacePolicy{Allow: []InterfaceRule{
+			{Network: "ip6", Label: "v6"},
+			{CIDRs: []*net.IPNet{mustParseCIDR("192.168.0.0/16")}, Label: "home"},
+		}}
+		label, ok := policy.allows("eth0", net.ParseIP("2001:db8::1"))
+		Expect(ok).To(BeTrue())
+		Expect(label).To(Equal("v6"))
+		label, ok = policy.allows("eth0", net.ParseIP("192.168.1.2"))
+		Expect(ok).To(BeTrue())
+		Expect(label).To(Equal("home"))
+		_, ok = policy.allows("eth0", net.ParseIP("10.0.0.1"))
+		Expect(ok).To(BeFalse())
+	})
+
+	It("denies before allowing", func() {
+		policy := &InterfacePolicy{
+			Allow: []InterfaceRule{{Label: "any"}},
+			Deny:  []InterfaceRule{{Interface: "veth*"}, {Network: "ip6"}},
+		}
+		_, ok := policy.allows("veth0", net.ParseIP("10.0.0.1"))
+		Expect(ok).To(BeFalse())
+		_, ok = policy.allows("eth0", net.ParseIP("2001:db8::1"))
+		Expect(ok).To(BeFalse())
+		_, ok = policy.allows("eth0", net.ParseIP("10.0.0.1"))
+		Expect(ok).To(BeTrue())
+	})
+
+	It("labels local addresses in the pconnManager", func() {
+		pcm := &pconnManager{labels: map[string]string{"10.0.0.1": "wifi"}}
+		Expect(pcm.label(&net.UDPAddr{IP: net.ParseIP("10.0.0.1"), Port: 1234})).To(Equal("wifi"))
+		Expect(pcm.label(&net.UDPAddr{IP: net.IPv4zero})).To(BeEmpty())
+		Expect((*pconnManager)(nil).label(&net.UDPAddr{IP: net.ParseIP("10.0.0.1")})).To(BeEmpty())
+	})
+})
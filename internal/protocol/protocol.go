@@ -33,6 +33,30 @@ type PathID uint8
 // Initial PathID
 const InitialPathID = 0
 
+// A PathPriority tells the schedulers if a path may be used
+type PathPriority uint8
+
+const (
+	// PathPriorityActive paths are used by the schedulers
+	PathPriorityActive PathPriority = iota
+	// PathPriorityBackup paths are only used if no active path can send, or meet the deadline
+	PathPriorityBackup
+	// PathPriorityDisabled paths are not used to send data, but stay open
+	PathPriorityDisabled
+)
+
+func (p PathPriority) String() string {
+	switch p {
+	case PathPriorityActive:
+		return "active"
+	case PathPriorityBackup:
+		return "backup"
+	case PathPriorityDisabled:
+		return "disabled"
+	}
+	return "unknown"
+}
+
 // A ByteCount in QUIC
 type ByteCount uint64
 
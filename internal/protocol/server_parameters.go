@@ -128,3 +128,9 @@ const ClosedSessionDeleteTimeout = time.Minute
 
 // NumCachedCertificates is the number of cached compressed certificate chains, each taking ~1K space
 const NumCachedCertificates = 128
+
+// MaxDatagramSize is the maximum size of the payload of a DATAGRAM frame, so that it fits into a packet with an ACK frame
+const MaxDatagramSize ByteCount = 1000
+
+// MaxDatagramQueueLen is the maximum number of datagrams queued for sending, or until the application reads them
+const MaxDatagramQueueLen = 128
@@ -15,11 +15,15 @@ const (
 	VersionUnsupported VersionNumber = -1
 	VersionUnknown     VersionNumber = -2
 	VersionMP          VersionNumber = 512
+	// VersionDeadline is VersionMP with the deadline extension.
+	// Peers that don't support it negotiate VersionMP, which doesn't carry deadline information.
+	VersionDeadline VersionNumber = 513
 )
 
 // SupportedVersions lists the versions that the server supports
 // must be in sorted descending order
 var SupportedVersions = []VersionNumber{
+	VersionDeadline,
 	VersionMP,
 	Version39,
 	Version38,
@@ -31,6 +35,11 @@ func (vn VersionNumber) UsesTLS() bool {
 	return vn == VersionTLS
 }
 
+// UsesDeadlines says if this QUIC version carries deadline information in the public header and in ACK frames
+func (vn VersionNumber) UsesDeadlines() bool {
+	return vn == VersionDeadline
+}
+
 func (vn VersionNumber) String() string {
 	switch vn {
 	case VersionWhatever:
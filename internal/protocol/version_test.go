@@ -13,6 +13,18 @@ var _ = Describe("Version", func() {
 		Expect(VersionTLS.UsesTLS()).To(BeTrue())
 	})
 
+	It("says if a version uses the deadline extension", func() {
+		Expect(Version39.UsesDeadlines()).To(BeFalse())
+		Expect(VersionMP.UsesDeadlines()).To(BeFalse())
+		Expect(VersionDeadline.UsesDeadlines()).To(BeTrue())
+		Expect((VersionDeadline + 1).UsesDeadlines()).To(BeFalse())
+	})
+
+	It("falls back to the version without the deadline extension", func() {
+		Expect(ChooseSupportedVersion(SupportedVersions, []VersionNumber{VersionMP, Version39})).To(Equal(VersionMP))
+		Expect(ChooseSupportedVersion(SupportedVersions, []VersionNumber{VersionMP, VersionDeadline})).To(Equal(VersionDeadline))
+	})
+
 	It("has the right string representation", func() {
 		Expect(Version37.String()).To(Equal("37"))
 		Expect(Version38.String()).To(Equal("38"))
@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// The variable-length integer encoding of the IETF QUIC drafts.
+// The two most significant bits of the first byte encode the length of the integer (1, 2, 4 or 8 bytes),
+// the remaining bits encode the value in network byte order.
+const (
+	maxVarInt1 = 63
+	maxVarInt2 = 16383
+	maxVarInt4 = 1073741823
+	maxVarInt8 = 4611686018427387903
+)
+
+// MaxVarInt4 is the largest value that can be encoded in 4 bytes
+const MaxVarInt4 = maxVarInt4
+
+// ReadVarInt reads a number in the QUIC varint format
+func ReadVarInt(b io.ByteReader) (uint64, error) {
+	firstByte, err := b.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	length := 1 << ((firstByte & 0xc0) >> 6)
+	val := uint64(firstByte & 0x3f)
+	for i := 1; i < length; i++ {
+		next, err := b.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		val = val<<8 + uint64(next)
+	}
+	return val, nil
+}
+
+// WriteVarInt writes a number in the QUIC varint format
+func WriteVarInt(b *bytes.Buffer, i uint64) {
+	switch {
+	case i <= maxVarInt1:
+		b.WriteByte(uint8(i))
+	case i <= maxVarInt2:
+		b.Write([]byte{uint8(i>>8) | 0x40, uint8(i)})
+	case i <= maxVarInt4:
+		b.Write([]byte{uint8(i>>24) | 0x80, uint8(i >> 16), uint8(i >> 8), uint8(i)})
+	case i <= maxVarInt8:
+		b.Write([]byte{
+			uint8(i>>56) | 0xc0, uint8(i >> 48), uint8(i >> 40), uint8(i >> 32),
+			uint8(i >> 24), uint8(i >> 16), uint8(i >> 8), uint8(i),
+		})
+	default:
+		panic(fmt.Sprintf("%#x doesn't fit into 62 bits", i))
+	}
+}
+
+// VarIntLen determines the number of bytes that will be needed to write a number
+func VarIntLen(i uint64) protocol.ByteCount {
+	switch {
+	case i <= maxVarInt1:
+		return 1
+	case i <= maxVarInt2:
+		return 2
+	case i <= maxVarInt4:
+		return 4
+	case i <= maxVarInt8:
+		return 8
+	}
+	panic(fmt.Sprintf("%#x doesn't fit into 62 bits", i))
+}
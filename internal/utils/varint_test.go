@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"bytes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Varint encoding / decoding", func() {
+	Context("decoding", func() {
+		It("reads a 1 byte number", func() {
+			val, err := ReadVarInt(bytes.NewReader([]byte{0x25}))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(val).To(Equal(uint64(37)))
+		})
+
+		It("reads a 2 byte number", func() {
+			val, err := ReadVarInt(bytes.NewReader([]byte{0x7b, 0xbd}))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(val).To(Equal(uint64(15293)))
+		})
+
+		It("reads a 4 byte number", func() {
+			val, err := ReadVarInt(bytes.NewReader([]byte{0x9d, 0x7f, 0x3e, 0x7d}))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(val).To(Equal(uint64(494878333)))
+		})
+
+		It("reads an 8 byte number", func() {
+			val, err := ReadVarInt(bytes.NewReader([]byte{0xc2, 0x19, 0x7c, 0x5e, 0xff, 0x14, 0xe8, 0x8c}))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(val).To(Equal(uint64(151288809941952652)))
+		})
+
+		It("errors on EOF", func() {
+			_, err := ReadVarInt(bytes.NewReader([]byte{0x9d, 0x7f}))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("encoding", func() {
+		It("writes a 1 byte number", func() {
+			b := &bytes.Buffer{}
+			WriteVarInt(b, 37)
+			Expect(b.Bytes()).To(Equal([]byte{0x25}))
+		})
+
+		It("writes a 2 byte number", func() {
+			b := &bytes.Buffer{}
+			WriteVarInt(b, 15293)
+			Expect(b.Bytes()).To(Equal([]byte{0x7b, 0xbd}))
+		})
+
+		It("writes a 4 byte number", func() {
+			b := &bytes.Buffer{}
+			WriteVarInt(b, 494878333)
+			Expect(b.Bytes()).To(Equal([]byte{0x9d, 0x7f, 0x3e, 0x7d}))
+		})
+
+		It("writes an 8 byte number", func() {
+			b := &bytes.Buffer{}
+			WriteVarInt(b, 151288809941952652)
+			Expect(b.Bytes()).To(Equal([]byte{0xc2, 0x19, 0x7c, 0x5e, 0xff, 0x14, 0xe8, 0x8c}))
+		})
+
+		It("panics when given a too large number", func() {
+			Expect(func() { WriteVarInt(&bytes.Buffer{}, maxVarInt8+1) }).Should(Panic())
+		})
+
+		It("determines the length", func() {
+			Expect(VarIntLen(maxVarInt1)).To(BeEquivalentTo(1))
+			Expect(VarIntLen(maxVarInt1 + 1)).To(BeEquivalentTo(2))
+			Expect(VarIntLen(maxVarInt2 + 1)).To(BeEquivalentTo(4))
+			Expect(VarIntLen(maxVarInt4 + 1)).To(BeEquivalentTo(8))
+		})
+	})
+})
@@ -34,10 +34,14 @@ type AckFrame struct {
 	DelayTime          time.Duration
 
 	//czy:add meeting Deadline Information
-	NumMeetDeadline uint16
-	NumHasDeadline  uint16
-	CurNotSent      uint16
-	Alpha           uint16
+	CurNotSent uint16
+	// BatchLength is the echoed length of the batch CurNotSent is relative to
+	BatchLength uint16
+	Alpha       uint16
+	// DeadlineSamples report, for packets with a deadline received since the last ACK, how late they arrived
+	DeadlineSamples []DeadlineSample
+	// HasDeadlineInformation is set on received frames if the deadline extension was negotiated
+	HasDeadlineInformation bool
 }
 
 // ParseAckFrame reads an ACK frame
@@ -85,11 +89,22 @@ func ParseAckFrame(r *bytes.Reader, version protocol.VersionNumber) (*AckFrame,
 	}
 	frame.DelayTime = time.Duration(delay) * time.Microsecond
 
-	//czy:parse Deadline information in byte flow
-	frame.NumMeetDeadline, err = utils.GetByteOrder(version).ReadUint16(r)
-	frame.NumHasDeadline, err = utils.GetByteOrder(version).ReadUint16(r)
-	frame.CurNotSent, err = utils.GetByteOrder(version).ReadUint16(r)
-	frame.Alpha, err = utils.GetByteOrder(version).ReadUint16(r)
+	//czy:parse Deadline information in byte flow, if the deadline extension was negotiated
+	if version.UsesDeadlines() {
+		if frame.CurNotSent, err = utils.GetByteOrder(version).ReadUint16(r); err != nil {
+			return nil, err
+		}
+		if frame.BatchLength, err = utils.GetByteOrder(version).ReadUint16(r); err != nil {
+			return nil, err
+		}
+		if frame.Alpha, err = utils.GetByteOrder(version).ReadUint16(r); err != nil {
+			return nil, err
+		}
+		if frame.DeadlineSamples, err = parseDeadlineSamples(r, frame.LargestAcked, version); err != nil {
+			return nil, err
+		}
+		frame.HasDeadlineInformation = true
+	}
 
 	var numAckBlocks uint8
 	if hasMissingRanges {
@@ -254,11 +269,13 @@ func (f *AckFrame) Write(b *bytes.Buffer, version protocol.VersionNumber) error
 	f.DelayTime = time.Since(f.PacketReceivedTime)
 	utils.GetByteOrder(version).WriteUfloat16(b, uint64(f.DelayTime/time.Microsecond))
 
-	//czy: write Deadline information in byte flow
-	utils.GetByteOrder(version).WriteUint16(b, uint16(f.NumMeetDeadline))
-	utils.GetByteOrder(version).WriteUint16(b, uint16(f.NumHasDeadline))
-	utils.GetByteOrder(version).WriteUint16(b, uint16(f.CurNotSent))
-	utils.GetByteOrder(version).WriteUint16(b, uint16(f.Alpha))
+	//czy: write Deadline information in byte flow, if the deadline extension was negotiated
+	if version.UsesDeadlines() {
+		utils.GetByteOrder(version).WriteUint16(b, uint16(f.CurNotSent))
+		utils.GetByteOrder(version).WriteUint16(b, uint16(f.BatchLength))
+		utils.GetByteOrder(version).WriteUint16(b, uint16(f.Alpha))
+		writeDeadlineSamples(b, f.writableDeadlineSamples(), f.LargestAcked, version)
+	}
 
 	var numRanges uint64
 	var numRangesWritten uint64
@@ -366,8 +383,11 @@ func (f *AckFrame) Write(b *bytes.Buffer, version protocol.VersionNumber) error
 
 // MinLength of a written frame
 func (f *AckFrame) MinLength(version protocol.VersionNumber) (protocol.ByteCount, error) {
-	length := protocol.ByteCount(1 + 2 + 9 + 1) // 1 TypeByte, 2 ACK delay time, 2*2=4 two uint16 deadline information, 1 Num Timestamp
-	// Deadline Information maybe is 4 bytes, but 4 bytes will error, and every large 4, e.g 5,6 is no error
+	length := protocol.ByteCount(1 + 2 + 1) // 1 TypeByte, 2 ACK delay time, 1 Num Timestamp
+	if version.UsesDeadlines() {
+		// 3*2 bytes for CurNotSent, BatchLength and Alpha, 1 byte for the number of samples, and one byte of margin
+		length += 8 + deadlineSampleLength*protocol.ByteCount(len(f.writableDeadlineSamples()))
+	}
 	length += protocol.ByteCount(protocol.GetPacketNumberLength(f.LargestAcked))
 
 	missingSequenceNumberDeltaLen := protocol.ByteCount(f.getMissingSequenceNumberDeltaLen())
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"math"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
@@ -1198,6 +1199,101 @@ var _ = Describe("AckFrame", func() {
 			})
 		})
 
+		Context("deadline information", func() {
+			It("writes and parses the deadline information with the deadline extension", func() {
+				frameOrig := &AckFrame{
+					LargestAcked: 0x1337,
+					LowestAcked:  0x1300,
+					CurNotSent:   2,
+					BatchLength:  6,
+					Alpha:        12,
+					DeadlineSamples: []DeadlineSample{
+						{PacketNumber: 0x1337, Lateness: -3 * time.Millisecond},
+						{PacketNumber: 0x1310, Lateness: 1500 * time.Microsecond},
+					},
+				}
+				Expect(frameOrig.Write(b, protocol.VersionDeadline)).To(Succeed())
+				Expect(frameOrig.MinLength(protocol.VersionDeadline)).To(BeNumerically(">=", b.Len()))
+				r := bytes.NewReader(b.Bytes())
+				frame, err := ParseAckFrame(r, protocol.VersionDeadline)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frame.HasDeadlineInformation).To(BeTrue())
+				Expect(frame.DeadlineSamples).To(Equal(frameOrig.DeadlineSamples))
+				Expect(frame.DeadlineSamples[0].MetDeadline()).To(BeTrue())
+				Expect(frame.DeadlineSamples[1].MetDeadline()).To(BeFalse())
+				Expect(frame.CurNotSent).To(Equal(uint16(2)))
+				Expect(frame.BatchLength).To(Equal(uint16(6)))
+				Expect(frame.Alpha).To(Equal(uint16(12)))
+				Expect(r.Len()).To(BeZero())
+			})
+
+			It("writes at most MaxDeadlineSamples samples", func() {
+				frameOrig := &AckFrame{LargestAcked: 100, LowestAcked: 1}
+				for i := 1; i <= 100; i++ {
+					frameOrig.DeadlineSamples = append(frameOrig.DeadlineSamples, DeadlineSample{PacketNumber: protocol.PacketNumber(i)})
+				}
+				Expect(frameOrig.Write(b, protocol.VersionDeadline)).To(Succeed())
+				Expect(frameOrig.MinLength(protocol.VersionDeadline)).To(BeNumerically(">=", b.Len()))
+				frame, err := ParseAckFrame(bytes.NewReader(b.Bytes()), protocol.VersionDeadline)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frame.DeadlineSamples).To(Equal(frameOrig.DeadlineSamples[:MaxDeadlineSamples]))
+			})
+
+			It("drops samples too far below the LargestAcked", func() {
+				frameOrig := &AckFrame{
+					LargestAcked:    0x20000,
+					LowestAcked:     0x20000,
+					DeadlineSamples: []DeadlineSample{{PacketNumber: 1}, {PacketNumber: 0x1ffff}},
+				}
+				Expect(frameOrig.Write(b, protocol.VersionDeadline)).To(Succeed())
+				frame, err := ParseAckFrame(bytes.NewReader(b.Bytes()), protocol.VersionDeadline)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frame.DeadlineSamples).To(Equal([]DeadlineSample{{PacketNumber: 0x1ffff}}))
+			})
+
+			It("clamps the lateness", func() {
+				frameOrig := &AckFrame{
+					LargestAcked:    1,
+					LowestAcked:     1,
+					DeadlineSamples: []DeadlineSample{{PacketNumber: 1, Lateness: time.Hour}},
+				}
+				Expect(frameOrig.Write(b, protocol.VersionDeadline)).To(Succeed())
+				frame, err := ParseAckFrame(bytes.NewReader(b.Bytes()), protocol.VersionDeadline)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frame.DeadlineSamples[0].Lateness).To(Equal(time.Duration(math.MaxInt32) * time.Microsecond))
+			})
+
+			It("errors on samples above the LargestAcked", func() {
+				frameOrig := &AckFrame{
+					LargestAcked:    2,
+					LowestAcked:     2,
+					DeadlineSamples: []DeadlineSample{{PacketNumber: 2}},
+				}
+				Expect(frameOrig.Write(b, protocol.VersionDeadline)).To(Succeed())
+				data := b.Bytes()
+				// the delta of the sample follows the type byte, the LargestAcked, the ACK delay, CurNotSent, BatchLength, Alpha and the number of samples
+				data[1+1+2+6+1] = 0xff
+				_, err := ParseAckFrame(bytes.NewReader(data), protocol.VersionDeadline)
+				Expect(err).To(MatchError(ErrInvalidAckRanges))
+			})
+
+			It("doesn't write deadline information without the deadline extension", func() {
+				frameOrig := &AckFrame{
+					LargestAcked:    1,
+					LowestAcked:     1,
+					DeadlineSamples: []DeadlineSample{{PacketNumber: 1}},
+				}
+				Expect(frameOrig.Write(b, protocol.VersionMP)).To(Succeed())
+				Expect(frameOrig.MinLength(protocol.VersionMP)).To(Equal(protocol.ByteCount(b.Len())))
+				r := bytes.NewReader(b.Bytes())
+				frame, err := ParseAckFrame(r, protocol.VersionMP)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frame.HasDeadlineInformation).To(BeFalse())
+				Expect(frame.DeadlineSamples).To(BeEmpty())
+				Expect(r.Len()).To(BeZero())
+			})
+		})
+
 		Context("min length", func() {
 			It("has proper min length", func() {
 				f := &AckFrame{
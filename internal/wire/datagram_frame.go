@@ -0,0 +1,58 @@
+package wire
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/qerr"
+)
+
+// A DatagramFrame carries unreliable application data. It is never retransmitted.
+// It is only sent if the deadline extension was negotiated.
+type DatagramFrame struct {
+	Data []byte
+	// Deadline is the delivery deadline of the data. It is not sent in the frame, but in the public header of the packet.
+	Deadline time.Time
+}
+
+// Write writes a DATAGRAM frame
+func (f *DatagramFrame) Write(b *bytes.Buffer, version protocol.VersionNumber) error {
+	b.WriteByte(0x15)
+	utils.GetByteOrder(version).WriteUint16(b, uint16(len(f.Data)))
+	b.Write(f.Data)
+	return nil
+}
+
+// MinLength of a written frame
+func (f *DatagramFrame) MinLength(version protocol.VersionNumber) (protocol.ByteCount, error) {
+	return 1 + 2 + protocol.ByteCount(len(f.Data)), nil
+}
+
+// ParseDatagramFrame parses a DATAGRAM frame
+func ParseDatagramFrame(r *bytes.Reader, version protocol.VersionNumber) (*DatagramFrame, error) {
+	frame := &DatagramFrame{}
+
+	// read the TypeByte
+	if _, err := r.ReadByte(); err != nil {
+		return nil, err
+	}
+
+	dataLen, err := utils.GetByteOrder(version).ReadUint16(r)
+	if err != nil {
+		return nil, err
+	}
+	if protocol.ByteCount(dataLen) > protocol.MaxDatagramSize {
+		return nil, qerr.Error(qerr.InvalidFrameData, "datagram too large")
+	}
+	if int(dataLen) > r.Len() {
+		return nil, io.EOF
+	}
+	frame.Data = make([]byte, dataLen)
+	if _, err := io.ReadFull(r, frame.Data); err != nil {
+		return nil, err
+	}
+	return frame, nil
+}
@@ -0,0 +1,59 @@
+package wire
+
+import (
+	"bytes"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/qerr"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DatagramFrame", func() {
+	Context("when parsing", func() {
+		It("accepts sample frame", func() {
+			b := bytes.NewReader([]byte{0x15, 0x0, 0x3, 0xde, 0xca, 0xfb})
+			frame, err := ParseDatagramFrame(b, versionBigEndian)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame.Data).To(Equal([]byte{0xde, 0xca, 0xfb}))
+			Expect(b.Len()).To(BeZero())
+		})
+
+		It("accepts empty datagrams", func() {
+			frame, err := ParseDatagramFrame(bytes.NewReader([]byte{0x15, 0x0, 0x0}), versionBigEndian)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame.Data).To(BeEmpty())
+		})
+
+		It("errors on EOFs", func() {
+			data := []byte{0x15, 0x0, 0x2, 0x13, 0x37}
+			_, err := ParseDatagramFrame(bytes.NewReader(data), versionBigEndian)
+			Expect(err).NotTo(HaveOccurred())
+			for i := range data {
+				_, err := ParseDatagramFrame(bytes.NewReader(data[0:i]), versionBigEndian)
+				Expect(err).To(HaveOccurred())
+			}
+		})
+
+		It("rejects too large datagrams", func() {
+			_, err := ParseDatagramFrame(bytes.NewReader([]byte{0x15, 0xff, 0xff}), versionBigEndian)
+			Expect(err).To(MatchError(qerr.Error(qerr.InvalidFrameData, "datagram too large")))
+		})
+	})
+
+	Context("when writing", func() {
+		It("writes a sample frame", func() {
+			frame := DatagramFrame{Data: []byte{0x13, 0x37}}
+			b := &bytes.Buffer{}
+			Expect(frame.Write(b, versionBigEndian)).To(Succeed())
+			Expect(b.Bytes()).To(Equal([]byte{0x15, 0x0, 0x2, 0x13, 0x37}))
+		})
+
+		It("has the correct min length", func() {
+			frame := DatagramFrame{Data: make([]byte, 100)}
+			b := &bytes.Buffer{}
+			Expect(frame.Write(b, versionBigEndian)).To(Succeed())
+			Expect(frame.MinLength(versionBigEndian)).To(Equal(protocol.ByteCount(b.Len())))
+		})
+	})
+})
@@ -0,0 +1,88 @@
+package wire
+
+import (
+	"bytes"
+	"math"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+// MaxDeadlineSamples is the maximum number of DeadlineSamples written in one ACK frame
+const MaxDeadlineSamples = 32
+
+// a sample is written as a 2 byte delta to the LargestAcked and the lateness in microseconds in 4 bytes
+const deadlineSampleLength = 2 + 4
+
+// A DeadlineSample reports how late a packet with a deadline arrived.
+// It is the deadline extension's counterpart of an ACK receive timestamp.
+type DeadlineSample struct {
+	PacketNumber protocol.PacketNumber
+	// Lateness is the receive time minus the deadline. It is negative if the packet arrived in time.
+	Lateness time.Duration
+}
+
+// MetDeadline says if the packet arrived before its deadline
+func (s DeadlineSample) MetDeadline() bool {
+	return s.Lateness < 0
+}
+
+// writableDeadlineSamples returns the samples that fit into the frame.
+// Samples for packets too far below the LargestAcked are dropped.
+func (f *AckFrame) writableDeadlineSamples() []DeadlineSample {
+	samples := make([]DeadlineSample, 0, utils.Min(len(f.DeadlineSamples), MaxDeadlineSamples))
+	for _, s := range f.DeadlineSamples {
+		if len(samples) == MaxDeadlineSamples {
+			break
+		}
+		if s.PacketNumber > f.LargestAcked || f.LargestAcked-s.PacketNumber > math.MaxUint16 {
+			continue
+		}
+		samples = append(samples, s)
+	}
+	return samples
+}
+
+func writeDeadlineSamples(b *bytes.Buffer, samples []DeadlineSample, largestAcked protocol.PacketNumber, version protocol.VersionNumber) {
+	b.WriteByte(uint8(len(samples)))
+	for _, s := range samples {
+		utils.GetByteOrder(version).WriteUint16(b, uint16(largestAcked-s.PacketNumber))
+		lateness := s.Lateness / time.Microsecond
+		if lateness > math.MaxInt32 {
+			lateness = math.MaxInt32
+		} else if lateness < math.MinInt32 {
+			lateness = math.MinInt32
+		}
+		utils.GetByteOrder(version).WriteUint32(b, uint32(int32(lateness)))
+	}
+}
+
+func parseDeadlineSamples(r *bytes.Reader, largestAcked protocol.PacketNumber, version protocol.VersionNumber) ([]DeadlineSample, error) {
+	numSamples, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if numSamples == 0 {
+		return nil, nil
+	}
+	samples := make([]DeadlineSample, numSamples)
+	for i := range samples {
+		delta, err := utils.GetByteOrder(version).ReadUint16(r)
+		if err != nil {
+			return nil, err
+		}
+		if protocol.PacketNumber(delta) > largestAcked {
+			return nil, ErrInvalidAckRanges
+		}
+		lateness, err := utils.GetByteOrder(version).ReadUint32(r)
+		if err != nil {
+			return nil, err
+		}
+		samples[i] = DeadlineSample{
+			PacketNumber: largestAcked - protocol.PacketNumber(delta),
+			Lateness:     time.Duration(int32(lateness)) * time.Microsecond,
+		}
+	}
+	return samples, nil
+}
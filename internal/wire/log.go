@@ -26,6 +26,12 @@ func LogFrame(frame Frame, sent bool) {
 		utils.Debugf("\t%s &wire.AddAddressFrame{IPVersion: %d, Addr: %s}", dir, f.IPVersion, f.Addr.String())
 	case *ClosePathFrame:
 		utils.Debugf("\t%s &wire.ClosePathFrame{PathID: 0x%x, LargestAcked: 0x%x, LowestAcked: 0x%x, AckRanges: %#v}", dir, f.PathID, f.LargestAcked, f.LowestAcked, f.AckRanges)
+	case *PathPriorityFrame:
+		utils.Debugf("\t%s &wire.PathPriorityFrame{PathID: 0x%x, Priority: %s}", dir, f.PathID, f.Priority)
+	case *StreamGapFrame:
+		utils.Debugf("\t%s &wire.StreamGapFrame{StreamID: %d, Offset: 0x%x, Byte length: 0x%x}", dir, f.StreamID, f.Offset, f.ByteLen)
+	case *DatagramFrame:
+		utils.Debugf("\t%s &wire.DatagramFrame{Data length: 0x%x}", dir, len(f.Data))
 	default:
 		utils.Debugf("\t%s %#v", dir, frame)
 	}
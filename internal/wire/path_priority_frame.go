@@ -0,0 +1,53 @@
+package wire
+
+import (
+	"bytes"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/qerr"
+)
+
+// A PathPriorityFrame tells the peer how to use a path, like the MP_PRIO option of MPTCP
+type PathPriorityFrame struct {
+	PathID   protocol.PathID
+	Priority protocol.PathPriority
+}
+
+// Write writes a PATH_PRIORITY frame
+func (f *PathPriorityFrame) Write(b *bytes.Buffer, version protocol.VersionNumber) error {
+	b.WriteByte(0x14)
+	b.WriteByte(uint8(f.PathID))
+	b.WriteByte(uint8(f.Priority))
+	return nil
+}
+
+// MinLength of a written frame
+func (f *PathPriorityFrame) MinLength(version protocol.VersionNumber) (protocol.ByteCount, error) {
+	return 1 + 1 + 1, nil
+}
+
+// ParsePathPriorityFrame parses a PATH_PRIORITY frame
+func ParsePathPriorityFrame(r *bytes.Reader, version protocol.VersionNumber) (*PathPriorityFrame, error) {
+	frame := &PathPriorityFrame{}
+
+	// read the TypeByte
+	if _, err := r.ReadByte(); err != nil {
+		return nil, err
+	}
+
+	pathID, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	frame.PathID = protocol.PathID(pathID)
+
+	priority, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	frame.Priority = protocol.PathPriority(priority)
+	if frame.Priority > protocol.PathPriorityDisabled {
+		return nil, qerr.Error(qerr.InvalidFrameData, "unknown path priority")
+	}
+	return frame, nil
+}
@@ -0,0 +1,54 @@
+package wire
+
+import (
+	"bytes"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/qerr"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PathPriorityFrame", func() {
+	Context("when parsing", func() {
+		It("accepts sample frame", func() {
+			b := bytes.NewReader([]byte{0x14, 0x3, 0x1})
+			frame, err := ParsePathPriorityFrame(b, versionBigEndian)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame.PathID).To(Equal(protocol.PathID(3)))
+			Expect(frame.Priority).To(Equal(protocol.PathPriorityBackup))
+			Expect(b.Len()).To(BeZero())
+		})
+
+		It("errors on EOFs", func() {
+			data := []byte{0x14, 0x3, 0x2}
+			_, err := ParsePathPriorityFrame(bytes.NewReader(data), versionBigEndian)
+			Expect(err).NotTo(HaveOccurred())
+			for i := range data {
+				_, err := ParsePathPriorityFrame(bytes.NewReader(data[0:i]), versionBigEndian)
+				Expect(err).To(HaveOccurred())
+			}
+		})
+
+		It("rejects unknown priorities", func() {
+			_, err := ParsePathPriorityFrame(bytes.NewReader([]byte{0x14, 0x3, 0x3}), versionBigEndian)
+			Expect(err).To(MatchError(qerr.Error(qerr.InvalidFrameData, "unknown path priority")))
+		})
+	})
+
+	Context("when writing", func() {
+		It("writes a sample frame", func() {
+			frame := PathPriorityFrame{PathID: 5, Priority: protocol.PathPriorityDisabled}
+			b := &bytes.Buffer{}
+			Expect(frame.Write(b, versionBigEndian)).To(Succeed())
+			Expect(b.Bytes()).To(Equal([]byte{0x14, 0x5, 0x2}))
+		})
+
+		It("has the correct min length", func() {
+			frame := PathPriorityFrame{PathID: 5}
+			b := &bytes.Buffer{}
+			Expect(frame.Write(b, versionBigEndian)).To(Succeed())
+			Expect(frame.MinLength(versionBigEndian)).To(Equal(protocol.ByteCount(b.Len())))
+		})
+	})
+})
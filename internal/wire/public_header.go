@@ -36,9 +36,47 @@ type PublicHeader struct {
 	SupportedVersions    []protocol.VersionNumber // VersionNumbers sent by the server
 	DiversificationNonce []byte
 	//czy
-	Deadline   time.Time
+	// DeadlineTTL is the time left until the earliest deadline of the packet when it is sent, 0 if it has none.
+	// A packet that already missed its deadline is sent with the shortest TTL.
+	// It is relative to the send time, so that the peers don't need synchronised clocks.
+	DeadlineTTL time.Duration
+	// Timestamp is the send time of the packet, relative to an epoch chosen by the sender.
+	// It allows the receiver to estimate the clock offset to the sender.
+	Timestamp  time.Duration
 	CurNotSent uint8
-	Alpha      uint8
+	// BatchLength is the number of packets of the batch the packet was scheduled in, CurNotSent of them were not sent.
+	// It is 0 for packets that were not scheduled in a batch.
+	BatchLength uint8
+	Alpha       uint8
+}
+
+// MaxDeadlineTTL is the largest DeadlineTTL that can be encoded, longer TTLs are truncated
+const MaxDeadlineTTL = utils.MaxVarInt4 * time.Microsecond
+
+// MaxDeadlineTTLLength is the length of the encoding of MaxDeadlineTTL
+const MaxDeadlineTTLLength = protocol.ByteCount(4)
+
+// encodeDuration converts a duration to microseconds for a varint
+func encodeDuration(d time.Duration) uint64 {
+	if d <= 0 {
+		return 0
+	}
+	return uint64(d / time.Microsecond)
+}
+
+func (h *PublicHeader) encodedDeadlineTTL() uint64 {
+	if h.DeadlineTTL == 0 {
+		return 0
+	}
+	ttl := h.DeadlineTTL
+	if ttl > MaxDeadlineTTL {
+		ttl = MaxDeadlineTTL
+	}
+	// a packet that already missed its deadline still has one
+	if ttl < time.Microsecond {
+		ttl = time.Microsecond
+	}
+	return encodeDuration(ttl)
 }
 
 // Write writes a public header. Warning: This API should not be considered stable and will change soon.
@@ -121,14 +159,16 @@ func (h *PublicHeader) Write(b *bytes.Buffer, version protocol.VersionNumber, pe
 		return errors.New("PublicHeader: PacketNumberLen not set")
 	}
 
-	deadlineTimeBytes, err := h.Deadline.MarshalBinary()
-	if err != nil {
+	// the deadline extension is only used if it was negotiated
+	if !version.UsesDeadlines() {
 		return nil
 	}
-	_, err = b.Write(deadlineTimeBytes)
+	utils.WriteVarInt(b, h.encodedDeadlineTTL())
+	utils.WriteVarInt(b, encodeDuration(h.Timestamp))
 
 	// write curNotSent uint16
 	b.WriteByte(h.CurNotSent)
+	b.WriteByte(h.BatchLength)
 	b.WriteByte(h.Alpha)
 
 	return nil
@@ -274,28 +314,46 @@ func ParsePublicHeader(b *bytes.Reader, packetSentBy protocol.Perspective, versi
 	}
 
 	// Packet number
-	if header.hasPacketNumber(packetSentBy) {
-		packetNumber, err := utils.GetByteOrder(version).ReadUintN(b, uint8(header.PacketNumberLen))
-		if err != nil {
-			return nil, err
-		}
-		header.PacketNumber = protocol.PacketNumber(packetNumber)
+	if !header.hasPacketNumber(packetSentBy) {
+		return header, nil
 	}
-	// parse deadline
-	//And deadline length maybe always 15 bytes
-	deadlineBytesToRead := make([]byte, 15)
-	_, err = b.Read(deadlineBytesToRead)
-	header.Deadline.UnmarshalBinary(deadlineBytesToRead)
+	packetNumber, err := utils.GetByteOrder(version).ReadUintN(b, uint8(header.PacketNumberLen))
+	if err != nil {
+		return nil, err
+	}
+	header.PacketNumber = protocol.PacketNumber(packetNumber)
+
+	if !version.UsesDeadlines() {
+		return header, nil
+	}
+	// parse deadline TTL and timestamp
+	deadlineTTL, err := utils.ReadVarInt(b)
+	if err != nil {
+		return nil, err
+	}
+	header.DeadlineTTL = time.Duration(deadlineTTL) * time.Microsecond
+	timestamp, err := utils.ReadVarInt(b)
+	if err != nil {
+		return nil, err
+	}
+	header.Timestamp = time.Duration(timestamp) * time.Microsecond
 
-	// parse curNotSent and Alpha
-	header.CurNotSent, err = b.ReadByte()
-	header.Alpha, err = b.ReadByte()
+	// parse curNotSent, BatchLength and Alpha
+	if header.CurNotSent, err = b.ReadByte(); err != nil {
+		return nil, err
+	}
+	if header.BatchLength, err = b.ReadByte(); err != nil {
+		return nil, err
+	}
+	if header.Alpha, err = b.ReadByte(); err != nil {
+		return nil, err
+	}
 	return header, nil
 }
 
 // GetLength gets the length of the publicHeader in bytes.
 // It can only be called for regular packets.
-func (h *PublicHeader) GetLength(pers protocol.Perspective) (protocol.ByteCount, error) {
+func (h *PublicHeader) GetLength(pers protocol.Perspective, version protocol.VersionNumber) (protocol.ByteCount, error) {
 	if h.VersionFlag && h.ResetFlag {
 		return 0, errResetAndVersionFlagSet
 	}
@@ -328,9 +386,13 @@ func (h *PublicHeader) GetLength(pers protocol.Perspective) (protocol.ByteCount,
 	if h.MultipathFlag {
 		length += 1
 	}
-	length += 15 //Default:15 for deadline
-	length += 1  // One byte for uint8 curNotSent
-	length += 1  // One byte for uint8 alpha
+	if h.hasPacketNumber(pers) && version.UsesDeadlines() {
+		length += utils.VarIntLen(h.encodedDeadlineTTL())
+		length += utils.VarIntLen(encodeDuration(h.Timestamp))
+		length += 1 // One byte for uint8 curNotSent
+		length += 1 // One byte for uint8 BatchLength
+		length += 1 // One byte for uint8 alpha
+	}
 
 	return length, nil
 }
@@ -3,6 +3,8 @@ package wire
 import (
 	"bytes"
 	"encoding/binary"
+	"io"
+	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
@@ -415,7 +417,7 @@ var _ = Describe("Public Header", func() {
 		Context("GetLength", func() {
 			It("errors when calling GetLength for Version Negotiation packets", func() {
 				hdr := PublicHeader{VersionFlag: true}
-				_, err := hdr.GetLength(protocol.PerspectiveServer)
+				_, err := hdr.GetLength(protocol.PerspectiveServer, protocol.VersionWhatever)
 				Expect(err).To(MatchError(errGetLengthNotForVersionNegotiation))
 			})
 
@@ -424,7 +426,7 @@ var _ = Describe("Public Header", func() {
 					ResetFlag:   true,
 					VersionFlag: true,
 				}
-				_, err := hdr.GetLength(protocol.PerspectiveServer)
+				_, err := hdr.GetLength(protocol.PerspectiveServer, protocol.VersionWhatever)
 				Expect(err).To(MatchError(errResetAndVersionFlagSet))
 			})
 
@@ -433,7 +435,7 @@ var _ = Describe("Public Header", func() {
 					ConnectionID: 0x4cfa9f9b668619f6,
 					PacketNumber: 0xDECAFBAD,
 				}
-				_, err := hdr.GetLength(protocol.PerspectiveServer)
+				_, err := hdr.GetLength(protocol.PerspectiveServer, protocol.VersionWhatever)
 				Expect(err).To(MatchError(errPacketNumberLenNotSet))
 			})
 
@@ -443,7 +445,7 @@ var _ = Describe("Public Header", func() {
 					PacketNumber:    0xDECAFBAD,
 					PacketNumberLen: protocol.PacketNumberLen6,
 				}
-				length, err := hdr.GetLength(protocol.PerspectiveServer)
+				length, err := hdr.GetLength(protocol.PerspectiveServer, protocol.VersionWhatever)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(length).To(Equal(protocol.ByteCount(1 + 8 + 6))) // 1 byte public flag, 8 bytes connectionID, and packet number
 			})
@@ -457,7 +459,7 @@ var _ = Describe("Public Header", func() {
 					VersionFlag:          true,
 					VersionNumber:        versionLittleEndian,
 				}
-				length, err := hdr.GetLength(protocol.PerspectiveClient)
+				length, err := hdr.GetLength(protocol.PerspectiveClient, protocol.VersionWhatever)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(length).To(Equal(protocol.ByteCount(1 + 4 + 6))) // 1 byte public flag, 4 version number, and packet number
 			})
@@ -469,7 +471,7 @@ var _ = Describe("Public Header", func() {
 					PacketNumber:         0xDECAFBAD,
 					PacketNumberLen:      protocol.PacketNumberLen6,
 				}
-				length, err := hdr.GetLength(protocol.PerspectiveServer)
+				length, err := hdr.GetLength(protocol.PerspectiveServer, protocol.VersionWhatever)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(length).To(Equal(protocol.ByteCount(1 + 6))) // 1 byte public flag, and packet number
 			})
@@ -480,7 +482,7 @@ var _ = Describe("Public Header", func() {
 					PacketNumber:    0xDECAFBAD,
 					PacketNumberLen: protocol.PacketNumberLen2,
 				}
-				length, err := hdr.GetLength(protocol.PerspectiveServer)
+				length, err := hdr.GetLength(protocol.PerspectiveServer, protocol.VersionWhatever)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(length).To(Equal(protocol.ByteCount(1 + 8 + 2))) // 1 byte public flag, 8 byte connectionID, and packet number
 			})
@@ -490,7 +492,7 @@ var _ = Describe("Public Header", func() {
 					DiversificationNonce: []byte("foo"),
 					PacketNumberLen:      protocol.PacketNumberLen1,
 				}
-				length, err := hdr.GetLength(protocol.PerspectiveServer)
+				length, err := hdr.GetLength(protocol.PerspectiveServer, protocol.VersionWhatever)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(length).To(Equal(protocol.ByteCount(1 + 8 + 3 + 1))) // 1 byte public flag, 8 byte connectionID, 3 byte DiversificationNonce, 1 byte PacketNumber
 			})
@@ -500,7 +502,7 @@ var _ = Describe("Public Header", func() {
 					ResetFlag:    true,
 					ConnectionID: 0x4cfa9f9b668619f6,
 				}
-				length, err := hdr.GetLength(protocol.PerspectiveServer)
+				length, err := hdr.GetLength(protocol.PerspectiveServer, protocol.VersionWhatever)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(length).To(Equal(protocol.ByteCount(1 + 8))) // 1 byte public flag, 8 byte connectionID
 			})
@@ -630,4 +632,86 @@ var _ = Describe("Public Header", func() {
 			})
 		})
 	})
+
+	Context("deadlines", func() {
+		version := protocol.VersionDeadline
+
+		It("writes and parses the deadline TTL and the timestamp", func() {
+			b := &bytes.Buffer{}
+			hdr := PublicHeader{
+				ConnectionID:    0x4cfa9f9b668619f6,
+				PacketNumber:    2,
+				PacketNumberLen: protocol.PacketNumberLen1,
+				DeadlineTTL:     50 * time.Millisecond,
+				Timestamp:       3 * time.Second,
+				CurNotSent:      7,
+				BatchLength:     9,
+				Alpha:           10,
+			}
+			err := hdr.Write(b, version, protocol.PerspectiveServer)
+			Expect(err).ToNot(HaveOccurred())
+			length, err := hdr.GetLength(protocol.PerspectiveServer, version)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b.Len()).To(BeEquivalentTo(length))
+			// 4 bytes for 50000us and for 3000000us
+			Expect(length).To(Equal(protocol.ByteCount(1 + 8 + 1 + 4 + 4 + 3)))
+			parsed, err := ParsePublicHeader(bytes.NewReader(b.Bytes()), protocol.PerspectiveServer, version)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(parsed.DeadlineTTL).To(Equal(50 * time.Millisecond))
+			Expect(parsed.Timestamp).To(Equal(3 * time.Second))
+			Expect(parsed.CurNotSent).To(Equal(uint8(7)))
+			Expect(parsed.BatchLength).To(Equal(uint8(9)))
+			Expect(parsed.Alpha).To(Equal(uint8(10)))
+		})
+
+		It("truncates long TTLs", func() {
+			hdr := PublicHeader{ConnectionID: 1, PacketNumberLen: protocol.PacketNumberLen1, DeadlineTTL: time.Hour}
+			b := &bytes.Buffer{}
+			Expect(hdr.Write(b, version, protocol.PerspectiveServer)).To(Succeed())
+			parsed, err := ParsePublicHeader(bytes.NewReader(b.Bytes()), protocol.PerspectiveServer, version)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(parsed.DeadlineTTL).To(Equal(MaxDeadlineTTL))
+		})
+
+		It("keeps the deadline of packets that already missed it", func() {
+			hdr := PublicHeader{ConnectionID: 1, PacketNumberLen: protocol.PacketNumberLen1, DeadlineTTL: -time.Millisecond}
+			b := &bytes.Buffer{}
+			Expect(hdr.Write(b, version, protocol.PerspectiveServer)).To(Succeed())
+			parsed, err := ParsePublicHeader(bytes.NewReader(b.Bytes()), protocol.PerspectiveServer, version)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(parsed.DeadlineTTL).To(Equal(time.Microsecond))
+		})
+
+		It("errors if the trailer is missing", func() {
+			b := bytes.NewReader([]byte{0x08, 0xf6, 0x19, 0x86, 0x66, 0x9b, 0x9f, 0xfa, 0x4c, 0xde, 0x0})
+			_, err := ParsePublicHeader(b, protocol.PerspectiveClient, version)
+			Expect(err).To(MatchError(io.EOF))
+		})
+
+		It("only writes deadline information with the deadline extension", func() {
+			hdr := PublicHeader{
+				ConnectionID:    0x4cfa9f9b668619f6,
+				PacketNumber:    2,
+				PacketNumberLen: protocol.PacketNumberLen1,
+				DeadlineTTL:     50 * time.Millisecond,
+				Timestamp:       3 * time.Second,
+			}
+			b := &bytes.Buffer{}
+			Expect(hdr.Write(b, protocol.VersionMP, protocol.PerspectiveServer)).To(Succeed())
+			Expect(b.Bytes()).To(Equal([]byte{0x08, 0xf6, 0x19, 0x86, 0x66, 0x9b, 0x9f, 0xfa, 0x4c, 0x2}))
+			length, err := hdr.GetLength(protocol.PerspectiveServer, protocol.VersionMP)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(length).To(Equal(protocol.ByteCount(1 + 8 + 1)))
+			parsed, err := ParsePublicHeader(bytes.NewReader(b.Bytes()), protocol.PerspectiveServer, protocol.VersionMP)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(parsed.DeadlineTTL).To(BeZero())
+		})
+
+		It("doesn't read a trailer for public resets", func() {
+			b := bytes.NewReader([]byte{0xa, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x42})
+			_, err := ParsePublicHeader(b, protocol.PerspectiveServer, protocol.VersionUnknown)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b.Len()).To(Equal(1))
+		})
+	})
 })
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"io"
+	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
@@ -17,6 +18,14 @@ type StreamFrame struct {
 	DataLenPresent bool
 	Offset         protocol.ByteCount
 	Data           []byte
+	// Deadline is the time by which the data should be delivered to the peer.
+	// It is only used locally by the scheduler and is never serialized.
+	Deadline time.Time
+	// Gap is set on frames that stand in for data the peer abandoned, see StreamGapFrame.
+	// It is only used locally and is never serialized.
+	Gap bool
+	// GapLen is the length of the abandoned data of a Gap frame, which carries no Data
+	GapLen protocol.ByteCount
 }
 
 var (
@@ -198,5 +207,8 @@ func (f *StreamFrame) MinLength(protocol.VersionNumber) (protocol.ByteCount, err
 
 // DataLen gives the length of data in bytes
 func (f *StreamFrame) DataLen() protocol.ByteCount {
+	if f.Gap {
+		return f.GapLen
+	}
 	return protocol.ByteCount(len(f.Data))
 }
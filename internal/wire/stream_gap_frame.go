@@ -0,0 +1,63 @@
+package wire
+
+import (
+	"bytes"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/qerr"
+)
+
+// A StreamGapFrame tells the peer that stream data won't be retransmitted, because its deadline expired.
+// It is only sent if the deadline extension was negotiated.
+type StreamGapFrame struct {
+	StreamID protocol.StreamID
+	Offset   protocol.ByteCount
+	ByteLen  protocol.ByteCount
+}
+
+// Write writes a STREAM_GAP frame
+func (f *StreamGapFrame) Write(b *bytes.Buffer, version protocol.VersionNumber) error {
+	b.WriteByte(0x13)
+	utils.GetByteOrder(version).WriteUint32(b, uint32(f.StreamID))
+	utils.GetByteOrder(version).WriteUint64(b, uint64(f.Offset))
+	utils.GetByteOrder(version).WriteUint64(b, uint64(f.ByteLen))
+	return nil
+}
+
+// MinLength of a written frame
+func (f *StreamGapFrame) MinLength(version protocol.VersionNumber) (protocol.ByteCount, error) {
+	return 1 + 4 + 8 + 8, nil
+}
+
+// ParseStreamGapFrame parses a STREAM_GAP frame
+func ParseStreamGapFrame(r *bytes.Reader, version protocol.VersionNumber) (*StreamGapFrame, error) {
+	frame := &StreamGapFrame{}
+
+	// read the TypeByte
+	if _, err := r.ReadByte(); err != nil {
+		return nil, err
+	}
+
+	sid, err := utils.GetByteOrder(version).ReadUint32(r)
+	if err != nil {
+		return nil, err
+	}
+	frame.StreamID = protocol.StreamID(sid)
+
+	offset, err := utils.GetByteOrder(version).ReadUint64(r)
+	if err != nil {
+		return nil, err
+	}
+	frame.Offset = protocol.ByteCount(offset)
+
+	byteLen, err := utils.GetByteOrder(version).ReadUint64(r)
+	if err != nil {
+		return nil, err
+	}
+	frame.ByteLen = protocol.ByteCount(byteLen)
+	if frame.Offset+frame.ByteLen < frame.Offset {
+		return nil, qerr.Error(qerr.InvalidStreamData, "gap overflows maximum offset")
+	}
+	return frame, nil
+}
@@ -0,0 +1,76 @@
+package wire
+
+import (
+	"bytes"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/qerr"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("StreamGapFrame", func() {
+	Context("when parsing", func() {
+		It("accepts sample frame", func() {
+			b := bytes.NewReader([]byte{0x13,
+				0xde, 0xad, 0xbe, 0xef, // stream id
+				0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, // offset
+				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x5, 0x39, // byte length
+			})
+			frame, err := ParseStreamGapFrame(b, versionBigEndian)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame.StreamID).To(Equal(protocol.StreamID(0xdeadbeef)))
+			Expect(frame.Offset).To(Equal(protocol.ByteCount(0x1122334455667788)))
+			Expect(frame.ByteLen).To(Equal(protocol.ByteCount(1337)))
+			Expect(b.Len()).To(BeZero())
+		})
+
+		It("errors on EOFs", func() {
+			data := []byte{0x13,
+				0xde, 0xad, 0xbe, 0xef, // stream id
+				0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, // offset
+				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x5, 0x39, // byte length
+			}
+			_, err := ParseStreamGapFrame(bytes.NewReader(data), versionBigEndian)
+			Expect(err).NotTo(HaveOccurred())
+			for i := range data {
+				_, err := ParseStreamGapFrame(bytes.NewReader(data[0:i]), versionBigEndian)
+				Expect(err).To(HaveOccurred())
+			}
+		})
+
+		It("rejects gaps that overflow the maximum offset", func() {
+			data := []byte{0x13,
+				0xde, 0xad, 0xbe, 0xef, // stream id
+				0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xf0, // offset
+				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x20, // byte length
+			}
+			_, err := ParseStreamGapFrame(bytes.NewReader(data), versionBigEndian)
+			Expect(err).To(MatchError(qerr.Error(qerr.InvalidStreamData, "gap overflows maximum offset")))
+		})
+	})
+
+	Context("when writing", func() {
+		It("writes a sample frame", func() {
+			frame := StreamGapFrame{
+				StreamID: 0xdeadbeef,
+				Offset:   0x1122334455667788,
+				ByteLen:  1337,
+			}
+			b := &bytes.Buffer{}
+			Expect(frame.Write(b, versionBigEndian)).To(Succeed())
+			Expect(b.Bytes()).To(Equal([]byte{0x13,
+				0xde, 0xad, 0xbe, 0xef, // stream id
+				0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, // offset
+				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x5, 0x39, // byte length
+			}))
+		})
+
+		It("has the correct min length", func() {
+			frame := StreamGapFrame{StreamID: 5, Offset: 100, ByteLen: 10}
+			b := &bytes.Buffer{}
+			Expect(frame.Write(b, versionBigEndian)).To(Succeed())
+			Expect(frame.MinLength(versionBigEndian)).To(Equal(protocol.ByteCount(b.Len())))
+		})
+	})
+})
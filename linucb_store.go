@@ -0,0 +1,305 @@
+package quic
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// linUCBStateVersion is the version of the serialized LinUCBState.
+// Version 0 is the legacy format: the matrices as one number per line, without a header.
+const linUCBStateVersion = 1
+
+// ErrLinUCBStateNotFound is returned by a LinUCBStore if no state was saved yet
+var ErrLinUCBStateNotFound = errors.New("linUCB state not found")
+
+// LinUCBState holds the linUCB matrices of the first (fast) and second (slow) path
+type LinUCBState struct {
+	MAaF [banditDimension][banditDimension]float64
+	MAaS [banditDimension][banditDimension]float64
+	MbaF [banditDimension]float64
+	MbaS [banditDimension]float64
+}
+
+// newLinUCBState returns the state of a bandit that didn't learn anything yet
+func newLinUCBState() *LinUCBState {
+	l := &LinUCBState{}
+	for i := 0; i < banditDimension; i++ {
+		l.MAaF[i][i] = 1
+		l.MAaS[i][i] = 1
+	}
+	return l
+}
+
+// A LinUCBStore persists the linUCB state of the lowband and peek schedulers between connections.
+// It may be shared by multiple sessions, so it must be safe for concurrent use.
+type LinUCBStore interface {
+	// Load returns the saved state, or ErrLinUCBStateNotFound if there is none
+	Load() (*LinUCBState, error)
+	// Save replaces the saved state
+	Save(*LinUCBState) error
+}
+
+// defaultLinUCBStore is used if the Config doesn't set a LinUCBStore.
+// It keeps the state in memory, so sessions of the same process learn from each other.
+var defaultLinUCBStore = NewMemoryLinUCBStore()
+
+type memoryLinUCBStore struct {
+	mutex sync.Mutex
+	state *LinUCBState
+}
+
+// NewMemoryLinUCBStore creates a LinUCBStore that keeps the state in memory
+func NewMemoryLinUCBStore() LinUCBStore {
+	return &memoryLinUCBStore{}
+}
+
+func (m *memoryLinUCBStore) Load() (*LinUCBState, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.state == nil {
+		return nil, ErrLinUCBStateNotFound
+	}
+	state := *m.state
+	return &state, nil
+}
+
+func (m *memoryLinUCBStore) Save(state *LinUCBState) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	s := *state
+	m.state = &s
+	return nil
+}
+
+type fileLinUCBStore struct {
+	mutex sync.Mutex
+	path  string
+}
+
+// NewFileLinUCBStore creates a LinUCBStore that keeps the state in a single file.
+// The file is replaced atomically on every save. Files in the legacy format are read as well.
+func NewFileLinUCBStore(path string) LinUCBStore {
+	return &fileLinUCBStore{path: path}
+}
+
+func (f *fileLinUCBStore) Load() (*LinUCBState, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return readLinUCBStateFile(f.path)
+}
+
+func (f *fileLinUCBStore) Save(state *LinUCBState) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return writeLinUCBStateFile(f.path, state)
+}
+
+const (
+	linUCBDirPrefix = "linucb-"
+	linUCBDirSuffix = ".json"
+)
+
+type dirLinUCBStore struct {
+	mutex sync.Mutex
+	dir   string
+	keep  int
+}
+
+// NewDirLinUCBStore creates a LinUCBStore that saves every state as a new numbered file in dir.
+// The latest state that can be read is loaded. Only the last keep files are kept, all of them if keep is 0.
+func NewDirLinUCBStore(dir string, keep int) LinUCBStore {
+	return &dirLinUCBStore{dir: dir, keep: keep}
+}
+
+// revisions returns the revisions found in the directory, in ascending order
+func (d *dirLinUCBStore) revisions() ([]uint64, error) {
+	files, err := ioutil.ReadDir(d.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var revs []uint64
+	for _, fi := range files {
+		name := fi.Name()
+		if fi.IsDir() || !strings.HasPrefix(name, linUCBDirPrefix) || !strings.HasSuffix(name, linUCBDirSuffix) {
+			continue
+		}
+		rev, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, linUCBDirPrefix), linUCBDirSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		revs = append(revs, rev)
+	}
+	sort.Slice(revs, func(i, j int) bool { return revs[i] < revs[j] })
+	return revs, nil
+}
+
+func (d *dirLinUCBStore) revisionPath(rev uint64) string {
+	return filepath.Join(d.dir, fmt.Sprintf("%s%08d%s", linUCBDirPrefix, rev, linUCBDirSuffix))
+}
+
+func (d *dirLinUCBStore) Load() (*LinUCBState, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	revs, err := d.revisions()
+	if err != nil {
+		return nil, err
+	}
+	// fall back to older revisions if the latest one can't be read
+	err = ErrLinUCBStateNotFound
+	for i := len(revs) - 1; i >= 0; i-- {
+		var state *LinUCBState
+		state, err = readLinUCBStateFile(d.revisionPath(revs[i]))
+		if err == nil {
+			return state, nil
+		}
+	}
+	return nil, err
+}
+
+func (d *dirLinUCBStore) Save(state *LinUCBState) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if err := os.MkdirAll(d.dir, 0755); err != nil {
+		return err
+	}
+	revs, err := d.revisions()
+	if err != nil {
+		return err
+	}
+	var rev uint64 = 1
+	if len(revs) > 0 {
+		rev = revs[len(revs)-1] + 1
+	}
+	if err := writeLinUCBStateFile(d.revisionPath(rev), state); err != nil {
+		return err
+	}
+	revs = append(revs, rev)
+	if d.keep > 0 && len(revs) > d.keep {
+		for _, old := range revs[:len(revs)-d.keep] {
+			os.Remove(d.revisionPath(old))
+		}
+	}
+	return nil
+}
+
+// linUCBStateFile is the serialized LinUCBState
+type linUCBStateFile struct {
+	Version   int `json:"version"`
+	Dimension int `json:"dimension"`
+	LinUCBState
+}
+
+func readLinUCBStateFile(path string) (*LinUCBState, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrLinUCBStateNotFound
+		}
+		return nil, err
+	}
+	return parseLinUCBState(data)
+}
+
+func parseLinUCBState(data []byte) (*LinUCBState, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, ErrLinUCBStateNotFound
+	}
+	if data[0] != '{' {
+		return parseLegacyLinUCBState(bytes.NewReader(data))
+	}
+	var f linUCBStateFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	if f.Version != linUCBStateVersion {
+		return nil, fmt.Errorf("linUCB state: unsupported version %d", f.Version)
+	}
+	if f.Dimension != banditDimension {
+		return nil, fmt.Errorf("linUCB state: dimension %d doesn't match %d", f.Dimension, banditDimension)
+	}
+	return &f.LinUCBState, nil
+}
+
+// parseLegacyLinUCBState reads the matrices as written by earlier versions, one number per line
+func parseLegacyLinUCBState(r io.Reader) (*LinUCBState, error) {
+	l := &LinUCBState{}
+	values := make([]*float64, 0, 2*banditDimension*banditDimension+2*banditDimension)
+	for _, m := range []*[banditDimension][banditDimension]float64{&l.MAaF, &l.MAaS} {
+		for i := range m {
+			for j := range m[i] {
+				values = append(values, &m[i][j])
+			}
+		}
+	}
+	for _, v := range []*[banditDimension]float64{&l.MbaF, &l.MbaS} {
+		for i := range v {
+			values = append(values, &v[i])
+		}
+	}
+	scanner := bufio.NewScanner(r)
+	for _, v := range values {
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return nil, err
+			}
+			return nil, io.ErrUnexpectedEOF
+		}
+		val, err := strconv.ParseFloat(strings.TrimSpace(scanner.Text()), 64)
+		if err != nil {
+			return nil, err
+		}
+		*v = val
+	}
+	return l, nil
+}
+
+// writeLinUCBStateFile writes the state to a temporary file and renames it,
+// so that readers never see a partially written state
+func writeLinUCBStateFile(path string, state *LinUCBState) error {
+	data, err := json.Marshal(&linUCBStateFile{
+		Version:     linUCBStateVersion,
+		Dimension:   banditDimension,
+		LinUCBState: *state,
+	})
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
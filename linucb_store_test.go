@@ -0,0 +1,187 @@
+package quic
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type countingLinUCBStore struct {
+	LinUCBStore
+	loads, saves int
+}
+
+func (c *countingLinUCBStore) Load() (*LinUCBState, error) {
+	c.loads++
+	return c.LinUCBStore.Load()
+}
+
+func (c *countingLinUCBStore) Save(state *LinUCBState) error {
+	c.saves++
+	return c.LinUCBStore.Save(state)
+}
+
+var _ = Describe("linUCB state store", func() {
+	var (
+		dir   string
+		state *LinUCBState
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "linucb")
+		Expect(err).ToNot(HaveOccurred())
+		state = newLinUCBState()
+		state.MbaF[2] = 0.5
+		state.MAaS[1][3] = 1.25
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("starts with the identity matrices", func() {
+		s := newLinUCBState()
+		Expect(s.MAaF[0][0]).To(Equal(1.0))
+		Expect(s.MAaS[5][5]).To(Equal(1.0))
+		Expect(s.MAaF[0][1]).To(BeZero())
+	})
+
+	Context("in memory", func() {
+		It("saves and loads a copy of the state", func() {
+			store := NewMemoryLinUCBStore()
+			_, err := store.Load()
+			Expect(err).To(MatchError(ErrLinUCBStateNotFound))
+			Expect(store.Save(state)).To(Succeed())
+			state.MbaF[2] = 3
+			loaded, err := store.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(loaded.MbaF[2]).To(Equal(0.5))
+		})
+	})
+
+	Context("in a file", func() {
+		var path string
+
+		BeforeEach(func() {
+			path = filepath.Join(dir, "lin")
+		})
+
+		It("saves and loads the state", func() {
+			store := NewFileLinUCBStore(path)
+			_, err := store.Load()
+			Expect(err).To(MatchError(ErrLinUCBStateNotFound))
+			Expect(store.Save(state)).To(Succeed())
+			loaded, err := store.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(loaded).To(Equal(state))
+		})
+
+		It("doesn't leave temporary files behind", func() {
+			store := NewFileLinUCBStore(path)
+			Expect(store.Save(state)).To(Succeed())
+			Expect(store.Save(state)).To(Succeed())
+			files, err := ioutil.ReadDir(dir)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(files).To(HaveLen(1))
+		})
+
+		It("reads the legacy format", func() {
+			var lines []string
+			for i := 0; i < 2*banditDimension*banditDimension+2*banditDimension; i++ {
+				lines = append(lines, fmt.Sprintf("%.8f", float64(i)))
+			}
+			Expect(ioutil.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600)).To(Succeed())
+			loaded, err := NewFileLinUCBStore(path).Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(loaded.MAaF[0][1]).To(Equal(1.0))
+			Expect(loaded.MAaS[0][0]).To(Equal(float64(banditDimension * banditDimension)))
+			Expect(loaded.MbaS[banditDimension-1]).To(Equal(float64(len(lines) - 1)))
+		})
+
+		It("rejects truncated legacy files", func() {
+			Expect(ioutil.WriteFile(path, []byte("1.0\n2.0\n"), 0600)).To(Succeed())
+			_, err := NewFileLinUCBStore(path).Load()
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("rejects unknown versions", func() {
+			Expect(ioutil.WriteFile(path, []byte(`{"version": 42, "dimension": 6}`), 0600)).To(Succeed())
+			_, err := NewFileLinUCBStore(path).Load()
+			Expect(err).To(MatchError("linUCB state: unsupported version 42"))
+		})
+	})
+
+	Context("in a directory", func() {
+		It("loads the latest state", func() {
+			store := NewDirLinUCBStore(filepath.Join(dir, "states"), 0)
+			_, err := store.Load()
+			Expect(err).To(MatchError(ErrLinUCBStateNotFound))
+			Expect(store.Save(state)).To(Succeed())
+			state.MbaF[2] = 2
+			Expect(store.Save(state)).To(Succeed())
+			loaded, err := store.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(loaded.MbaF[2]).To(Equal(2.0))
+		})
+
+		It("only keeps the last states", func() {
+			store := NewDirLinUCBStore(dir, 2)
+			for i := 0; i < 4; i++ {
+				Expect(store.Save(state)).To(Succeed())
+			}
+			files, err := ioutil.ReadDir(dir)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(files).To(HaveLen(2))
+			Expect(files[0].Name()).To(Equal("linucb-00000003.json"))
+			Expect(files[1].Name()).To(Equal("linucb-00000004.json"))
+		})
+
+		It("falls back to an older state if the latest one is corrupted", func() {
+			store := NewDirLinUCBStore(dir, 0)
+			Expect(store.Save(state)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(dir, "linucb-00000002.json"), []byte("{"), 0600)).To(Succeed())
+			loaded, err := store.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(loaded).To(Equal(state))
+		})
+	})
+
+	Context("schedulers", func() {
+		var store *countingLinUCBStore
+
+		BeforeEach(func() {
+			store = &countingLinUCBStore{LinUCBStore: NewMemoryLinUCBStore()}
+		})
+
+		It("loads the state lazily", func() {
+			sch := newBanditScheduler(&Config{LinUCBStore: store}).(*banditScheduler)
+			Expect(store.loads).To(BeZero())
+			Expect(sch.Close()).To(Succeed())
+			Expect(store.saves).To(BeZero())
+			Expect(sch.linUCBState()).To(Equal(newLinUCBState()))
+			Expect(store.loads).To(Equal(1))
+			sch.linUCBState()
+			Expect(store.loads).To(Equal(1))
+		})
+
+		It("saves the state when the session is closed", func() {
+			Expect(store.Save(state)).To(Succeed())
+			sch := newBanditScheduler(&Config{LinUCBStore: store}).(*banditScheduler)
+			sch.linUCBState().MbaS[0] = 7
+			Expect(sch.Close()).To(Succeed())
+			loaded, err := store.Load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(loaded.MbaS[0]).To(Equal(7.0))
+		})
+
+		It("uses the default store without a config", func() {
+			Expect(newPeekabooScheduler(nil).(*peekabooScheduler).store).To(Equal(defaultLinUCBStore))
+		})
+	})
+})
@@ -0,0 +1,118 @@
+package quic
+
+import (
+	"errors"
+	"math"
+)
+
+// An lpSolver solves the linear programs of the batch schedulers:
+// maximize c·x subject to A·x <= b and x >= 0.
+// All entries of b must be non-negative, so that x = 0 is feasible.
+type lpSolver interface {
+	maximize(c []float64, A [][]float64, b []float64) ([]float64, error)
+}
+
+// defaultLPSolver is used by linOpt and linOptCost.
+// Building with the lpsolve tag replaces it with lp_solve, which needs cgo.
+var defaultLPSolver lpSolver = simplexSolver{}
+
+const (
+	simplexEpsilon       = 1e-9
+	simplexMaxIterations = 10000
+)
+
+var (
+	errSimplexNegativeBound = errors.New("simplex: negative right-hand side")
+	errSimplexUnbounded     = errors.New("simplex: problem is unbounded")
+	errSimplexIterations    = errors.New("simplex: too many iterations")
+)
+
+// simplexSolver is a pure-Go implementation of the simplex algorithm on a dense tableau.
+// The batch problems have at most a few hundred variables, so there is no need for a sparse representation.
+// It uses Bland's rule, so it never cycles on degenerate problems.
+type simplexSolver struct{}
+
+func (simplexSolver) maximize(c []float64, A [][]float64, b []float64) ([]float64, error) {
+	m, n := len(A), len(c)
+	// Every constraint gets a slack variable, which forms the initial basis.
+	// Row m is the objective row, column n+m holds the right-hand side.
+	width := n + m + 1
+	tableau := make([][]float64, m+1)
+	basis := make([]int, m)
+	for i := 0; i < m; i++ {
+		if b[i] < 0 {
+			return nil, errSimplexNegativeBound
+		}
+		tableau[i] = make([]float64, width)
+		copy(tableau[i], A[i])
+		tableau[i][n+i] = 1
+		tableau[i][width-1] = b[i]
+		basis[i] = n + i
+	}
+	tableau[m] = make([]float64, width)
+	for j := 0; j < n; j++ {
+		tableau[m][j] = -c[j]
+	}
+
+	for iteration := 0; ; iteration++ {
+		if iteration >= simplexMaxIterations {
+			return nil, errSimplexIterations
+		}
+		// entering variable: the first one that improves the objective
+		col := -1
+		for j := 0; j < width-1; j++ {
+			if tableau[m][j] < -simplexEpsilon {
+				col = j
+				break
+			}
+		}
+		if col == -1 {
+			break
+		}
+		// leaving variable: minimum ratio, ties broken by the lowest index
+		row := -1
+		minRatio := math.Inf(1)
+		for i := 0; i < m; i++ {
+			if tableau[i][col] <= simplexEpsilon {
+				continue
+			}
+			ratio := tableau[i][width-1] / tableau[i][col]
+			if ratio < minRatio-simplexEpsilon || (ratio < minRatio+simplexEpsilon && basis[i] < basis[row]) {
+				row = i
+				minRatio = ratio
+			}
+		}
+		if row == -1 {
+			return nil, errSimplexUnbounded
+		}
+		pivot(tableau, row, col)
+		basis[row] = col
+	}
+
+	x := make([]float64, n)
+	for i, v := range basis {
+		if v < n {
+			x[v] = tableau[i][width-1]
+		}
+	}
+	return x, nil
+}
+
+func pivot(tableau [][]float64, row, col int) {
+	p := tableau[row][col]
+	for j := range tableau[row] {
+		tableau[row][j] /= p
+	}
+	for i := range tableau {
+		if i == row {
+			continue
+		}
+		f := tableau[i][col]
+		if f == 0 {
+			continue
+		}
+		for j := range tableau[i] {
+			tableau[i][j] -= f * tableau[row][j]
+		}
+	}
+}
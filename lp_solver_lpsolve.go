@@ -0,0 +1,32 @@
+//go:build lpsolve
+// +build lpsolve
+
+package quic
+
+import (
+	"fmt"
+
+	"github.com/draffensperger/golp"
+)
+
+func init() {
+	defaultLPSolver = lpSolveSolver{}
+}
+
+// lpSolveSolver solves the linear programs with lp_solve, through cgo
+type lpSolveSolver struct{}
+
+func (lpSolveSolver) maximize(c []float64, A [][]float64, b []float64) ([]float64, error) {
+	lp := golp.NewLP(0, len(c))
+	lp.SetObjFn(c)
+	lp.SetMaximize()
+	for i := range A {
+		if err := lp.AddConstraint(A[i], golp.LE, b[i]); err != nil {
+			return nil, err
+		}
+	}
+	if res := lp.Solve(); res != golp.OPTIMAL {
+		return nil, fmt.Errorf("lp_solve: %v", res)
+	}
+	return lp.Variables(), nil
+}
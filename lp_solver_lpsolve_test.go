@@ -0,0 +1,47 @@
+//go:build lpsolve
+// +build lpsolve
+
+package quic
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("lp_solve", func() {
+	AfterEach(func() {
+		defaultLPSolver = lpSolveSolver{}
+	})
+
+	It("is used when building with the lpsolve tag", func() {
+		Expect(defaultLPSolver).To(Equal(lpSolveSolver{}))
+	})
+
+	It("gives the same policies as the simplex solver", func() {
+		for _, tc := range batchLPTestCases {
+			defaultLPSolver = simplexSolver{}
+			simplexResult := solveBatchLPTestCase(tc)
+			defaultLPSolver = lpSolveSolver{}
+			lpSolveResult := solveBatchLPTestCase(tc)
+			Expect(metDeadlines(tc, lpSolveResult)).To(Equal(tc.met))
+			if tc.policy == nil {
+				// the optimal assignment is not unique, but the number of packets meeting their deadline is
+				Expect(metDeadlines(tc, simplexResult)).To(Equal(metDeadlines(tc, lpSolveResult)))
+				continue
+			}
+			Expect(simplexResult).To(Equal(lpSolveResult))
+			Expect(resultToPolicy(lpSolveResult)).To(Equal(tc.policy))
+		}
+	})
+
+	It("gives the same optimum as the simplex solver", func() {
+		c := []float64{1, 1, 1}
+		A := [][]float64{{1, 1, 0}, {1, 1, 0}, {0, 1, 1}}
+		b := []float64{1, 1, 0}
+		x1, err := simplexSolver{}.maximize(c, A, b)
+		Expect(err).ToNot(HaveOccurred())
+		x2, err := lpSolveSolver{}.maximize(c, A, b)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(simplexValue(c, x1)).To(BeNumerically("~", simplexValue(c, x2), 1e-9))
+	})
+})
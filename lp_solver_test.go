@@ -0,0 +1,169 @@
+package quic
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// batchLPTestCase is a batch problem with a unique optimal assignment
+type batchLPTestCase struct {
+	deadlines, delays, cwnds []float64
+	policy                   []int
+	// met is the number of packets meeting their deadline in the optimal assignment
+	met int
+}
+
+var batchLPTestCases = []batchLPTestCase{
+	{
+		deadlines: []float64{10, 50, 100},
+		delays:    []float64{5, 40, 80},
+		cwnds:     []float64{1, 1, 1},
+		policy:    []int{1, 2, 3},
+		met:       3,
+	},
+	{
+		deadlines: []float64{100, 10, 30, 10},
+		delays:    []float64{20, 5},
+		cwnds:     []float64{2, 2},
+		policy:    []int{1, 2, 1, 2},
+		met:       4,
+	},
+	{
+		// only one packet fits into the cwnd of the fast path, the others miss their deadline anyway
+		deadlines: []float64{8, 100, 100, 100},
+		delays:    []float64{80, 60, 5, 70},
+		cwnds:     []float64{0, 1, 1, 2},
+		policy:    nil,
+		met:       4,
+	},
+}
+
+func simplexValue(c, x []float64) float64 {
+	var v float64
+	for i := range c {
+		v += c[i] * x[i]
+	}
+	return v
+}
+
+var _ = Describe("LP solver", func() {
+	Context("simplex", func() {
+		It("solves a small LP", func() {
+			// maximize 3x + 5y s.t. x <= 4, 2y <= 12, 3x + 2y <= 18
+			x, err := simplexSolver{}.maximize(
+				[]float64{3, 5},
+				[][]float64{{1, 0}, {0, 2}, {3, 2}},
+				[]float64{4, 12, 18},
+			)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(x[0]).To(BeNumerically("~", 2, 1e-9))
+			Expect(x[1]).To(BeNumerically("~", 6, 1e-9))
+		})
+
+		It("handles degenerate problems", func() {
+			c := []float64{1, 1, 1}
+			x, err := simplexSolver{}.maximize(c, [][]float64{{1, 1, 0}, {1, 1, 0}, {0, 1, 1}}, []float64{1, 1, 0})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(simplexValue(c, x)).To(BeNumerically("~", 1, 1e-9))
+		})
+
+		It("returns zero for an empty problem", func() {
+			x, err := simplexSolver{}.maximize([]float64{1, 2}, [][]float64{{1, 1}}, []float64{0})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(x).To(Equal([]float64{0, 0}))
+		})
+
+		It("detects unbounded problems", func() {
+			_, err := simplexSolver{}.maximize([]float64{1, 1}, [][]float64{{1, -1}}, []float64{1})
+			Expect(err).To(MatchError(errSimplexUnbounded))
+		})
+
+		It("rejects negative bounds", func() {
+			_, err := simplexSolver{}.maximize([]float64{1}, [][]float64{{1}}, []float64{-1})
+			Expect(err).To(MatchError(errSimplexNegativeBound))
+		})
+	})
+
+	Context("batch scheduling", func() {
+		It("solves the batch problems with the simplex solver", func() {
+			solver := defaultLPSolver
+			defer func() { defaultLPSolver = solver }()
+			defaultLPSolver = simplexSolver{}
+			for _, tc := range batchLPTestCases {
+				result := solveBatchLPTestCase(tc)
+				Expect(metDeadlines(tc, result)).To(Equal(tc.met))
+				if tc.policy != nil {
+					Expect(resultToPolicy(result)).To(Equal(tc.policy))
+				}
+			}
+		})
+
+		It("assigns the packets to any number of paths", func() {
+			for _, tc := range batchLPTestCases {
+				if tc.policy == nil {
+					continue
+				}
+				policy := linOpt(generateSequence(len(tc.deadlines)), tc.deadlines, tc.delays, tc.cwnds)
+				Expect(policy).To(Equal(tc.policy))
+			}
+		})
+
+		It("respects the cwnd of the paths", func() {
+			tc := batchLPTestCases[2]
+			policy := linOpt(generateSequence(len(tc.deadlines)), tc.deadlines, tc.delays, tc.cwnds)
+			perPath := make([]float64, len(tc.delays))
+			for _, p := range policy {
+				if p > 0 {
+					perPath[p-1]++
+				}
+			}
+			for j := range perPath {
+				Expect(perPath[j]).To(BeNumerically("<=", tc.cwnds[j]))
+			}
+		})
+
+		It("keeps the batch within the budget", func() {
+			costs := []float64{5, 1, 1}
+			policy := linOptCost(generateSequence(3), []float64{10, 100, 100}, []float64{5, 40, 80}, []float64{3, 3, 3}, costs, 2)
+			var cost float64
+			var sent int
+			for _, p := range policy {
+				if p > 0 {
+					cost += costs[p-1]
+					sent++
+				}
+			}
+			Expect(policy[0]).To(BeZero())
+			Expect(sent).To(Equal(2))
+			Expect(cost).To(BeNumerically("<=", 2))
+		})
+	})
+})
+
+// solveBatchLPTestCase solves the LP relaxation of linOpt with the defaultLPSolver, rounded to whole packets
+func solveBatchLPTestCase(tc batchLPTestCase) [][]float64 {
+	C, A, b := batchLP(tc.deadlines, tc.delays, tc.cwnds)
+	result := solveBatchLP(C, A, b, len(tc.deadlines), len(tc.delays))
+	for i := range result {
+		for j := range result[i] {
+			if result[i][j] > 0.5 {
+				result[i][j] = 1
+			} else {
+				result[i][j] = 0
+			}
+		}
+	}
+	return result
+}
+
+func metDeadlines(tc batchLPTestCase, result [][]float64) int {
+	var met int
+	for i := range result {
+		for j := range result[i] {
+			if result[i][j] == 1 && tc.deadlines[i] >= tc.delays[j] {
+				met++
+			}
+		}
+	}
+	return met
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/lucas-clemente/quic-go/ackhandler"
 	"github.com/lucas-clemente/quic-go/internal/handshake"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 )
 
@@ -33,6 +34,9 @@ type packetPacker struct {
 	controlFrames []wire.Frame
 	stopWaiting   map[protocol.PathID]*wire.StopWaitingFrame
 	ackFrame      map[protocol.PathID]*wire.AckFrame
+
+	// epoch of the timestamps in the public headers
+	epoch time.Time
 }
 
 func newPacketPacker(connectionID protocol.ConnectionID,
@@ -51,6 +55,7 @@ func newPacketPacker(connectionID protocol.ConnectionID,
 		streamFramer:         streamFramer,
 		stopWaiting:          make(map[protocol.PathID]*wire.StopWaitingFrame),
 		ackFrame:             make(map[protocol.PathID]*wire.AckFrame),
+		epoch:                time.Now(),
 	}
 }
 
@@ -73,10 +78,8 @@ func (p *packetPacker) PackPing(pf *wire.PingFrame, pth *path) (*packedPacket, e
 	// Add the PingFrame in front of the controlFrames
 	pth.SetLeastUnacked(pth.sentPacketHandler.GetLeastUnacked())
 	p.controlFrames = append([]wire.Frame{pf}, p.controlFrames...)
-	//czy:this deadline is no value
-	var deadline time.Time
 	curNotSent := uint8(0)
-	return p.PackPacket(pth, deadline, curNotSent, uint8(1))
+	return p.PackPacket(pth, curNotSent, 0, uint8(1))
 }
 
 func (p *packetPacker) PackAckPacket(pth *path) (*packedPacket, error) {
@@ -130,24 +133,28 @@ func (p *packetPacker) PackHandshakeRetransmission(packet *ackhandler.Packet, pt
 
 // PackPacket packs a new packet
 // the other controlFrames are sent in the next packet, but might be queued and sent in the next packet if the packet would overflow MaxPacketSize otherwise
-func (p *packetPacker) PackPacket(pth *path, deadline time.Time, curNotSent uint8, alpha uint8) (*packedPacket, error) {
+// The deadline of the packet is the earliest deadline of the StreamFrames and DatagramFrames it contains
+func (p *packetPacker) PackPacket(pth *path, curNotSent uint8, batchLength uint8, alpha uint8) (*packedPacket, error) {
 	if p.streamFramer.HasCryptoStreamFrame() {
 		return p.packCryptoPacket(pth)
 	}
 
 	encLevel, sealer := p.cryptoSetup.GetSealer()
 
-	//czy:Add deadline to publicHeader(encLevel, pth, deadline)
 	publicHeader := p.getPublicHeader(encLevel, pth)
 	//czy
-	publicHeader.Deadline = deadline
 	publicHeader.CurNotSent = curNotSent
+	publicHeader.BatchLength = batchLength
 	publicHeader.Alpha = alpha
 
-	publicHeaderLength, err := publicHeader.GetLength(p.perspective)
+	publicHeaderLength, err := publicHeader.GetLength(p.perspective, p.version)
 	if err != nil {
 		return nil, err
 	}
+	// The deadline is only known once the payload is composed, so reserve room for the longest TTL
+	if p.version.UsesDeadlines() {
+		publicHeaderLength += wire.MaxDeadlineTTLLength - utils.VarIntLen(0)
+	}
 	if p.stopWaiting[pth.pathID] != nil {
 		p.stopWaiting[pth.pathID].PacketNumber = publicHeader.PacketNumber
 		p.stopWaiting[pth.pathID].PacketNumberLen = publicHeader.PacketNumberLen
@@ -183,6 +190,12 @@ func (p *packetPacker) PackPacket(pth *path, deadline time.Time, curNotSent uint
 	p.stopWaiting[pth.pathID] = nil
 	p.ackFrame[pth.pathID] = nil
 
+	// The deadline is sent relative to the send time, since the clocks of the peers aren't synchronised
+	deadline := earliestDeadline(payloadFrames)
+	if !deadline.IsZero() {
+		publicHeader.DeadlineTTL = deadline.Sub(time.Now())
+	}
+
 	//czy:将包头和payload写成数据raw （byte）
 	raw, err := p.writeAndSealPacket(publicHeader, payloadFrames, sealer, pth)
 	if err != nil {
@@ -198,10 +211,85 @@ func (p *packetPacker) PackPacket(pth *path, deadline time.Time, curNotSent uint
 	}, nil
 }
 
+// PackDuplicate packs a copy of the STREAM frames of a sent packet, to be sent on another path.
+// It returns nil if the packet has no STREAM frames, or if they don't fit into a packet of the path.
+// DATAGRAM frames are not copied, since the peer can't tell them apart.
+func (p *packetPacker) PackDuplicate(packet *ackhandler.Packet, pth *path, alpha uint8) (*packedPacket, error) {
+	if packet.EncryptionLevel != protocol.EncryptionForwardSecure {
+		return nil, nil
+	}
+	var frames []wire.Frame
+	var payloadLength protocol.ByteCount
+	for _, frame := range packet.Frames {
+		f, ok := frame.(*wire.StreamFrame)
+		if !ok {
+			continue
+		}
+		// the original frame may be split if it is retransmitted
+		c := *f
+		frames = append(frames, &c)
+		length, _ := c.MinLength(p.version) // can never error
+		payloadLength += length + c.DataLen()
+	}
+	if len(frames) == 0 {
+		return nil, nil
+	}
+
+	encLevel, sealer := p.cryptoSetup.GetSealer()
+	if encLevel != protocol.EncryptionForwardSecure {
+		return nil, nil
+	}
+	publicHeader := p.getPublicHeader(encLevel, pth)
+	publicHeader.Alpha = alpha
+	deadline := earliestDeadline(frames)
+	if !deadline.IsZero() {
+		publicHeader.DeadlineTTL = deadline.Sub(time.Now())
+	}
+	publicHeaderLength, err := publicHeader.GetLength(p.perspective, p.version)
+	if err != nil {
+		return nil, err
+	}
+	if publicHeaderLength+payloadLength+protocol.ByteCount(sealer.Overhead()) > protocol.MaxPacketSize {
+		return nil, nil
+	}
+	raw, err := p.writeAndSealPacket(publicHeader, frames, sealer, pth)
+	if err != nil {
+		return nil, err
+	}
+	return &packedPacket{
+		number:          publicHeader.PacketNumber,
+		raw:             raw,
+		frames:          frames,
+		encryptionLevel: encLevel,
+		m_deadline:      deadline,
+	}, nil
+}
+
+// earliestDeadline returns the earliest deadline of the StreamFrames and DatagramFrames, or a zero time if none has a deadline
+func earliestDeadline(frames []wire.Frame) time.Time {
+	var deadline time.Time
+	for _, frame := range frames {
+		var frameDeadline time.Time
+		switch f := frame.(type) {
+		case *wire.StreamFrame:
+			frameDeadline = f.Deadline
+		case *wire.DatagramFrame:
+			frameDeadline = f.Deadline
+		}
+		if frameDeadline.IsZero() {
+			continue
+		}
+		if deadline.IsZero() || frameDeadline.Before(deadline) {
+			deadline = frameDeadline
+		}
+	}
+	return deadline
+}
+
 func (p *packetPacker) packCryptoPacket(pth *path) (*packedPacket, error) {
 	encLevel, sealer := p.cryptoSetup.GetSealerForCryptoStream()
 	publicHeader := p.getPublicHeader(encLevel, pth)
-	publicHeaderLength, err := publicHeader.GetLength(p.perspective)
+	publicHeaderLength, err := publicHeader.GetLength(p.perspective, p.version)
 	if err != nil {
 		return nil, err
 	}
@@ -267,6 +355,13 @@ func (p *packetPacker) composeNextPacket(
 		return payloadFrames, nil
 	}
 
+	// datagrams go first, their data is useless once it is late
+	datagrams, datagramsLen := p.streamFramer.PopDatagramFrames(maxFrameSize - payloadLength)
+	for _, f := range datagrams {
+		payloadFrames = append(payloadFrames, f)
+	}
+	payloadLength += datagramsLen
+
 	// temporarily increase the maxFrameSize by 2 bytes
 	// this leads to a properly sized packet in all cases, since we do all the packet length calculations with StreamFrames that have the DataLen set
 	// however, for the last StreamFrame in the packet, we can omit the DataLen, thus saving 2 bytes and yielding a packet of exactly the correct size
@@ -285,6 +380,9 @@ func (p *packetPacker) composeNextPacket(
 	for b := p.streamFramer.PopBlockedFrame(); b != nil; b = p.streamFramer.PopBlockedFrame() {
 		p.controlFrames = append(p.controlFrames, b)
 	}
+	for g := p.streamFramer.PopStreamGapFrame(); g != nil; g = p.streamFramer.PopStreamGapFrame() {
+		p.controlFrames = append(p.controlFrames, g)
+	}
 
 	return payloadFrames, nil
 }
@@ -308,6 +406,7 @@ func (p *packetPacker) getPublicHeader(encLevel protocol.EncryptionLevel, pth *p
 		PacketNumber:         pnum,
 		PacketNumberLen:      packetNumberLen,
 		TruncateConnectionID: p.connectionParameters.TruncateConnectionID(),
+		Timestamp:            time.Since(p.epoch),
 	}
 
 	if p.perspective == protocol.PerspectiveServer && encLevel == protocol.EncryptionSecure {
@@ -73,7 +73,7 @@ var _ = Describe("Packet packer", func() {
 		streamFramer = newStreamFramer(streamsMap, nil)
 
 		pth = &path{
-			sentPacketHandler:     ackhandler.NewSentPacketHandler(&congestion.RTTStats{}, nil, nil),
+			sentPacketHandler:     ackhandler.NewSentPacketHandler(&congestion.RTTStats{}, nil, nil, nil),
 			packetNumberGenerator: newPacketNumberGenerator(protocol.SkipPacketAveragePeriodLength),
 		}
 
@@ -92,7 +92,7 @@ var _ = Describe("Packet packer", func() {
 	})
 
 	It("returns nil when no packet is queued", func() {
-		p, err := packer.PackPacket(pth)
+		p, err := packer.PackPacket(pth, 0, 0, 0)
 		Expect(p).To(BeNil())
 		Expect(err).ToNot(HaveOccurred())
 	})
@@ -103,7 +103,7 @@ var _ = Describe("Packet packer", func() {
 			Data:     []byte{0xDE, 0xCA, 0xFB, 0xAD},
 		}
 		streamFramer.AddFrameForRetransmission(f)
-		p, err := packer.PackPacket(pth)
+		p, err := packer.PackPacket(pth, 0, 0, 0)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(p).ToNot(BeNil())
 		b := &bytes.Buffer{}
@@ -119,7 +119,7 @@ var _ = Describe("Packet packer", func() {
 			Data:     []byte("foobar"),
 		}
 		streamFramer.AddFrameForRetransmission(f)
-		p, err := packer.PackPacket(pth)
+		p, err := packer.PackPacket(pth, 0, 0, 0)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(p.encryptionLevel).To(Equal(protocol.EncryptionForwardSecure))
 	})
@@ -184,7 +184,7 @@ var _ = Describe("Packet packer", func() {
 	It("packs only control frames", func() {
 		packer.QueueControlFrame(&wire.RstStreamFrame{}, pth)
 		packer.QueueControlFrame(&wire.WindowUpdateFrame{}, pth)
-		p, err := packer.PackPacket(pth)
+		p, err := packer.PackPacket(pth, 0, 0, 0)
 		Expect(p).ToNot(BeNil())
 		Expect(err).ToNot(HaveOccurred())
 		Expect(p.frames).To(HaveLen(2))
@@ -193,11 +193,11 @@ var _ = Describe("Packet packer", func() {
 
 	It("increases the packet number", func() {
 		packer.QueueControlFrame(&wire.RstStreamFrame{}, pth)
-		p1, err := packer.PackPacket(pth)
+		p1, err := packer.PackPacket(pth, 0, 0, 0)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(p1).ToNot(BeNil())
 		packer.QueueControlFrame(&wire.RstStreamFrame{}, pth)
-		p2, err := packer.PackPacket(pth)
+		p2, err := packer.PackPacket(pth, 0, 0, 0)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(p2).ToNot(BeNil())
 		Expect(p2.number).To(BeNumerically(">", p1.number))
@@ -208,7 +208,7 @@ var _ = Describe("Packet packer", func() {
 		swf := &wire.StopWaitingFrame{LeastUnacked: 10}
 		packer.QueueControlFrame(&wire.RstStreamFrame{}, pth)
 		packer.QueueControlFrame(swf, pth)
-		p, err := packer.PackPacket(pth)
+		p, err := packer.PackPacket(pth, 0, 0, 0)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(p).ToNot(BeNil())
 		Expect(p.frames).To(HaveLen(2))
@@ -221,7 +221,7 @@ var _ = Describe("Packet packer", func() {
 		swf := &wire.StopWaitingFrame{LeastUnacked: packetNumber - 0x100}
 		packer.QueueControlFrame(&wire.RstStreamFrame{}, pth)
 		packer.QueueControlFrame(swf, pth)
-		p, err := packer.PackPacket(pth)
+		p, err := packer.PackPacket(pth, 0, 0, 0)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(p.frames[0].(*wire.StopWaitingFrame).PacketNumberLen).To(Equal(protocol.PacketNumberLen4))
 	})
@@ -229,14 +229,14 @@ var _ = Describe("Packet packer", func() {
 	It("does not pack a packet containing only a StopWaitingFrame", func() {
 		swf := &wire.StopWaitingFrame{LeastUnacked: 10}
 		packer.QueueControlFrame(swf, pth)
-		p, err := packer.PackPacket(pth)
+		p, err := packer.PackPacket(pth, 0, 0, 0)
 		Expect(p).To(BeNil())
 		Expect(err).ToNot(HaveOccurred())
 	})
 
 	It("packs a packet if it has queued control frames, but no new control frames", func() {
 		packer.controlFrames = []wire.Frame{&wire.BlockedFrame{StreamID: 0}}
-		p, err := packer.PackPacket(pth)
+		p, err := packer.PackPacket(pth, 0, 0, 0)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(p).ToNot(BeNil())
 	})
@@ -247,7 +247,7 @@ var _ = Describe("Packet packer", func() {
 		packer.controlFrames = []wire.Frame{&wire.BlockedFrame{StreamID: 0}}
 		packer.connectionID = 0x1337
 		packer.version = 123
-		p, err := packer.PackPacket(pth)
+		p, err := packer.PackPacket(pth, 0, 0, 0)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(p).ToNot(BeNil())
 		hdr, err := wire.ParsePublicHeader(bytes.NewReader(p.raw), protocol.PerspectiveClient, packer.version)
@@ -261,7 +261,7 @@ var _ = Describe("Packet packer", func() {
 		packer.cryptoSetup.(*mockCryptoSetup).encLevelSeal = protocol.EncryptionForwardSecure
 		packer.controlFrames = []wire.Frame{&wire.BlockedFrame{StreamID: 0}}
 		packer.connectionID = 0x1337
-		p, err := packer.PackPacket(pth)
+		p, err := packer.PackPacket(pth, 0, 0, 0)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(p).ToNot(BeNil())
 		hdr, err := wire.ParsePublicHeader(bytes.NewReader(p.raw), protocol.PerspectiveClient, packer.version)
@@ -308,7 +308,7 @@ var _ = Describe("Packet packer", func() {
 
 	It("only increases the packet number when there is an actual packet to send", func() {
 		pth.packetNumberGenerator.nextToSkip = 1000
-		p, err := packer.PackPacket(pth)
+		p, err := packer.PackPacket(pth, 0, 0, 0)
 		Expect(p).To(BeNil())
 		Expect(err).ToNot(HaveOccurred())
 		Expect(pth.packetNumberGenerator.Peek()).To(Equal(protocol.PacketNumber(1)))
@@ -317,7 +317,7 @@ var _ = Describe("Packet packer", func() {
 			Data:     []byte{0xDE, 0xCA, 0xFB, 0xAD},
 		}
 		streamFramer.AddFrameForRetransmission(f)
-		p, err = packer.PackPacket(pth)
+		p, err = packer.PackPacket(pth, 0, 0, 0)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(p).ToNot(BeNil())
 		Expect(p.number).To(Equal(protocol.PacketNumber(1)))
@@ -358,12 +358,12 @@ var _ = Describe("Packet packer", func() {
 			}
 			streamFramer.AddFrameForRetransmission(f1)
 			streamFramer.AddFrameForRetransmission(f2)
-			p, err := packer.PackPacket(pth)
+			p, err := packer.PackPacket(pth, 0, 0, 0)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(p.raw).To(HaveLen(int(protocol.MaxPacketSize - 1)))
 			Expect(p.frames).To(HaveLen(1))
 			Expect(p.frames[0].(*wire.StreamFrame).DataLenPresent).To(BeFalse())
-			p, err = packer.PackPacket(pth)
+			p, err = packer.PackPacket(pth, 0, 0, 0)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(p.frames).To(HaveLen(1))
 			Expect(p.frames[0].(*wire.StreamFrame).DataLenPresent).To(BeFalse())
@@ -385,7 +385,7 @@ var _ = Describe("Packet packer", func() {
 			streamFramer.AddFrameForRetransmission(f1)
 			streamFramer.AddFrameForRetransmission(f2)
 			streamFramer.AddFrameForRetransmission(f3)
-			p, err := packer.PackPacket(pth)
+			p, err := packer.PackPacket(pth, 0, 0, 0)
 			Expect(p).ToNot(BeNil())
 			Expect(err).ToNot(HaveOccurred())
 			b := &bytes.Buffer{}
@@ -439,23 +439,23 @@ var _ = Describe("Packet packer", func() {
 			}
 			streamFramer.AddFrameForRetransmission(f1)
 			streamFramer.AddFrameForRetransmission(f2)
-			p, err := packer.PackPacket(pth)
+			p, err := packer.PackPacket(pth, 0, 0, 0)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(p.frames).To(HaveLen(1))
 			Expect(p.frames[0].(*wire.StreamFrame).DataLenPresent).To(BeFalse())
 			Expect(p.raw).To(HaveLen(int(protocol.MaxPacketSize)))
-			p, err = packer.PackPacket(pth)
+			p, err = packer.PackPacket(pth, 0, 0, 0)
 			Expect(p.frames).To(HaveLen(2))
 			Expect(p.frames[0].(*wire.StreamFrame).DataLenPresent).To(BeTrue())
 			Expect(p.frames[1].(*wire.StreamFrame).DataLenPresent).To(BeFalse())
 			Expect(err).ToNot(HaveOccurred())
 			Expect(p.raw).To(HaveLen(int(protocol.MaxPacketSize)))
-			p, err = packer.PackPacket(pth)
+			p, err = packer.PackPacket(pth, 0, 0, 0)
 			Expect(p.frames).To(HaveLen(1))
 			Expect(p.frames[0].(*wire.StreamFrame).DataLenPresent).To(BeFalse())
 			Expect(err).ToNot(HaveOccurred())
 			Expect(p).ToNot(BeNil())
-			p, err = packer.PackPacket(pth)
+			p, err = packer.PackPacket(pth, 0, 0, 0)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(p).To(BeNil())
 		})
@@ -468,7 +468,7 @@ var _ = Describe("Packet packer", func() {
 			minLength, _ := f.MinLength(0)
 			f.Data = bytes.Repeat([]byte{'f'}, int(maxFrameSize-minLength+1)) // + 1 since MinceLength is 1 bigger than the actual StreamFrame header
 			streamFramer.AddFrameForRetransmission(f)
-			p, err := packer.PackPacket(pth)
+			p, err := packer.PackPacket(pth, 0, 0, 0)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(p).ToNot(BeNil())
 			Expect(p.raw).To(HaveLen(int(protocol.MaxPacketSize)))
@@ -498,7 +498,7 @@ var _ = Describe("Packet packer", func() {
 				Data:     []byte("foobar"),
 			}
 			streamFramer.AddFrameForRetransmission(f)
-			p, err := packer.PackPacket(pth)
+			p, err := packer.PackPacket(pth, 0, 0, 0)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(p).To(BeNil())
 		})
@@ -511,7 +511,7 @@ var _ = Describe("Packet packer", func() {
 				Data:     []byte("foobar"),
 			}
 			streamFramer.AddFrameForRetransmission(f)
-			p, err := packer.PackPacket(pth)
+			p, err := packer.PackPacket(pth, 0, 0, 0)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(p.encryptionLevel).To(Equal(protocol.EncryptionSecure))
 			Expect(p.frames[0]).To(Equal(f))
@@ -524,7 +524,7 @@ var _ = Describe("Packet packer", func() {
 				Data:     []byte("foobar"),
 			}
 			streamFramer.AddFrameForRetransmission(f)
-			p, err := packer.PackPacket(pth)
+			p, err := packer.PackPacket(pth, 0, 0, 0)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(p).To(BeNil())
 		})
@@ -532,7 +532,7 @@ var _ = Describe("Packet packer", func() {
 		It("sends unencrypted stream data on the crypto stream", func() {
 			packer.cryptoSetup.(*mockCryptoSetup).encLevelSealCrypto = protocol.EncryptionUnencrypted
 			cryptoStream.dataForWriting = []byte("foobar")
-			p, err := packer.PackPacket(pth)
+			p, err := packer.PackPacket(pth, 0, 0, 0)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(p.encryptionLevel).To(Equal(protocol.EncryptionUnencrypted))
 			Expect(p.frames).To(HaveLen(1))
@@ -542,7 +542,7 @@ var _ = Describe("Packet packer", func() {
 		It("sends encrypted stream data on the crypto stream", func() {
 			packer.cryptoSetup.(*mockCryptoSetup).encLevelSealCrypto = protocol.EncryptionSecure
 			cryptoStream.dataForWriting = []byte("foobar")
-			p, err := packer.PackPacket(pth)
+			p, err := packer.PackPacket(pth, 0, 0, 0)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(p.encryptionLevel).To(Equal(protocol.EncryptionSecure))
 			Expect(p.frames).To(HaveLen(1))
@@ -553,7 +553,7 @@ var _ = Describe("Packet packer", func() {
 			packer.cryptoSetup.(*mockCryptoSetup).encLevelSeal = protocol.EncryptionUnencrypted
 			packer.QueueControlFrame(&wire.AckFrame{}, pth)
 			streamFramer.AddFrameForRetransmission(&wire.StreamFrame{StreamID: 3, Data: []byte("foobar")})
-			p, err := packer.PackPacket(pth)
+			p, err := packer.PackPacket(pth, 0, 0, 0)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(p.frames).To(HaveLen(1))
 			Expect(func() { _ = p.frames[0].(*wire.AckFrame) }).NotTo(Panic())
@@ -603,7 +603,7 @@ var _ = Describe("Packet packer", func() {
 
 	It("returns nil if we only have a single STOP_WAITING", func() {
 		packer.QueueControlFrame(&wire.StopWaitingFrame{}, pth)
-		p, err := packer.PackPacket(pth)
+		p, err := packer.PackPacket(pth, 0, 0, 0)
 		Expect(err).NotTo(HaveOccurred())
 		Expect(p).To(BeNil())
 	})
@@ -611,7 +611,7 @@ var _ = Describe("Packet packer", func() {
 	It("packs a single ACK", func() {
 		ack := &wire.AckFrame{LargestAcked: 42}
 		packer.QueueControlFrame(ack, pth)
-		p, err := packer.PackPacket(pth)
+		p, err := packer.PackPacket(pth, 0, 0, 0)
 		Expect(err).NotTo(HaveOccurred())
 		Expect(p).ToNot(BeNil())
 		Expect(p.frames[0]).To(Equal(ack))
@@ -620,7 +620,7 @@ var _ = Describe("Packet packer", func() {
 	It("does not return nil if we only have a single ACK but request it to be sent", func() {
 		ack := &wire.AckFrame{}
 		packer.QueueControlFrame(ack, pth)
-		p, err := packer.PackPacket(pth)
+		p, err := packer.PackPacket(pth, 0, 0, 0)
 		Expect(err).NotTo(HaveOccurred())
 		Expect(p).ToNot(BeNil())
 	})
@@ -628,7 +628,7 @@ var _ = Describe("Packet packer", func() {
 	It("queues a control frame to be sent in the next packet", func() {
 		wuf := &wire.WindowUpdateFrame{StreamID: 5}
 		packer.QueueControlFrame(wuf, pth)
-		p, err := packer.PackPacket(pth)
+		p, err := packer.PackPacket(pth, 0, 0, 0)
 		Expect(err).NotTo(HaveOccurred())
 		Expect(p.frames).To(HaveLen(1))
 		Expect(p.frames[0]).To(Equal(wuf))
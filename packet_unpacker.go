@@ -106,6 +106,33 @@ func (u *packetUnpacker) Unpack(publicHeaderBinary []byte, hdr *wire.PublicHeade
 				frame, err = wire.ParseClosePathFrame(r, u.version)
 			case 0x12:
 				frame, err = wire.ParsePathsFrame(r, u.version)
+			case 0x13:
+				if !u.version.UsesDeadlines() {
+					err = qerr.Error(qerr.InvalidFrameData, "STREAM_GAP frame without the deadline extension")
+					break
+				}
+				frame, err = wire.ParseStreamGapFrame(r, u.version)
+				if err != nil {
+					err = qerr.Error(qerr.InvalidFrameData, err.Error())
+				}
+			case 0x14:
+				if !u.version.UsesDeadlines() {
+					err = qerr.Error(qerr.InvalidFrameData, "PATH_PRIORITY frame without the deadline extension")
+					break
+				}
+				frame, err = wire.ParsePathPriorityFrame(r, u.version)
+				if err != nil {
+					err = qerr.Error(qerr.InvalidFrameData, err.Error())
+				}
+			case 0x15:
+				if !u.version.UsesDeadlines() {
+					err = qerr.Error(qerr.InvalidFrameData, "DATAGRAM frame without the deadline extension")
+					break
+				}
+				frame, err = wire.ParseDatagramFrame(r, u.version)
+				if err != nil {
+					err = qerr.Error(qerr.InvalidFrameData, err.Error())
+				}
 			default:
 				err = qerr.Error(qerr.InvalidFrameData, fmt.Sprintf("unknown type byte 0x%x", typeByte))
 			}
@@ -2,6 +2,7 @@ package quic
 
 import (
 	"bytes"
+	"io"
 
 	"github.com/lucas-clemente/quic-go/internal/crypto"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
@@ -198,6 +199,36 @@ var _ = Describe("Packet unpacker", func() {
 		}))
 	})
 
+	Context("unpacking PATH_PRIORITY frames", func() {
+		BeforeEach(func() {
+			f := &wire.PathPriorityFrame{PathID: 3, Priority: protocol.PathPriorityBackup}
+			Expect(f.Write(buf, protocol.VersionDeadline)).To(Succeed())
+			setData(buf.Bytes())
+		})
+
+		It("unpacks PATH_PRIORITY frames with the deadline extension", func() {
+			unpacker.version = protocol.VersionDeadline
+			packet, err := unpacker.Unpack(hdrBin, hdr, data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(packet.frames).To(Equal([]wire.Frame{
+				&wire.PathPriorityFrame{PathID: 3, Priority: protocol.PathPriorityBackup},
+			}))
+		})
+
+		It("rejects PATH_PRIORITY frames without the deadline extension", func() {
+			unpacker.version = protocol.VersionMP
+			_, err := unpacker.Unpack(hdrBin, hdr, data)
+			Expect(err).To(MatchError("InvalidFrameData: PATH_PRIORITY frame without the deadline extension"))
+		})
+
+		It("errors on truncated PATH_PRIORITY frames", func() {
+			unpacker.version = protocol.VersionDeadline
+			setData(buf.Bytes()[:2])
+			_, err := unpacker.Unpack(hdrBin, hdr, data)
+			Expect(err).To(MatchError(qerr.Error(qerr.InvalidFrameData, io.EOF.Error())))
+		})
+	})
+
 	It("errors on invalid type", func() {
 		setData([]byte{0x08})
 		_, err := unpacker.Unpack(hdrBin, hdr, data)
@@ -21,6 +21,10 @@ type path struct {
 	pathID protocol.PathID
 	conn   connection
 	sess   *session
+	// label is the label of the local interface, according to the InterfacePolicy
+	label string
+	// priority tells the schedulers if they may use this path. It is only accessed by the run loop of the session.
+	priority protocol.PathPriority
 
 	rttStats *congestion.RTTStats
 
@@ -31,7 +35,9 @@ type path struct {
 	closeChan chan *qerr.QuicError
 	runClosed chan struct{}
 
+	// potentiallyFailed is set while the health of the path is not PathHealthActive
 	potentiallyFailed utils.AtomicBool
+	health            pathHealth
 
 	sentPacket chan struct{}
 
@@ -48,25 +54,33 @@ type path struct {
 	lastNetworkActivityTime time.Time
 
 	timer *utils.Timer
+
+	// price of sending on this path, according to the CostPolicy
+	price float64
+	// totalCost is the cost spent on this path
+	totalCost float64
+
+	// reinjections counts the lost packets whose stream data was reinjected on this path,
+	// lateReinjections those that this path, the fastest one, couldn't deliver by their deadline
+	reinjections     uint64
+	lateReinjections uint64
+
+	// tracedCongestionWindow is the congestion window of the last TraceCongestionWindowChanged event
+	tracedCongestionWindow protocol.ByteCount
 }
 
 // setup initializes values that are independent of the perspective
-func (p *path) setup(oliaSenders map[protocol.PathID]*congestion.OliaSender) {
+func (p *path) setup(coupledGroup *congestion.CoupledGroup) {
 	p.rttStats = &congestion.RTTStats{}
 
-	var cong congestion.SendAlgorithm
-
-	if p.sess.version >= protocol.VersionMP && oliaSenders != nil && p.pathID != protocol.InitialPathID {
-		cong = congestion.NewOliaSender(oliaSenders, p.rttStats, protocol.InitialCongestionWindow, protocol.DefaultMaxCongestionWindow)
-		oliaSenders[p.pathID] = cong.(*congestion.OliaSender)
-	}
+	cong := p.congestionControl(coupledGroup)
 
-	sentPacketHandler := ackhandler.NewSentPacketHandler(p.rttStats, cong, p.onRTO)
+	sentPacketHandler := ackhandler.NewSentPacketHandler(p.rttStats, cong, p.onRTO, p.banditConfig())
 
 	now := time.Now()
 
 	p.sentPacketHandler = sentPacketHandler
-	p.receivedPacketHandler = ackhandler.NewReceivedPacketHandler(p.sess.version)
+	p.receivedPacketHandler = ackhandler.NewReceivedPacketHandler(p.sess.version, p.rttStats)
 
 	p.packetNumberGenerator = newPacketNumberGenerator(protocol.SkipPacketAveragePeriodLength)
 
@@ -80,11 +94,35 @@ func (p *path) setup(oliaSenders map[protocol.PathID]*congestion.OliaSender) {
 	p.open.Set(true)
 	p.potentiallyFailed.Set(false)
 
+	if p.sess.config != nil && p.conn != nil {
+		// On the server, this is the wildcard address of the listening socket, which gets the DefaultPrice
+		p.price = p.sess.config.CostPolicy.price(p.conn.LocalAddr(), p.label)
+	}
+	p.sess.trace(TracePathOpened, pathEvent(p))
+
 	// Once the path is setup, run it
 	go p.run()
 }
 
+// banditConfig returns the configuration of the fluctuation monitor of the path
+func (p *path) banditConfig() *ackhandler.BanditConfig {
+	if p.sess.config == nil {
+		return nil
+	}
+	config := &ackhandler.BanditConfig{}
+	if p.sess.config.Bandit != nil {
+		*config = *p.sess.config.Bandit
+	}
+	if config.BatchSize == 0 {
+		config.BatchSize = p.sess.config.BatchSize
+	}
+	return config
+}
+
 func (p *path) close() error {
+	if p.open.Get() {
+		p.sess.trace(TracePathClosed, pathEvent(p))
+	}
 	p.open.Set(false)
 	return nil
 }
@@ -189,8 +227,8 @@ func (p *path) handlePacketImpl(pkt *receivedPacket) error {
 	hdr := pkt.publicHeader
 	data := pkt.data
 
-	// We just received a new packet on that path, so it works
-	p.potentiallyFailed.Set(false)
+	// We just received a new packet on that path, so it might work again
+	p.onPacketReceivedHealth(pkt.rcvTime)
 
 	// Calculate packet number
 	// czy:why need here?
@@ -236,7 +274,7 @@ func (p *path) handlePacketImpl(pkt *receivedPacket) error {
 	}
 
 	//czy: Update curNotSent in sentPacketHandler, and sent it with ack
-	p.receivedPacketHandler.UpdateCurNotSent(uint16(hdr.CurNotSent))
+	p.receivedPacketHandler.UpdateCurNotSent(uint16(hdr.CurNotSent), uint16(hdr.BatchLength))
 	p.receivedPacketHandler.UpdateAlpha(uint16(hdr.Alpha))
 
 	if err != nil {
@@ -249,8 +287,7 @@ func (p *path) handlePacketImpl(pkt *receivedPacket) error {
 func (p *path) onRTO(lastSentTime time.Time) bool {
 	// Was there any activity since last sent packet?
 	if p.lastNetworkActivityTime.Before(lastSentTime) {
-		p.potentiallyFailed.Set(true)
-		p.sess.schedulePathsFrame()
+		p.suspect(time.Now())
 		return true
 	}
 	return false
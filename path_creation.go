@@ -0,0 +1,103 @@
+package quic
+
+import "net"
+
+// A PathCreationMode chooses the local and remote addresses the path manager pairs into paths
+type PathCreationMode string
+
+// The path creation modes
+const (
+	// PathCreationFullMesh creates a path between every local address and every remote address of the same IP version
+	PathCreationFullMesh PathCreationMode = "fullmesh"
+	// PathCreationPerInterface creates one path per local interface, to the first remote address of the same IP version
+	PathCreationPerInterface PathCreationMode = "per-interface"
+)
+
+// A LocalAddress is a local address paths can be created on
+type LocalAddress struct {
+	Addr net.UDPAddr
+	// Interface is the name of the network interface of the address. It is empty on the server.
+	Interface string
+	// Label is the label the InterfacePolicy gave to the address
+	Label string
+}
+
+// An AddressPair is the local and the remote address of a path
+type AddressPair struct {
+	Local  net.UDPAddr
+	Remote net.UDPAddr
+}
+
+// A PathPairingFunc chooses the paths to create. It is called whenever local or remote addresses appear.
+// The remote addresses are the address of the initial path and the addresses advertised by the peer, IPv4 first.
+// Pairs that already have a path, or whose local address is unknown, are skipped.
+type PathPairingFunc func(local []LocalAddress, remote []net.UDPAddr) []AddressPair
+
+// A PathCreationPolicy decides which paths are created between the local and the remote addresses
+type PathCreationPolicy struct {
+	// Mode is used if Pair is nil. If empty, PathCreationFullMesh is used.
+	Mode PathCreationMode
+	// Pair, if set, chooses the paths to create
+	Pair PathPairingFunc
+}
+
+// pairs returns the paths to create
+func (p *PathCreationPolicy) pairs(local []LocalAddress, remote []net.UDPAddr) []AddressPair {
+	if p == nil {
+		return fullMeshPairs(local, remote)
+	}
+	if p.Pair != nil {
+		return p.Pair(local, remote)
+	}
+	switch p.Mode {
+	case PathCreationPerInterface:
+		return perInterfacePairs(local, remote)
+	}
+	return fullMeshPairs(local, remote)
+}
+
+func fullMeshPairs(local []LocalAddress, remote []net.UDPAddr) []AddressPair {
+	var pairs []AddressPair
+	for _, l := range local {
+		for _, r := range remote {
+			if sameIPVersion(l.Addr, r) {
+				pairs = append(pairs, AddressPair{Local: l.Addr, Remote: r})
+			}
+		}
+	}
+	return pairs
+}
+
+// perInterfacePairs pairs the first address of every interface that has a remote address of the same IP version.
+// Addresses without an interface count as interfaces of their own.
+func perInterfacePairs(local []LocalAddress, remote []net.UDPAddr) []AddressPair {
+	var pairs []AddressPair
+	paired := make(map[string]bool)
+	for _, l := range local {
+		if l.Interface != "" && paired[l.Interface] {
+			continue
+		}
+		for _, r := range remote {
+			if sameIPVersion(l.Addr, r) {
+				pairs = append(pairs, AddressPair{Local: l.Addr, Remote: r})
+				paired[l.Interface] = true
+				break
+			}
+		}
+	}
+	return pairs
+}
+
+// sameIPVersion returns if a path can be created between two addresses.
+// A socket listening on an unspecified address can reach both IP versions.
+func sameIPVersion(local net.UDPAddr, remote net.UDPAddr) bool {
+	return local.IP.IsUnspecified() || getIPVersion(local.IP) == getIPVersion(remote.IP)
+}
+
+// pathCreationPolicy returns the PathCreationPolicy of a Config, which may be nil
+func pathCreationPolicy(config *Config) *PathCreationPolicy {
+	if config == nil {
+		return nil
+	}
+	return config.PathCreation
+}
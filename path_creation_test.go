@@ -0,0 +1,135 @@
+package quic
+
+import (
+	"net"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Path creation", func() {
+	udpAddr := func(s string) net.UDPAddr {
+		a, err := net.ResolveUDPAddr("udp", s)
+		Expect(err).ToNot(HaveOccurred())
+		return *a
+	}
+
+	var (
+		wlan4  LocalAddress
+		wlan6  LocalAddress
+		rmnet4 LocalAddress
+		local  []LocalAddress
+		remote []net.UDPAddr
+	)
+
+	BeforeEach(func() {
+		wlan4 = LocalAddress{Addr: udpAddr("10.0.0.1:1000"), Interface: "wlan0", Label: "wifi"}
+		wlan6 = LocalAddress{Addr: udpAddr("[2001:db8::1]:1001"), Interface: "wlan0", Label: "wifi"}
+		rmnet4 = LocalAddress{Addr: udpAddr("10.1.0.1:1002"), Interface: "rmnet0", Label: "cellular"}
+		local = []LocalAddress{wlan4, wlan6, rmnet4}
+		remote = []net.UDPAddr{udpAddr("192.0.2.1:4433"), udpAddr("192.0.2.2:4433"), udpAddr("[2001:db8::2]:4433")}
+	})
+
+	It("creates a full mesh by default", func() {
+		pairs := (*PathCreationPolicy)(nil).pairs(local, remote)
+		Expect(pairs).To(Equal([]AddressPair{
+			{Local: wlan4.Addr, Remote: remote[0]},
+			{Local: wlan4.Addr, Remote: remote[1]},
+			{Local: wlan6.Addr, Remote: remote[2]},
+			{Local: rmnet4.Addr, Remote: remote[0]},
+			{Local: rmnet4.Addr, Remote: remote[1]},
+		}))
+		Expect((&PathCreationPolicy{}).pairs(local, remote)).To(Equal(pairs))
+	})
+
+	It("creates one path per interface", func() {
+		pairs := (&PathCreationPolicy{Mode: PathCreationPerInterface}).pairs(local, remote)
+		Expect(pairs).To(Equal([]AddressPair{
+			{Local: wlan4.Addr, Remote: remote[0]},
+			{Local: rmnet4.Addr, Remote: remote[0]},
+		}))
+	})
+
+	It("uses an address of another IP version if the first one can't reach the peer", func() {
+		pairs := (&PathCreationPolicy{Mode: PathCreationPerInterface}).pairs(local, remote[2:])
+		Expect(pairs).To(Equal([]AddressPair{{Local: wlan6.Addr, Remote: remote[2]}}))
+	})
+
+	It("uses the callback", func() {
+		policy := &PathCreationPolicy{
+			Mode: PathCreationPerInterface,
+			Pair: func(l []LocalAddress, r []net.UDPAddr) []AddressPair {
+				Expect(l).To(Equal(local))
+				Expect(r).To(Equal(remote))
+				return []AddressPair{{Local: l[2].Addr, Remote: r[1]}}
+			},
+		}
+		Expect(policy.pairs(local, remote)).To(Equal([]AddressPair{{Local: rmnet4.Addr, Remote: remote[1]}}))
+	})
+
+	It("pairs unspecified addresses with both IP versions", func() {
+		any := LocalAddress{Addr: udpAddr("[::]:4433")}
+		Expect(fullMeshPairs([]LocalAddress{any}, remote)).To(HaveLen(3))
+	})
+
+	It("ignores addresses the peer advertises twice", func() {
+		addrs := appendRemoteAddr(nil, remote[0])
+		addrs = appendRemoteAddr(addrs, remote[1])
+		addrs = appendRemoteAddr(addrs, udpAddr("192.0.2.1:4433"))
+		Expect(addrs).To(Equal(remote[:2]))
+	})
+
+	Context("local addresses", func() {
+		var pconnAny net.PacketConn
+
+		BeforeEach(func() {
+			var err error
+			pconnAny, err = net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			pconnAny.Close()
+		})
+
+		It("lists the addresses of the client with their interfaces and labels", func() {
+			pcm := &pconnManager{
+				perspective: protocol.PerspectiveClient,
+				pconnAny:    pconnAny,
+				localAddrs:  []net.UDPAddr{wlan4.Addr, rmnet4.Addr},
+				labels:      map[string]string{"10.0.0.1": "wifi", "10.1.0.1": "cellular"},
+				interfaces:  map[string]string{"10.0.0.1": "wlan0", "10.1.0.1": "rmnet0"},
+			}
+			Expect(pcm.localAddresses()).To(Equal([]LocalAddress{wlan4, rmnet4}))
+		})
+
+		It("uses the pconn listening on any address on the server", func() {
+			pcm := &pconnManager{perspective: protocol.PerspectiveServer, pconnAny: pconnAny}
+			local := pcm.localAddresses()
+			Expect(local).To(HaveLen(1))
+			Expect(local[0].Addr.String()).To(Equal(pconnAny.LocalAddr().String()))
+			pconn, ok := pcm.pconnOf(local[0].Addr)
+			Expect(ok).To(BeTrue())
+			Expect(pconn).To(Equal(pconnAny))
+			_, ok = pcm.pconnOf(wlan4.Addr)
+			Expect(ok).To(BeFalse())
+		})
+
+		It("doesn't let the server create paths without a policy", func() {
+			pcm := &pconnManager{perspective: protocol.PerspectiveServer, pconnAny: pconnAny}
+			sess := &session{
+				perspective: protocol.PerspectiveServer,
+				config:      &Config{CreatePaths: true},
+				createPaths: true,
+				paths:       make(map[protocol.PathID]*path),
+			}
+			pm := &pathManager{pconnMgr: pcm, sess: sess, nxtPathID: 2, advertisedLocAddrs: make(map[string]bool)}
+			Expect(pm.handleAddAddressFrame(&wire.AddAddressFrame{IPVersion: 4, Addr: remote[0]})).To(Succeed())
+			Expect(pm.remoteAddrs4).To(Equal(remote[:1]))
+			Expect(sess.paths).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,241 @@
+package quic
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+// A PathHealth is the state of the failure detection of a path.
+// Only active paths are used by the schedulers.
+type PathHealth uint8
+
+const (
+	// PathHealthActive paths work. It is the health of new paths.
+	PathHealthActive PathHealth = iota
+	// PathHealthSuspect paths had a retransmission timeout without any packet received since the last packet sent,
+	// or were reported as failed by the peer. They are probed with PINGs.
+	PathHealthSuspect
+	// PathHealthFailed paths didn't answer FailureThreshold PINGs. They are probed with an exponential backoff.
+	PathHealthFailed
+	// PathHealthRecovering paths received a packet after they were suspect or failed.
+	// They are active again once RestoreThreshold PINGs in a row were answered.
+	PathHealthRecovering
+)
+
+func (h PathHealth) String() string {
+	switch h {
+	case PathHealthActive:
+		return "active"
+	case PathHealthSuspect:
+		return "suspect"
+	case PathHealthFailed:
+		return "failed"
+	case PathHealthRecovering:
+		return "recovering"
+	}
+	return "unknown"
+}
+
+// The defaults of the PathHealthConfig
+const (
+	DefaultPathProbeInterval    = 100 * time.Millisecond
+	DefaultPathMaxProbeInterval = 5 * time.Second
+	DefaultPathFailureThreshold = 3
+	DefaultPathRestoreThreshold = 3
+)
+
+// PathHealthConfig configures the failure detection of the paths.
+// Zero values are replaced by the defaults.
+type PathHealthConfig struct {
+	// ProbeInterval is the time between the PINGs sent on a path that isn't active.
+	// It is at least twice the smoothed RTT of the path. It doubles after every unanswered PING,
+	// until the path is active again, so that a flapping path takes longer and longer to recover.
+	ProbeInterval time.Duration
+	// MaxProbeInterval caps the exponential backoff of the PINGs
+	MaxProbeInterval time.Duration
+	// FailureThreshold is the number of unanswered PINGs after which a suspect path is failed
+	FailureThreshold int
+	// RestoreThreshold is the number of PINGs in a row a recovering path must answer to be active again.
+	// A single unanswered PING, or a retransmission timeout, makes it failed again.
+	RestoreThreshold int
+}
+
+// populate returns a copy of the config with the defaults filled in
+func (c *PathHealthConfig) populate() *PathHealthConfig {
+	config := &PathHealthConfig{}
+	if c != nil {
+		*config = *c
+	}
+	if config.ProbeInterval <= 0 {
+		config.ProbeInterval = DefaultPathProbeInterval
+	}
+	if config.MaxProbeInterval < config.ProbeInterval {
+		config.MaxProbeInterval = utils.MaxDuration(DefaultPathMaxProbeInterval, config.ProbeInterval)
+	}
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = DefaultPathFailureThreshold
+	}
+	if config.RestoreThreshold <= 0 {
+		config.RestoreThreshold = DefaultPathRestoreThreshold
+	}
+	return config
+}
+
+// pathHealth is the failure detection state machine of a path. It is only accessed by the run loop of the session.
+type pathHealth struct {
+	state PathHealth
+
+	// probeInterval is the current time between two PINGs
+	probeInterval time.Duration
+	nextProbe     time.Time
+	// probeOutstanding is set when a PING was sent, until a packet is received on the path
+	probeOutstanding bool
+	// unanswered counts the PINGs of a suspect path that were not answered,
+	// answered the PINGs in a row a recovering path answered
+	unanswered int
+	answered   int
+}
+
+func (p *path) healthConfig() *PathHealthConfig {
+	if p.sess.config == nil {
+		return (*PathHealthConfig)(nil).populate()
+	}
+	return p.sess.config.PathHealth.populate()
+}
+
+// baseProbeInterval is the time between PINGs before any backoff
+func (p *path) baseProbeInterval() time.Duration {
+	interval := p.healthConfig().ProbeInterval
+	if p.rttStats != nil {
+		interval = utils.MaxDuration(interval, 2*p.rttStats.SmoothedRTT())
+	}
+	return interval
+}
+
+// setHealth changes the health of the path, keeps potentiallyFailed in sync and traces the transition
+func (p *path) setHealth(health PathHealth) {
+	old := p.health.state
+	if old == health {
+		return
+	}
+	p.health.state = health
+	utils.Infof("Path %x of connection %x is %s, was %s", p.pathID, p.sess.connectionID, health, old)
+	p.sess.trace(TracePathHealthChanged, &PathHealthEvent{PathID: p.pathID, Label: p.label, OldHealth: old, NewHealth: health})
+	if failed := health != PathHealthActive; failed != p.potentiallyFailed.Get() {
+		p.potentiallyFailed.Set(failed)
+		// the peer learns about it from the RemoteRTTs of the PATHS frame
+		p.sess.schedulePathsFrame()
+	}
+}
+
+// suspect starts probing an active path. It is called after a retransmission timeout
+// without any packet received since the last packet sent, or if the peer reports the path as failed.
+func (p *path) suspect(now time.Time) {
+	switch p.health.state {
+	case PathHealthActive:
+		p.health.probeInterval = p.baseProbeInterval()
+		p.health.nextProbe = now
+		p.health.probeOutstanding = false
+		p.health.unanswered = 0
+		p.setHealth(PathHealthSuspect)
+	case PathHealthRecovering:
+		p.fail()
+	}
+}
+
+func (p *path) fail() {
+	p.health.answered = 0
+	p.setHealth(PathHealthFailed)
+}
+
+// onPacketReceivedHealth updates the health when a packet was received on the path
+func (p *path) onPacketReceivedHealth(now time.Time) {
+	switch p.health.state {
+	case PathHealthSuspect, PathHealthFailed:
+		p.health.nextProbe = now.Add(p.health.probeInterval)
+		p.health.probeOutstanding = false
+		p.health.unanswered = 0
+		p.health.answered = 1
+		p.setHealth(PathHealthRecovering)
+		p.maybeRestore()
+	case PathHealthRecovering:
+		if p.health.probeOutstanding {
+			p.health.probeOutstanding = false
+			p.health.answered++
+			p.maybeRestore()
+		}
+	}
+}
+
+func (p *path) maybeRestore() {
+	if p.health.answered >= p.healthConfig().RestoreThreshold {
+		p.health.answered = 0
+		p.setHealth(PathHealthActive)
+	}
+}
+
+// nextProbeTime returns when the next PING is due, or the zero time if the path is active
+func (p *path) nextProbeTime() time.Time {
+	if p.health.state == PathHealthActive || !p.open.Get() {
+		return time.Time{}
+	}
+	return p.health.nextProbe
+}
+
+// probeDue returns if a PING must be sent on the path now, and updates the health if the last PING was not answered
+func (p *path) probeDue(now time.Time) bool {
+	next := p.nextProbeTime()
+	if next.IsZero() || now.Before(next) {
+		return false
+	}
+	if p.health.probeOutstanding {
+		config := p.healthConfig()
+		p.health.probeInterval = utils.MinDuration(2*p.health.probeInterval, config.MaxProbeInterval)
+		switch p.health.state {
+		case PathHealthSuspect:
+			p.health.unanswered++
+			if p.health.unanswered >= config.FailureThreshold {
+				p.fail()
+			}
+		case PathHealthRecovering:
+			p.fail()
+		}
+	}
+	p.health.probeOutstanding = true
+	p.health.nextProbe = now.Add(p.health.probeInterval)
+	return true
+}
+
+// nextPathProbe returns when the next PING is due on any path, or the zero time if no path is probed
+func (s *session) nextPathProbe() time.Time {
+	s.pathsLock.RLock()
+	defer s.pathsLock.RUnlock()
+	var next time.Time
+	for _, pth := range s.paths {
+		if t := pth.nextProbeTime(); !t.IsZero() && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+	return next
+}
+
+// probePaths sends the PINGs that are due on the paths that are not active
+func (s *session) probePaths(now time.Time) error {
+	s.pathsLock.RLock()
+	paths := make([]*path, 0, len(s.paths))
+	for _, pth := range s.paths {
+		paths = append(paths, pth)
+	}
+	s.pathsLock.RUnlock()
+	// probeDue may schedule a PATHS frame, which takes the pathsLock
+	for _, pth := range paths {
+		if !pth.probeDue(now) {
+			continue
+		}
+		if err := s.sendPing(pth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package quic
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/congestion"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Path health", func() {
+	var (
+		sess   *session
+		pth    *path
+		tracer *MemoryTracer
+		now    time.Time
+	)
+
+	BeforeEach(func() {
+		tracer = &MemoryTracer{}
+		sess = &session{
+			config: &Config{
+				PathHealth: &PathHealthConfig{ProbeInterval: 100 * time.Millisecond, MaxProbeInterval: 300 * time.Millisecond, FailureThreshold: 2, RestoreThreshold: 2},
+				Tracer:     tracer,
+			},
+			paths:        make(map[protocol.PathID]*path),
+			streamFramer: newStreamFramer(nil, nil),
+		}
+		pth = &path{pathID: 1, sess: sess, rttStats: &congestion.RTTStats{}}
+		pth.open.Set(true)
+		sess.paths[1] = pth
+		now = time.Now()
+	})
+
+	healthChanges := func() []PathHealth {
+		var changes []PathHealth
+		for _, e := range tracer.Events() {
+			Expect(e.Type).To(Equal(TracePathHealthChanged))
+			changes = append(changes, e.Data.(*PathHealthEvent).NewHealth)
+		}
+		return changes
+	}
+
+	It("fills in the defaults", func() {
+		config := (*PathHealthConfig)(nil).populate()
+		Expect(config).To(Equal(&PathHealthConfig{
+			ProbeInterval:    DefaultPathProbeInterval,
+			MaxProbeInterval: DefaultPathMaxProbeInterval,
+			FailureThreshold: DefaultPathFailureThreshold,
+			RestoreThreshold: DefaultPathRestoreThreshold,
+		}))
+		config = (&PathHealthConfig{ProbeInterval: 10 * time.Second}).populate()
+		Expect(config.MaxProbeInterval).To(Equal(10 * time.Second))
+	})
+
+	It("isn't probed while it is active", func() {
+		Expect(pth.nextProbeTime()).To(BeZero())
+		Expect(pth.probeDue(now)).To(BeFalse())
+		Expect(sess.nextPathProbe()).To(BeZero())
+	})
+
+	It("becomes suspect after a retransmission timeout without activity, and tells the peer", func() {
+		pth.lastNetworkActivityTime = now.Add(-time.Second)
+		Expect(pth.onRTO(now.Add(-time.Second / 2))).To(BeTrue())
+		Expect(pth.health.state).To(Equal(PathHealthSuspect))
+		Expect(pth.potentiallyFailed.Get()).To(BeTrue())
+		Expect(sess.streamFramer.PopPathsFrame().RemoteRTTs).To(Equal([]time.Duration{time.Hour}))
+		Expect(sess.nextPathProbe()).ToNot(BeZero())
+		Expect(healthChanges()).To(Equal([]PathHealth{PathHealthSuspect}))
+	})
+
+	It("stays active after a retransmission timeout if packets were received", func() {
+		pth.lastNetworkActivityTime = now
+		Expect(pth.onRTO(now.Add(-time.Second))).To(BeFalse())
+		Expect(pth.health.state).To(Equal(PathHealthActive))
+	})
+
+	It("becomes suspect if the peer reports it as failed", func() {
+		sess.remoteRTTs = make(map[protocol.PathID]time.Duration)
+		Expect(sess.handleFrames([]wire.Frame{&wire.PathsFrame{NumPaths: 1, PathIDs: []protocol.PathID{1}, RemoteRTTs: []time.Duration{time.Hour}}}, nil)).To(Succeed())
+		Expect(pth.health.state).To(Equal(PathHealthSuspect))
+	})
+
+	It("fails after unanswered probes, and backs off", func() {
+		pth.suspect(now)
+		Expect(pth.probeDue(now)).To(BeTrue())
+		Expect(pth.probeDue(now.Add(50 * time.Millisecond))).To(BeFalse())
+		// the first probe is not answered
+		Expect(pth.probeDue(now.Add(100 * time.Millisecond))).To(BeTrue())
+		Expect(pth.health.state).To(Equal(PathHealthSuspect))
+		Expect(pth.nextProbeTime()).To(Equal(now.Add(300 * time.Millisecond)))
+		// the second one neither
+		Expect(pth.probeDue(now.Add(300 * time.Millisecond))).To(BeTrue())
+		Expect(pth.health.state).To(Equal(PathHealthFailed))
+		Expect(pth.nextProbeTime()).To(Equal(now.Add(600 * time.Millisecond)))
+		Expect(healthChanges()).To(Equal([]PathHealth{PathHealthSuspect, PathHealthFailed}))
+	})
+
+	It("probes paths with a long RTT less often", func() {
+		pth.rttStats.UpdateRTT(200*time.Millisecond, 0, now)
+		pth.suspect(now)
+		Expect(pth.probeDue(now)).To(BeTrue())
+		Expect(pth.nextProbeTime()).To(Equal(now.Add(400 * time.Millisecond)))
+	})
+
+	It("is restored once enough probes were answered", func() {
+		pth.suspect(now)
+		Expect(pth.probeDue(now)).To(BeTrue())
+		pth.onPacketReceivedHealth(now.Add(10 * time.Millisecond))
+		Expect(pth.health.state).To(Equal(PathHealthRecovering))
+		Expect(pth.potentiallyFailed.Get()).To(BeTrue())
+		// packets that don't answer a probe don't count
+		pth.onPacketReceivedHealth(now.Add(20 * time.Millisecond))
+		Expect(pth.health.state).To(Equal(PathHealthRecovering))
+		Expect(pth.probeDue(now.Add(110 * time.Millisecond))).To(BeTrue())
+		pth.onPacketReceivedHealth(now.Add(120 * time.Millisecond))
+		Expect(pth.health.state).To(Equal(PathHealthActive))
+		Expect(pth.potentiallyFailed.Get()).To(BeFalse())
+		Expect(pth.nextProbeTime()).To(BeZero())
+		Expect(healthChanges()).To(Equal([]PathHealth{PathHealthSuspect, PathHealthRecovering, PathHealthActive}))
+	})
+
+	It("fails again if a recovering path flaps", func() {
+		pth.suspect(now)
+		Expect(pth.probeDue(now)).To(BeTrue())
+		pth.onPacketReceivedHealth(now.Add(10 * time.Millisecond))
+		Expect(pth.probeDue(now.Add(110 * time.Millisecond))).To(BeTrue())
+		Expect(pth.probeDue(now.Add(210 * time.Millisecond))).To(BeTrue())
+		Expect(pth.health.state).To(Equal(PathHealthFailed))
+		pth.onPacketReceivedHealth(now.Add(220 * time.Millisecond))
+		Expect(pth.health.state).To(Equal(PathHealthRecovering))
+		pth.suspect(now.Add(230 * time.Millisecond))
+		Expect(pth.health.state).To(Equal(PathHealthFailed))
+		Expect(healthChanges()).To(Equal([]PathHealth{PathHealthSuspect, PathHealthRecovering, PathHealthFailed, PathHealthRecovering, PathHealthFailed}))
+	})
+
+	It("isn't used by the schedulers until it is active", func() {
+		pth.suspect(now)
+		info := PathInfo{PathID: 1, SendingAllowed: true, Health: pth.health.state, PotentiallyFailed: pth.potentiallyFailed.Get()}
+		Expect(info.canSend()).To(BeFalse())
+	})
+})
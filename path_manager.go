@@ -23,8 +23,8 @@ type pathManager struct {
 
 	advertisedLocAddrs map[string]bool
 
-	// TODO (QDC): find a cleaner way
-	oliaSenders map[protocol.PathID]*congestion.OliaSender
+	// coupledGroup couples the congestion controllers of the paths
+	coupledGroup *congestion.CoupledGroup
 
 	handshakeCompleted chan struct{}
 	runClosed          chan struct{}
@@ -33,8 +33,8 @@ type pathManager struct {
 
 func (pm *pathManager) setup(conn connection) {
 	// Initial PathID is 0
-	// PathIDs of client-initiated paths are even
-	// those of server-initiated paths odd
+	// PathIDs of client-initiated paths are odd
+	// those of server-initiated paths even
 	if pm.sess.perspective == protocol.PerspectiveClient {
 		pm.nxtPathID = 1
 	} else {
@@ -49,7 +49,7 @@ func (pm *pathManager) setup(conn connection) {
 	pm.timer = time.NewTimer(0)
 	pm.nbPaths = 0
 
-	pm.oliaSenders = make(map[protocol.PathID]*congestion.OliaSender)
+	pm.coupledGroup = congestion.NewCoupledGroup()
 
 	// Setup the first path of the connection
 	pm.sess.paths[protocol.InitialPathID] = &path{
@@ -59,7 +59,7 @@ func (pm *pathManager) setup(conn connection) {
 	}
 
 	// Setup this first path
-	pm.sess.paths[protocol.InitialPathID].setup(pm.oliaSenders)
+	pm.sess.paths[protocol.InitialPathID].setup(pm.coupledGroup)
 
 	// With the initial path, get the remoteAddr to create paths accordingly
 	if conn.RemoteAddr() != nil {
@@ -100,6 +100,7 @@ runLoop:
 		case <-pm.runClosed:
 			break runLoop
 		case <-pm.pconnMgr.changePaths:
+			pm.closeStalePaths()
 			if pm.sess.createPaths {
 				pm.createPaths()
 			}
@@ -129,35 +130,56 @@ func (pm *pathManager) advertiseAddresses() {
 	}
 }
 
-func (pm *pathManager) createPath(locAddr net.UDPAddr, remAddr net.UDPAddr) error {
+// createPath creates a path, unless an open path already uses the addresses, and returns its PathID.
+// The mutex of the pconnManager must be held.
+func (pm *pathManager) createPath(locAddr net.UDPAddr, remAddr net.UDPAddr) (protocol.PathID, error) {
+	pconn, ok := pm.pconnMgr.pconnOf(locAddr)
+	if !ok {
+		return 0, errUnknownLocalAddr
+	}
+	if !sameIPVersion(locAddr, remAddr) {
+		return 0, errIPVersionMismatch
+	}
 	// First check that the path does not exist yet
 	pm.sess.pathsLock.Lock()
 	defer pm.sess.pathsLock.Unlock()
 	paths := pm.sess.paths
-	for _, pth := range paths {
+	for pathID, pth := range paths {
+		if !pth.open.Get() {
+			// A closed path can't be used again, but its addresses can
+			continue
+		}
 		locAddrPath := pth.conn.LocalAddr().String()
 		remAddrPath := pth.conn.RemoteAddr().String()
 		if locAddr.String() == locAddrPath && remAddr.String() == remAddrPath {
 			// Path already exists, so don't create it again
-			return nil
+			return pathID, nil
 		}
 	}
 	// No matching path, so create it
 	pth := &path{
 		pathID: pm.nxtPathID,
 		sess:   pm.sess,
-		conn:   &conn{pconn: pm.pconnMgr.pconns[locAddr.String()], currentAddr: &remAddr},
+		conn:   &conn{pconn: pconn, currentAddr: &remAddr},
+		label:  pm.pconnMgr.labelOf(&locAddr),
 	}
-	pth.setup(pm.oliaSenders)
+	pth.setup(pm.coupledGroup)
 	pm.sess.paths[pm.nxtPathID] = pth
 	if utils.Debug() {
-		utils.Debugf("Created path %x on %s to %s", pm.nxtPathID, locAddr.String(), remAddr.String())
+		utils.Debugf("Created path %x (%s) on %s to %s", pm.nxtPathID, pth.label, locAddr.String(), remAddr.String())
 	}
 	pm.nxtPathID += 2
 	// Send a PING frame to get latency info about the new path and informing the
 	// peer of its existence
 	// Because we hold pathsLock, it is safe to send packet now
-	return pm.sess.sendPing(pth)
+	return pth.pathID, pm.sess.sendPing(pth)
+}
+
+// openPath opens a path requested by the application
+func (pm *pathManager) openPath(locAddr net.UDPAddr, remAddr net.UDPAddr) (protocol.PathID, error) {
+	pm.pconnMgr.mutex.Lock()
+	defer pm.pconnMgr.mutex.Unlock()
+	return pm.createPath(locAddr, remAddr)
 }
 
 func (pm *pathManager) createPaths() error {
@@ -165,42 +187,80 @@ func (pm *pathManager) createPaths() error {
 		utils.Debugf("Path manager tries to create paths")
 	}
 
-	// XXX (QDC): don't let the server create paths for now
-	if pm.sess.perspective == protocol.PerspectiveServer {
-		pm.advertiseAddresses()
+	// Tell the peer about new local addresses
+	pm.advertiseAddresses()
+	policy := pathCreationPolicy(pm.sess.config)
+	// The server only opens paths toward the addresses advertised by the client if asked to
+	if pm.sess.perspective == protocol.PerspectiveServer && policy == nil {
 		return nil
 	}
-	// TODO (QDC): clearly not optimali
 	pm.pconnMgr.mutex.Lock()
 	defer pm.pconnMgr.mutex.Unlock()
-	for _, locAddr := range pm.pconnMgr.localAddrs {
-		version := getIPVersion(locAddr.IP)
-		if version == 4 {
-			for _, remAddr := range pm.remoteAddrs4 {
-				err := pm.createPath(locAddr, remAddr)
-				if err != nil {
-					return err
-				}
-			}
-		} else {
-			for _, remAddr := range pm.remoteAddrs6 {
-				err := pm.createPath(locAddr, remAddr)
-				if err != nil {
-					return err
-				}
+	remote := make([]net.UDPAddr, 0, len(pm.remoteAddrs4)+len(pm.remoteAddrs6))
+	remote = append(remote, pm.remoteAddrs4...)
+	remote = append(remote, pm.remoteAddrs6...)
+	for _, pair := range policy.pairs(pm.pconnMgr.localAddresses(), remote) {
+		_, err := pm.createPath(pair.Local, pair.Remote)
+		switch err {
+		case nil:
+		case errUnknownLocalAddr, errIPVersionMismatch:
+			// The PathPairingFunc returned a pair that can't be used
+			if utils.Debug() {
+				utils.Debugf("Path manager can't create a path on %s to %s: %v", pair.Local.String(), pair.Remote.String(), err)
 			}
+		default:
+			return err
 		}
 	}
 	pm.sess.schedulePathsFrame()
 	return nil
 }
 
+// closeStalePaths closes the paths whose local address disappeared, and sends a CLOSE_PATH frame for them
+func (pm *pathManager) closeStalePaths() {
+	pm.pconnMgr.mutex.Lock()
+	live := map[net.PacketConn]bool{pm.pconnMgr.pconnAny: true}
+	for _, pconn := range pm.pconnMgr.pconns {
+		live[pconn] = true
+	}
+	// An address that comes back has to be advertised again
+	for locAddr := range pm.advertisedLocAddrs {
+		if _, ok := pm.pconnMgr.pconns[locAddr]; !ok {
+			delete(pm.advertisedLocAddrs, locAddr)
+		}
+	}
+	pm.pconnMgr.mutex.Unlock()
+
+	var stale []protocol.PathID
+	pm.sess.pathsLock.RLock()
+	for pathID, pth := range pm.sess.paths {
+		c, ok := pth.conn.(*conn)
+		if !ok || pathID == protocol.InitialPathID || !pth.open.Get() {
+			continue
+		}
+		if !live[c.pconn] {
+			stale = append(stale, pathID)
+		}
+	}
+	pm.sess.pathsLock.RUnlock()
+
+	for _, pathID := range stale {
+		if utils.Debug() {
+			utils.Debugf("Closing path %x, its local address disappeared", pathID)
+		}
+		pm.sess.requestClosePath(pathID)
+	}
+}
+
 func (pm *pathManager) createPathFromRemote(p *receivedPacket) (*path, error) {
-	pm.sess.pathsLock.Lock()
-	defer pm.sess.pathsLock.Unlock()
 	localPconn := p.rcvPconn
 	remoteAddr := p.remoteAddr
 	pathID := p.publicHeader.PathID
+	// The mutex of the pconnManager is always taken before pathsLock, so look up the label first
+	label := pm.pconnMgr.label(localPconn.LocalAddr())
+
+	pm.sess.pathsLock.Lock()
+	defer pm.sess.pathsLock.Unlock()
 
 	// Sanity check: pathID should not exist yet
 	_, ko := pm.sess.paths[pathID]
@@ -220,9 +280,10 @@ func (pm *pathManager) createPathFromRemote(p *receivedPacket) (*path, error) {
 		pathID: pathID,
 		sess:   pm.sess,
 		conn:   &conn{pconn: localPconn, currentAddr: remoteAddr},
+		label:  label,
 	}
 
-	pth.setup(pm.oliaSenders)
+	pth.setup(pm.coupledGroup)
 	pm.sess.paths[pathID] = pth
 
 	if utils.Debug() {
@@ -235,9 +296,9 @@ func (pm *pathManager) createPathFromRemote(p *receivedPacket) (*path, error) {
 func (pm *pathManager) handleAddAddressFrame(f *wire.AddAddressFrame) error {
 	switch f.IPVersion {
 	case 4:
-		pm.remoteAddrs4 = append(pm.remoteAddrs4, f.Addr)
+		pm.remoteAddrs4 = appendRemoteAddr(pm.remoteAddrs4, f.Addr)
 	case 6:
-		pm.remoteAddrs6 = append(pm.remoteAddrs6, f.Addr)
+		pm.remoteAddrs6 = appendRemoteAddr(pm.remoteAddrs6, f.Addr)
 	default:
 		return wire.ErrUnknownIPVersion
 	}
@@ -247,6 +308,16 @@ func (pm *pathManager) handleAddAddressFrame(f *wire.AddAddressFrame) error {
 	return nil
 }
 
+// appendRemoteAddr adds a remote address, unless the peer already advertised it
+func appendRemoteAddr(addrs []net.UDPAddr, addr net.UDPAddr) []net.UDPAddr {
+	for _, a := range addrs {
+		if a.String() == addr.String() {
+			return addrs
+		}
+	}
+	return append(addrs, addr)
+}
+
 func (pm *pathManager) closePath(pthID protocol.PathID) error {
 	pm.sess.pathsLock.RLock()
 	defer pm.sess.pathsLock.RUnlock()
@@ -260,6 +331,9 @@ func (pm *pathManager) closePath(pthID protocol.PathID) error {
 	if pth.open.Get() {
 		pth.closeChan <- nil
 	}
+	if pm.coupledGroup != nil {
+		pm.coupledGroup.Remove(pthID)
+	}
 
 	return nil
 }
@@ -0,0 +1,79 @@
+package quic
+
+import (
+	"errors"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+)
+
+var (
+	errUnknownPath         = errors.New("unknown or closed path")
+	errUnknownPathPriority = errors.New("unknown path priority")
+	errSessionClosed       = errors.New("session closed")
+)
+
+// a pathPriorityRequest is answered by the run loop of the session
+type pathPriorityRequest struct {
+	pathID   protocol.PathID
+	priority protocol.PathPriority
+	err      chan error
+}
+
+// SetPathPriority changes the priority of an open path, and sends a PATH_PRIORITY frame to the peer
+func (s *session) SetPathPriority(pathID PathID, priority PathPriority) error {
+	if priority > PathPriorityDisabled {
+		return errUnknownPathPriority
+	}
+	r := pathPriorityRequest{pathID: pathID, priority: priority, err: make(chan error, 1)}
+	select {
+	case s.pathPriorityRequests <- r:
+		return <-r.err
+	case <-s.ctx.Done():
+		return errSessionClosed
+	}
+}
+
+// setPathPriority must be called from the run loop.
+// If signal is set, the peer is told about the change.
+func (s *session) setPathPriority(pathID protocol.PathID, priority protocol.PathPriority, signal bool) error {
+	s.pathsLock.RLock()
+	pth, ok := s.paths[pathID]
+	s.pathsLock.RUnlock()
+	if !ok || !pth.open.Get() {
+		return errUnknownPath
+	}
+	if pth.priority == priority {
+		return nil
+	}
+	if utils.Debug() {
+		utils.Debugf("Path %x is now %s", pathID, priority)
+	}
+	pth.priority = priority
+	// Without the deadline extension, the peer doesn't know the PATH_PRIORITY frame and the priority stays local
+	if signal && s.version.UsesDeadlines() {
+		s.streamFramer.AddPathPriorityFrameForTransmission(pathID, priority)
+	}
+	return nil
+}
+
+// retransmitPathPriorityFrame queues a lost PATH_PRIORITY frame again.
+// The frame is dropped if the path was closed or its priority changed since: the peer then already got, or will get, the newer priority.
+func (s *session) retransmitPathPriorityFrame(frame *wire.PathPriorityFrame) {
+	s.pathsLock.RLock()
+	pth, ok := s.paths[frame.PathID]
+	s.pathsLock.RUnlock()
+	if !ok || !pth.open.Get() || pth.priority != frame.Priority {
+		return
+	}
+	s.streamFramer.AddPathPriorityFrameForTransmission(frame.PathID, pth.priority)
+}
+
+func (s *session) handlePathPriorityFrame(frame *wire.PathPriorityFrame) error {
+	// The path may have been closed in the meantime
+	if err := s.setPathPriority(frame.PathID, frame.Priority, false); err != nil && err != errUnknownPath {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package quic
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Path priorities", func() {
+	var snapshot *PathSnapshot
+
+	BeforeEach(func() {
+		snapshot = &PathSnapshot{
+			FromPath:        NoPath,
+			RemainingBudget: math.Inf(1),
+			BatchBudget:     math.Inf(1),
+			Paths: []PathInfo{
+				{PathID: 0, SendingAllowed: true},
+				{PathID: 1, SendingAllowed: true, SmoothedRTT: 200 * time.Millisecond, Alpha: 1, CongestionWindow: 10 * protocol.MaxPacketSize},
+				{PathID: 3, SendingAllowed: true, SmoothedRTT: 20 * time.Millisecond, Alpha: 1, CongestionWindow: 10 * protocol.MaxPacketSize, Priority: PathPriorityBackup},
+				{PathID: 5, SendingAllowed: true, SmoothedRTT: 10 * time.Millisecond, Alpha: 1, CongestionWindow: 10 * protocol.MaxPacketSize, Priority: PathPriorityDisabled},
+			},
+		}
+	})
+
+	pathIDs := func(paths []PathInfo) []PathID {
+		ids := make([]PathID, len(paths))
+		for i := range paths {
+			ids[i] = paths[i].PathID
+		}
+		return ids
+	}
+
+	Context("in the snapshot", func() {
+		It("leaves out the backup paths while an active path can send", func() {
+			snapshot.applyPriorities()
+			Expect(pathIDs(snapshot.Paths)).To(Equal([]PathID{0, 1}))
+			Expect(pathIDs(snapshot.BackupPaths)).To(Equal([]PathID{3}))
+			Expect(snapshot.Path(3)).ToNot(BeNil())
+			Expect(snapshot.Path(5)).To(BeNil())
+		})
+
+		It("includes the backup paths if no active path can send", func() {
+			snapshot.Paths[1].PotentiallyFailed = true
+			snapshot.applyPriorities()
+			Expect(pathIDs(snapshot.Paths)).To(Equal([]PathID{0, 1, 3}))
+			Expect(snapshot.BackupPaths).To(BeEmpty())
+		})
+
+		It("lets the rtt scheduler fall back to a backup path", func() {
+			snapshot.applyPriorities()
+			Expect((&rttScheduler{}).SelectPath(snapshot)).To(Equal(PathID(1)))
+			snapshot = &PathSnapshot{Paths: append(snapshot.Paths, snapshot.BackupPaths...)}
+			snapshot.Paths[1].SendingAllowed = false
+			snapshot.applyPriorities()
+			Expect((&rttScheduler{}).SelectPath(snapshot)).To(Equal(PathID(3)))
+		})
+	})
+
+	Context("BatchLinOpt", func() {
+		It("doesn't use backup paths if the active paths meet the deadlines", func() {
+			snapshot.applyPriorities()
+			paths := newBatchLinOptScheduler(nil).(BatchPathScheduler).SelectBatch(snapshot, []time.Duration{200 * time.Millisecond, 300 * time.Millisecond})
+			Expect(paths).To(Equal([]PathID{1, 1}))
+		})
+
+		It("uses backup paths for deadlines the active paths can't meet", func() {
+			tracer := &MemoryTracer{}
+			snapshot.applyPriorities()
+			sch := newBatchLinOptScheduler(&Config{Tracer: tracer}).(BatchPathScheduler)
+			paths := sch.SelectBatch(snapshot, []time.Duration{50 * time.Millisecond, 60 * time.Millisecond})
+			Expect(paths).To(Equal([]PathID{3, 3}))
+			Expect(tracer.Events()).To(HaveLen(2))
+			Expect(tracer.Events()[1].Data.(*LPSolvedEvent).Paths).To(Equal([]PathID{1, 3}))
+		})
+	})
+
+	Context("reinjection", func() {
+		It("only reinjects on a backup path if no active path is in time", func() {
+			snapshot.applyPriorities()
+			pathID, inTime := selectReinjectionPath(snapshot, time.Second)
+			Expect(pathID).To(Equal(PathID(1)))
+			Expect(inTime).To(BeTrue())
+			pathID, inTime = selectReinjectionPath(snapshot, 50*time.Millisecond)
+			Expect(pathID).To(Equal(PathID(3)))
+			Expect(inTime).To(BeTrue())
+		})
+	})
+
+	Context("in the session", func() {
+		var sess *session
+
+		BeforeEach(func() {
+			sess = &session{
+				paths:                make(map[protocol.PathID]*path),
+				streamFramer:         newStreamFramer(nil, nil),
+				pathPriorityRequests: make(chan pathPriorityRequest),
+				version:              protocol.VersionDeadline,
+			}
+			sess.ctx, sess.ctxCancel = context.WithCancel(context.Background())
+			pth := &path{pathID: 1, sess: sess}
+			pth.open.Set(true)
+			sess.paths[1] = pth
+		})
+
+		AfterEach(func() {
+			sess.ctxCancel()
+		})
+
+		It("changes the priority and tells the peer", func() {
+			go func() {
+				defer GinkgoRecover()
+				r := <-sess.pathPriorityRequests
+				r.err <- sess.setPathPriority(r.pathID, r.priority, true)
+			}()
+			Expect(sess.SetPathPriority(1, PathPriorityBackup)).To(Succeed())
+			Expect(sess.paths[1].priority).To(Equal(PathPriorityBackup))
+			Expect(sess.streamFramer.PopPathPriorityFrame()).To(Equal(&wire.PathPriorityFrame{PathID: 1, Priority: PathPriorityBackup}))
+			Expect(sess.streamFramer.PopPathPriorityFrame()).To(BeNil())
+		})
+
+		It("keeps the priority local without the deadline extension", func() {
+			sess.version = protocol.VersionMP
+			Expect(sess.setPathPriority(1, PathPriorityBackup, true)).To(Succeed())
+			Expect(sess.paths[1].priority).To(Equal(PathPriorityBackup))
+			Expect(sess.streamFramer.PopPathPriorityFrame()).To(BeNil())
+		})
+
+		It("doesn't tell the peer if the priority didn't change", func() {
+			Expect(sess.setPathPriority(1, PathPriorityActive, true)).To(Succeed())
+			Expect(sess.streamFramer.PopPathPriorityFrame()).To(BeNil())
+		})
+
+		It("rejects unknown paths and priorities", func() {
+			Expect(sess.setPathPriority(3, PathPriorityBackup, true)).To(MatchError(errUnknownPath))
+			sess.paths[1].open.Set(false)
+			Expect(sess.setPathPriority(1, PathPriorityBackup, true)).To(MatchError(errUnknownPath))
+			Expect(sess.SetPathPriority(1, 7)).To(MatchError(errUnknownPathPriority))
+		})
+
+		It("fails once the session is closed", func() {
+			sess.ctxCancel()
+			Expect(sess.SetPathPriority(1, PathPriorityDisabled)).To(MatchError(errSessionClosed))
+		})
+
+		Context("retransmitting PATH_PRIORITY frames", func() {
+			It("queues the current priority again", func() {
+				sess.paths[1].priority = PathPriorityBackup
+				sess.retransmitPathPriorityFrame(&wire.PathPriorityFrame{PathID: 1, Priority: PathPriorityBackup})
+				Expect(sess.streamFramer.PopPathPriorityFrame()).To(Equal(&wire.PathPriorityFrame{PathID: 1, Priority: PathPriorityBackup}))
+			})
+
+			It("drops frames for a priority the path doesn't have anymore", func() {
+				sess.paths[1].priority = PathPriorityDisabled
+				sess.retransmitPathPriorityFrame(&wire.PathPriorityFrame{PathID: 1, Priority: PathPriorityBackup})
+				Expect(sess.streamFramer.PopPathPriorityFrame()).To(BeNil())
+			})
+
+			It("drops frames for closed paths", func() {
+				sess.paths[1].priority = PathPriorityBackup
+				sess.paths[1].open.Set(false)
+				sess.retransmitPathPriorityFrame(&wire.PathPriorityFrame{PathID: 1, Priority: PathPriorityBackup})
+				sess.retransmitPathPriorityFrame(&wire.PathPriorityFrame{PathID: 5, Priority: PathPriorityBackup})
+				Expect(sess.streamFramer.PopPathPriorityFrame()).To(BeNil())
+			})
+		})
+
+		It("applies the priority requested by the peer", func() {
+			Expect(sess.handlePathPriorityFrame(&wire.PathPriorityFrame{PathID: 1, Priority: PathPriorityDisabled})).To(Succeed())
+			Expect(sess.paths[1].priority).To(Equal(PathPriorityDisabled))
+			Expect(sess.streamFramer.PopPathPriorityFrame()).To(BeNil())
+			Expect(sess.handlePathPriorityFrame(&wire.PathPriorityFrame{PathID: 9, Priority: PathPriorityBackup})).To(Succeed())
+		})
+	})
+})
@@ -0,0 +1,298 @@
+package quic
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// NoPath is returned by a PathScheduler to indicate that no packet should be sent right now
+const NoPath = PathID(255)
+
+// PathInfo is a snapshot of the state of a path, as seen by a PathScheduler
+type PathInfo struct {
+	PathID PathID
+	// Label is the label of the local interface, according to the InterfacePolicy
+	Label string
+	// Priority is the priority of the path. Backup paths are only used if no active path can send or meet the deadline.
+	Priority PathPriority
+
+	SmoothedRTT   time.Duration
+	LatestRTT     time.Duration
+	MeanDeviation time.Duration
+
+	CongestionWindow protocol.ByteCount
+	BytesInFlight    protocol.ByteCount
+	// SendingAllowed is false if the path is closed or congestion limited
+	SendingAllowed bool
+	// Health is the state of the failure detection of the path
+	Health PathHealth
+	// PotentiallyFailed is set while the Health is not PathHealthActive
+	PotentiallyFailed bool
+
+	// Alpha is the deadline stringency factor estimated by the fluctuation monitor
+	Alpha float32
+	// Quota is the number of packets the scheduler sent on this path
+	Quota uint
+
+	// PacketCost is the cost of sending a full-size packet on this path, according to the CostPolicy
+	PacketCost float64
+	// TotalCost is the cost spent on this path so far
+	TotalCost float64
+
+	PacketsSent          uint64
+	PacketsRetransmitted uint64
+	PacketsLost          uint64
+	LastPacketNumber     uint64
+	LeastUnacked         protocol.PacketNumber
+
+	// Reinjections is the number of lost packets whose stream data was reinjected on this path,
+	// LateReinjections the number of those that couldn't be delivered by their deadline on any path
+	Reinjections     uint64
+	LateReinjections uint64
+}
+
+// A PathSnapshot is the input of a PathScheduler
+type PathSnapshot struct {
+	ConnectionID protocol.ConnectionID
+	// Elapsed is the time since the session was created
+	Elapsed time.Duration
+
+	// Paths are sorted by PathID. The initial path is always included, disabled paths never.
+	// Backup paths are only included if no active path can send.
+	Paths []PathInfo
+	// BackupPaths are the backup paths left out of Paths, sorted by PathID.
+	// Schedulers that know the deadlines may use them for packets the active paths can't deliver in time.
+	BackupPaths []PathInfo
+
+	// HasRetransmission is set if a retransmission was dequeued from FromPath
+	HasRetransmission       bool
+	HasStreamRetransmission bool
+	FromPath                PathID
+
+	// SendWindow is the flow control send window of the first data stream
+	SendWindow protocol.ByteCount
+	// QueuedBytes is the amount of stream data waiting to be sent
+	QueuedBytes protocol.ByteCount
+
+	// RemainingBudget is the cost the connection may still spend, +Inf if the CostPolicy has no budget
+	RemainingBudget float64
+	// BatchBudget is the cost the next batch may spend, +Inf if there is no limit
+	BatchBudget float64
+}
+
+// PathWithLabel returns the snapshot of the first path with the given label, or nil if there is none.
+// The initial path is skipped, as it doesn't belong to an interface.
+func (s *PathSnapshot) PathWithLabel(label string) *PathInfo {
+	for i := range s.Paths {
+		if s.Paths[i].PathID != protocol.InitialPathID && s.Paths[i].Label == label {
+			return &s.Paths[i]
+		}
+	}
+	return nil
+}
+
+// Path returns the snapshot of the path with the given PathID, or nil if it doesn't exist.
+// Backup paths are found even if they were left out of Paths.
+func (s *PathSnapshot) Path(pathID PathID) *PathInfo {
+	for i := range s.Paths {
+		if s.Paths[i].PathID == pathID {
+			return &s.Paths[i]
+		}
+	}
+	for i := range s.BackupPaths {
+		if s.BackupPaths[i].PathID == pathID {
+			return &s.BackupPaths[i]
+		}
+	}
+	return nil
+}
+
+// canSend returns if a scheduler may pick the path for new data
+func (p *PathInfo) canSend() bool {
+	return p.PathID != protocol.InitialPathID && p.SendingAllowed && !p.PotentiallyFailed
+}
+
+// applyPriorities drops the disabled paths, and moves the backup paths to BackupPaths if an active path can send
+func (s *PathSnapshot) applyPriorities() {
+	var hasActivePath bool
+	for i := range s.Paths {
+		if s.Paths[i].Priority == PathPriorityActive && s.Paths[i].canSend() {
+			hasActivePath = true
+			break
+		}
+	}
+	paths := s.Paths[:0]
+	for _, pth := range s.Paths {
+		switch {
+		case pth.Priority == PathPriorityDisabled && pth.PathID != protocol.InitialPathID:
+		case pth.Priority == PathPriorityBackup && hasActivePath && pth.PathID != protocol.InitialPathID:
+			s.BackupPaths = append(s.BackupPaths, pth)
+		default:
+			paths = append(paths, pth)
+		}
+	}
+	s.Paths = paths
+}
+
+// A PathScheduler decides on which path the next packet is sent
+type PathScheduler interface {
+	// SelectPath returns the path for the next packet, or NoPath if nothing should be sent right now.
+	// It is only called if a path other than the initial path is available.
+	SelectPath(s *PathSnapshot) PathID
+}
+
+// A BatchPathScheduler decides on which paths a batch of packets is sent
+type BatchPathScheduler interface {
+	PathScheduler
+	// SelectBatch returns a path for every packet of the batch, given the time left until the deadline of each packet.
+	// Packets assigned to NoPath are held back. If nil is returned, nothing is sent.
+	SelectBatch(s *PathSnapshot, deadlines []time.Duration) []PathID
+}
+
+// A DuplicatingPathScheduler is a PathScheduler that sends packets with a deadline on more than one path.
+// SelectDuplicates is called after a packet with a deadline and stream data was sent on the path sentOn, given the time left until its deadline.
+// The packet is sent again on every path returned. DuplicateSent is called for every copy that was actually sent, with the cost charged for it.
+type DuplicatingPathScheduler interface {
+	PathScheduler
+	SelectDuplicates(s *PathSnapshot, sentOn PathID, timeLeft time.Duration) []PathID
+	DuplicateSent(pathID PathID, cost float64)
+}
+
+// A PathSchedulerFinisher is a PathScheduler that wants to be notified when the session stops sending.
+// OnFinish is called with a nil error once the last stream data was sent, or with the error that aborted sending.
+type PathSchedulerFinisher interface {
+	OnFinish(s *PathSnapshot, err error)
+}
+
+// A PathSchedulerCloser is a PathScheduler that wants to be notified when the session is closed,
+// e.g. to persist what it learnt during the connection.
+type PathSchedulerCloser interface {
+	Close() error
+}
+
+// A PathSchedulerFactory creates a PathScheduler for a new session
+type PathSchedulerFactory func(config *Config) PathScheduler
+
+const defaultPathScheduler = "rtt"
+
+var (
+	pathSchedulersMutex sync.RWMutex
+	pathSchedulers      = make(map[string]PathSchedulerFactory)
+)
+
+func init() {
+	RegisterPathScheduler("rtt", newRTTScheduler)
+	//random is roundrobin, not random
+	RegisterPathScheduler("random", newRoundRobinScheduler)
+	RegisterPathScheduler("lowband", newBanditScheduler)
+	RegisterPathScheduler("peek", newPeekabooScheduler)
+	RegisterPathScheduler("ecf", newECFScheduler)
+	RegisterPathScheduler("blest", newBLESTScheduler)
+	RegisterPathScheduler("dqnAgent", newDQNScheduler)
+	RegisterPathScheduler("primary", newPrimaryScheduler)
+	RegisterPathScheduler("secondPath", newSecondPathScheduler)
+	RegisterPathScheduler("BatchLinOpt", newBatchLinOptScheduler)
+	RegisterPathScheduler("BatchEDF", newBatchEDFScheduler)
+	RegisterPathScheduler("BatchPrimary", newBatchPrimaryScheduler)
+	RegisterPathScheduler("redundant", newRedundantScheduler)
+}
+
+// RegisterPathScheduler makes a PathScheduler available under name.
+// It is selected by setting Config.SchedulerName to that name.
+// Registering a name twice replaces the previous factory.
+func RegisterPathScheduler(name string, factory PathSchedulerFactory) {
+	pathSchedulersMutex.Lock()
+	defer pathSchedulersMutex.Unlock()
+	pathSchedulers[name] = factory
+}
+
+// newPathScheduler creates the PathScheduler selected by the config, falling back to the rtt scheduler
+func newPathScheduler(config *Config) PathScheduler {
+	pathSchedulersMutex.RLock()
+	factory, ok := pathSchedulers[config.SchedulerName]
+	if !ok {
+		factory = pathSchedulers[defaultPathScheduler]
+	}
+	pathSchedulersMutex.RUnlock()
+	return factory(config)
+}
+
+// Lock of s.paths must be held
+func (sch *scheduler) getPathSnapshot(s *session, hasRetransmission bool, hasStreamRetransmission bool, fromPth *path) *PathSnapshot {
+	snapshot := &PathSnapshot{
+		ConnectionID:            s.connectionID,
+		Elapsed:                 time.Since(s.sessionCreationTime),
+		Paths:                   make([]PathInfo, 0, len(s.paths)),
+		HasRetransmission:       hasRetransmission,
+		HasStreamRetransmission: hasStreamRetransmission,
+		FromPath:                NoPath,
+	}
+	if fromPth != nil {
+		snapshot.FromPath = fromPth.pathID
+	}
+	now := time.Now()
+	snapshot.RemainingBudget = sch.cost.remainingBudget(now)
+	snapshot.BatchBudget = sch.cost.batchBudget(now)
+	snapshot.SendWindow, _ = s.flowControlManager.SendWindowSize(protocol.StreamID(5))
+	s.streamsMap.Iterate(func(str *stream) (bool, error) {
+		if str != nil {
+			snapshot.QueuedBytes += str.lenOfDataForWriting()
+		}
+		return true, nil
+	})
+
+	for pathID, pth := range s.paths {
+		sent, retrans, lost := pth.sentPacketHandler.GetStatistics()
+		snapshot.Paths = append(snapshot.Paths, PathInfo{
+			PathID:               pathID,
+			Label:                pth.label,
+			Priority:             pth.priority,
+			SmoothedRTT:          pth.rttStats.SmoothedRTT(),
+			LatestRTT:            pth.rttStats.LatestRTT(),
+			MeanDeviation:        pth.rttStats.MeanDeviation(),
+			CongestionWindow:     pth.sentPacketHandler.GetCongestionWindow(),
+			BytesInFlight:        pth.sentPacketHandler.GetBytesInFlight(),
+			SendingAllowed:       pth.SendingAllowed(),
+			Health:               pth.health.state,
+			PotentiallyFailed:    pth.potentiallyFailed.Get(),
+			Alpha:                pth.sentPacketHandler.GetPathAlpha(),
+			Quota:                sch.quotas[pathID],
+			PacketCost:           sch.cost.policy.charge(pth.price, protocol.MaxPacketSize),
+			TotalCost:            pth.totalCost,
+			PacketsSent:          sent,
+			PacketsRetransmitted: retrans,
+			PacketsLost:          lost,
+			Reinjections:         pth.reinjections,
+			LateReinjections:     pth.lateReinjections,
+			LastPacketNumber:     pth.sentPacketHandler.GetLastPackets(),
+			LeastUnacked:         pth.sentPacketHandler.GetLeastUnacked(),
+		})
+	}
+	sort.Slice(snapshot.Paths, func(i, j int) bool { return snapshot.Paths[i].PathID < snapshot.Paths[j].PathID })
+	snapshot.applyPriorities()
+	return snapshot
+}
+
+// selectUnprobedPathForRetransmission returns a path with a lower quota than the one a retransmission was dequeued from,
+// if that path was never probed. It returns NoPath otherwise.
+// FIXME Only works at the beginning... Cope with new paths during the connection
+func selectUnprobedPathForRetransmission(s *PathSnapshot) PathID {
+	fromPth := s.Path(s.FromPath)
+	if !s.HasRetransmission || !s.HasStreamRetransmission || fromPth == nil || fromPth.SmoothedRTT != 0 {
+		return NoPath
+	}
+	// Is there any other path with a lower number of packet sent?
+	for _, pth := range s.Paths {
+		if pth.PathID == protocol.InitialPathID || pth.PathID == fromPth.PathID {
+			continue
+		}
+		// The congestion window was checked when duplicating the packet
+		if pth.Quota < fromPth.Quota {
+			return pth.PathID
+		}
+	}
+	return NoPath
+}
@@ -0,0 +1,320 @@
+package quic
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/ackhandler"
+	"github.com/lucas-clemente/quic-go/congestion"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type constantPathScheduler struct {
+	pathID PathID
+}
+
+func (c *constantPathScheduler) SelectPath(*PathSnapshot) PathID { return c.pathID }
+
+var _ = Describe("Path Scheduler", func() {
+	var snapshot *PathSnapshot
+
+	BeforeEach(func() {
+		snapshot = &PathSnapshot{
+			FromPath: NoPath,
+			Paths: []PathInfo{
+				{PathID: 0, SendingAllowed: true},
+				{PathID: 1, SendingAllowed: true, SmoothedRTT: 40 * time.Millisecond, Quota: 3},
+				{PathID: 3, SendingAllowed: true, SmoothedRTT: 10 * time.Millisecond, Quota: 5},
+			},
+		}
+	})
+
+	Context("registry", func() {
+		It("creates a registered scheduler", func() {
+			RegisterPathScheduler("test-constant", func(*Config) PathScheduler { return &constantPathScheduler{pathID: 3} })
+			sch := newPathScheduler(&Config{SchedulerName: "test-constant"})
+			Expect(sch.SelectPath(snapshot)).To(Equal(PathID(3)))
+		})
+
+		It("falls back to the rtt scheduler", func() {
+			Expect(newPathScheduler(&Config{})).To(BeAssignableToTypeOf(&rttScheduler{}))
+			Expect(newPathScheduler(&Config{SchedulerName: "unknown"})).To(BeAssignableToTypeOf(&rttScheduler{}))
+		})
+
+		It("registers the batch schedulers", func() {
+			_, ok := newPathScheduler(&Config{SchedulerName: "BatchEDF"}).(BatchPathScheduler)
+			Expect(ok).To(BeTrue())
+			_, ok = newPathScheduler(&Config{SchedulerName: "BatchLinOpt"}).(BatchPathScheduler)
+			Expect(ok).To(BeTrue())
+			_, ok = newPathScheduler(&Config{SchedulerName: "rtt"}).(BatchPathScheduler)
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	It("finds paths in the snapshot", func() {
+		Expect(snapshot.Path(3).SmoothedRTT).To(Equal(10 * time.Millisecond))
+		Expect(snapshot.Path(5)).To(BeNil())
+	})
+
+	Context("rtt", func() {
+		It("selects the path with the lowest RTT", func() {
+			Expect((&rttScheduler{}).SelectPath(snapshot)).To(Equal(PathID(3)))
+		})
+
+		It("ignores paths that are not allowed to send", func() {
+			snapshot.Path(3).SendingAllowed = false
+			Expect((&rttScheduler{}).SelectPath(snapshot)).To(Equal(PathID(1)))
+		})
+
+		It("ignores potentially failed paths", func() {
+			snapshot.Path(3).PotentiallyFailed = true
+			Expect((&rttScheduler{}).SelectPath(snapshot)).To(Equal(PathID(1)))
+		})
+
+		It("returns NoPath if no path can send", func() {
+			snapshot.Path(1).SendingAllowed = false
+			snapshot.Path(3).SendingAllowed = false
+			Expect((&rttScheduler{}).SelectPath(snapshot)).To(Equal(NoPath))
+		})
+	})
+
+	Context("round-robin", func() {
+		It("selects the path with the lowest quota", func() {
+			Expect((&roundRobinScheduler{}).SelectPath(snapshot)).To(Equal(PathID(1)))
+			snapshot.Path(1).Quota = 6
+			Expect((&roundRobinScheduler{}).SelectPath(snapshot)).To(Equal(PathID(3)))
+		})
+	})
+
+	Context("fixed path", func() {
+		It("only sends on its path", func() {
+			sch := &fixedPathScheduler{pathID: 1}
+			Expect(sch.SelectPath(snapshot)).To(Equal(PathID(1)))
+			snapshot.Path(1).SendingAllowed = false
+			Expect(sch.SelectPath(snapshot)).To(Equal(NoPath))
+		})
+
+		It("selects its path by label", func() {
+			snapshot.Path(3).Label = "wifi"
+			sch := newPathScheduler(&Config{SchedulerName: "primary", PrimaryPathLabel: "wifi"})
+			Expect(sch.SelectPath(snapshot)).To(Equal(PathID(3)))
+			sch = newPathScheduler(&Config{SchedulerName: "secondPath", SecondPathLabel: "cellular"})
+			Expect(sch.SelectPath(snapshot)).To(Equal(NoPath))
+		})
+	})
+
+	Context("ECF", func() {
+		It("uses the fastest path when it can send", func() {
+			Expect((&ecfScheduler{}).SelectPath(snapshot)).To(Equal(PathID(3)))
+		})
+
+		It("uses the second path when the fastest path is blocked", func() {
+			snapshot.Path(3).SendingAllowed = false
+			Expect((&ecfScheduler{}).SelectPath(snapshot)).To(Equal(PathID(1)))
+		})
+
+		It("prefers an unprobed path with a lower quota for retransmissions", func() {
+			snapshot.HasRetransmission = true
+			snapshot.HasStreamRetransmission = true
+			snapshot.FromPath = 3
+			snapshot.Path(3).SmoothedRTT = 0
+			Expect((&ecfScheduler{}).SelectPath(snapshot)).To(Equal(PathID(1)))
+		})
+	})
+
+	Context("BatchEDF", func() {
+		It("selects a path for every packet, without reordering the batch", func() {
+			deadlines := []time.Duration{30 * time.Millisecond, 10 * time.Millisecond, noDeadline}
+			paths := (&batchEDFScheduler{}).SelectBatch(snapshot, deadlines)
+			Expect(paths).To(Equal([]PathID{3, 3, 3}))
+			Expect(deadlines).To(Equal([]time.Duration{30 * time.Millisecond, 10 * time.Millisecond, noDeadline}))
+		})
+	})
+
+	Context("BatchPrimary", func() {
+		It("sends the whole batch on the first path", func() {
+			paths := newBatchPrimaryScheduler(nil).(BatchPathScheduler).SelectBatch(snapshot, make([]time.Duration, 4))
+			Expect(paths).To(Equal([]PathID{1, 1, 1, 1}))
+		})
+
+		It("sends nothing if the first path is blocked", func() {
+			snapshot.Path(1).SendingAllowed = false
+			Expect(newBatchPrimaryScheduler(nil).(BatchPathScheduler).SelectBatch(snapshot, make([]time.Duration, 4))).To(BeNil())
+		})
+	})
+
+	Context("BatchLinOpt rounding", func() {
+		It("assigns every packet to the path with the largest fraction", func() {
+			result := [][]float64{
+				{0, 0, 1},
+				{0.2, 0.7, 0.1},
+				{0, 0, 0},
+				{1, 0, 0},
+			}
+			Expect(resultToPolicy(result)).To(Equal([]int{3, 2, 0, 1}))
+		})
+
+		It("ignores numerical noise of the solver", func() {
+			Expect(resultToPolicy([][]float64{{1e-9, 0, 0}})).To(Equal([]int{0}))
+		})
+
+		It("rounds integral solutions exactly, for any number of paths", func() {
+			result := [][]float64{
+				{0, 0, 0, 1},
+				{0, 1, 0, 0},
+				{0, 0, 0, 0},
+			}
+			Expect(resultToPolicyWithGreedyRounding(result)).To(Equal([]int{4, 2, 0}))
+		})
+
+		It("only rounds to paths that got a fraction of the packet", func() {
+			for i := 0; i < 100; i++ {
+				policy := resultToPolicyWithGreedyRounding([][]float64{{0, 0.5, 0, 0.3}})
+				Expect(policy[0]).To(BeElementOf(0, 2, 4))
+			}
+		})
+
+		It("maps the policy to the eligible paths", func() {
+			eligible := []*PathInfo{snapshot.Path(1), snapshot.Path(3)}
+			Expect(PolicyToSelectPath([]int{2, 0, 1, 5}, eligible)).To(Equal([]PathID{3, NoPath, 1, NoPath}))
+		})
+
+		It("moves the packets a path has no room for to the next best path", func() {
+			result := [][]float64{
+				{0.6, 0.4, 0},
+				{0.9, 0.1, 0},
+				{0.5, 0, 0.5},
+				{0.7, 0, 0},
+			}
+			// the packets with the largest fractions keep the first path
+			Expect(enforceCwnds([]int{1, 1, 1, 1}, result, []float64{2, 1, 0})).To(Equal([]int{2, 1, 0, 1}))
+		})
+
+		It("keeps policies that respect the CWNDs", func() {
+			result := [][]float64{{1, 0}, {0, 1}, {0, 0}}
+			Expect(enforceCwnds([]int{1, 2, 0}, result, []float64{1, 1})).To(Equal([]int{1, 2, 0}))
+		})
+
+		It("never exceeds a CWND with greedy rounding", func() {
+			result := [][]float64{
+				{0.5, 0.5},
+				{0.5, 0.5},
+				{0.5, 0.5},
+				{0.5, 0.5},
+			}
+			for i := 0; i < 100; i++ {
+				policy := enforceCwnds(resultToPolicyWithGreedyRounding(result), result, []float64{2, 2})
+				counts := make(map[int]int)
+				for _, p := range policy {
+					counts[p]++
+				}
+				Expect(counts[1]).To(BeNumerically("<=", 2))
+				Expect(counts[2]).To(BeNumerically("<=", 2))
+			}
+		})
+	})
+
+	Context("reinjection", func() {
+		BeforeEach(func() {
+			snapshot.Path(1).Alpha = 1
+			snapshot.Path(3).Alpha = 1
+		})
+
+		It("reinjects on the path that delivers the data the earliest", func() {
+			pathID, inTime := selectReinjectionPath(snapshot, 20*time.Millisecond)
+			Expect(pathID).To(Equal(PathID(3)))
+			Expect(inTime).To(BeTrue())
+		})
+
+		It("uses the one-way delay of the path", func() {
+			pathID, inTime := selectReinjectionPath(snapshot, 5*time.Millisecond)
+			Expect(pathID).To(Equal(PathID(3)))
+			Expect(inTime).To(BeTrue())
+		})
+
+		It("forgets the path chosen for an earlier packet", func() {
+			sch := &scheduler{reinjectPath: &path{pathID: 3}}
+			packet := &ackhandler.Packet{PacketNumber: 7, Frames: []wire.Frame{&wire.StreamFrame{StreamID: 5}}}
+			sch.reinject(nil, packet, &path{pathID: 1})
+			Expect(sch.reinjectPath).To(BeNil())
+		})
+
+		It("scales the RTT by the alpha of the path", func() {
+			snapshot.Path(3).Alpha = 5
+			pathID, inTime := selectReinjectionPath(snapshot, 45*time.Millisecond)
+			Expect(pathID).To(Equal(PathID(1)))
+			Expect(inTime).To(BeTrue())
+		})
+
+		It("flags data that no path can deliver in time", func() {
+			pathID, inTime := selectReinjectionPath(snapshot, 4*time.Millisecond)
+			Expect(pathID).To(Equal(PathID(3)))
+			Expect(inTime).To(BeFalse())
+		})
+
+		It("ignores paths that can't send", func() {
+			snapshot.Path(3).PotentiallyFailed = true
+			pathID, _ := selectReinjectionPath(snapshot, time.Second)
+			Expect(pathID).To(Equal(PathID(1)))
+			snapshot.Path(1).SendingAllowed = false
+			pathID, inTime := selectReinjectionPath(snapshot, time.Second)
+			Expect(pathID).To(Equal(NoPath))
+			Expect(inTime).To(BeFalse())
+		})
+
+		It("uses the earliest deadline of the stream frames", func() {
+			now := time.Now()
+			frames := []wire.Frame{
+				&wire.StreamFrame{StreamID: 5, Deadline: now.Add(time.Second)},
+				&wire.PingFrame{},
+				&wire.StreamFrame{StreamID: 7},
+				&wire.StreamFrame{StreamID: 9, Deadline: now.Add(time.Millisecond)},
+			}
+			Expect(earliestStreamDeadline(frames)).To(Equal(now.Add(time.Millisecond)))
+			Expect(earliestStreamDeadline(frames[1:3])).To(BeZero())
+		})
+	})
+
+	Context("batches", func() {
+		var (
+			sess *session
+			sch  *scheduler
+		)
+
+		BeforeEach(func() {
+			sess = &session{
+				paths:        make(map[protocol.PathID]*path),
+				streamFramer: newStreamFramer(newStreamsMap(nil, protocol.PerspectiveServer, nil), nil),
+			}
+			for _, pathID := range []protocol.PathID{0, 1, 3} {
+				sess.paths[pathID] = &path{
+					pathID:            pathID,
+					sentPacketHandler: ackhandler.NewSentPacketHandler(&congestion.RTTStats{}, nil, nil, nil),
+				}
+			}
+			sch = &scheduler{}
+		})
+
+		It("builds the batch from the queued packets", func() {
+			now := time.Now()
+			sess.streamFramer.AddDatagramForTransmission(&wire.DatagramFrame{Data: make([]byte, 1000), Deadline: now.Add(20 * time.Millisecond)})
+			sess.streamFramer.AddDatagramForTransmission(&wire.DatagramFrame{Data: make([]byte, 1000)})
+			Expect(sch.getBatchDeadlines(sess, 6, now)).To(Equal([]time.Duration{20 * time.Millisecond, noDeadline}))
+			Expect(sch.getBatchDeadlines(sess, 1, now)).To(HaveLen(1))
+		})
+
+		It("has a single packet if nothing is queued", func() {
+			Expect(sch.getBatchDeadlines(sess, 6, time.Now())).To(Equal([]time.Duration{noDeadline}))
+		})
+
+		It("counts the packets that fit into the congestion windows, without the initial path", func() {
+			cwnd := sess.paths[1].sentPacketHandler.GetCongestionWindow()
+			Expect(sch.batchCapacity(sess)).To(Equal(2 * int(cwnd/protocol.MaxPacketSize)))
+			delete(sess.paths, 3)
+			Expect(sch.batchCapacity(sess)).To(Equal(int(cwnd / protocol.MaxPacketSize)))
+		})
+	})
+})
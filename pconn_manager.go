@@ -2,7 +2,6 @@ package quic
 
 import (
 	"net"
-	"strings"
 	"sync"
 	"time"
 
@@ -26,8 +25,18 @@ type pconnManager struct {
 	pconnAny net.PacketConn
 
 	localAddrs []net.UDPAddr
+	// labels are the labels of the local addresses, keyed by IP
+	labels map[string]string
+	// interfaces are the interface names of the local addresses, keyed by IP
+	interfaces map[string]string
 
 	perspective protocol.Perspective
+	// policy selects the interfaces the client opens sockets on
+	policy *InterfacePolicy
+	// watcher reports address changes to the client
+	watcher addressWatcher
+	// retired are the pconns of addresses that disappeared. Their read errors are expected.
+	retired map[net.PacketConn]bool
 
 	rcvRawPackets chan *receivedRawPacket
 
@@ -42,6 +51,9 @@ type pconnManager struct {
 func (pcm *pconnManager) setup(pconnArg net.PacketConn, listenAddr net.Addr) error {
 	pcm.pconns = make(map[string]net.PacketConn)
 	pcm.localAddrs = make([]net.UDPAddr, 0)
+	pcm.labels = make(map[string]string)
+	pcm.interfaces = make(map[string]string)
+	pcm.retired = make(map[net.PacketConn]bool)
 	pcm.rcvRawPackets = make(chan *receivedRawPacket)
 	pcm.changePaths = make(chan struct{}, 1)
 	pcm.closeConns = make(chan struct{}, 1)
@@ -94,6 +106,12 @@ listenLoop:
 		// If it does, we only read a truncate packet, which will then end up undecryptable
 		n, addr, err = pconn.ReadFrom(data)
 		if err != nil {
+			pcm.mutex.Lock()
+			retired := pcm.retired[pconn]
+			pcm.mutex.Unlock()
+			if retired {
+				break listenLoop
+			}
 			// XXX (QDC): as soon as a path failed, kill the connection.
 			// TODO (QDC): be more resilient in the future without breaking expectations
 			select {
@@ -129,8 +147,16 @@ func (pcm *pconnManager) run() {
 	go pcm.listen(pcm.pconnAny)
 	// XXX (QDC): maybe wait for one handshake to complete, but maybe not needed
 	// FIXME Server starting on any vs. server with non-any address
+	var addressEvents <-chan addressEvent
 	if pcm.perspective == protocol.PerspectiveClient {
 		pcm.createPconns()
+		watcher, err := newAddressWatcher()
+		if err != nil {
+			utils.Errorf("pconn_manager: not watching addresses: %v", err)
+		} else if watcher != nil {
+			pcm.watcher = watcher
+			addressEvents = watcher.Events()
+		}
 	}
 
 	select {
@@ -154,8 +180,18 @@ runLoop:
 		case <-pcm.timer.C:
 			pcm.createPconns()
 			pcm.timer.Reset(duration)
+		case ev, ok := <-addressEvents:
+			if !ok {
+				// fall back to polling
+				addressEvents = nil
+				continue
+			}
+			pcm.handleAddressEvent(ev)
 		}
 	}
+	if pcm.watcher != nil {
+		pcm.watcher.Close()
+	}
 	// Close pconns
 	pcm.closePconns()
 }
@@ -198,9 +234,10 @@ func (pcm *pconnManager) createPconns() error {
 	if err != nil {
 		return err
 	}
+	// the addresses that are still there
+	seen := make(map[string]bool)
 	for _, i := range ifaces {
-		// TODO (QDC): do this in a generic way
-		if !strings.Contains(i.Name, "eth") && !strings.Contains(i.Name, "rmnet") && !strings.Contains(i.Name, "wlan") {
+		if i.Flags&net.FlagUp == 0 {
 			continue
 		}
 		addrs, err := i.Addrs()
@@ -216,6 +253,11 @@ func (pcm *pconnManager) createPconns() error {
 			if !ip.IsGlobalUnicast() {
 				continue
 			}
+			label, ok := pcm.policy.allows(i.Name, ip)
+			if !ok {
+				continue
+			}
+			seen[ip.String()] = true
 			// TODO (QDC): Clearly not optimal
 			found := false
 		lookingLoop:
@@ -226,17 +268,122 @@ func (pcm *pconnManager) createPconns() error {
 				}
 			}
 			if !found {
+				pcm.mutex.Lock()
+				pcm.labels[ip.String()] = label
+				pcm.interfaces[ip.String()] = i.Name
+				pcm.mutex.Unlock()
 				locAddr, err := pcm.createPconn(ip)
 				if err != nil {
 					return err
 				}
+				pcm.mutex.Lock()
 				pcm.localAddrs = append(pcm.localAddrs, *locAddr)
+				pcm.mutex.Unlock()
 			}
 		}
 	}
+	pcm.removeAddrs(func(ip net.IP) bool { return !seen[ip.String()] })
 	return nil
 }
 
+// handleAddressEvent opens pconns on new addresses, and closes the pconns of addresses that disappeared
+func (pcm *pconnManager) handleAddressEvent(ev addressEvent) {
+	if utils.Debug() {
+		utils.Debugf("pconn_manager: address event %+v", ev)
+	}
+	if ev.Removed && ev.IP != nil {
+		pcm.removeAddrs(ev.IP.Equal)
+		return
+	}
+	// A new address, or an interface that went up or down
+	if err := pcm.createPconns(); err != nil {
+		utils.Errorf("pconn_manager: %v", err)
+	}
+}
+
+// removeAddrs closes the pconns of the local addresses that match.
+// The path manager then closes the paths that used them.
+func (pcm *pconnManager) removeAddrs(match func(ip net.IP) bool) {
+	pcm.mutex.Lock()
+	var removed bool
+	localAddrs := make([]net.UDPAddr, 0, len(pcm.localAddrs))
+	for _, locAddr := range pcm.localAddrs {
+		if !match(locAddr.IP) {
+			localAddrs = append(localAddrs, locAddr)
+			continue
+		}
+		removed = true
+		if pconn, ok := pcm.pconns[locAddr.String()]; ok {
+			delete(pcm.pconns, locAddr.String())
+			pcm.retired[pconn] = true
+			pconn.Close()
+		}
+		delete(pcm.labels, locAddr.IP.String())
+		delete(pcm.interfaces, locAddr.IP.String())
+		if utils.Debug() {
+			utils.Debugf("Closed pconn on %s", locAddr.String())
+		}
+	}
+	pcm.localAddrs = localAddrs
+	pcm.mutex.Unlock()
+
+	if removed {
+		select {
+		case pcm.changePaths <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// labelOf returns the label of a local address. The mutex must be held.
+func (pcm *pconnManager) labelOf(addr net.Addr) string {
+	if udpAddr, ok := addr.(*net.UDPAddr); ok {
+		return pcm.labels[udpAddr.IP.String()]
+	}
+	return ""
+}
+
+// localAddresses returns the local addresses paths can be created on. The mutex must be held.
+// The server only has the pconn listening on any address.
+func (pcm *pconnManager) localAddresses() []LocalAddress {
+	if pcm.perspective == protocol.PerspectiveServer {
+		if udpAddr, ok := pcm.pconnAny.LocalAddr().(*net.UDPAddr); ok {
+			return []LocalAddress{{Addr: *udpAddr}}
+		}
+		return nil
+	}
+	local := make([]LocalAddress, 0, len(pcm.localAddrs))
+	for _, locAddr := range pcm.localAddrs {
+		local = append(local, LocalAddress{
+			Addr:      locAddr,
+			Interface: pcm.interfaces[locAddr.IP.String()],
+			Label:     pcm.labels[locAddr.IP.String()],
+		})
+	}
+	return local
+}
+
+// pconnOf returns the pconn of a local address. The mutex must be held.
+func (pcm *pconnManager) pconnOf(addr net.UDPAddr) (net.PacketConn, bool) {
+	if pconn, ok := pcm.pconns[addr.String()]; ok {
+		return pconn, true
+	}
+	if pcm.pconnAny != nil && pcm.pconnAny.LocalAddr().String() == addr.String() {
+		return pcm.pconnAny, true
+	}
+	return nil, false
+}
+
+// label returns the label of a local address
+func (pcm *pconnManager) label(addr net.Addr) string {
+	if pcm == nil {
+		return ""
+	}
+	pcm.mutex.Lock()
+	defer pcm.mutex.Unlock()
+	return pcm.labelOf(addr)
+}
+
 func (pcm *pconnManager) closePconns() {
 	for _, pconn := range pcm.pconns {
 		pconn.Close()
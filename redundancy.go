@@ -0,0 +1,322 @@
+package quic
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/ackhandler"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+)
+
+// The defaults of the RedundancyConfig
+const (
+	DefaultRedundancyConfidence        = 0.95
+	DefaultRedundancyMaxCopies         = 2
+	DefaultRedundancyMaxDuplicateRatio = 0.2
+)
+
+// maxTrackedDuplicates is the number of duplicated packets whose deadline outcome is tracked at the same time
+const maxTrackedDuplicates = 1024
+
+// RedundancyConfig configures the redundant scheduler, which sends a packet on several paths
+// if no single path is predicted to deliver it by its deadline.
+// Zero values are replaced by the defaults.
+type RedundancyConfig struct {
+	// Confidence is the probability with which a packet should meet its deadline.
+	// The one-way delay of a path is modelled as a normal distribution, with half the smoothed RTT as mean
+	// and half the mean deviation as standard deviation, both scaled by the alpha of the path.
+	Confidence float64
+	// MaxCopies is the maximum number of paths a packet is sent on, including the first one
+	MaxCopies int
+	// MaxDuplicateRatio is the maximum number of duplicates, as a fraction of the packets sent on all paths
+	MaxDuplicateRatio float64
+	// MaxDuplicateCost is the cost the duplicates may spend, in the unit of the CostPolicy.
+	// Duplicates never exceed the budget of the CostPolicy. If zero, the duplicates are only limited by that budget.
+	MaxDuplicateCost float64
+}
+
+// populate returns a copy of the config with the defaults filled in
+func (c *RedundancyConfig) populate() *RedundancyConfig {
+	config := &RedundancyConfig{}
+	if c != nil {
+		*config = *c
+	}
+	if config.Confidence <= 0 || config.Confidence >= 1 {
+		config.Confidence = DefaultRedundancyConfidence
+	}
+	if config.MaxCopies <= 1 {
+		config.MaxCopies = DefaultRedundancyMaxCopies
+	}
+	if config.MaxDuplicateRatio <= 0 {
+		config.MaxDuplicateRatio = DefaultRedundancyMaxDuplicateRatio
+	}
+	return config
+}
+
+// deadlineProbability estimates the probability that a packet sent now on the path arrives within timeLeft.
+// It is 0 for paths without an RTT estimate.
+func deadlineProbability(pth *PathInfo, timeLeft time.Duration) float64 {
+	if pth.SmoothedRTT == 0 || timeLeft <= 0 {
+		return 0
+	}
+	alpha := float64(pth.Alpha)
+	if alpha == 0 {
+		alpha = 1
+	}
+	mean := float64(pth.SmoothedRTT) / 2 * alpha
+	dev := float64(pth.MeanDeviation) / 2 * alpha
+	if dev == 0 {
+		if mean <= float64(timeLeft) {
+			return 1
+		}
+		return 0
+	}
+	return 0.5 * (1 + math.Erf((float64(timeLeft)-mean)/(dev*math.Sqrt2)))
+}
+
+// redundantScheduler sends on the lowest RTT path, and duplicates packets that this path alone is unlikely to deliver in time
+type redundantScheduler struct {
+	rttScheduler
+
+	config *RedundancyConfig
+	// duplicates is the number of duplicates sent, duplicateCost what they cost
+	duplicates    uint64
+	duplicateCost float64
+}
+
+var _ DuplicatingPathScheduler = &redundantScheduler{}
+
+func newRedundantScheduler(config *Config) PathScheduler {
+	sch := &redundantScheduler{}
+	if config != nil {
+		sch.config = config.Redundancy.populate()
+	} else {
+		sch.config = (*RedundancyConfig)(nil).populate()
+	}
+	return sch
+}
+
+// SelectDuplicates adds the paths with the highest probability to deliver the packet in time,
+// until the packet meets its deadline with the configured confidence, or the budget is exhausted.
+// The budget is only charged by DuplicateSent.
+func (sch *redundantScheduler) SelectDuplicates(s *PathSnapshot, sentOn PathID, timeLeft time.Duration) []PathID {
+	pth := s.Path(sentOn)
+	if pth == nil {
+		return nil
+	}
+	miss := 1 - deadlineProbability(pth, timeLeft)
+	if 1-miss >= sch.config.Confidence {
+		return nil
+	}
+
+	var packetsSent uint64
+	for _, paths := range [][]PathInfo{s.Paths, s.BackupPaths} {
+		for _, p := range paths {
+			packetsSent += p.PacketsSent
+		}
+	}
+	maxDuplicates := uint64(sch.config.MaxDuplicateRatio * float64(packetsSent))
+
+	type candidate struct {
+		pth         *PathInfo
+		probability float64
+	}
+	var candidates []candidate
+	for _, paths := range [][]PathInfo{s.Paths, s.BackupPaths} {
+		for i := range paths {
+			p := &paths[i]
+			if p.PathID == sentOn || (p.PathID == protocol.InitialPathID && len(s.Paths) > 1) {
+				continue
+			}
+			if !p.SendingAllowed || p.PotentiallyFailed {
+				continue
+			}
+			if prob := deadlineProbability(p, timeLeft); prob > 0 {
+				candidates = append(candidates, candidate{pth: p, probability: prob})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].probability != candidates[j].probability {
+			return candidates[i].probability > candidates[j].probability
+		}
+		return candidates[i].pth.PacketCost < candidates[j].pth.PacketCost
+	})
+
+	var duplicates []PathID
+	remainingBudget := s.RemainingBudget
+	duplicateCost := sch.duplicateCost
+	for _, c := range candidates {
+		if len(duplicates)+1 >= sch.config.MaxCopies || sch.duplicates+uint64(len(duplicates)) >= maxDuplicates {
+			break
+		}
+		if c.pth.PacketCost > remainingBudget {
+			continue
+		}
+		if sch.config.MaxDuplicateCost > 0 && duplicateCost+c.pth.PacketCost > sch.config.MaxDuplicateCost {
+			continue
+		}
+		duplicates = append(duplicates, c.pth.PathID)
+		duplicateCost += c.pth.PacketCost
+		remainingBudget -= c.pth.PacketCost
+		miss *= 1 - c.probability
+		if 1-miss >= sch.config.Confidence {
+			break
+		}
+	}
+	return duplicates
+}
+
+// DuplicateSent counts a duplicate that was sent, and what it cost
+func (sch *redundantScheduler) DuplicateSent(pathID PathID, cost float64) {
+	sch.duplicates++
+	sch.duplicateCost += cost
+}
+
+// hasStreamFrames says if the frames carry stream data, which is the only data that is duplicated
+func hasStreamFrames(frames []wire.Frame) bool {
+	for _, frame := range frames {
+		if _, ok := frame.(*wire.StreamFrame); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// A pathPacket identifies a packet sent on a path
+type pathPacket struct {
+	pathID       protocol.PathID
+	packetNumber protocol.PacketNumber
+}
+
+// a duplicateGroup is a packet and its duplicates
+type duplicateGroup struct {
+	original       pathPacket
+	copies         []pathPacket
+	originalMissed bool
+	copyMet        bool
+}
+
+// duplicateTracker matches the deadline samples of the duplicated packets, to count how often a duplicate met a deadline that the original packet missed.
+// It is only accessed by the run loop of the session.
+type duplicateTracker struct {
+	groups map[pathPacket]*duplicateGroup
+	// order is used to forget the oldest groups
+	order []*duplicateGroup
+
+	// sent is the number of duplicates sent, savedDeadlines the number of packets that only met their deadline thanks to a duplicate
+	sent           uint64
+	savedDeadlines uint64
+}
+
+func newDuplicateTracker() *duplicateTracker {
+	return &duplicateTracker{groups: make(map[pathPacket]*duplicateGroup)}
+}
+
+// add records that the original packet was duplicated
+func (t *duplicateTracker) add(original, duplicate pathPacket) {
+	t.sent++
+	g, ok := t.groups[original]
+	if !ok {
+		if len(t.order) >= maxTrackedDuplicates {
+			t.remove(t.order[0])
+		}
+		g = &duplicateGroup{original: original}
+		t.groups[original] = g
+		t.order = append(t.order, g)
+	}
+	g.copies = append(g.copies, duplicate)
+	t.groups[duplicate] = g
+}
+
+func (t *duplicateTracker) remove(g *duplicateGroup) {
+	delete(t.groups, g.original)
+	for _, c := range g.copies {
+		delete(t.groups, c)
+	}
+	for i, o := range t.order {
+		if o == g {
+			t.order = append(t.order[:i], t.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// receivedDeadlineSamples processes the deadline samples the peer reported for a path
+func (t *duplicateTracker) receivedDeadlineSamples(pathID protocol.PathID, samples []wire.DeadlineSample) {
+	for _, sample := range samples {
+		g, ok := t.groups[pathPacket{pathID: pathID, packetNumber: sample.PacketNumber}]
+		if !ok {
+			continue
+		}
+		if g.original.pathID == pathID && g.original.packetNumber == sample.PacketNumber {
+			if sample.MetDeadline() {
+				// the duplicates weren't needed
+				t.remove(g)
+				continue
+			}
+			g.originalMissed = true
+		} else if sample.MetDeadline() {
+			g.copyMet = true
+		}
+		if g.originalMissed && g.copyMet {
+			t.savedDeadlines++
+			t.remove(g)
+		}
+	}
+}
+
+// maybeDuplicate sends copies of a packet on the paths the DuplicatingPathScheduler selects
+// Lock of s.paths must be free
+func (sch *scheduler) maybeDuplicate(s *session, pkt *ackhandler.Packet, pth *path) error {
+	duplicator, ok := sch.pathScheduler.(DuplicatingPathScheduler)
+	if !ok || pkt == nil || pkt.Deadline.IsZero() || pkt.EncryptionLevel != protocol.EncryptionForwardSecure || !hasStreamFrames(pkt.Frames) {
+		return nil
+	}
+	s.pathsLock.RLock()
+	if len(s.paths) <= 1 {
+		s.pathsLock.RUnlock()
+		return nil
+	}
+	snapshot := sch.getPathSnapshot(s, false, false, nil)
+	var paths []*path
+	for _, pathID := range duplicator.SelectDuplicates(snapshot, pth.pathID, time.Until(pkt.Deadline)) {
+		if dupPth, ok := s.paths[pathID]; ok && dupPth != pth {
+			paths = append(paths, dupPth)
+		}
+	}
+	s.pathsLock.RUnlock()
+
+	for _, dupPth := range paths {
+		alpha := int(math.Round(float64(dupPth.sentPacketHandler.GetPathAlpha() * 10.0)))
+		packet, err := s.packer.PackDuplicate(pkt, dupPth, uint8(alpha))
+		if err != nil {
+			return err
+		}
+		if packet == nil {
+			// the packet doesn't fit with the header of the path
+			continue
+		}
+		length := protocol.ByteCount(len(packet.raw))
+		if err := s.sendPackedPacket(packet, dupPth); err != nil {
+			return err
+		}
+		sch.quotas[dupPth.pathID]++
+		duplicator.DuplicateSent(dupPth.pathID, sch.chargeCost(dupPth, length))
+		sch.duplicates.add(
+			pathPacket{pathID: pth.pathID, packetNumber: pkt.PacketNumber},
+			pathPacket{pathID: dupPth.pathID, packetNumber: packet.number},
+		)
+		utils.Debugf("\tDuplicated packet 0x%x of path %d as packet 0x%x on path %d", pkt.PacketNumber, pth.pathID, packet.number, dupPth.pathID)
+		s.trace(TracePacketDuplicated, &PacketDuplicatedEvent{
+			PathID:                pth.pathID,
+			PacketNumber:          pkt.PacketNumber,
+			DuplicatePathID:       dupPth.pathID,
+			DuplicatePacketNumber: packet.number,
+		})
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package quic
+
+import (
+	"math"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Redundancy", func() {
+	It("fills in the defaults", func() {
+		config := (&RedundancyConfig{MaxCopies: 3}).populate()
+		Expect(config.Confidence).To(Equal(DefaultRedundancyConfidence))
+		Expect(config.MaxCopies).To(Equal(3))
+		Expect(config.MaxDuplicateRatio).To(Equal(DefaultRedundancyMaxDuplicateRatio))
+		Expect((*RedundancyConfig)(nil).populate().MaxCopies).To(Equal(DefaultRedundancyMaxCopies))
+	})
+
+	Context("deadline probability", func() {
+		It("is zero for paths without an RTT estimate", func() {
+			Expect(deadlineProbability(&PathInfo{}, time.Second)).To(BeZero())
+		})
+
+		It("uses the one-way delay if the RTT doesn't vary", func() {
+			pth := &PathInfo{SmoothedRTT: 40 * time.Millisecond, Alpha: 1}
+			Expect(deadlineProbability(pth, 20*time.Millisecond)).To(Equal(1.0))
+			Expect(deadlineProbability(pth, 19*time.Millisecond)).To(BeZero())
+		})
+
+		It("models the delay as a normal distribution", func() {
+			pth := &PathInfo{SmoothedRTT: 40 * time.Millisecond, MeanDeviation: 10 * time.Millisecond, Alpha: 1}
+			Expect(deadlineProbability(pth, 20*time.Millisecond)).To(BeNumerically("~", 0.5, 1e-9))
+			Expect(deadlineProbability(pth, 25*time.Millisecond)).To(BeNumerically("~", 0.5*(1+math.Erf(1/math.Sqrt2)), 1e-9))
+			Expect(deadlineProbability(pth, 15*time.Millisecond)).To(BeNumerically("<", 0.5))
+		})
+
+		It("scales the delay by the alpha of the path", func() {
+			pth := &PathInfo{SmoothedRTT: 40 * time.Millisecond, Alpha: 1.5}
+			Expect(deadlineProbability(pth, 25*time.Millisecond)).To(BeZero())
+			Expect(deadlineProbability(pth, 30*time.Millisecond)).To(Equal(1.0))
+		})
+	})
+
+	Context("redundant scheduler", func() {
+		var (
+			sch      *redundantScheduler
+			snapshot *PathSnapshot
+		)
+
+		BeforeEach(func() {
+			sch = newRedundantScheduler(&Config{}).(*redundantScheduler)
+			snapshot = &PathSnapshot{
+				RemainingBudget: math.Inf(1),
+				Paths: []PathInfo{
+					{PathID: 0, SendingAllowed: true, PacketsSent: 10},
+					{PathID: 1, SendingAllowed: true, SmoothedRTT: 20 * time.Millisecond, MeanDeviation: 20 * time.Millisecond, Alpha: 1, PacketsSent: 50},
+					{PathID: 3, SendingAllowed: true, SmoothedRTT: 30 * time.Millisecond, MeanDeviation: 2 * time.Millisecond, Alpha: 1, PacketsSent: 40},
+					{PathID: 5, SendingAllowed: true, SmoothedRTT: 60 * time.Millisecond, Alpha: 1},
+				},
+			}
+		})
+
+		It("sends on the lowest RTT path", func() {
+			Expect(sch.SelectPath(snapshot)).To(Equal(PathID(1)))
+		})
+
+		It("doesn't duplicate packets that are likely to meet their deadline", func() {
+			Expect(sch.SelectDuplicates(snapshot, 1, 50*time.Millisecond)).To(BeEmpty())
+		})
+
+		It("duplicates on the path most likely to meet the deadline", func() {
+			Expect(sch.SelectDuplicates(snapshot, 1, 18*time.Millisecond)).To(Equal([]PathID{3}))
+		})
+
+		It("only counts the duplicates that were sent", func() {
+			Expect(sch.SelectDuplicates(snapshot, 1, 18*time.Millisecond)).To(HaveLen(1))
+			Expect(sch.duplicates).To(BeZero())
+			sch.DuplicateSent(3, 1.5)
+			Expect(sch.duplicates).To(Equal(uint64(1)))
+			Expect(sch.duplicateCost).To(Equal(1.5))
+		})
+
+		It("sends on more paths if allowed", func() {
+			snapshot.Path(3).MeanDeviation = 10 * time.Millisecond
+			snapshot.Path(5).SmoothedRTT = 34 * time.Millisecond
+			snapshot.Path(5).MeanDeviation = 10 * time.Millisecond
+			Expect(sch.SelectDuplicates(snapshot, 1, 18*time.Millisecond)).To(Equal([]PathID{3}))
+			sch.config.MaxCopies = 3
+			Expect(sch.SelectDuplicates(snapshot, 1, 18*time.Millisecond)).To(Equal([]PathID{3, 5}))
+		})
+
+		It("doesn't use paths that can't send", func() {
+			snapshot.Path(3).SendingAllowed = false
+			Expect(sch.SelectDuplicates(snapshot, 1, 18*time.Millisecond)).To(BeEmpty())
+		})
+
+		It("respects the duplicate ratio", func() {
+			sch.config.MaxDuplicateRatio = 0.01
+			Expect(sch.SelectDuplicates(snapshot, 1, 18*time.Millisecond)).To(HaveLen(1))
+			sch.DuplicateSent(3, 0)
+			Expect(sch.SelectDuplicates(snapshot, 1, 18*time.Millisecond)).To(BeEmpty())
+		})
+
+		It("counts the packets sent on backup paths for the duplicate ratio", func() {
+			sch.config.MaxDuplicateRatio = 0.01
+			sch.DuplicateSent(3, 0)
+			Expect(sch.SelectDuplicates(snapshot, 1, 18*time.Millisecond)).To(BeEmpty())
+			snapshot.BackupPaths = []PathInfo{{PathID: 7, Priority: PathPriorityBackup, PacketsSent: 100}}
+			Expect(sch.SelectDuplicates(snapshot, 1, 18*time.Millisecond)).To(HaveLen(1))
+		})
+
+		It("respects the cost budget", func() {
+			snapshot.Path(3).PacketCost = 2
+			snapshot.RemainingBudget = 1
+			Expect(sch.SelectDuplicates(snapshot, 1, 18*time.Millisecond)).To(BeEmpty())
+			snapshot.RemainingBudget = math.Inf(1)
+			sch.config.MaxDuplicateCost = 3
+			Expect(sch.SelectDuplicates(snapshot, 1, 18*time.Millisecond)).To(HaveLen(1))
+			sch.DuplicateSent(3, 2)
+			Expect(sch.SelectDuplicates(snapshot, 1, 18*time.Millisecond)).To(BeEmpty())
+		})
+
+		It("charges the cost of the duplicates that were sent", func() {
+			snapshot.Path(3).PacketCost = 2
+			sch.config.MaxDuplicateCost = 3
+			// the packet cost is an estimate, a small duplicate charged per byte costs less
+			sch.DuplicateSent(3, 0.5)
+			Expect(sch.SelectDuplicates(snapshot, 1, 18*time.Millisecond)).To(HaveLen(1))
+		})
+	})
+
+	Context("duplicate tracker", func() {
+		var tracker *duplicateTracker
+
+		original := pathPacket{pathID: 1, packetNumber: 10}
+		duplicate := pathPacket{pathID: 3, packetNumber: 4}
+
+		BeforeEach(func() {
+			tracker = newDuplicateTracker()
+			tracker.add(original, duplicate)
+		})
+
+		It("counts the deadlines saved by a duplicate", func() {
+			Expect(tracker.sent).To(Equal(uint64(1)))
+			tracker.receivedDeadlineSamples(3, []wire.DeadlineSample{{PacketNumber: 4, Lateness: -time.Millisecond}})
+			Expect(tracker.savedDeadlines).To(BeZero())
+			tracker.receivedDeadlineSamples(1, []wire.DeadlineSample{{PacketNumber: 10, Lateness: time.Millisecond}})
+			Expect(tracker.savedDeadlines).To(Equal(uint64(1)))
+			Expect(tracker.groups).To(BeEmpty())
+		})
+
+		It("doesn't count duplicates of packets that met their deadline", func() {
+			tracker.receivedDeadlineSamples(1, []wire.DeadlineSample{{PacketNumber: 10, Lateness: -time.Millisecond}})
+			Expect(tracker.groups).To(BeEmpty())
+			tracker.receivedDeadlineSamples(3, []wire.DeadlineSample{{PacketNumber: 4, Lateness: -time.Millisecond}})
+			Expect(tracker.savedDeadlines).To(BeZero())
+		})
+
+		It("doesn't count duplicates that missed the deadline too", func() {
+			tracker.receivedDeadlineSamples(1, []wire.DeadlineSample{{PacketNumber: 10, Lateness: time.Millisecond}})
+			tracker.receivedDeadlineSamples(3, []wire.DeadlineSample{{PacketNumber: 4, Lateness: time.Millisecond}})
+			Expect(tracker.savedDeadlines).To(BeZero())
+		})
+
+		It("forgets the oldest duplicates", func() {
+			for i := 0; i < maxTrackedDuplicates; i++ {
+				tracker.add(pathPacket{pathID: 1, packetNumber: protocol.PacketNumber(100 + i)}, pathPacket{pathID: 3, packetNumber: protocol.PacketNumber(100 + i)})
+			}
+			Expect(tracker.order).To(HaveLen(maxTrackedDuplicates))
+			Expect(tracker.groups).ToNot(HaveKey(original))
+			Expect(tracker.groups).ToNot(HaveKey(duplicate))
+		})
+	})
+})
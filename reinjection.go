@@ -0,0 +1,103 @@
+package quic
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/ackhandler"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+)
+
+// reinjectionDelay estimates how long data takes to reach the peer on a path:
+// the one-way delay (half the smoothed RTT), scaled by the deadline stringency factor of the bandit, like in the batch LP
+func reinjectionDelay(pth *PathInfo) time.Duration {
+	return time.Duration(float64(pth.SmoothedRTT) / 2 * float64(pth.Alpha))
+}
+
+// selectReinjectionPath returns the path that delivers lost data the earliest, given the time left until its deadline.
+// inTime is false if even this path can't meet the deadline. Paths without an RTT estimate are not considered.
+// Backup paths are only used if no active path meets the deadline. It returns NoPath if no path can send.
+func selectReinjectionPath(s *PathSnapshot, timeLeft time.Duration) (pathID PathID, inTime bool) {
+	pathID, delay := selectLowestDelayPath(s.Paths, len(s.Paths) > 1)
+	if pathID == NoPath || delay > timeLeft {
+		if backupID, backupDelay := selectLowestDelayPath(s.BackupPaths, true); backupID != NoPath && (pathID == NoPath || backupDelay < delay) {
+			pathID, delay = backupID, backupDelay
+		}
+	}
+	return pathID, pathID != NoPath && delay <= timeLeft
+}
+
+// selectLowestDelayPath returns the path with the lowest reinjectionDelay, or NoPath
+func selectLowestDelayPath(paths []PathInfo, skipInitialPath bool) (pathID PathID, lowestDelay time.Duration) {
+	pathID = NoPath
+	for i := range paths {
+		pth := &paths[i]
+		// XXX Prevent using initial pathID if multiple paths
+		if pth.PathID == protocol.InitialPathID && skipInitialPath {
+			continue
+		}
+		if !pth.SendingAllowed || pth.PotentiallyFailed || pth.SmoothedRTT == 0 {
+			continue
+		}
+		delay := reinjectionDelay(pth)
+		if pathID == NoPath || delay < lowestDelay {
+			pathID = pth.PathID
+			lowestDelay = delay
+		}
+	}
+	return pathID, lowestDelay
+}
+
+// earliestStreamDeadline returns the earliest deadline of the stream frames, or the zero time if none has a deadline
+func earliestStreamDeadline(frames []wire.Frame) time.Time {
+	var deadline time.Time
+	for _, frame := range frames {
+		f, ok := frame.(*wire.StreamFrame)
+		if !ok || f.Deadline.IsZero() {
+			continue
+		}
+		if deadline.IsZero() || f.Deadline.Before(deadline) {
+			deadline = f.Deadline
+		}
+	}
+	return deadline
+}
+
+// reinject chooses the path for the stream data of a lost packet, so that it still meets its deadline
+func (sch *scheduler) reinject(s *session, packet *ackhandler.Packet, fromPth *path) {
+	// Don't send this packet's data on the path chosen for an earlier one
+	sch.reinjectPath = nil
+	deadline := earliestStreamDeadline(packet.Frames)
+	if deadline.IsZero() {
+		return
+	}
+	s.pathsLock.RLock()
+	defer s.pathsLock.RUnlock()
+	snapshot := sch.getPathSnapshot(s, true, true, fromPth)
+	pathID, inTime := selectReinjectionPath(snapshot, time.Until(deadline))
+	pth, ok := s.paths[pathID]
+	if !ok {
+		return
+	}
+	pth.reinjections++
+	if !inTime {
+		pth.lateReinjections++
+		utils.Debugf("\tNo path can deliver the data of packet 0x%x from path %d by its deadline, reinjecting on path %d", packet.PacketNumber, fromPth.pathID, pathID)
+	} else {
+		utils.Debugf("\tReinjecting the data of packet 0x%x from path %d on path %d", packet.PacketNumber, fromPth.pathID, pathID)
+	}
+	sch.reinjectPath = pth
+}
+
+// pendingReinjectionPath returns the path chosen for the queued stream retransmissions, if it can send now
+func (sch *scheduler) pendingReinjectionPath(hasStreamRetransmission bool) *path {
+	if !hasStreamRetransmission {
+		sch.reinjectPath = nil
+		return nil
+	}
+	if sch.reinjectPath == nil || !sch.reinjectPath.SendingAllowed() {
+		return nil
+	}
+	return sch.reinjectPath
+}
@@ -9,7 +9,6 @@ import (
 	"gonum.org/v1/gonum/stat/distuv"
 	"math"
 	"math/rand"
-	"os"
 	"time"
 
 	"bitbucket.com/marcmolla/gorl/agents"
@@ -20,58 +19,13 @@ import (
 
 const banditAlpha = 0.75
 const banditDimension = 6
-const batch = 6 // linOpt Batch Size
+const defaultBatchSize = 6 // linOpt Batch Size
 
 type scheduler struct {
 	// XXX Currently round-robin based, inspired from MPTCP scheduler
 	quotas map[protocol.PathID]uint
 	// Selected scheduler
-	SchedulerName string
-	// Is training?
-	Training bool
-	// Training Agent
-	TrainingAgent agents.TrainingAgent
-	// Normal Agent
-	Agent agents.Agent
-
-	// Cached state for training
-	cachedState  types.Vector
-	cachedPathID protocol.PathID
-
-	AllowedCongestion int
-
-	// async updated reward
-	record        uint64
-	episoderecord uint64
-	statevector   [6000]types.Vector
-	packetvector  [6000]uint64
-	//rewardvector [6000]types.Output
-	actionvector   [6000]int
-	recordDuration [6000]types.Output
-	lastfiretime   time.Time
-	zz             [6000]time.Time
-	waiting        uint64
-
-	// linUCB
-	fe           uint64
-	se           uint64
-	MAaF         [banditDimension][banditDimension]float64
-	MAaS         [banditDimension][banditDimension]float64
-	MbaF         [banditDimension]float64
-	MbaS         [banditDimension]float64
-	featureone   [6000]float64
-	featuretwo   [6000]float64
-	featurethree [6000]float64
-	featurefour  [6000]float64
-	featurefive  [6000]float64
-	featuresix   [6000]float64
-	// Retrans cache
-	retrans map[protocol.PathID]uint64
-
-	// Write experiences
-	DumpExp   bool
-	DumpPath  string
-	dumpAgent experienceAgent
+	pathScheduler PathScheduler
 
 	//czy
 	NotSentPackets   uint64
@@ -79,60 +33,53 @@ type scheduler struct {
 	totalPktWithCost uint64
 	curNotSentPacket uint8
 
-	waitPackets []time.Time
-	startTime   time.Time
-}
+	// Cost spent against the budget of the CostPolicy
+	cost *costTracker
 
-func (sch *scheduler) setup() {
-	sch.quotas = make(map[protocol.PathID]uint)
-	sch.retrans = make(map[protocol.PathID]uint64)
-	sch.waiting = 0
+	// reinjectPath is the path chosen for the queued stream retransmissions
+	reinjectPath *path
 
-	//Read lin to buffer
-	// file, err := os.Open("/App/output/lin")
-	file, err := os.Open("../output/lin")
-	if err != nil {
-		panic(err)
-	}
+	// batchSize is the number of packets scheduled at once by a BatchPathScheduler
+	batchSize int
+	// heldBack is the number of packets at the head of the queue that a batch held back, and that are not counted in NotSentPackets yet
+	heldBack int
 
-	for i := 0; i < banditDimension; i++ {
-		for j := 0; j < banditDimension; j++ {
-			fmt.Fscanln(file, &sch.MAaF[i][j])
-		}
-	}
-	for i := 0; i < banditDimension; i++ {
-		for j := 0; j < banditDimension; j++ {
-			fmt.Fscanln(file, &sch.MAaS[i][j])
-		}
-	}
-	for i := 0; i < banditDimension; i++ {
-		fmt.Fscanln(file, &sch.MbaF[i])
-	}
-	for i := 0; i < banditDimension; i++ {
-		fmt.Fscanln(file, &sch.MbaS[i])
-	}
-	file.Close()
+	// duplicates tracks the packets a DuplicatingPathScheduler sent on more than one path
+	duplicates *duplicateTracker
+}
 
-	//TODO: expose to config
-	sch.DumpPath = "/tmp/"
-	sch.dumpAgent.Setup()
+func (sch *scheduler) setup(config *Config) {
+	sch.quotas = make(map[protocol.PathID]uint)
+	sch.pathScheduler = newPathScheduler(config)
+	sch.cost = newCostTracker(config.CostPolicy)
+	sch.duplicates = newDuplicateTracker()
+	sch.batchSize = config.BatchSize
+	if sch.batchSize <= 0 {
+		sch.batchSize = defaultBatchSize
+	}
+}
 
-	sch.startTime = time.Now()
+// chargeCost accounts the cost of a packet sent on the path, and returns it
+func (sch *scheduler) chargeCost(pth *path, length protocol.ByteCount) float64 {
+	if pth.price == 0 {
+		return 0
+	}
+	cost := sch.cost.policy.charge(pth.price, length)
+	pth.totalCost += cost
+	sch.totalCost += cost
+	sch.totalPktWithCost++
+	sch.cost.add(cost, time.Now())
+	return cost
+}
 
-	sch.cachedState = types.Vector{-1, -1}
-	if sch.SchedulerName == "dqnAgent" {
-		if sch.Training {
-			sch.TrainingAgent = GetTrainingAgent("", "", "", 0.)
-		} else {
-			sch.Agent = GetAgent("", "")
-		}
-	}
+// costConstraintAvailable says if CEDA-MPS should respect a cost budget
+func (sch *scheduler) costConstraintAvailable() bool {
+	return !math.IsInf(sch.cost.batchBudget(time.Now()), 1)
 }
 
 func (sch *scheduler) getRetransmission(s *session) (hasRetransmission bool, retransmitPacket *ackhandler.Packet, pth *path) {
 	// check for retransmissions first
 	for {
-		// TODO add ability to reinject on another path
 		// XXX We need to check on ALL paths if any packet should be first retransmitted
 		s.pathsLock.RLock()
 	retransmitLoop:
@@ -158,6 +105,8 @@ func (sch *scheduler) getRetransmission(s *session) (hasRetransmission bool, ret
 			return
 		}
 		utils.Debugf("\tDequeueing retransmission of packet 0x%x from path %d", retransmitPacket.PacketNumber, pth.pathID)
+		// reinject the stream data on the path that can still meet its deadline
+		sch.reinject(s, retransmitPacket, pth)
 		// resend the frames that were in the packet
 		for _, frame := range retransmitPacket.GetFramesForRetransmission() {
 			switch f := frame.(type) {
@@ -173,6 +122,9 @@ func (sch *scheduler) getRetransmission(s *session) (hasRetransmission bool, ret
 			case *wire.PathsFrame:
 				// Schedule a new PATHS frame to send
 				s.schedulePathsFrame()
+			case *wire.PathPriorityFrame:
+				// Only resend the priority the path still has
+				s.retransmitPathPriorityFrame(f)
 			default:
 				s.packer.QueueControlFrame(frame, pth)
 			}
@@ -181,202 +133,133 @@ func (sch *scheduler) getRetransmission(s *session) (hasRetransmission bool, ret
 	return
 }
 
-func (sch *scheduler) selectPathRoundRobin(s *session, hasRetransmission bool, hasStreamRetransmission bool, fromPth *path) *path {
-	if sch.quotas == nil {
-		sch.setup()
-	}
+// roundRobinScheduler sends on the path with the lowest quota
+type roundRobinScheduler struct{}
 
-	// XXX Avoid using PathID 0 if there is more than 1 path
-	if len(s.paths) <= 1 {
-		if !hasRetransmission && !s.paths[protocol.InitialPathID].SendingAllowed() {
-			return nil
-		}
-		return s.paths[protocol.InitialPathID]
-	}
+func newRoundRobinScheduler(*Config) PathScheduler { return &roundRobinScheduler{} }
 
+func (sch *roundRobinScheduler) SelectPath(s *PathSnapshot) PathID {
 	// TODO cope with decreasing number of paths (needed?)
-	var selectedPath *path
-	var lowerQuota, currentQuota uint
-	var ok bool
-
+	selectedPathID := NoPath
 	// Max possible value for lowerQuota at the beginning
-	lowerQuota = ^uint(0)
+	lowerQuota := ^uint(0)
 
 pathLoop:
-	for pathID, pth := range s.paths {
+	for _, pth := range s.Paths {
 		// Don't block path usage if we retransmit, even on another path
-		if !hasRetransmission && !pth.SendingAllowed() {
+		if !s.HasRetransmission && !pth.SendingAllowed {
 			continue pathLoop
 		}
 
 		// If this path is potentially failed, do no consider it for sending
-		if pth.potentiallyFailed.Get() {
+		if pth.PotentiallyFailed {
 			continue pathLoop
 		}
 
 		// XXX Prevent using initial pathID if multiple paths
-		if pathID == protocol.InitialPathID {
+		if pth.PathID == protocol.InitialPathID {
 			continue pathLoop
 		}
 
-		currentQuota, ok = sch.quotas[pathID]
-		if !ok {
-			sch.quotas[pathID] = 0
-			currentQuota = 0
-		}
-
-		if currentQuota < lowerQuota {
-			selectedPath = pth
-			lowerQuota = currentQuota
+		if pth.Quota < lowerQuota {
+			selectedPathID = pth.PathID
+			lowerQuota = pth.Quota
 		}
 	}
 
-	return selectedPath
-
+	return selectedPathID
 }
 
-func (sch *scheduler) selectPathLowLatency(s *session, hasRetransmission bool, hasStreamRetransmission bool, fromPth *path) *path {
-	utils.Debugf("selectPathLowLatency")
-	// XXX Avoid using PathID 0 if there is more than 1 path
-	if len(s.paths) <= 1 {
-		if !hasRetransmission && !s.paths[protocol.InitialPathID].SendingAllowed() {
-			utils.Debugf("Only initial path and sending not allowed without retransmission")
-			utils.Debugf("SCH RTT - NIL")
-			return nil
-		}
-		utils.Debugf("Only initial path and sending is allowed or has retransmission")
-		utils.Debugf("SCH RTT - InitialPath")
-		return s.paths[protocol.InitialPathID]
-	}
+// rttScheduler sends on the available path with the lowest smoothed RTT
+type rttScheduler struct{}
 
-	// FIXME Only works at the beginning... Cope with new paths during the connection
-	if hasRetransmission && hasStreamRetransmission && fromPth.rttStats.SmoothedRTT() == 0 {
-		// Is there any other path with a lower number of packet sent?
-		currentQuota := sch.quotas[fromPth.pathID]
-		for pathID, pth := range s.paths {
-			if pathID == protocol.InitialPathID || pathID == fromPth.pathID {
-				continue
-			}
-			// The congestion window was checked when duplicating the packet
-			if sch.quotas[pathID] < currentQuota {
-				utils.Debugf("has ret, has stream ret and sRTT == 0")
-				utils.Debugf("SCH RTT - Selecting %d by low quota", pathID)
-				return pth
-			}
-		}
+func newRTTScheduler(*Config) PathScheduler { return &rttScheduler{} }
+
+func (sch *rttScheduler) SelectPath(s *PathSnapshot) PathID {
+	utils.Debugf("selectPathLowLatency")
+	if pathID := selectUnprobedPathForRetransmission(s); pathID != NoPath {
+		utils.Debugf("has ret, has stream ret and sRTT == 0")
+		utils.Debugf("SCH RTT - Selecting %d by low quota", pathID)
+		return pathID
 	}
 
-	var selectedPath *path
 	var lowerRTT time.Duration
-	var currentRTT time.Duration
-	selectedPathID := protocol.PathID(255)
+	var lowerQuota uint
+	selectedPathID := NoPath
 
 pathLoop:
-	for pathID, pth := range s.paths {
+	for _, pth := range s.Paths {
 		// Don't block path usage if we retransmit, even on another path
-		if !hasRetransmission && !pth.SendingAllowed() {
-			utils.Debugf("Discarding %d - no has ret and sending is not allowed ", pathID)
+		if !s.HasRetransmission && !pth.SendingAllowed {
+			utils.Debugf("Discarding %d - no has ret and sending is not allowed ", pth.PathID)
 			continue pathLoop
 		}
 
 		// If this path is potentially failed, do not consider it for sending
-		if pth.potentiallyFailed.Get() {
-			utils.Debugf("Discarding %d - potentially failed", pathID)
+		if pth.PotentiallyFailed {
+			utils.Debugf("Discarding %d - potentially failed", pth.PathID)
 			continue pathLoop
 		}
 
 		// XXX Prevent using initial pathID if multiple paths
-		if pathID == protocol.InitialPathID {
+		if pth.PathID == protocol.InitialPathID {
 			continue pathLoop
 		}
 
-		currentRTT = pth.rttStats.SmoothedRTT()
+		currentRTT := pth.SmoothedRTT
 
 		// Prefer staying single-path if not blocked by current path
 		// Don't consider this sample if the smoothed RTT is 0
 		if lowerRTT != 0 && currentRTT == 0 {
-			utils.Debugf("Discarding %d - currentRTT == 0 and lowerRTT != 0 ", pathID)
+			utils.Debugf("Discarding %d - currentRTT == 0 and lowerRTT != 0 ", pth.PathID)
 			continue pathLoop
 		}
 
 		// Case if we have multiple paths unprobed
-		if currentRTT == 0 {
-			currentQuota, ok := sch.quotas[pathID]
-			if !ok {
-				sch.quotas[pathID] = 0
-				currentQuota = 0
-			}
-			lowerQuota, _ := sch.quotas[selectedPathID]
-			if selectedPath != nil && currentQuota > lowerQuota {
-				utils.Debugf("Discarding %d - higher quota ", pathID)
-				continue pathLoop
-			}
+		if currentRTT == 0 && selectedPathID != NoPath && pth.Quota > lowerQuota {
+			utils.Debugf("Discarding %d - higher quota ", pth.PathID)
+			continue pathLoop
 		}
 
-		if currentRTT != 0 && lowerRTT != 0 && selectedPath != nil && currentRTT >= lowerRTT {
-			utils.Debugf("Discarding %d - higher SRTT ", pathID)
+		if currentRTT != 0 && lowerRTT != 0 && selectedPathID != NoPath && currentRTT >= lowerRTT {
+			utils.Debugf("Discarding %d - higher SRTT ", pth.PathID)
 			continue pathLoop
 		}
 
 		// Update
 		lowerRTT = currentRTT
-		selectedPath = pth
-		selectedPathID = pathID
+		lowerQuota = pth.Quota
+		selectedPathID = pth.PathID
 	}
 	utils.Debugf("SCH RTT - Selecting %d by low RTT: %f", selectedPathID, lowerRTT)
-	return selectedPath
+	return selectedPathID
 }
 
-func (sch *scheduler) selectBLEST(s *session, hasRetransmission bool, hasStreamRetransmission bool, fromPth *path) *path {
-	utils.Debugf("selectPathBLEST")
-	// XXX Avoid using PathID 0 if there is more than 1 path
-	if len(s.paths) <= 1 {
-		if !hasRetransmission && !s.paths[protocol.InitialPathID].SendingAllowed() {
-			return nil
-		}
-		return s.paths[protocol.InitialPathID]
-	}
-
-	// FIXME Only works at the beginning... Cope with new paths during the connection
-	if hasRetransmission && hasStreamRetransmission && fromPth.rttStats.SmoothedRTT() == 0 {
-		// Is there any other path with a lower number of packet sent?
-		currentQuota := sch.quotas[fromPth.pathID]
-		for pathID, pth := range s.paths {
-			if pathID == protocol.InitialPathID || pathID == fromPth.pathID {
-				continue
-			}
-			// The congestion window was checked when duplicating the packet
-			if sch.quotas[pathID] < currentQuota {
-				return pth
-			}
-		}
-	}
-
-	var bestPath *path
-	var secondBestPath *path
+// selectBestPaths returns the path with the lowest smoothed RTT, and the available path with the second lowest one.
+// If skipBlocked is set, paths that are not allowed to send are ignored unless there is a retransmission.
+func selectBestPaths(s *PathSnapshot, skipBlocked bool) (bestPath *PathInfo, secondBestPath *PathInfo) {
 	var lowerRTT time.Duration
-	var currentRTT time.Duration
 	var secondLowerRTT time.Duration
-	bestPathID := protocol.PathID(255)
 
 pathLoop:
-	for pathID, pth := range s.paths {
+	for i := range s.Paths {
+		pth := &s.Paths[i]
 		// Don't block path usage if we retransmit, even on another path
-		if !hasRetransmission && !pth.SendingAllowed() {
+		if skipBlocked && !s.HasRetransmission && !pth.SendingAllowed {
 			continue pathLoop
 		}
 
 		// If this path is potentially failed, do not consider it for sending
-		if pth.potentiallyFailed.Get() {
+		if pth.PotentiallyFailed {
 			continue pathLoop
 		}
 
 		// XXX Prevent using initial pathID if multiple paths
-		if pathID == protocol.InitialPathID {
+		if pth.PathID == protocol.InitialPathID {
 			continue pathLoop
 		}
 
-		currentRTT = pth.rttStats.SmoothedRTT()
+		currentRTT := pth.SmoothedRTT
 
 		// Prefer staying single-path if not blocked by current path
 		// Don't consider this sample if the smoothed RTT is 0
@@ -385,20 +268,12 @@ pathLoop:
 		}
 
 		// Case if we have multiple paths unprobed
-		if currentRTT == 0 {
-			currentQuota, ok := sch.quotas[pathID]
-			if !ok {
-				sch.quotas[pathID] = 0
-				currentQuota = 0
-			}
-			lowerQuota, _ := sch.quotas[bestPathID]
-			if bestPath != nil && currentQuota > lowerQuota {
-				continue pathLoop
-			}
+		if currentRTT == 0 && bestPath != nil && pth.Quota > bestPath.Quota {
+			continue pathLoop
 		}
 
 		if currentRTT >= lowerRTT {
-			if (secondLowerRTT == 0 || currentRTT < secondLowerRTT) && pth.SendingAllowed() {
+			if (secondLowerRTT == 0 || currentRTT < secondLowerRTT) && pth.SendingAllowed {
 				// Update second best available path
 				secondLowerRTT = currentRTT
 				secondBestPath = pth
@@ -411,150 +286,88 @@ pathLoop:
 		// Update
 		lowerRTT = currentRTT
 		bestPath = pth
-		bestPathID = pathID
 	}
+	return
+}
+
+// blestScheduler implements BLEST, which avoids head-of-line blocking by waiting for the fast path
+type blestScheduler struct{}
+
+func newBLESTScheduler(*Config) PathScheduler { return &blestScheduler{} }
+
+func (sch *blestScheduler) SelectPath(s *PathSnapshot) PathID {
+	utils.Debugf("selectPathBLEST")
+	if pathID := selectUnprobedPathForRetransmission(s); pathID != NoPath {
+		return pathID
+	}
+
+	bestPath, secondBestPath := selectBestPaths(s, true)
 
 	if bestPath == nil {
 		if secondBestPath != nil {
-			return secondBestPath
+			return secondBestPath.PathID
 		}
-		return nil
+		return NoPath
 	}
 
-	if hasRetransmission || bestPath.SendingAllowed() {
-		return bestPath
+	if s.HasRetransmission || bestPath.SendingAllowed {
+		return bestPath.PathID
 	}
 
 	if secondBestPath == nil {
-		return nil
+		return NoPath
 	}
-	cwndBest := uint64(bestPath.sentPacketHandler.GetCongestionWindow())
+	lowerRTT := bestPath.SmoothedRTT
+	secondLowerRTT := secondBestPath.SmoothedRTT
+	cwndBest := uint64(bestPath.CongestionWindow)
 	FirstCo := uint64(protocol.DefaultTCPMSS) * uint64(secondLowerRTT) * (cwndBest*2*uint64(lowerRTT) + uint64(secondLowerRTT) - uint64(lowerRTT))
-	BSend, _ := s.flowControlManager.SendWindowSize(protocol.StreamID(5))
-	SecondCo := 2 * 1 * uint64(lowerRTT) * uint64(lowerRTT) * (uint64(BSend) - (uint64(secondBestPath.sentPacketHandler.GetBytesInFlight()) + uint64(protocol.DefaultTCPMSS)))
+	BSend := s.SendWindow
+	SecondCo := 2 * 1 * uint64(lowerRTT) * uint64(lowerRTT) * (uint64(BSend) - (uint64(secondBestPath.BytesInFlight) + uint64(protocol.DefaultTCPMSS)))
 
 	if FirstCo > SecondCo {
-		return nil
+		return NoPath
 	} else {
-		return secondBestPath
+		return secondBestPath.PathID
 	}
 }
 
-func (sch *scheduler) selectECF(s *session, hasRetransmission bool, hasStreamRetransmission bool, fromPth *path) *path {
-	utils.Debugf("selectPathECF")
-	// XXX Avoid using PathID 0 if there is more than 1 path
-	if len(s.paths) <= 1 {
-		if !hasRetransmission && !s.paths[protocol.InitialPathID].SendingAllowed() {
-			return nil
-		}
-		return s.paths[protocol.InitialPathID]
-	}
-
-	// FIXME Only works at the beginning... Cope with new paths during the connection
-	if hasRetransmission && hasStreamRetransmission && fromPth.rttStats.SmoothedRTT() == 0 {
-		// Is there any other path with a lower number of packet sent?
-		currentQuota := sch.quotas[fromPth.pathID]
-		for pathID, pth := range s.paths {
-			if pathID == protocol.InitialPathID || pathID == fromPth.pathID {
-				continue
-			}
-			// The congestion window was checked when duplicating the packet
-			if sch.quotas[pathID] < currentQuota {
-				return pth
-			}
-		}
-	}
-
-	var bestPath *path
-	var secondBestPath *path
-	var lowerRTT time.Duration
-	var currentRTT time.Duration
-	var secondLowerRTT time.Duration
-	bestPathID := protocol.PathID(255)
-
-pathLoop:
-	for pathID, pth := range s.paths {
-		// Don't block path usage if we retransmit, even on another path
-		if !hasRetransmission && !pth.SendingAllowed() {
-			continue pathLoop
-		}
-
-		// If this path is potentially failed, do not consider it for sending
-		if pth.potentiallyFailed.Get() {
-			continue pathLoop
-		}
-
-		// XXX Prevent using initial pathID if multiple paths
-		if pathID == protocol.InitialPathID {
-			continue pathLoop
-		}
-
-		currentRTT = pth.rttStats.SmoothedRTT()
-
-		// Prefer staying single-path if not blocked by current path
-		// Don't consider this sample if the smoothed RTT is 0
-		if lowerRTT != 0 && currentRTT == 0 {
-			continue pathLoop
-		}
-
-		// Case if we have multiple paths unprobed
-		if currentRTT == 0 {
-			currentQuota, ok := sch.quotas[pathID]
-			if !ok {
-				sch.quotas[pathID] = 0
-				currentQuota = 0
-			}
-			lowerQuota, _ := sch.quotas[bestPathID]
-			if bestPath != nil && currentQuota > lowerQuota {
-				continue pathLoop
-			}
-		}
+// ecfScheduler implements ECF (Earliest Completion First)
+type ecfScheduler struct {
+	waiting uint64
+}
 
-		if currentRTT >= lowerRTT {
-			if (secondLowerRTT == 0 || currentRTT < secondLowerRTT) && pth.SendingAllowed() {
-				// Update second best available path
-				secondLowerRTT = currentRTT
-				secondBestPath = pth
-			}
-			if currentRTT != 0 && lowerRTT != 0 && bestPath != nil {
-				continue pathLoop
-			}
-		}
+func newECFScheduler(*Config) PathScheduler { return &ecfScheduler{} }
 
-		// Update
-		lowerRTT = currentRTT
-		bestPath = pth
-		bestPathID = pathID
+func (sch *ecfScheduler) SelectPath(s *PathSnapshot) PathID {
+	utils.Debugf("selectPathECF")
+	if pathID := selectUnprobedPathForRetransmission(s); pathID != NoPath {
+		return pathID
 	}
 
+	bestPath, secondBestPath := selectBestPaths(s, true)
+
 	if bestPath == nil {
 		if secondBestPath != nil {
-			return secondBestPath
+			return secondBestPath.PathID
 		}
-		return nil
+		return NoPath
 	}
 
-	if hasRetransmission || bestPath.SendingAllowed() {
-		return bestPath
+	if s.HasRetransmission || bestPath.SendingAllowed {
+		return bestPath.PathID
 	}
 
 	if secondBestPath == nil {
-		return nil
+		return NoPath
 	}
 
-	var queueSize uint64
-	getQueueSize := func(s *stream) (bool, error) {
-		if s != nil {
-			queueSize = queueSize + uint64(s.lenOfDataForWriting())
-		}
-		return true, nil
-	}
-	s.streamsMap.Iterate(getQueueSize)
-
-	cwndBest := uint64(bestPath.sentPacketHandler.GetCongestionWindow())
-	cwndSecond := uint64(secondBestPath.sentPacketHandler.GetCongestionWindow())
-	deviationBest := uint64(bestPath.rttStats.MeanDeviation())
-	deviationSecond := uint64(secondBestPath.rttStats.MeanDeviation())
+	queueSize := uint64(s.QueuedBytes)
+	lowerRTT := bestPath.SmoothedRTT
+	secondLowerRTT := secondBestPath.SmoothedRTT
+	cwndBest := uint64(bestPath.CongestionWindow)
+	cwndSecond := uint64(secondBestPath.CongestionWindow)
+	deviationBest := uint64(bestPath.MeanDeviation)
+	deviationSecond := uint64(secondBestPath.MeanDeviation)
 
 	delta := deviationBest
 	if deviationBest < deviationSecond {
@@ -576,97 +389,194 @@ pathLoop:
 		rhsSecond := cwndSecond * (2*uint64(lowerRTT) + delta)
 		if lhsSecond > rhsSecond {
 			sch.waiting = 1
-			return nil
+			return NoPath
 		}
 	} else {
 		sch.waiting = 0
 	}
 
-	return secondBestPath
+	return secondBestPath.PathID
 }
 
-func (sch *scheduler) selectPathLowBandit(s *session, hasRetransmission bool, hasStreamRetransmission bool, fromPth *path) *path {
-	// XXX Avoid using PathID 0 if there is more than 1 path
-	if len(s.paths) <= 1 {
-		if !hasRetransmission && !s.paths[protocol.InitialPathID].SendingAllowed() {
-			return nil
-		}
-		return s.paths[protocol.InitialPathID]
+// linUCB lazily loads the linUCB state from a LinUCBStore
+type linUCB struct {
+	store LinUCBStore
+	state *LinUCBState
+}
+
+func newLinUCB(config *Config) linUCB {
+	store := defaultLinUCBStore
+	if config != nil && config.LinUCBStore != nil {
+		store = config.LinUCBStore
 	}
+	return linUCB{store: store}
+}
 
-	// FIXME Only works at the beginning... Cope with new paths during the connection
-	if hasRetransmission && hasStreamRetransmission && fromPth.rttStats.SmoothedRTT() == 0 {
-		// Is there any other path with a lower number of packet sent?
-		currentQuota := sch.quotas[fromPth.pathID]
-		for pathID, pth := range s.paths {
-			if pathID == protocol.InitialPathID || pathID == fromPth.pathID {
-				continue
-			}
-			// The congestion window was checked when duplicating the packet
-			if sch.quotas[pathID] < currentQuota {
-				return pth
+// linUCBState returns the state, loading it on first use
+func (l *linUCB) linUCBState() *LinUCBState {
+	if l.state == nil {
+		state, err := l.store.Load()
+		if err != nil {
+			if err != ErrLinUCBStateNotFound {
+				utils.Errorf("Loading the linUCB state failed, starting from scratch: %s", err)
 			}
+			state = newLinUCBState()
 		}
+		l.state = state
 	}
+	return l.state
+}
 
-	var bestPath *path
-	var secondBestPath *path
-	var lowerRTT time.Duration
-	var currentRTT time.Duration
-	var secondLowerRTT time.Duration
-	bestPathID := protocol.PathID(255)
+// saveLinUCBState stores the state, if it was used
+func (l *linUCB) saveLinUCBState() error {
+	if l.state == nil {
+		return nil
+	}
+	return l.store.Save(l.state)
+}
 
-pathLoop:
-	for pathID, pth := range s.paths {
-		// If this path is potentially failed, do not consider it for sending
-		if pth.potentiallyFailed.Get() {
-			continue pathLoop
+// theta returns the estimated reward of both paths for the feature, and the confidence widths
+func (l *LinUCBState) theta(feature *mat.Dense) (thetaFPro, thetaSPro, featureFProTwo, featureSProTwo float64) {
+	// Migrate from buffer to local variables
+	AaF := mat.NewDense(banditDimension, banditDimension, nil)
+	for i := 0; i < banditDimension; i++ {
+		for j := 0; j < banditDimension; j++ {
+			AaF.Set(i, j, l.MAaF[i][j])
+		}
+	}
+	AaS := mat.NewDense(banditDimension, banditDimension, nil)
+	for i := 0; i < banditDimension; i++ {
+		for j := 0; j < banditDimension; j++ {
+			AaS.Set(i, j, l.MAaS[i][j])
 		}
+	}
+	baF := mat.NewDense(banditDimension, 1, nil)
+	for i := 0; i < banditDimension; i++ {
+		baF.Set(i, 0, l.MbaF[i])
+	}
+	baS := mat.NewDense(banditDimension, 1, nil)
+	for i := 0; i < banditDimension; i++ {
+		baS.Set(i, 0, l.MbaS[i])
+	}
+
+	//Obtain theta
+	AaIF := mat.NewDense(banditDimension, banditDimension, nil)
+	AaIF.Inverse(AaF)
+	thetaF := mat.NewDense(banditDimension, 1, nil)
+	thetaF.Product(AaIF, baF)
+
+	AaIS := mat.NewDense(banditDimension, banditDimension, nil)
+	AaIS.Inverse(AaS)
+	thetaS := mat.NewDense(banditDimension, 1, nil)
+	thetaS.Product(AaIS, baS)
+
+	//Obtain bandit value
+	thetaFProM := mat.NewDense(1, 1, nil)
+	thetaFProM.Product(thetaF.T(), feature)
+	featureFProOne := mat.NewDense(1, banditDimension, nil)
+	featureFProOne.Product(feature.T(), AaIF)
+	featureFProTwoM := mat.NewDense(1, 1, nil)
+	featureFProTwoM.Product(featureFProOne, feature)
+
+	thetaSProM := mat.NewDense(1, 1, nil)
+	thetaSProM.Product(thetaS.T(), feature)
+	featureSProOne := mat.NewDense(1, banditDimension, nil)
+	featureSProOne.Product(feature.T(), AaIS)
+	featureSProTwoM := mat.NewDense(1, 1, nil)
+	featureSProTwoM.Product(featureSProOne, feature)
+
+	return thetaFProM.At(0, 0), thetaSProM.At(0, 0), featureFProTwoM.At(0, 0), featureSProTwoM.At(0, 0)
+}
 
-		// XXX Prevent using initial pathID if multiple paths
-		if pathID == protocol.InitialPathID {
-			continue pathLoop
+// update adds the reward observed for the feature to the matrices of the first or second path
+func (l *LinUCBState) update(feature *mat.Dense, reward float64, second bool) {
+	Aa, ba := &l.MAaF, &l.MbaF
+	if second {
+		Aa, ba = &l.MAaS, &l.MbaS
+	}
+	rewardMul := mat.NewDense(banditDimension, 1, nil)
+	rewardMul.Scale(reward, feature)
+	baM := mat.NewDense(banditDimension, 1, nil)
+	for i := 0; i < banditDimension; i++ {
+		baM.Set(i, 0, ba[i])
+	}
+	baM.Add(baM, rewardMul)
+	for i := 0; i < banditDimension; i++ {
+		ba[i] = baM.At(i, 0)
+	}
+	featureMul := mat.NewDense(banditDimension, banditDimension, nil)
+	featureMul.Product(feature, feature.T())
+	AaM := mat.NewDense(banditDimension, banditDimension, nil)
+	for i := 0; i < banditDimension; i++ {
+		for j := 0; j < banditDimension; j++ {
+			AaM.Set(i, j, Aa[i][j])
+		}
+	}
+	AaM.Add(AaM, featureMul)
+	for i := 0; i < banditDimension; i++ {
+		for j := 0; j < banditDimension; j++ {
+			Aa[i][j] = AaM.At(i, j)
 		}
+	}
+}
 
-		currentRTT = pth.rttStats.SmoothedRTT()
+// getBanditFeature computes the linUCB feature vector of the two best paths
+func getBanditFeature(s *PathSnapshot, bestPath *PathInfo, secondBestPath *PathInfo) *mat.Dense {
+	cwndBest := float64(bestPath.CongestionWindow)
+	cwndSecond := float64(secondBestPath.CongestionWindow)
+	BSend := s.SendWindow
+	inflightf := float64(bestPath.BytesInFlight)
+	inflights := float64(secondBestPath.BytesInFlight)
+	llowerRTT := bestPath.LatestRTT
+	lsecondLowerRTT := secondBestPath.LatestRTT
+	feature := mat.NewDense(banditDimension, 1, nil)
+	if 0 < float64(lsecondLowerRTT) && 0 < float64(llowerRTT) {
+		feature.Set(0, 0, cwndBest/float64(llowerRTT))
+		feature.Set(2, 0, float64(BSend)/float64(llowerRTT))
+		feature.Set(4, 0, inflightf/float64(llowerRTT))
+		feature.Set(1, 0, inflights/float64(lsecondLowerRTT))
+		feature.Set(3, 0, float64(BSend)/float64(lsecondLowerRTT))
+		feature.Set(5, 0, cwndSecond/float64(lsecondLowerRTT))
+	}
+	return feature
+}
 
-		// Prefer staying single-path if not blocked by current path
-		// Don't consider this sample if the smoothed RTT is 0
-		if lowerRTT != 0 && currentRTT == 0 {
-			continue pathLoop
-		}
+// selectFallbackPath is used by the bandit schedulers when no second path is available
+func selectFallbackPath(s *PathSnapshot) PathID {
+	if initialPath := s.Path(protocol.InitialPathID); (initialPath != nil && initialPath.SendingAllowed) || s.HasRetransmission {
+		return protocol.InitialPathID
+	}
+	return NoPath
+}
 
-		// Case if we have multiple paths unprobed
-		if currentRTT == 0 {
-			currentQuota, ok := sch.quotas[pathID]
-			if !ok {
-				sch.quotas[pathID] = 0
-				currentQuota = 0
-			}
-			lowerQuota, _ := sch.quotas[bestPathID]
-			if bestPath != nil && currentQuota > lowerQuota {
-				continue pathLoop
-			}
-		}
+// banditScheduler uses linUCB to decide whether to wait for the fast path or to send on the slow path
+type banditScheduler struct {
+	linUCB
 
-		if currentRTT >= lowerRTT {
-			if (secondLowerRTT == 0 || currentRTT < secondLowerRTT) && pth.SendingAllowed() {
-				// Update second best available path
-				secondLowerRTT = currentRTT
-				secondBestPath = pth
-			}
-			if currentRTT != 0 && lowerRTT != 0 && bestPath != nil {
-				continue pathLoop
-			}
-		}
+	waiting uint64
 
-		// Update
-		lowerRTT = currentRTT
-		bestPath = pth
-		bestPathID = pathID
+	// async updated reward
+	record        uint64
+	episoderecord uint64
+	packetvector  [6000]uint64
+	actionvector  [6000]int
+	zz            [6000]time.Time
+	fe            uint64
+	se            uint64
+	features      [6000][banditDimension]float64
+}
+
+func newBanditScheduler(config *Config) PathScheduler {
+	return &banditScheduler{linUCB: newLinUCB(config)}
+}
 
+func (sch *banditScheduler) SelectPath(s *PathSnapshot) PathID {
+	if pathID := selectUnprobedPathForRetransmission(s); pathID != NoPath {
+		return pathID
 	}
 
+	bestPath, secondBestPath := selectBestPaths(s, false)
+
 	//Get reward and Update Aa, ba
 	if bestPath != nil && secondBestPath != nil {
 		for sch.episoderecord < sch.record {
@@ -674,9 +584,9 @@ pathLoop:
 			cureNum := uint64(0)
 			curereward := float64(0)
 			if sch.actionvector[sch.episoderecord] == 0 {
-				cureNum = uint64(bestPath.sentPacketHandler.GetLeastUnacked() - 1)
+				cureNum = uint64(bestPath.LeastUnacked - 1)
 			} else {
-				cureNum = uint64(secondBestPath.sentPacketHandler.GetLeastUnacked() - 1)
+				cureNum = uint64(secondBestPath.LeastUnacked - 1)
 			}
 			if sch.packetvector[sch.episoderecord] <= cureNum {
 				curereward = float64(protocol.DefaultTCPMSS) / float64(time.Since(sch.zz[sch.episoderecord]))
@@ -685,558 +595,319 @@ pathLoop:
 			}
 			//Update Aa, ba
 			feature := mat.NewDense(banditDimension, 1, nil)
-			feature.Set(0, 0, sch.featureone[sch.episoderecord])
-			feature.Set(1, 0, sch.featuretwo[sch.episoderecord])
-			feature.Set(2, 0, sch.featurethree[sch.episoderecord])
-			feature.Set(3, 0, sch.featurefour[sch.episoderecord])
-			feature.Set(4, 0, sch.featurefive[sch.episoderecord])
-			feature.Set(5, 0, sch.featuresix[sch.episoderecord])
+			for i := 0; i < banditDimension; i++ {
+				feature.Set(i, 0, sch.features[sch.episoderecord][i])
+			}
 
 			if sch.actionvector[sch.episoderecord] == 0 {
-				rewardMul := mat.NewDense(banditDimension, 1, nil)
-				rewardMul.Scale(curereward, feature)
-				baF := mat.NewDense(banditDimension, 1, nil)
-				for i := 0; i < banditDimension; i++ {
-					baF.Set(i, 0, sch.MbaF[i])
-				}
-				baF.Add(baF, rewardMul)
-				for i := 0; i < banditDimension; i++ {
-					sch.MbaF[i] = baF.At(i, 0)
-				}
-				featureMul := mat.NewDense(banditDimension, banditDimension, nil)
-				featureMul.Product(feature, feature.T())
-				AaF := mat.NewDense(banditDimension, banditDimension, nil)
-				for i := 0; i < banditDimension; i++ {
-					for j := 0; j < banditDimension; j++ {
-						AaF.Set(i, j, sch.MAaF[i][j])
-					}
-				}
-				AaF.Add(AaF, featureMul)
-				for i := 0; i < banditDimension; i++ {
-					for j := 0; j < banditDimension; j++ {
-						sch.MAaF[i][j] = AaF.At(i, j)
-					}
-				}
+				sch.linUCBState().update(feature, curereward, false)
 				sch.fe += 1
 			} else {
-				rewardMul := mat.NewDense(banditDimension, 1, nil)
-				rewardMul.Scale(curereward, feature)
-				baS := mat.NewDense(banditDimension, 1, nil)
-				for i := 0; i < banditDimension; i++ {
-					baS.Set(i, 0, sch.MbaS[i])
-				}
-				baS.Add(baS, rewardMul)
-				for i := 0; i < banditDimension; i++ {
-					sch.MbaS[i] = baS.At(i, 0)
-				}
-				featureMul := mat.NewDense(banditDimension, banditDimension, nil)
-				featureMul.Product(feature, feature.T())
-				AaS := mat.NewDense(banditDimension, banditDimension, nil)
-				for i := 0; i < banditDimension; i++ {
-					for j := 0; j < banditDimension; j++ {
-						AaS.Set(i, j, sch.MAaS[i][j])
-					}
-				}
-				AaS.Add(AaS, featureMul)
-				for i := 0; i < banditDimension; i++ {
-					for j := 0; j < banditDimension; j++ {
-						sch.MAaS[i][j] = AaS.At(i, j)
-					}
-				}
+				sch.linUCBState().update(feature, curereward, true)
 				sch.se += 1
 			}
 			//Update pointer
-			sch.episoderecord += 1
-		}
-	}
-
-	if bestPath == nil {
-		if secondBestPath != nil {
-			return secondBestPath
-		}
-		if s.paths[protocol.InitialPathID].SendingAllowed() || hasRetransmission {
-			return s.paths[protocol.InitialPathID]
-		} else {
-			return nil
-		}
-	}
-	if bestPath.SendingAllowed() {
-		sch.waiting = 0
-		return bestPath
-	}
-	if secondBestPath == nil {
-		if s.paths[protocol.InitialPathID].SendingAllowed() || hasRetransmission {
-			return s.paths[protocol.InitialPathID]
-		} else {
-			return nil
-		}
-	}
-
-	if hasRetransmission && secondBestPath.SendingAllowed() {
-		return secondBestPath
-	}
-	if hasRetransmission {
-		return s.paths[protocol.InitialPathID]
-	}
-
-	if sch.waiting == 1 {
-		return nil
-	} else {
-		// Migrate from buffer to local variables
-		AaF := mat.NewDense(banditDimension, banditDimension, nil)
-		for i := 0; i < banditDimension; i++ {
-			for j := 0; j < banditDimension; j++ {
-				AaF.Set(i, j, sch.MAaF[i][j])
-			}
-		}
-		AaS := mat.NewDense(banditDimension, banditDimension, nil)
-		for i := 0; i < banditDimension; i++ {
-			for j := 0; j < banditDimension; j++ {
-				AaS.Set(i, j, sch.MAaS[i][j])
-			}
-		}
-		baF := mat.NewDense(banditDimension, 1, nil)
-		for i := 0; i < banditDimension; i++ {
-			baF.Set(i, 0, sch.MbaF[i])
-		}
-		baS := mat.NewDense(banditDimension, 1, nil)
-		for i := 0; i < banditDimension; i++ {
-			baS.Set(i, 0, sch.MbaS[i])
-		}
-
-		//Features
-		cwndBest := float64(bestPath.sentPacketHandler.GetCongestionWindow())
-		cwndSecond := float64(secondBestPath.sentPacketHandler.GetCongestionWindow())
-		BSend, _ := s.flowControlManager.SendWindowSize(protocol.StreamID(5))
-		inflightf := float64(bestPath.sentPacketHandler.GetBytesInFlight())
-		inflights := float64(secondBestPath.sentPacketHandler.GetBytesInFlight())
-		llowerRTT := bestPath.rttStats.LatestRTT()
-		lsecondLowerRTT := secondBestPath.rttStats.LatestRTT()
-		feature := mat.NewDense(banditDimension, 1, nil)
-		if 0 < float64(lsecondLowerRTT) && 0 < float64(llowerRTT) {
-			feature.Set(0, 0, cwndBest/float64(llowerRTT))
-			feature.Set(2, 0, float64(BSend)/float64(llowerRTT))
-			feature.Set(4, 0, inflightf/float64(llowerRTT))
-			feature.Set(1, 0, inflights/float64(lsecondLowerRTT))
-			feature.Set(3, 0, float64(BSend)/float64(lsecondLowerRTT))
-			feature.Set(5, 0, cwndSecond/float64(lsecondLowerRTT))
-		} else {
-			feature.Set(0, 0, 0)
-			feature.Set(2, 0, 0)
-			feature.Set(4, 0, 0)
-			feature.Set(1, 0, 0)
-			feature.Set(3, 0, 0)
-			feature.Set(5, 0, 0)
-		}
-
-		//Buffer feature for latter update
-		sch.featureone[sch.record] = feature.At(0, 0)
-		sch.featuretwo[sch.record] = feature.At(1, 0)
-		sch.featurethree[sch.record] = feature.At(2, 0)
-		sch.featurefour[sch.record] = feature.At(3, 0)
-		sch.featurefive[sch.record] = feature.At(4, 0)
-		sch.featuresix[sch.record] = feature.At(5, 0)
-
-		//Obtain theta
-		AaIF := mat.NewDense(banditDimension, banditDimension, nil)
-		AaIF.Inverse(AaF)
-		thetaF := mat.NewDense(banditDimension, 1, nil)
-		thetaF.Product(AaIF, baF)
-
-		AaIS := mat.NewDense(banditDimension, banditDimension, nil)
-		AaIS.Inverse(AaS)
-		thetaS := mat.NewDense(banditDimension, 1, nil)
-		thetaS.Product(AaIS, baS)
-
-		//Obtain bandit value
-		thetaFPro := mat.NewDense(1, 1, nil)
-		thetaFPro.Product(thetaF.T(), feature)
-		featureFProOne := mat.NewDense(1, banditDimension, nil)
-		featureFProOne.Product(feature.T(), AaIF)
-		featureFProTwo := mat.NewDense(1, 1, nil)
-		featureFProTwo.Product(featureFProOne, feature)
-
-		thetaSPro := mat.NewDense(1, 1, nil)
-		thetaSPro.Product(thetaS.T(), feature)
-		featureSProOne := mat.NewDense(1, banditDimension, nil)
-		featureSProOne.Product(feature.T(), AaIS)
-		featureSProTwo := mat.NewDense(1, 1, nil)
-		featureSProTwo.Product(featureSProOne, feature)
-
-		//Make decision based on bandit value
-		if (thetaSPro.At(0, 0) + banditAlpha*math.Sqrt(featureSProTwo.At(0, 0))) < (thetaFPro.At(0, 0) + banditAlpha*math.Sqrt(featureFProTwo.At(0, 0))) {
-			sch.waiting = 1
-			sch.zz[sch.record] = time.Now()
-			sch.actionvector[sch.record] = 0
-			sch.packetvector[sch.record] = bestPath.sentPacketHandler.GetLastPackets() + 1
-			sch.record += 1
-			return nil
-		} else {
-			sch.waiting = 0
-			sch.zz[sch.record] = time.Now()
-			sch.actionvector[sch.record] = 1
-			sch.packetvector[sch.record] = secondBestPath.sentPacketHandler.GetLastPackets() + 1
-			sch.record += 1
-			return secondBestPath
+			sch.episoderecord += 1
 		}
-
 	}
 
-}
-
-func (sch *scheduler) selectPathPeek(s *session, hasRetransmission bool, hasStreamRetransmission bool, fromPth *path) *path {
-	// XXX Avoid using PathID 0 if there is more than 1 path
-	if len(s.paths) <= 1 {
-		if !hasRetransmission && !s.paths[protocol.InitialPathID].SendingAllowed() {
-			return nil
+	if bestPath == nil {
+		if secondBestPath != nil {
+			return secondBestPath.PathID
 		}
-		return s.paths[protocol.InitialPathID]
+		return selectFallbackPath(s)
+	}
+	if bestPath.SendingAllowed {
+		sch.waiting = 0
+		return bestPath.PathID
+	}
+	if secondBestPath == nil {
+		return selectFallbackPath(s)
 	}
 
-	// FIXME Only works at the beginning... Cope with new paths during the connection
-	if hasRetransmission && hasStreamRetransmission && fromPth.rttStats.SmoothedRTT() == 0 {
-		// Is there any other path with a lower number of packet sent?
-		currentQuota := sch.quotas[fromPth.pathID]
-		for pathID, pth := range s.paths {
-			if pathID == protocol.InitialPathID || pathID == fromPth.pathID {
-				continue
-			}
-			// The congestion window was checked when duplicating the packet
-			if sch.quotas[pathID] < currentQuota {
-				return pth
-			}
-		}
+	if s.HasRetransmission && secondBestPath.SendingAllowed {
+		return secondBestPath.PathID
+	}
+	if s.HasRetransmission {
+		return protocol.InitialPathID
 	}
 
-	var bestPath *path
-	var secondBestPath *path
-	var lowerRTT time.Duration
-	var currentRTT time.Duration
-	var secondLowerRTT time.Duration
-	bestPathID := protocol.PathID(255)
+	if sch.waiting == 1 {
+		return NoPath
+	}
 
-pathLoop:
-	for pathID, pth := range s.paths {
-		// If this path is potentially failed, do not consider it for sending
-		if pth.potentiallyFailed.Get() {
-			continue pathLoop
-		}
+	//Features
+	feature := getBanditFeature(s, bestPath, secondBestPath)
 
-		// XXX Prevent using initial pathID if multiple paths
-		if pathID == protocol.InitialPathID {
-			continue pathLoop
-		}
+	//Buffer feature for latter update
+	for i := 0; i < banditDimension; i++ {
+		sch.features[sch.record][i] = feature.At(i, 0)
+	}
 
-		currentRTT = pth.rttStats.SmoothedRTT()
+	thetaFPro, thetaSPro, featureFProTwo, featureSProTwo := sch.linUCBState().theta(feature)
 
-		// Prefer staying single-path if not blocked by current path
-		// Don't consider this sample if the smoothed RTT is 0
-		if lowerRTT != 0 && currentRTT == 0 {
-			continue pathLoop
-		}
+	//Make decision based on bandit value
+	if (thetaSPro + banditAlpha*math.Sqrt(featureSProTwo)) < (thetaFPro + banditAlpha*math.Sqrt(featureFProTwo)) {
+		sch.waiting = 1
+		sch.zz[sch.record] = time.Now()
+		sch.actionvector[sch.record] = 0
+		sch.packetvector[sch.record] = bestPath.LastPacketNumber + 1
+		sch.record += 1
+		return NoPath
+	}
+	sch.waiting = 0
+	sch.zz[sch.record] = time.Now()
+	sch.actionvector[sch.record] = 1
+	sch.packetvector[sch.record] = secondBestPath.LastPacketNumber + 1
+	sch.record += 1
+	return secondBestPath.PathID
+}
 
-		// Case if we have multiple paths unprobed
-		if currentRTT == 0 {
-			currentQuota, ok := sch.quotas[pathID]
-			if !ok {
-				sch.quotas[pathID] = 0
-				currentQuota = 0
-			}
-			lowerQuota, _ := sch.quotas[bestPathID]
-			if bestPath != nil && currentQuota > lowerQuota {
-				continue pathLoop
-			}
-		}
+// Close stores the learnt linUCB matrices for the next connection
+func (sch *banditScheduler) Close() error {
+	return sch.saveLinUCBState()
+}
 
-		if currentRTT >= lowerRTT {
-			if (secondLowerRTT == 0 || currentRTT < secondLowerRTT) && pth.SendingAllowed() {
-				// Update second best available path
-				secondLowerRTT = currentRTT
-				secondBestPath = pth
-			}
-			if currentRTT != 0 && lowerRTT != 0 && bestPath != nil {
-				continue pathLoop
-			}
-		}
+// peekabooScheduler implements Peekaboo, using the linUCB estimates with a stochastic decision
+type peekabooScheduler struct {
+	linUCB
 
-		// Update
-		lowerRTT = currentRTT
-		bestPath = pth
-		bestPathID = pathID
+	waiting uint64
+}
+
+func newPeekabooScheduler(config *Config) PathScheduler {
+	return &peekabooScheduler{linUCB: newLinUCB(config)}
+}
 
+func (sch *peekabooScheduler) SelectPath(s *PathSnapshot) PathID {
+	if pathID := selectUnprobedPathForRetransmission(s); pathID != NoPath {
+		return pathID
 	}
 
+	bestPath, secondBestPath := selectBestPaths(s, false)
+
 	if bestPath == nil {
 		if secondBestPath != nil {
-			return secondBestPath
-		}
-		if s.paths[protocol.InitialPathID].SendingAllowed() || hasRetransmission {
-			return s.paths[protocol.InitialPathID]
-		} else {
-			return nil
+			return secondBestPath.PathID
 		}
+		return selectFallbackPath(s)
 	}
-	if bestPath.SendingAllowed() {
+	if bestPath.SendingAllowed {
 		sch.waiting = 0
-		return bestPath
+		return bestPath.PathID
 	}
 	if secondBestPath == nil {
-		if s.paths[protocol.InitialPathID].SendingAllowed() || hasRetransmission {
-			return s.paths[protocol.InitialPathID]
-		} else {
-			return nil
-		}
+		return selectFallbackPath(s)
 	}
 
-	if hasRetransmission && secondBestPath.SendingAllowed() {
-		return secondBestPath
+	if s.HasRetransmission && secondBestPath.SendingAllowed {
+		return secondBestPath.PathID
 	}
-	if hasRetransmission {
-		return s.paths[protocol.InitialPathID]
+	if s.HasRetransmission {
+		return protocol.InitialPathID
 	}
 
 	if sch.waiting == 1 {
-		return nil
-	} else {
-		// Migrate from buffer to local variables
-		AaF := mat.NewDense(banditDimension, banditDimension, nil)
-		for i := 0; i < banditDimension; i++ {
-			for j := 0; j < banditDimension; j++ {
-				AaF.Set(i, j, sch.MAaF[i][j])
-			}
-		}
-		AaS := mat.NewDense(banditDimension, banditDimension, nil)
-		for i := 0; i < banditDimension; i++ {
-			for j := 0; j < banditDimension; j++ {
-				AaS.Set(i, j, sch.MAaS[i][j])
-			}
-		}
-		baF := mat.NewDense(banditDimension, 1, nil)
-		for i := 0; i < banditDimension; i++ {
-			baF.Set(i, 0, sch.MbaF[i])
-		}
-		baS := mat.NewDense(banditDimension, 1, nil)
-		for i := 0; i < banditDimension; i++ {
-			baS.Set(i, 0, sch.MbaS[i])
-		}
-
-		//Features
-		cwndBest := float64(bestPath.sentPacketHandler.GetCongestionWindow())
-		cwndSecond := float64(secondBestPath.sentPacketHandler.GetCongestionWindow())
-		BSend, _ := s.flowControlManager.SendWindowSize(protocol.StreamID(5))
-		inflightf := float64(bestPath.sentPacketHandler.GetBytesInFlight())
-		inflights := float64(secondBestPath.sentPacketHandler.GetBytesInFlight())
-		llowerRTT := bestPath.rttStats.LatestRTT()
-		lsecondLowerRTT := secondBestPath.rttStats.LatestRTT()
-		feature := mat.NewDense(banditDimension, 1, nil)
-		if 0 < float64(lsecondLowerRTT) && 0 < float64(llowerRTT) {
-			feature.Set(0, 0, cwndBest/float64(llowerRTT))
-			feature.Set(2, 0, float64(BSend)/float64(llowerRTT))
-			feature.Set(4, 0, inflightf/float64(llowerRTT))
-			feature.Set(1, 0, inflights/float64(lsecondLowerRTT))
-			feature.Set(3, 0, float64(BSend)/float64(lsecondLowerRTT))
-			feature.Set(5, 0, cwndSecond/float64(lsecondLowerRTT))
-		} else {
-			feature.Set(0, 0, 0)
-			feature.Set(2, 0, 0)
-			feature.Set(4, 0, 0)
-			feature.Set(1, 0, 0)
-			feature.Set(3, 0, 0)
-			feature.Set(5, 0, 0)
-		}
-
-		//Obtain theta
-		AaIF := mat.NewDense(banditDimension, banditDimension, nil)
-		AaIF.Inverse(AaF)
-		thetaF := mat.NewDense(banditDimension, 1, nil)
-		thetaF.Product(AaIF, baF)
-
-		AaIS := mat.NewDense(banditDimension, banditDimension, nil)
-		AaIS.Inverse(AaS)
-		thetaS := mat.NewDense(banditDimension, 1, nil)
-		thetaS.Product(AaIS, baS)
-
-		//Obtain bandit value
-		thetaFPro := mat.NewDense(1, 1, nil)
-		thetaFPro.Product(thetaF.T(), feature)
-
-		thetaSPro := mat.NewDense(1, 1, nil)
-		thetaSPro.Product(thetaS.T(), feature)
-
-		//Make decision based on bandit value and stochastic value
-		if thetaSPro.At(0, 0) < thetaFPro.At(0, 0) {
-			if rand.Intn(100) < 70 {
-				sch.waiting = 1
-				return nil
-			} else {
-				sch.waiting = 0
-				return secondBestPath
-			}
-		} else {
-			if rand.Intn(100) < 90 {
-				sch.waiting = 0
-				return secondBestPath
-			} else {
-				sch.waiting = 1
-				return nil
-			}
-		}
+		return NoPath
 	}
 
-}
+	//Features
+	feature := getBanditFeature(s, bestPath, secondBestPath)
+	thetaFPro, thetaSPro, _, _ := sch.linUCBState().theta(feature)
 
-func (sch *scheduler) selectPathRandom(s *session, hasRetransmission bool, hasStreamRetransmission bool, fromPth *path) *path {
-	utils.Debugf("selectPathRandom")
-	// XXX Avoid using PathID 0 if there is more than 1 path
-	if len(s.paths) <= 1 {
-		if !hasRetransmission && !s.paths[protocol.InitialPathID].SendingAllowed() {
-			return nil
+	//Make decision based on bandit value and stochastic value
+	if thetaSPro < thetaFPro {
+		if rand.Intn(100) < 70 {
+			sch.waiting = 1
+			return NoPath
 		}
-		return s.paths[protocol.InitialPathID]
+		sch.waiting = 0
+		return secondBestPath.PathID
+	}
+	if rand.Intn(100) < 90 {
+		sch.waiting = 0
+		return secondBestPath.PathID
 	}
-	var availablePaths []protocol.PathID
+	sch.waiting = 1
+	return NoPath
+}
 
-	for pathID, pth := range s.paths {
-		cong := float32(pth.sentPacketHandler.GetCongestionWindow()) - float32(pth.sentPacketHandler.GetBytesInFlight())
-		allowed := pth.SendingAllowed() || (cong <= 0 && float32(cong) >= -float32(pth.sentPacketHandler.GetCongestionWindow())*float32(sch.AllowedCongestion)*0.01)
+// fixedPathScheduler always sends on the same path, once it is available
+type fixedPathScheduler struct {
+	pathID PathID
+	// label selects the path by the label of its interface instead of by pathID, if it is set
+	label string
+}
 
-		if pathID != protocol.InitialPathID && (allowed || hasRetransmission) {
-			//if pathID != protocol.InitialPathID && (pth.SendingAllowed() || hasRetransmission){
-			availablePaths = append(availablePaths, pathID)
-		}
+func newPrimaryScheduler(config *Config) PathScheduler {
+	sch := &fixedPathScheduler{pathID: protocol.PathID(1)}
+	if config != nil {
+		sch.label = config.PrimaryPathLabel
 	}
+	return sch
+}
 
-	if len(availablePaths) == 0 {
-		return nil
+func newSecondPathScheduler(config *Config) PathScheduler {
+	sch := &fixedPathScheduler{pathID: protocol.PathID(3)}
+	if config != nil {
+		sch.label = config.SecondPathLabel
 	}
-
-	pathID := rand.Intn(len(availablePaths))
-	utils.Debugf("Selecting path %d", pathID)
-	return s.paths[availablePaths[pathID]]
+	return sch
 }
 
-func (sch *scheduler) selectFirstPath(s *session, hasRetransmission bool, hasStreamRetransmission bool, fromPth *path) *path {
-	if len(s.paths) <= 1 {
-		if !hasRetransmission && !s.paths[protocol.InitialPathID].SendingAllowed() {
-			return nil
-		}
-		return s.paths[protocol.InitialPathID]
+func (sch *fixedPathScheduler) SelectPath(s *PathSnapshot) PathID {
+	pth := s.Path(sch.pathID)
+	if sch.label != "" {
+		pth = s.PathWithLabel(sch.label)
 	}
-	for pathID, pth := range s.paths {
-		if pathID == protocol.PathID(1) && pth.SendingAllowed() {
-			return pth
-		}
+	if pth != nil && pth.SendingAllowed {
+		return pth.PathID
 	}
-
-	return nil
+	return NoPath
 }
 
-func (sch *scheduler) selectSecondPath(s *session, hasRetransmission bool, hasStreamRetransmission bool, fromPth *path) *path {
-	if len(s.paths) <= 1 {
-		if !hasRetransmission && !s.paths[protocol.InitialPathID].SendingAllowed() {
-			return nil
-		}
-		return s.paths[protocol.InitialPathID]
-	}
-	for pathID, pth := range s.paths {
-		if pathID == protocol.PathID(3) && pth.SendingAllowed() {
-			return pth
-		}
-	}
+// dqnScheduler selects the path with a DQN agent
+type dqnScheduler struct {
+	// Is training?
+	Training bool
+	// Training Agent
+	TrainingAgent agents.TrainingAgent
+	// Normal Agent
+	Agent agents.Agent
 
-	return nil
+	// async updated reward
+	record        uint64
+	episoderecord uint64
+	statevector   [6000]types.Vector
+	packetvector  [6000]uint64
+	//rewardvector [6000]types.Output
+	actionvector   [6000]int
+	recordDuration [6000]types.Output
+	lastfiretime   time.Time
+
+	// Write experiences
+	DumpExp   bool
+	dumpAgent experienceAgent
 }
 
-func (sch *scheduler) selectPathDQNAgent(s *session, hasRetransmission bool, hasStreamRetransmission bool, fromPth *path) *path {
-	// XXX Avoid using PathID 0 if there is more than 1 path
-	if len(s.paths) <= 1 {
-		if !hasRetransmission && !s.paths[protocol.InitialPathID].SendingAllowed() {
-			return nil
-		}
-		return s.paths[protocol.InitialPathID]
+func newDQNScheduler(config *Config) PathScheduler {
+	sch := &dqnScheduler{
+		Training: config.Training,
+		DumpExp:  config.DumpExperiences,
 	}
+	sch.dumpAgent.Setup()
+	if sch.Training {
+		sch.TrainingAgent = GetTrainingAgent("", "", "", 0.)
+	} else {
+		sch.Agent = GetAgent("", "")
+	}
+	return sch
+}
 
-	if len(s.paths) == 2 {
-		for pathID, path := range s.paths {
-			if pathID != protocol.InitialPathID {
-				utils.Debugf("Selecting path %d as unique path", pathID)
-				return path
+func (sch *dqnScheduler) SelectPath(s *PathSnapshot) PathID {
+	if len(s.Paths) == 2 {
+		for _, pth := range s.Paths {
+			if pth.PathID != protocol.InitialPathID {
+				utils.Debugf("Selecting path %d as unique path", pth.PathID)
+				return pth.PathID
 			}
 		}
 	}
 
 	//Check for available paths
-	var availablePaths []protocol.PathID
-	for pathID, path := range s.paths {
-		if path.sentPacketHandler.SendingAllowed() && pathID != protocol.InitialPathID {
-			availablePaths = append(availablePaths, pathID)
+	var availablePaths []PathID
+	for _, pth := range s.Paths {
+		if pth.SendingAllowed && pth.PathID != protocol.InitialPathID {
+			availablePaths = append(availablePaths, pth.PathID)
 		}
 	}
 
 	if len(availablePaths) == 0 {
-		if s.paths[protocol.InitialPathID].SendingAllowed() || hasRetransmission {
-			return s.paths[protocol.InitialPathID]
-		} else {
-			return nil
-		}
+		return selectFallbackPath(s)
 	} else if len(availablePaths) == 1 {
-		return s.paths[availablePaths[0]]
+		return availablePaths[0]
 	}
 
 	action, paths := GetStateAndReward(sch, s)
 
 	if paths == nil {
-		return s.paths[protocol.InitialPathID]
+		return protocol.InitialPathID
 	}
 
 	return paths[action]
 }
 
+// OnFinish closes the training episode
+func (sch *dqnScheduler) OnFinish(s *PathSnapshot, err error) {
+	if err != nil {
+		if err == ackhandler.ErrTooManyTrackedSentPackets && sch.Training {
+			sch.TrainingAgent.CloseEpisode(uint64(s.ConnectionID), -100, false)
+		}
+		return
+	}
+	if sch.Training {
+		var maxRTT time.Duration
+		for _, pth := range s.Paths {
+			if pth.SmoothedRTT > maxRTT {
+				maxRTT = pth.SmoothedRTT
+			}
+		}
+		sch.TrainingAgent.CloseEpisode(uint64(s.ConnectionID), RewardFinalGoodput(sch, s, s.Elapsed, maxRTT), false)
+	}
+	utils.Infof("Dump: %t, Training:%t", sch.DumpExp, sch.Training)
+	if sch.DumpExp && !sch.Training {
+		utils.Infof("Closing episode %d", uint64(s.ConnectionID))
+		sch.dumpAgent.CloseExperience(uint64(s.ConnectionID))
+	}
+}
+
 // Lock of s.paths must be held
 func (sch *scheduler) selectPath(s *session, hasRetransmission bool, hasStreamRetransmission bool, fromPth *path) *path {
-	// XXX Currently round-robin
-	if sch.SchedulerName == "rtt" {
-		return sch.selectPathLowLatency(s, hasRetransmission, hasStreamRetransmission, fromPth)
-	} else if sch.SchedulerName == "random" {
-		//random is roundrobin, not random
-		return sch.selectPathRoundRobin(s, hasRetransmission, hasStreamRetransmission, fromPth)
-	} else if sch.SchedulerName == "lowband" {
-		return sch.selectPathLowBandit(s, hasRetransmission, hasStreamRetransmission, fromPth)
-	} else if sch.SchedulerName == "peek" {
-		return sch.selectPathPeek(s, hasRetransmission, hasStreamRetransmission, fromPth)
-	} else if sch.SchedulerName == "ecf" {
-		return sch.selectECF(s, hasRetransmission, hasStreamRetransmission, fromPth)
-	} else if sch.SchedulerName == "blest" {
-		return sch.selectBLEST(s, hasRetransmission, hasStreamRetransmission, fromPth)
-	} else if sch.SchedulerName == "dqnAgent" {
-		return sch.selectPathDQNAgent(s, hasRetransmission, hasStreamRetransmission, fromPth)
-	} else if sch.SchedulerName == "primary" {
-		return sch.selectFirstPath(s, hasRetransmission, hasStreamRetransmission, fromPth)
-	} else if sch.SchedulerName == "secondPath" {
-		return sch.selectSecondPath(s, hasRetransmission, hasStreamRetransmission, fromPth)
-	} else {
-		// Default, rtt
-		return sch.selectPathLowLatency(s, hasRetransmission, hasStreamRetransmission, fromPth)
+	// XXX Avoid using PathID 0 if there is more than 1 path
+	if len(s.paths) <= 1 {
+		if !hasRetransmission && !s.paths[protocol.InitialPathID].SendingAllowed() {
+			return nil
+		}
+		return s.paths[protocol.InitialPathID]
+	}
+	if pth := sch.pendingReinjectionPath(hasStreamRetransmission); pth != nil {
+		return pth
+	}
+	snapshot := sch.getPathSnapshot(s, hasRetransmission, hasStreamRetransmission, fromPth)
+	return s.paths[sch.pathScheduler.SelectPath(snapshot)]
+}
+
+// finish notifies the PathScheduler that the session stopped sending
+func (sch *scheduler) finish(s *session, err error) {
+	finisher, ok := sch.pathScheduler.(PathSchedulerFinisher)
+	if !ok {
+		return
+	}
+	s.pathsLock.RLock()
+	snapshot := sch.getPathSnapshot(s, false, false, nil)
+	s.pathsLock.RUnlock()
+	finisher.OnFinish(snapshot, err)
+}
+
+// close notifies the PathScheduler that the session is closed
+func (sch *scheduler) close() {
+	closer, ok := sch.pathScheduler.(PathSchedulerCloser)
+	if !ok {
+		return
+	}
+	if err := closer.Close(); err != nil {
+		utils.Errorf("Closing the path scheduler failed: %s", err)
 	}
-	// return sch.selectPathRoundRobin(s, hasRetransmission, hasStreamRetransmission, fromPth)
 }
 
 // Lock of s.paths must be free (in case of log print)
 func (sch *scheduler) performPacketSending(s *session, windowUpdateFrames []*wire.WindowUpdateFrame,
-	pth *path, deadline time.Time, curNotSent uint8, alpha uint8) (*ackhandler.Packet, bool, error) {
-	// add cost here
-	if pth.pathID == protocol.PathID(1) {
-		sch.totalCost += path1Cost
-		sch.totalPktWithCost += 1
-	} else if pth.pathID == protocol.PathID(3) {
-		sch.totalCost += path3Cost
-		sch.totalPktWithCost += 1
-	}
+	pth *path, curNotSent uint8, batchLength uint8, alpha uint8) (*ackhandler.Packet, bool, error) {
 	// add a retransmittable frame
 	if pth.sentPacketHandler.ShouldSendRetransmittablePacket() {
 		s.packer.QueueControlFrame(&wire.PingFrame{}, pth)
 	}
-	packet, err := s.packer.PackPacket(pth, deadline, curNotSent, alpha)
+	packet, err := s.packer.PackPacket(pth, curNotSent, batchLength, alpha)
 	if err != nil || packet == nil {
 		// always trigger by payloadFrame = 0
 		return nil, false, err
@@ -1253,8 +924,8 @@ func (sch *scheduler) performPacketSending(s *session, windowUpdateFrames []*wir
 
 	// Packet sent, so update its quota
 	sch.quotas[pth.pathID]++
-
-	sRTT := make(map[protocol.PathID]time.Duration)
+	// and charge its cost
+	sch.chargeCost(pth, protocol.ByteCount(len(packet.raw)))
 
 	// Provide some logging if it is the last packet
 	for _, frame := range packet.frames {
@@ -1270,63 +941,17 @@ func (sch *scheduler) performPacketSending(s *session, windowUpdateFrames []*wir
 					rcvPkts, hasDeadlinePkts, meetDeadlinePkts := pth.receivedPacketHandler.GetStatistics()
 					utils.Infof("Path %x: sent %d retrans %d lost %d; rcv %d rtt %v", pathID, sntPkts, sntRetrans, sntLost, rcvPkts, pth.rttStats.SmoothedRTT())
 					utils.Infof("hasDeadlinePkts %d; meetDeadlinePkts %d", hasDeadlinePkts, meetDeadlinePkts)
+					utils.Infof("total cost %f", pth.totalCost)
 					// TODO: Remove it
 					utils.Infof("Congestion Window: %d", pth.sentPacketHandler.GetCongestionWindow())
-					if sch.Training {
-						sRTT[pathID] = pth.rttStats.SmoothedRTT()
-					}
 				}
 				notSentPkts := sch.GetNotSentPackets()
 				utils.Infof("Not Sent Packets Num:%d", notSentPkts) //only linOpt not zeros
 				TotalCost := sch.GetTotalCost()
 				totalPkts := sch.GetTotalPktWithCost()
 				utils.Infof("normalization total cost", TotalCost/float64(totalPkts))
-				// peekaboo log
-				// utils.Infof("Action: %d", sch.actionvector)
-				// utils.Infof("record: %d", sch.record)
-				// utils.Infof("epsidoe: %d", sch.episoderecord)
-				// utils.Infof("fe: %d", sch.fe)
-				// utils.Infof("se: %d", sch.se)
-				if sch.Training && sch.SchedulerName == "dqnAgent" {
-					duration := time.Since(s.sessionCreationTime)
-					var maxRTT time.Duration
-					for pathID := range sRTT {
-						if sRTT[pathID] > maxRTT {
-							maxRTT = sRTT[pathID]
-						}
-					}
-					sch.TrainingAgent.CloseEpisode(uint64(s.connectionID), RewardFinalGoodput(sch, s, duration, maxRTT), false)
-				}
-				utils.Infof("Dump: %t, Training:%t, scheduler:%s", sch.DumpExp, sch.Training, sch.SchedulerName)
-				if sch.DumpExp && !sch.Training && sch.SchedulerName == "dqnAgent" {
-					utils.Infof("Closing episode %d", uint64(s.connectionID))
-					sch.dumpAgent.CloseExperience(uint64(s.connectionID))
-				}
 				s.pathsLock.RUnlock()
-				//Write lin parameters
-				// os.Remove("/App/output/lin")
-				// os.Create("/App/output/lin")
-				// file2, _ := os.OpenFile("/App/output/lin", os.O_WRONLY, 0600)
-				os.Remove("../output/lin")
-				os.Create("../output/lin")
-				file2, _ := os.OpenFile("../output/lin", os.O_WRONLY, 0600)
-				for i := 0; i < banditDimension; i++ {
-					for j := 0; j < banditDimension; j++ {
-						fmt.Fprintf(file2, "%.8f\n", sch.MAaF[i][j])
-					}
-				}
-				for i := 0; i < banditDimension; i++ {
-					for j := 0; j < banditDimension; j++ {
-						fmt.Fprintf(file2, "%.8f\n", sch.MAaS[i][j])
-					}
-				}
-				for j := 0; j < banditDimension; j++ {
-					fmt.Fprintf(file2, "%.8f\n", sch.MbaF[j])
-				}
-				for j := 0; j < banditDimension; j++ {
-					fmt.Fprintf(file2, "%.8f\n", sch.MbaS[j])
-				}
-				file2.Close()
+				sch.finish(s, nil)
 			}
 		default:
 		}
@@ -1378,9 +1003,8 @@ func (sch *scheduler) ackRemainingPaths(s *session, totalWindowUpdateFrames []*w
 				// Avoid internal error bug
 				packet, err = s.packer.PackAckPacket(pthTmp)
 			} else {
-				var deadline time.Time
 				curNotSent := uint8(0)
-				packet, err = s.packer.PackPacket(pthTmp, deadline, curNotSent, uint8(10))
+				packet, err = s.packer.PackPacket(pthTmp, curNotSent, 0, uint8(10))
 			}
 			if err != nil {
 				return err
@@ -1395,6 +1019,16 @@ func (sch *scheduler) ackRemainingPaths(s *session, totalWindowUpdateFrames []*w
 	return nil
 }
 
+// countHeldBack counts the next n packets leaving the head of the queue, because they were sent or dropped, in NotSentPackets if a batch held them back.
+// This way a packet is counted once, however many batches held it back.
+func (sch *scheduler) countHeldBack(n int) {
+	if n > sch.heldBack {
+		n = sch.heldBack
+	}
+	sch.NotSentPackets += uint64(n)
+	sch.heldBack -= n
+}
+
 func (sch *scheduler) GetNotSentPackets() uint64 {
 	return sch.NotSentPackets
 }
@@ -1439,52 +1073,60 @@ func (sch *scheduler) sendPacket(s *session) error {
 		s.packer.QueueControlFrame(wuf, pth)
 	}
 
-	// czy:we can generate deadline here, and schedule the packet to be sent at the deadline, then add deadline to packet
-	// then receive packet, get deadline and received time.
-
 	// Repeatedly try sending until we don't have any more data, or run out of the congestion window
-	if len(sch.SchedulerName) > 5 && sch.SchedulerName[:5] == "Batch" {
+	if batchScheduler, ok := sch.pathScheduler.(BatchPathScheduler); ok {
 		for {
+			// every batch chooses its own path for the control frames
+			pth = nil
+
 			// We first check for retransmissions
 			hasRetransmission, retransmitHandshakePacket, fromPth := sch.getRetransmission(s)
+			// data that can't arrive in time anymore is not scheduled
+			sch.countHeldBack(s.streamFramer.AbandonExpiredData(time.Now()))
 			// XXX There might still be some stream frames to be retransmitted
 			hasStreamRetransmission := s.streamFramer.HasFramesForRetransmission()
 
-			// czy:generate batch size deadline
-			generateTime := time.Now()
-			deadlineBatch := sch.GenerateBatchDeadline(batch, generateTime)
+			// czy:collect the deadlines of the packets queued, at most a batch of them
+			deadlineBatch := sch.getBatchDeadlines(s, sch.batchSize, time.Now())
 
 			// select paths here for batch packet——Default: all select first path
 			s.pathsLock.RLock()
-			// whether scheduler can make decision
-			if len(s.paths) > 1 && !sch.canMadeDecision(s, batch) {
-				// can not make decision
-				s.pathsLock.RUnlock()
-				windowUpdateFrames := s.getWindowUpdateFrames(false)
-				return sch.ackRemainingPaths(s, windowUpdateFrames)
+			// only decide for the packets that fit into the congestion windows, the others are part of the next batch
+			if len(s.paths) > 1 {
+				capacity := sch.batchCapacity(s)
+				if capacity == 0 {
+					// can not make decision
+					s.pathsLock.RUnlock()
+					windowUpdateFrames := s.getWindowUpdateFrames(false)
+					return sch.ackRemainingPaths(s, windowUpdateFrames)
+				}
+				if len(deadlineBatch) > capacity {
+					deadlineBatch = deadlineBatch[:capacity]
+				}
 			}
-			pthBatch := sch.selectBatchPath(s, hasRetransmission, hasStreamRetransmission, fromPth, deadlineBatch)
+			pthBatch := sch.selectBatchPath(s, batchScheduler, hasRetransmission, hasStreamRetransmission, fromPth, deadlineBatch)
 			s.pathsLock.RUnlock()
 
 			// LinOptCost will wait for low-cost path
-			if costConstraintAvailable {
-				sch.choosePacketsForLowCost(s, deadlineBatch, pthBatch, generateTime)
+			if sch.costConstraintAvailable() {
+				sch.choosePacketsForLowCost(s, deadlineBatch, pthBatch)
 				if sch.maybeUpdateWindow(s) {
 					windowUpdateFrames := s.getWindowUpdateFrames(false)
 					return sch.ackRemainingPaths(s, windowUpdateFrames)
 				}
 			}
 
+			// the packets are sent in queue order, up to the first one no path was chosen for.
+			// It and the packets behind it stay queued for the next batch, but the first packet has to be sent.
+			s.pathsLock.RLock()
+			sch.fallbackBatchPath(s, hasRetransmission, hasStreamRetransmission, fromPth, pthBatch)
+			s.pathsLock.RUnlock()
+
 			// this pth to deal with the special case, like retransmission
-			// set the first not nil value of pthBatch to pth
 			if pthBatch == nil {
 				fmt.Println("pthBatch is nil")
-			}
-			for _, pthValue := range pthBatch {
-				if pthValue != nil {
-					pth = pthValue
-					break
-				}
+			} else {
+				pth = pthBatch[0]
 			}
 
 			// XXX No more path available, should we have a new QUIC error message?
@@ -1544,31 +1186,31 @@ func (sch *scheduler) sendPacket(s *session) error {
 				s.packer.QueueControlFrame(pf, pth)
 			}
 
+			// Also add PATH_PRIORITY frames, if any
+			for ppf := s.streamFramer.PopPathPriorityFrame(); ppf != nil; ppf = s.streamFramer.PopPathPriorityFrame() {
+				s.packer.QueueControlFrame(ppf, pth)
+			}
+
 			// Initial curNotSentPacket
-			sch.curNotSentPacket = 0
-			for _, pth := range pthBatch {
-				if pth == nil {
-					sch.curNotSentPacket++
-				}
+			sendable := 0
+			for sendable < len(pthBatch) && pthBatch[sendable] != nil {
+				sendable++
 			}
+			sch.curNotSentPacket = uint8(len(pthBatch) - sendable)
 
 			// PerformSendingPacket at pthBatch
 			// This pkt is Packet, sent is true
-			for i := 0; i < batch; i++ {
-				deadline := generateTime.Add(time.Duration(deadlineBatch[i]) * time.Millisecond)
+			var sentPackets int
+			for i := 0; i < sendable; i++ {
 				pth = pthBatch[i]
-				if pth == nil {
-					//LOG packets not transmit
-					sch.NotSentPackets++
-					continue
-				}
 				// TODO:pth may be nil
 				alpha := pth.sentPacketHandler.GetPathAlpha() * 10.0
 				alpha_10 := int(math.Round(float64(alpha))) // alpha * 10, and sent to client
-				pkt, sent, err := sch.performPacketSending(s, windowUpdateFrames, pth, deadline, sch.curNotSentPacket, uint8(alpha_10))
+				pkt, sent, err := sch.performPacketSending(s, windowUpdateFrames, pth, sch.curNotSentPacket, uint8(len(pthBatch)), uint8(alpha_10))
 				if err != nil {
 					if err == ackhandler.ErrTooManyTrackedSentPackets {
 						utils.Errorf("Closing episode")
+						sch.finish(s, err)
 					}
 					return err
 				}
@@ -1578,6 +1220,11 @@ func (sch *scheduler) sendPacket(s *session) error {
 					// Prevent sending empty packets
 					return sch.ackRemainingPaths(s, windowUpdateFrames)
 				}
+				sentPackets++
+				sch.countHeldBack(1)
+				if err := sch.maybeDuplicate(s, pkt, pth); err != nil {
+					return err
+				}
 
 				// Duplicate traffic when it was sent on an unknown performing path
 				// FIXME adapt for new paths coming during the connection
@@ -1596,14 +1243,15 @@ func (sch *scheduler) sendPacket(s *session) error {
 						}
 					}
 				}
-
-				// And try pinging on potentially failed paths
-				if fromPth != nil && fromPth.potentiallyFailed.Get() {
-					err = s.sendPing(fromPth)
-					if err != nil {
-						return err
-					}
-				}
+			}
+			// the packets of the batch that weren't sent are the head of the queue now
+			if notSent := len(pthBatch) - sentPackets; notSent > sch.heldBack {
+				sch.heldBack = notSent
+			}
+			// No packet of the batch was sent, the next one would be the same
+			if sentPackets == 0 {
+				windowUpdateFrames := s.getWindowUpdateFrames(false)
+				return sch.ackRemainingPaths(s, windowUpdateFrames)
 			}
 		}
 	} else {
@@ -1613,14 +1261,6 @@ func (sch *scheduler) sendPacket(s *session) error {
 			// XXX There might still be some stream frames to be retransmitted
 			hasStreamRetransmission := s.streamFramer.HasFramesForRetransmission()
 
-			//czy:generate deadline hear
-			randNum := rand.Intn(30) + 10 //10ms - 40ms
-			deadline := time.Now().Add(time.Duration(randNum) * time.Millisecond)
-			// use generator to generate deadline
-			//min := 30 //deadline between 30ms and 50ms
-			//max := 50
-			//deadline := uniformDeadlineGenerator(min, max)
-
 			// Select the path here
 			s.pathsLock.RLock()
 			pth = sch.selectPath(s, hasRetransmission, hasStreamRetransmission, fromPth)
@@ -1675,14 +1315,17 @@ func (sch *scheduler) sendPacket(s *session) error {
 				s.packer.QueueControlFrame(pf, pth)
 			}
 
+			// Also add PATH_PRIORITY frames, if any
+			for ppf := s.streamFramer.PopPathPriorityFrame(); ppf != nil; ppf = s.streamFramer.PopPathPriorityFrame() {
+				s.packer.QueueControlFrame(ppf, pth)
+			}
+
 			// This pkt is Packet, sent is true
-			pkt, sent, err := sch.performPacketSending(s, windowUpdateFrames, pth, deadline, uint8(0), uint8(10))
+			pkt, sent, err := sch.performPacketSending(s, windowUpdateFrames, pth, uint8(0), 0, uint8(10))
 			if err != nil {
 				if err == ackhandler.ErrTooManyTrackedSentPackets {
 					utils.Errorf("Closing episode")
-					if sch.SchedulerName == "dqnAgent" && sch.Training {
-						sch.TrainingAgent.CloseEpisode(uint64(s.connectionID), -100, false)
-					}
+					sch.finish(s, err)
 				}
 				return err
 			}
@@ -1691,6 +1334,9 @@ func (sch *scheduler) sendPacket(s *session) error {
 				// Prevent sending empty packets
 				return sch.ackRemainingPaths(s, windowUpdateFrames)
 			}
+			if err := sch.maybeDuplicate(s, pkt, pth); err != nil {
+				return err
+			}
 
 			// Duplicate traffic when it was sent on an unknown performing path
 			// FIXME adapt for new paths coming during the connection
@@ -1710,33 +1356,46 @@ func (sch *scheduler) sendPacket(s *session) error {
 					}
 				}
 			}
-
-			// And try pinging on potentially failed paths
-			if fromPth != nil && fromPth.potentiallyFailed.Get() {
-				err = s.sendPing(fromPth)
-				if err != nil {
-					return err
-				}
-			}
 		}
 	}
 }
 
-func (sch *scheduler) choosePacketsForLowCost(s *session, deadlineBatch []int, pthBatch []*path, generateTime time.Time) {
+// choosePacketsForLowCost holds back the packets that can wait for the low-cost path.
+// They stay queued in the stream framer and are considered again in the next batch.
+func (sch *scheduler) choosePacketsForLowCost(s *session, deadlineBatch []time.Duration, pthBatch []*path) {
 	minRtt := sch.GetMinimunRTT(s)
 	if isFloat64Zero(minRtt) {
 		// minRTT value is not valid, give up to choose
 		return
 	}
+	cheapestPath := sch.getCheapestPath(s)
+	if cheapestPath == nil {
+		return
+	}
 	for i, deadline := range deadlineBatch {
-		if pthBatch[i] != nil && pthBatch[i].pathID == protocol.PathID(1) && float64(deadline) > (minRtt*3.0/2.0) {
-			deadlineTime := generateTime.Add(time.Duration(deadline) * time.Millisecond)
-			sch.waitPackets = append(sch.waitPackets, deadlineTime)
+		if i >= len(pthBatch) {
+			break
+		}
+		if pthBatch[i] != nil && pthBatch[i].price > cheapestPath.price && float64(deadline)/float64(time.Millisecond) > (minRtt*3.0/2.0) {
 			pthBatch[i] = nil
 		}
 	}
 }
 
+// getCheapestPath returns the path with the lowest price, or nil if there is only the initial path
+func (sch *scheduler) getCheapestPath(s *session) *path {
+	var cheapestPath *path
+	for pathID, pth := range s.paths {
+		if pathID == protocol.InitialPathID {
+			continue
+		}
+		if cheapestPath == nil || pth.price < cheapestPath.price {
+			cheapestPath = pth
+		}
+	}
+	return cheapestPath
+}
+
 func isFloat64Zero(f float64) bool {
 	epsilon := 1e-6
 	return math.Abs(f) < epsilon
@@ -1747,9 +1406,10 @@ func float64Equal(a, b float64) bool {
 	return math.Abs(a-b) < epsilon
 }
 
+// maybeUpdateWindow says if the low-cost path is congestion limited, so that we should wait for it
 func (sch *scheduler) maybeUpdateWindow(s *session) bool {
 	if len(s.paths) > 2 {
-		pth := s.paths[protocol.PathID(3)]
+		pth := sch.getCheapestPath(s)
 		remainingCwnd := pth.sentPacketHandler.GetCongestionWindow() - pth.sentPacketHandler.GetBytesInFlight()
 		if uint64(remainingCwnd) == uint64(0) {
 			return true
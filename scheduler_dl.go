@@ -55,14 +55,15 @@ func NormalizeTimes(stat time.Duration) types.Output {
 	return types.Output(stat.Nanoseconds()) / types.Output(time.Millisecond.Nanoseconds()*150)
 }
 
-func RewardFinalGoodput(sch *scheduler, s *session, duration time.Duration, _ time.Duration) types.Output {
+func RewardFinalGoodput(sch *dqnScheduler, s *PathSnapshot, duration time.Duration, _ time.Duration) types.Output {
 	packetNumber := make(map[protocol.PathID]uint64)
 	retransNumber := make(map[protocol.PathID]uint64)
 	firstPath, secondPath := protocol.PathID(255), protocol.PathID(255)
 
-	for pathID, path := range s.paths {
+	for _, path := range s.Paths {
+		pathID := path.PathID
 		if pathID != protocol.InitialPathID {
-			packetNumber[pathID], retransNumber[pathID], _ = path.sentPacketHandler.GetStatistics()
+			packetNumber[pathID], retransNumber[pathID] = path.PacketsSent, path.PacketsRetransmitted
 			// Ordering paths
 			if firstPath == protocol.PathID(255) {
 				firstPath = pathID
@@ -87,7 +88,7 @@ func RewardFinalGoodput(sch *scheduler, s *session, duration time.Duration, _ ti
 	return partialReward
 }
 
-func GetStateAndReward(sch *scheduler, s *session) (int, []*path) {
+func GetStateAndReward(sch *dqnScheduler, s *PathSnapshot) (int, []PathID) {
 	packetNumber := make(map[protocol.PathID]uint64)
 	retransNumber := make(map[protocol.PathID]uint64)
 
@@ -97,12 +98,13 @@ func GetStateAndReward(sch *scheduler, s *session) (int, []*path) {
 
 	firstPath, secondPath := protocol.PathID(255), protocol.PathID(255)
 
-	for pathID, path := range s.paths {
+	for _, path := range s.Paths {
+		pathID := path.PathID
 		if pathID != protocol.InitialPathID {
-			packetNumber[pathID], retransNumber[pathID], _ = path.sentPacketHandler.GetStatistics()
-			sRTT[pathID] = path.rttStats.SmoothedRTT()
-			cwnd[pathID] = path.sentPacketHandler.GetCongestionWindow()
-			cwndlevel[pathID] = types.Output(path.sentPacketHandler.GetBytesInFlight()) / types.Output(cwnd[pathID])
+			packetNumber[pathID], retransNumber[pathID] = path.PacketsSent, path.PacketsRetransmitted
+			sRTT[pathID] = path.SmoothedRTT
+			cwnd[pathID] = path.CongestionWindow
+			cwndlevel[pathID] = types.Output(path.BytesInFlight) / types.Output(cwnd[pathID])
 
 			// Ordering paths
 			if firstPath == protocol.PathID(255) {
@@ -124,13 +126,13 @@ func GetStateAndReward(sch *scheduler, s *session) (int, []*path) {
 	// if sch.Training{
 	// 	if packetNumberInitial > 20 {
 	// 		utils.Errorf("closing: zero tolerance")
-	// 		sch.TrainingAgent.CloseEpisode(uint64(s.connectionID), -100, false)
+	// 		sch.TrainingAgent.CloseEpisode(uint64(s.ConnectionID), -100, false)
 	// 		s.closeLocal(errors.New("closing: zero tolerance"))
 	// 	}
 	// }
 
 	//State
-	BSend, _ := s.flowControlManager.SendWindowSize(protocol.StreamID(5))
+	BSend := s.SendWindow
 	state := types.Vector{NormalizeTimes(sRTT[firstPath]), NormalizeTimes(sRTT[secondPath]),
 		types.Output(cwnd[firstPath]) / types.Output(protocol.DefaultTCPMSS) / 300, types.Output(cwnd[secondPath]) / types.Output(protocol.DefaultTCPMSS) / 300, cwndlevel[firstPath], cwndlevel[secondPath], types.Output(BSend) / types.Output(protocol.DefaultTCPMSS) / 300}
 
@@ -162,10 +164,10 @@ func GetStateAndReward(sch *scheduler, s *session) (int, []*path) {
 		if sch.Training {
 			realstate := sch.statevector[sch.record]
 			realaction := sch.actionvector[sch.record]
-			sch.TrainingAgent.SaveStep(uint64(s.connectionID), partialReward, realstate, realaction)
+			sch.TrainingAgent.SaveStep(uint64(s.ConnectionID), partialReward, realstate, realaction)
 		} else {
 			if sch.DumpExp {
-				sch.dumpAgent.AddStep(uint64(s.connectionID), []string{fmt.Sprint(sch.statevector[sch.record]), fmt.Sprint(sch.actionvector[sch.record])})
+				sch.dumpAgent.AddStep(uint64(s.ConnectionID), []string{fmt.Sprint(sch.statevector[sch.record]), fmt.Sprint(sch.actionvector[sch.record])})
 			}
 		}
 	} else {
@@ -183,10 +185,10 @@ func GetStateAndReward(sch *scheduler, s *session) (int, []*path) {
 					if sch.Training {
 						realstate := sch.statevector[sch.episoderecord]
 						realaction := sch.actionvector[sch.episoderecord]
-						sch.TrainingAgent.SaveStep(uint64(s.connectionID), partialReward, realstate, realaction)
+						sch.TrainingAgent.SaveStep(uint64(s.ConnectionID), partialReward, realstate, realaction)
 					} else {
 						if sch.DumpExp {
-							sch.dumpAgent.AddStep(uint64(s.connectionID), []string{fmt.Sprint(sch.statevector[sch.episoderecord]), fmt.Sprint(sch.actionvector[sch.episoderecord])})
+							sch.dumpAgent.AddStep(uint64(s.ConnectionID), []string{fmt.Sprint(sch.statevector[sch.episoderecord]), fmt.Sprint(sch.actionvector[sch.episoderecord])})
 						}
 					}
 					sch.episoderecord += 1
@@ -197,10 +199,10 @@ func GetStateAndReward(sch *scheduler, s *session) (int, []*path) {
 					if sch.Training {
 						realstate := sch.statevector[sch.episoderecord]
 						realaction := sch.actionvector[sch.episoderecord]
-						sch.TrainingAgent.SaveStep(uint64(s.connectionID), partialReward, realstate, realaction)
+						sch.TrainingAgent.SaveStep(uint64(s.ConnectionID), partialReward, realstate, realaction)
 					} else {
 						if sch.DumpExp {
-							sch.dumpAgent.AddStep(uint64(s.connectionID), []string{fmt.Sprint(sch.statevector[sch.episoderecord]), fmt.Sprint(sch.actionvector[sch.episoderecord])})
+							sch.dumpAgent.AddStep(uint64(s.ConnectionID), []string{fmt.Sprint(sch.statevector[sch.episoderecord]), fmt.Sprint(sch.actionvector[sch.episoderecord])})
 						}
 					}
 					sch.episoderecord += 1
@@ -213,10 +215,10 @@ func GetStateAndReward(sch *scheduler, s *session) (int, []*path) {
 	sch.record += 1
 	sch.lastfiretime = time.Now()
 
-	return action, []*path{s.paths[firstPath], s.paths[secondPath]}
+	return action, []PathID{firstPath, secondPath}
 }
 
-func CheckAction(action int, state types.Vector, s *session, sch *scheduler) {
+func CheckAction(action int, state types.Vector, s *session, sch *dqnScheduler) {
 	if action != 0 {
 		return
 	}
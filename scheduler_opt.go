@@ -1,9 +1,9 @@
 package quic
 
 import (
-	"fmt"
-	"github.com/draffensperger/golp"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"math"
 	"math/rand"
 	"sort"
 	"time"
@@ -11,95 +11,52 @@ import (
 
 // some parameter
 const banditAvailable = true
-const costConstraintAvailable = false
-const path1Cost = 1.5 //cellular link
-const path3Cost = 0.5 //WiFi link
-const budget = 4
 const alpha1 = 1.1
 const alpha2 = 1.2
 
 func linOpt(packetsNum []int, packetsDeadline []float64, pathDelay []float64, pathCwnd []float64) []int {
 	// TODO:packetsNum is unnecessary
-	S := len(packetsNum)     // num of packets
-	n := len(pathDelay)      // num of path
-	policy := make([]int, S) // scheduler decision: [1 2] means packet 1 schedule in path 1, packet 2 schedule in path 2
+	S := len(packetsNum) // num of packets
+	n := len(pathDelay)  // num of path
 
-	// Constraint coefficient matrix
-	A := make([][]float64, S+n)
-	for i := range A {
-		A[i] = make([]float64, S*n)
-	}
-	// packet constraint: decision of one packet in all path leq 1
-	for i := 0; i < S; i++ {
-		for j := i; j < S*n; j += S {
-			A[i][j] = 1
-		}
-	}
-	// CWND constraint: packets in one path leq CWND
-	for i := 0; i < n; i++ {
-		for j := (i * S); j < ((i + 1) * S); j++ {
-			A[S+i][j] = 1
-		}
-	}
-
-	b := make([]float64, S+n)
-	for i := 0; i < S; i++ {
-		b[i] = 1
-	}
-	copy(b[S:], pathCwnd)
-
-	// Objective function
-	// satisfy matrix
-	satisfyDeadline := make([][]float64, n)
-	for i := 0; i < n; i++ {
-		satisfyDeadline[i] = make([]float64, S)
-		for j := 0; j < S; j++ {
-			if packetsDeadline[j] >= pathDelay[i] {
-				satisfyDeadline[i][j] = 1
-			}
-		}
-	}
-	// transform to one-dimension vector
-	C := make([]float64, S*n)
-	for i := 0; i < n; i++ {
-		for j := 0; j < S; j++ {
-			C[i*S+j] = satisfyDeadline[i][j]
-		}
-	}
+	C, A, b := batchLP(packetsDeadline, pathDelay, pathCwnd)
 
 	// Solve
-	lp := golp.NewLP(0, S*n)
-	lp.SetObjFn(C)
-	lp.SetMaximize()
+	result := solveBatchLP(C, A, b, S, n)
+	// Convert solution to policy: [1 2] means packet 1 schedule in path 1, packet 2 schedule in path 2
+	return enforceCwnds(resultToPolicy(result), result, pathCwnd)
+}
 
-	for i := 0; i < len(A); i++ {
-		lp.AddConstraint(A[i], golp.LE, b[i])
-	}
+func linOptCost(packetsNum []int, packetsDeadline []float64, pathDelay []float64,
+	pathCwnd []float64, pathCost []float64, budgetConstraint float64) []int {
+	// TODO:packetsNum is unnecessary
+	S := len(packetsNum) // num of packets
+	n := len(pathDelay)  // num of path
 
-	lp.Solve()
-	vars := lp.Variables()
+	C, A, b := batchLP(packetsDeadline, pathDelay, pathCwnd)
 
-	result := make([][]float64, S)
-	for i := range result {
-		result[i] = make([]float64, n)
-		for j := range result[i] {
-			result[i][j] = vars[i+j*S]
-		}
+	// cost constraint: cost of the packets on all paths leq budget
+	costConstraint := make([]float64, S*n)
+	for i := 0; i < S*n; i++ {
+		costConstraint[i] = pathCost[i/S]
 	}
+	A = append(A, costConstraint)
+	b = append(b, budgetConstraint)
+
+	// Solve
+	result := solveBatchLP(C, A, b, S, n)
 	// Convert solution to policy
-	policy = resultToPolicy(result)
-	return policy
+	return enforceCwnds(resultToPolicyWithGreedyRounding(result), result, pathCwnd)
 }
 
-func linOptCost(packetsNum []int, packetsDeadline []float64, pathDelay []float64,
-	pathCwnd []float64, pathCost []float64, budgetConstraint float64) []int {
-	// TODO:packetsNum is unnecessary
-	S := len(packetsNum)     // num of packets
-	n := len(pathDelay)      // num of path
-	policy := make([]int, S) // scheduler decision: [1 2] means packet 1 schedule in path 1, packet 2 schedule in path 2
+// batchLP builds the LP of a batch of packets: maximize C*x s.t. A*x <= b.
+// The variable of packet i on path j is at index i+j*S, the objective counts the packets that meet their deadline.
+func batchLP(packetsDeadline []float64, pathDelay []float64, pathCwnd []float64) (C []float64, A [][]float64, b []float64) {
+	S := len(packetsDeadline) // num of packets
+	n := len(pathDelay)       // num of path
 
 	// Constraint coefficient matrix
-	A := make([][]float64, S+n+1) // add one cost constraint
+	A = make([][]float64, S+n)
 	for i := range A {
 		A[i] = make([]float64, S*n)
 	}
@@ -116,122 +73,136 @@ func linOptCost(packetsNum []int, packetsDeadline []float64, pathDelay []float64
 		}
 	}
 
-	for i := 0; i < S*n; i++ {
-		A[S+n][i] = pathCost[i/S]
-	}
-
-	b := make([]float64, S+n+1)
+	b = make([]float64, S+n)
 	for i := 0; i < S; i++ {
 		b[i] = 1
 	}
 	copy(b[S:], pathCwnd)
-	b[S+n] = budgetConstraint
 
-	// Objective function
-	// satisfy matrix
-	satisfyDeadline := make([][]float64, n)
+	// Objective function: a packet on a path counts if the path delivers it by its deadline
+	C = make([]float64, S*n)
 	for i := 0; i < n; i++ {
-		satisfyDeadline[i] = make([]float64, S)
 		for j := 0; j < S; j++ {
 			if packetsDeadline[j] >= pathDelay[i] {
-				satisfyDeadline[i][j] = 1
+				C[i*S+j] = 1
 			}
 		}
 	}
-	// transform to one-dimension vector
-	C := make([]float64, S*n)
-	for i := 0; i < n; i++ {
-		for j := 0; j < S; j++ {
-			C[i*S+j] = satisfyDeadline[i][j]
-		}
-	}
-
-	// Solve
-	lp := golp.NewLP(0, S*n+1)
-	lp.SetObjFn(C)
-	lp.SetMaximize()
+	return C, A, b
+}
 
-	for i := 0; i < len(A); i++ {
-		lp.AddConstraint(A[i], golp.LE, b[i])
+// solveBatchLP solves the LP of a batch of S packets on n paths with the defaultLPSolver.
+// The variable of packet i on path j is at index i+j*S. The result holds the fraction of every packet per path.
+// If the LP can't be solved, no packet is assigned.
+func solveBatchLP(C []float64, A [][]float64, b []float64, S int, n int) [][]float64 {
+	vars, err := defaultLPSolver.maximize(C, A, b)
+	if err != nil {
+		utils.Errorf("Batch Scheduler: solving the LP failed: %s", err)
+		vars = nil
 	}
-
-	lp.Solve()
-	vars := lp.Variables()
-
 	result := make([][]float64, S)
 	for i := range result {
 		result[i] = make([]float64, n)
 		for j := range result[i] {
-			result[i][j] = vars[i+j*S]
+			if i+j*S < len(vars) {
+				result[i][j] = vars[i+j*S]
+			}
 		}
 	}
-	// Convert solution to policy
-	policy = resultToPolicyWithGreedyRounding(result, S, n)
-	return policy
+	return result
 }
 
+// roundingEpsilon absorbs the numerical error of the LP solver
+const roundingEpsilon = 1e-6
+
+// resultToPolicy converts the LP solution to a policy.
+// Every packet is assigned to the path with the largest fraction of it, or not sent (0) if no path got any of it.
+// Paths are numbered from 1, in the order of the columns of the result.
 func resultToPolicy(result [][]float64) []int {
-	// TODO:just adapt to two paths
 	policy := make([]int, len(result))
-	for i := 0; i < len(result); i++ {
-		if len(result[i]) == 1 {
-			if result[i][0] == 0 {
-				policy[i] = 0
-			} else {
-				policy[i] = 1
-			}
-		} else if len(result[i]) == 2 {
-			if result[i][0] == 0 && result[i][1] == 0 {
-				policy[i] = 0
-			} else if result[i][0] != 0 {
-				policy[i] = 1
-			} else {
-				policy[i] = 2
+	for i, fractions := range result {
+		best := -1
+		for j, x := range fractions {
+			if x > roundingEpsilon && (best == -1 || x > fractions[best]) {
+				best = j
 			}
-		} else {
-			fmt.Println("TODO:just for one path and two path!")
 		}
+		policy[i] = best + 1
 	}
 	return policy
 }
 
 // Greedy Rounding
-func resultToPolicyWithGreedyRounding(result [][]float64, S int, n int) []int {
+// resultToPolicyWithGreedyRounding rounds the fractional assignment of every packet randomly.
+// The paths are tried in the order of decreasing fraction, each one is chosen with the probability of its fraction.
+// A packet that no path is chosen for is not sent (0).
+func resultToPolicyWithGreedyRounding(result [][]float64) []int {
 	policy := make([]int, len(result))
+	for i, fractions := range result {
+		order := make([]int, len(fractions))
+		for j := range order {
+			order[j] = j
+		}
+		sort.SliceStable(order, func(j, k int) bool { return fractions[order[j]] > fractions[order[k]] })
 
-	for i := 0; i < S; i++ {
-		probSort := make([]float64, n)
-		copy(probSort, result[i])
-
-		sort.Slice(probSort, func(j, k int) bool {
-			return probSort[j] > probSort[k]
-		})
-
-		for j := 0; j < n; j++ {
-			if probSort[j] == 1 {
-				index := findIndex(result[i], probSort[j])
-				policy[i] = index + 1
+		for _, j := range order {
+			x := fractions[j]
+			if x <= roundingEpsilon {
+				break
+			}
+			if x >= 1-roundingEpsilon || chooseByProb([]int{j + 1, 0}, []float64{x, 1 - x}) != 0 {
+				policy[i] = j + 1
 				break
-			} else {
-				if result[i][j] != 0 && policy[i] == 0 {
-					policyCandidate := []int{j + 1, 0} // rounding到第j个path或不发
-					prob := []float64{result[i][j], 1 - result[i][j]}
-					policy[i] = chooseByProb(policyCandidate, prob)
-				}
 			}
 		}
 	}
-
 	return policy
 }
 
-func findIndex(arr []float64, target float64) int {
-	for i, val := range arr {
-		if val == target {
-			return i
+// enforceCwnds makes a rounded policy respect the CWND of every path, which rounding the fractions may exceed.
+// On a path with too many packets, the ones with the largest fraction on it keep it. The others are moved to
+// the path with the next largest fraction that has room left, or not sent (0) if no such path has.
+func enforceCwnds(policy []int, result [][]float64, pathCwnd []float64) []int {
+	// fraction returns the fraction of packet i on the path numbered p (from 1)
+	fraction := func(i, p int) float64 { return result[i][p-1] }
+	order := make([]int, 0, len(policy))
+	for i, p := range policy {
+		if p > 0 {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return fraction(order[a], policy[order[a]]) > fraction(order[b], policy[order[b]])
+	})
+
+	used := make([]float64, len(pathCwnd))
+	var surplus []int
+	for _, i := range order {
+		j := policy[i] - 1
+		if used[j]+1 > pathCwnd[j]+roundingEpsilon {
+			surplus = append(surplus, i)
+			continue
 		}
+		used[j]++
 	}
-	return -1
+
+	for _, i := range surplus {
+		fractions := result[i]
+		best := -1
+		for j, x := range fractions {
+			if j == policy[i]-1 || x <= roundingEpsilon || used[j]+1 > pathCwnd[j]+roundingEpsilon {
+				continue
+			}
+			if best == -1 || x > fractions[best] {
+				best = j
+			}
+		}
+		policy[i] = best + 1
+		if best != -1 {
+			used[best]++
+		}
+	}
+	return policy
 }
 
 // chooseByProb choose a value by probability
@@ -247,45 +218,31 @@ func chooseByProb(value []int, Prob []float64) int {
 	return value[len(value)-1]
 }
 
-// GenerateBatchDeadline Generator of batch transfer
-// TODO:add min/max value interface
-func (sch *scheduler) GenerateBatchDeadline(size int, curTime time.Time) []int {
-	lenWait := len(sch.waitPackets)
-	Deadline := make([]int, size-lenWait)
-	for i := 0; i < size-lenWait; i++ {
-		//randNum := rand.Intn(50)
-		randNum := rand.Intn(30) + 13 //10-40 ms
-		Deadline[i] = randNum
-	}
-	for _, deadlineTime := range sch.waitPackets {
-		durationTime := deadlineTime.Sub(curTime)
-		Deadline = append(Deadline, int(durationTime.Milliseconds()))
-	}
-	sch.waitPackets = make([]time.Time, 0)
-	return Deadline
-}
-
-// select path for batch packet
-func (sch *scheduler) selectBatchPath(s *session, hasRetransmission bool,
-	hasStreamRetransmission bool, fromPth *path, deadlineBatch []int) []*path {
-	// XXX Currently round-robin
-	// TODO:BatchLinOpt do not realize
-	if sch.SchedulerName == "BatchLinOpt" {
-		fmt.Println("Batch Scheduler: BatchLinOpt")
-		return sch.selectBatchlinOpt(s, hasRetransmission, hasStreamRetransmission, fromPth, deadlineBatch)
-	} else if sch.SchedulerName == "BatchEDF" {
-		fmt.Println("Batch Scheduler: EDF")
-		return sch.selectBatchEDF(s, hasRetransmission, hasStreamRetransmission, fromPth, deadlineBatch)
-	} else {
-		// Default, all select first path
-		fmt.Println("Batch Scheduler: default--First path")
-		return sch.selectBatchFirstPath(s, hasRetransmission, hasStreamRetransmission, fromPth, deadlineBatch)
+// noDeadline is the relative deadline used for packets without a deadline, it is always satisfiable
+const noDeadline = time.Duration(math.MaxInt64)
+
+// getBatchDeadlines returns the time left until the deadlines of the packets queued in the stream framer, at most size of them.
+// The batch is as long as the queued data: if nothing is queued, it has a single packet for the control frames and retransmissions.
+// Packets without a deadline get noDeadline.
+func (sch *scheduler) getBatchDeadlines(s *session, size int, curTime time.Time) []time.Duration {
+	queued := s.streamFramer.PeekPackets(size)
+	if len(queued) == 0 {
+		return []time.Duration{noDeadline}
+	}
+	deadlines := make([]time.Duration, len(queued))
+	for i, p := range queued {
+		deadlines[i] = noDeadline
+		if !p.Deadline.IsZero() {
+			deadlines[i] = p.Deadline.Sub(curTime)
+		}
 	}
+	return deadlines
 }
 
-func (sch *scheduler) selectBatchFirstPath(s *session,
-	hasRetransmission bool, hasStreamRetransmission bool,
-	fromPth *path, deadlineBatch []int) []*path {
+// select path for batch packet
+// Lock of s.paths must be held
+func (sch *scheduler) selectBatchPath(s *session, batchScheduler BatchPathScheduler, hasRetransmission bool,
+	hasStreamRetransmission bool, fromPth *path, deadlineBatch []time.Duration) []*path {
 	if len(s.paths) <= 1 {
 		if !hasRetransmission && !s.paths[protocol.InitialPathID].SendingAllowed() {
 			return nil
@@ -297,80 +254,155 @@ func (sch *scheduler) selectBatchFirstPath(s *session,
 		return paths
 	}
 
-	paths := make([]*path, len(deadlineBatch))
-	// a flag
-	canSend := false
-	for i := 0; i < len(deadlineBatch); i++ {
-		for pathID, pth := range s.paths {
-			if pathID == protocol.PathID(1) && pth.SendingAllowed() {
-				paths[i] = pth
-				canSend = true
-				break
-			}
+	snapshot := sch.getPathSnapshot(s, hasRetransmission, hasStreamRetransmission, fromPth)
+	pathIDs := batchScheduler.SelectBatch(snapshot, deadlineBatch)
+	if pathIDs == nil {
+		return nil
+	}
+	paths := make([]*path, len(pathIDs))
+	for i, pathID := range pathIDs {
+		paths[i] = s.paths[pathID]
+	}
+	// the first packet of the batch carries the queued stream retransmissions
+	if pth := sch.pendingReinjectionPath(hasStreamRetransmission); pth != nil && len(paths) > 0 {
+		paths[0] = pth
+	}
+	return paths
+}
+
+// fallbackBatchPath makes sure that a batch makes progress if its first packet wasn't assigned to a path,
+// e.g. because no path can meet its deadline, or because it waits for the low-cost path.
+// The packets are sent in queue order up to the first one without a path, so the batch would otherwise send nothing.
+// The first packet is then sent on the cheapest path if the cost is constrained, otherwise on the path with the lowest delay.
+// Lock of s.paths must be held
+func (sch *scheduler) fallbackBatchPath(s *session, hasRetransmission bool, hasStreamRetransmission bool, fromPth *path, pthBatch []*path) {
+	if len(pthBatch) == 0 || pthBatch[0] != nil {
+		return
+	}
+	if sch.costConstraintAvailable() {
+		if pth := sch.getCheapestPath(s); pth != nil && pth.SendingAllowed() {
+			pthBatch[0] = pth
+			return
 		}
 	}
+	snapshot := sch.getPathSnapshot(s, hasRetransmission, hasStreamRetransmission, fromPth)
+	pathID, _ := selectLowestDelayPath(snapshot.Paths, len(snapshot.Paths) > 1)
+	if pathID == NoPath {
+		pathID, _ = selectLowestDelayPath(snapshot.BackupPaths, true)
+	}
+	if pth, ok := s.paths[pathID]; ok {
+		utils.Debugf("Batch Scheduler: the first packet of the batch was not assigned, sending it on path %d", pathID)
+		pthBatch[0] = pth
+	}
+}
 
-	if canSend {
-		return paths
-	} else {
+// batchPrimaryScheduler sends the whole batch on the first path
+type batchPrimaryScheduler struct {
+	fixedPathScheduler
+}
+
+func newBatchPrimaryScheduler(config *Config) PathScheduler {
+	return &batchPrimaryScheduler{*newPrimaryScheduler(config).(*fixedPathScheduler)}
+}
+
+func (sch *batchPrimaryScheduler) SelectBatch(s *PathSnapshot, deadlineBatch []time.Duration) []PathID {
+	utils.Debugf("Batch Scheduler: default--First path")
+	pathID := sch.SelectPath(s)
+	if pathID == NoPath {
 		return nil
 	}
+	paths := make([]PathID, len(deadlineBatch))
+	for i := range paths {
+		paths[i] = pathID
+	}
+	return paths
 }
 
-func (sch *scheduler) selectBatchlinOpt(s *session,
-	hasRetransmission bool, hasStreamRetransmission bool,
-	fromPth *path, deadlineBatch []int) []*path {
-	if len(s.paths) <= 1 {
-		if !hasRetransmission && !s.paths[protocol.InitialPathID].SendingAllowed() {
-			return nil
-		}
-		paths := make([]*path, len(deadlineBatch))
-		for i := range paths {
-			paths[i] = s.paths[protocol.InitialPathID]
-		}
-		return paths
+// batchLinOptScheduler implements DA-MPS, and CEDA-MPS if the CostPolicy has a budget
+type batchLinOptScheduler struct {
+	rttScheduler
+	tracer Tracer
+}
+
+func newBatchLinOptScheduler(config *Config) PathScheduler {
+	sch := &batchLinOptScheduler{}
+	if config != nil {
+		sch.tracer = config.Tracer
+	}
+	return sch
+}
+
+func (sch *batchLinOptScheduler) SelectBatch(s *PathSnapshot, deadlineBatch []time.Duration) []PathID {
+	utils.Debugf("Batch Scheduler: BatchLinOpt")
+	eligiblePaths := linOptEligiblePaths(s.Paths)
+	paths := sch.solve(s, eligiblePaths, deadlineBatch)
+	// Only use the backup paths for the packets the active paths can't deliver by their deadline
+	if backupPaths := linOptEligiblePaths(s.BackupPaths); len(backupPaths) > 0 && hasUnscheduledPacket(paths, len(deadlineBatch)) {
+		utils.Debugf("Batch Scheduler: BatchLinOpt adds %d backup paths", len(backupPaths))
+		paths = sch.solve(s, append(eligiblePaths, backupPaths...), deadlineBatch)
 	}
 
+	// compute cost
+	if paths != nil && !math.IsInf(s.BatchBudget, 1) {
+		cost := computeCost(s, paths)
+		utils.Debugf("Current Cost: %f", cost)
+		//sch.totalCost += cost // can not add total cost here, because maybe some packets is not sent
+	}
+	return paths
+}
+
+// linOptEligiblePaths returns the paths that have remaining cwnd, without the initial path
+func linOptEligiblePaths(paths []PathInfo) []*PathInfo {
 	// Create a slice to store the eligible paths
-	eligiblePaths := []*path{}
+	eligiblePaths := []*PathInfo{}
 
 	// Iterate over the paths and filter out the initial path
-	for pathID, pth := range s.paths {
-		if pathID == protocol.InitialPathID {
+	for i := range paths {
+		pth := &paths[i]
+		if pth.PathID == protocol.InitialPathID {
 			continue
-		} else {
-			pthCwnd := pth.sentPacketHandler.GetCongestionWindow() // notice this Cwnd is bytes
-			pthInflight := pth.sentPacketHandler.GetBytesInFlight()
-			// path that has remaining cwnd is available
-			if pthCwnd >= pthInflight {
-				eligiblePaths = append(eligiblePaths, pth)
-			}
+		}
+		// path that has remaining cwnd is available
+		if pth.CongestionWindow >= pth.BytesInFlight {
+			eligiblePaths = append(eligiblePaths, pth)
+		}
+	}
+	return eligiblePaths
+}
+
+// hasUnscheduledPacket returns if a packet of the batch was not assigned to a path
+func hasUnscheduledPacket(paths []PathID, batchSize int) bool {
+	if len(paths) < batchSize {
+		return true
+	}
+	for _, pathID := range paths {
+		if pathID == NoPath {
+			return true
 		}
 	}
+	return false
+}
 
+// solve assigns the packets of the batch to the eligible paths with the LP
+func (sch *batchLinOptScheduler) solve(s *PathSnapshot, eligiblePaths []*PathInfo, deadlineBatch []time.Duration) []PathID {
 	// Collect the path one way delays and CWNDs
 	pathDelays := make([]float64, len(eligiblePaths))
 	pathCWNDs := make([]float64, len(eligiblePaths))
 	// cost constraint
 	pathCost := make([]float64, len(eligiblePaths))
 	for i, pth := range eligiblePaths {
-		//pathDelays[i] = (float64(pth.rttStats.SmoothedRTT()) / float64(time.Millisecond)) / 2
-		tempPathDelays := (float64(pth.rttStats.SmoothedRTT()) / float64(time.Millisecond)) / 2
+		tempPathDelays := (float64(pth.SmoothedRTT) / float64(time.Millisecond)) / 2
 		if banditAvailable {
-			pathDelays[i] = tempPathDelays * float64(pth.sentPacketHandler.GetPathAlpha())
+			pathDelays[i] = tempPathDelays * float64(pth.Alpha)
 			//pathDelays[i] = tempPathDelays * alpha1
 			//pathDelays[i] = tempPathDelays * alpha2
 		} else {
 			pathDelays[i] = tempPathDelays
 		}
 
-		if pth.pathID == protocol.PathID(1) {
-			pathCost[i] = path1Cost
-		} else if pth.pathID == protocol.PathID(3) {
-			pathCost[i] = path3Cost
-		}
+		pathCost[i] = pth.PacketCost
 
-		remainingCwnd := pth.sentPacketHandler.GetCongestionWindow() - pth.sentPacketHandler.GetBytesInFlight()
+		remainingCwnd := pth.CongestionWindow - pth.BytesInFlight
 		// TODO:remainingCwnd / protocol.MaxPacketSize is a uint64
 		pathCWNDs[i] = float64(remainingCwnd / protocol.MaxPacketSize)
 	}
@@ -381,66 +413,69 @@ func (sch *scheduler) selectBatchlinOpt(s *session,
 	}
 
 	packetsNum := generateSequence(len(deadlineBatch))
-	packetsDeadline := convertToIntSlice(deadlineBatch)
+	packetsDeadline := convertToMilliseconds(deadlineBatch)
 
-	// linOpt solver, when costConstraintAvailable is true, call linOptCost
+	// linOpt solver, when the batch has a cost budget, call linOptCost
 	// policy is a 1*batchSize vector
 	var policy []int
+	costConstraintAvailable := !math.IsInf(s.BatchBudget, 1)
 	if costConstraintAvailable {
-		policy = linOptCost(packetsNum, packetsDeadline, pathDelays, pathCWNDs, pathCost, budget)
+		policy = linOptCost(packetsNum, packetsDeadline, pathDelays, pathCWNDs, pathCost, s.BatchBudget)
 	} else {
 		policy = linOpt(packetsNum, packetsDeadline, pathDelays, pathCWNDs)
 	}
 	paths := PolicyToSelectPath(policy, eligiblePaths)
-
-	// compute cost
-	if costConstraintAvailable {
-		cost := computeCost(paths)
-		fmt.Println("Current Cost:", cost)
-		//sch.totalCost += cost // can not add total cost here, because maybe some packets is not sent
+	if sch.tracer != nil {
+		e := &LPSolvedEvent{
+			Deadlines: packetsDeadline,
+			Paths:     make([]PathID, len(eligiblePaths)),
+			Delays:    pathDelays,
+			Cwnds:     pathCWNDs,
+			Policy:    policy,
+		}
+		for i, pth := range eligiblePaths {
+			e.Paths[i] = pth.PathID
+		}
+		if costConstraintAvailable {
+			e.Costs = pathCost
+			budget := s.BatchBudget
+			e.Budget = &budget
+		}
+		traceEvent(sch.tracer, s.ConnectionID, TraceLPSolved, e)
 	}
 	return paths
 }
 
-func (sch *scheduler) selectBatchEDF(s *session,
-	hasRetransmission bool, hasStreamRetransmission bool,
-	fromPth *path, deadlineBatch []int) []*path {
+// batchEDFScheduler sends the packets with the earliest deadlines first, on the lowest RTT path
+type batchEDFScheduler struct {
+	rttScheduler
+}
 
-	// Sort Deadline, will change scheduler.go DeadlineBatch
-	sort.Ints(deadlineBatch)
+func newBatchEDFScheduler(*Config) PathScheduler { return &batchEDFScheduler{} }
 
-	if len(s.paths) <= 1 {
-		if !hasRetransmission && !s.paths[protocol.InitialPathID].SendingAllowed() {
-			return nil
-		}
-		paths := make([]*path, len(deadlineBatch))
-		for i := range paths {
-			paths[i] = s.paths[protocol.InitialPathID]
-		}
-		return paths
+func (sch *batchEDFScheduler) SelectBatch(s *PathSnapshot, deadlineBatch []time.Duration) []PathID {
+	utils.Debugf("Batch Scheduler: EDF")
+	// visit the packets by deadline, without reordering the caller's batch: the decisions are in queue order
+	order := make([]int, len(deadlineBatch))
+	for i := range order {
+		order[i] = i
 	}
-
-	// Create a slice to store the eligible paths
-	eligiblePaths := []*path{}
+	sort.SliceStable(order, func(i, j int) bool { return deadlineBatch[order[i]] < deadlineBatch[order[j]] })
 
 	// Iterate and pick out the pathBatch through minRTT
-	for i := 0; i < len(deadlineBatch); i++ {
-		pth := sch.selectPathLowLatency(s, hasRetransmission, hasStreamRetransmission, fromPth)
-		eligiblePaths = append(eligiblePaths, pth)
+	eligiblePaths := make([]PathID, len(deadlineBatch))
+	for _, i := range order {
+		eligiblePaths[i] = sch.SelectPath(s)
 	}
 
 	return eligiblePaths
 }
 
-func computeCost(paths []*path) float64 {
+func computeCost(s *PathSnapshot, paths []PathID) float64 {
 	var cost float64
-	for _, pth := range paths {
-		if pth != nil {
-			if pth.pathID == protocol.PathID(1) {
-				cost += path1Cost
-			} else if pth.pathID == protocol.PathID(3) {
-				cost += path3Cost
-			}
+	for _, pathID := range paths {
+		if pth := s.Path(pathID); pth != nil {
+			cost += pth.PacketCost
 		}
 	}
 	return cost
@@ -454,57 +489,44 @@ func generateSequence(length int) []int {
 	return sequence
 }
 
-func convertToIntSlice(input []int) []float64 {
+func convertToMilliseconds(input []time.Duration) []float64 {
 	converted := make([]float64, len(input))
-	for i, num := range input {
-		converted[i] = float64(num)
+	for i, d := range input {
+		converted[i] = float64(d) / float64(time.Millisecond)
 	}
 	return converted
 }
 
-func PolicyToSelectPath(policy []int, eligiblePath []*path) []*path {
-	var selectedPaths []*path
+func PolicyToSelectPath(policy []int, eligiblePath []*PathInfo) []PathID {
+	var selectedPaths []PathID
 
 	for _, p := range policy {
 		if p == 0 {
-			selectedPaths = append(selectedPaths, nil)
+			selectedPaths = append(selectedPaths, NoPath)
 		} else if p <= len(eligiblePath) {
-			selectedPaths = append(selectedPaths, eligiblePath[p-1])
+			selectedPaths = append(selectedPaths, eligiblePath[p-1].PathID)
 		} else {
-			selectedPaths = append(selectedPaths, nil)
+			selectedPaths = append(selectedPaths, NoPath)
 		}
 	}
 
 	return selectedPaths
 }
 
-func (sch *scheduler) canMadeDecision(s *session, batch int) bool {
-	// Create a slice to store the eligible paths
-	eligiblePaths := []*path{}
+// batchCapacity returns the number of full packets the congestion windows of the paths other than the initial path allow to send.
+// Lock of s.paths must be held
+func (sch *scheduler) batchCapacity(s *session) int {
+	var capacity int
 	for pathID, pth := range s.paths {
 		if pathID == protocol.InitialPathID {
 			continue
-		} else {
-			pthCwnd := pth.sentPacketHandler.GetCongestionWindow() // notice this Cwnd is bytes
-			pthInflight := pth.sentPacketHandler.GetBytesInFlight()
-			// path that has remaining cwnd is available
-			if pthCwnd >= pthInflight {
-				eligiblePaths = append(eligiblePaths, pth)
-			}
+		}
+		cwnd := pth.sentPacketHandler.GetCongestionWindow() // notice this Cwnd is bytes
+		inflight := pth.sentPacketHandler.GetBytesInFlight()
+		// path that has remaining cwnd is available
+		if cwnd > inflight {
+			capacity += int((cwnd - inflight) / protocol.MaxPacketSize)
 		}
 	}
-
-	// Collect all the path CWNDs
-	var allPathCwnds int
-
-	for _, pth := range eligiblePaths {
-		remainingCwnd := pth.sentPacketHandler.GetCongestionWindow() - pth.sentPacketHandler.GetBytesInFlight()
-		// TODO:remainingCwnd / protocol.MaxPacketSize is a uint64
-		allPathCwnds = allPathCwnds + int(remainingCwnd/protocol.MaxPacketSize)
-	}
-	if allPathCwnds >= batch {
-		return true
-	} else {
-		return false
-	}
+	return capacity
 }
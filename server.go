@@ -211,6 +211,20 @@ func populateServerConfig(config *Config) *Config {
 		Epsilon:                               config.Epsilon,
 		AllowedCongestion:                     config.AllowedCongestion,
 		DumpExperiences:                       config.DumpExperiences,
+		CostPolicy:                            config.CostPolicy,
+		PartialReliability:                    config.PartialReliability,
+		LinUCBStore:                           config.LinUCBStore,
+		BatchSize:                             config.BatchSize,
+		Tracer:                                config.Tracer,
+		PathCreation:                          config.PathCreation,
+		PathHealth:                            config.PathHealth,
+		StreamOrder:                           config.StreamOrder,
+		Redundancy:                            config.Redundancy,
+		CongestionControl:                     config.CongestionControl,
+		InterfacePolicy:                       config.InterfacePolicy,
+		PrimaryPathLabel:                      config.PrimaryPathLabel,
+		SecondPathLabel:                       config.SecondPathLabel,
+		Bandit:                                config.Bandit,
 	}
 }
 
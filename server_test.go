@@ -67,6 +67,16 @@ func (s *mockSession) LocalAddr() net.Addr              { panic("not implemented
 func (s *mockSession) RemoteAddr() net.Addr             { return s.remoteAddr }
 func (*mockSession) Context() context.Context           { panic("not implemented") }
 func (*mockSession) GetVersion() protocol.VersionNumber { return protocol.VersionWhatever }
+func (*mockSession) PathStats() []PathStats             { panic("not implemented") }
+func (*mockSession) ConnectionStats() ConnectionStats   { panic("not implemented") }
+func (*mockSession) SetPathPriority(PathID, PathPriority) error {
+	panic("not implemented")
+}
+func (*mockSession) Paths() []PathState                          { panic("not implemented") }
+func (*mockSession) OpenPath(net.Addr, net.Addr) (PathID, error) { panic("not implemented") }
+func (*mockSession) ClosePath(PathID) error                      { panic("not implemented") }
+func (*mockSession) SendDatagram([]byte, time.Time) error        { panic("not implemented") }
+func (*mockSession) ReceiveDatagram() ([]byte, error)            { panic("not implemented") }
 
 var _ Session = &mockSession{}
 var _ NonFWSession = &mockSession{}
@@ -125,6 +135,8 @@ var _ = Describe("Server", func() {
 			utils.LittleEndian.WriteUint32(b, protocol.VersionNumberToTag(protocol.SupportedVersions[0]))
 			firstPacket = []byte{0x09, 0xf6, 0x19, 0x86, 0x66, 0x9b, 0x9f, 0xfa, 0x4c}
 			firstPacket = append(append(firstPacket, b.Bytes()...), 0x01)
+			// deadline TTL, timestamp, curNotSent, batch length and alpha of the deadline extension
+			firstPacket = append(firstPacket, 0x00, 0x00, 0x00, 0x00, 0x00)
 		})
 
 		It("returns the address", func() {
@@ -89,6 +89,23 @@ type session struct {
 	closeChan chan closeError
 	closeOnce sync.Once
 
+	// statsRequests are answered by the run loop with the ConnectionStats
+	statsRequests chan chan ConnectionStats
+	// closePathRequests are paths the run loop closes with a CLOSE_PATH frame
+	closePathRequests chan protocol.PathID
+	// pathPriorityRequests are priority changes requested by the application
+	pathPriorityRequests chan pathPriorityRequest
+	// pathsRequests are answered by the run loop with the state of the paths
+	pathsRequests chan chan []PathState
+	// openPathRequests are paths the application asks the run loop to open
+	openPathRequests chan *openPathRequest
+	// datagramRequests are datagrams the application asks the run loop to send
+	datagramRequests chan *wire.DatagramFrame
+	// receivedDatagrams are the datagrams the application didn't read yet
+	receivedDatagrams chan []byte
+	// duplicatesReceived counts the STREAM frames that only contained data received before
+	duplicatesReceived uint64
+
 	ctx       context.Context
 	ctxCancel context.CancelFunc
 
@@ -193,6 +210,13 @@ func (s *session) setup(
 	s.handshakeCompleteChan = make(chan error, 1)
 	s.receivedPackets = make(chan *receivedPacket, protocol.MaxSessionUnprocessedPackets)
 	s.closeChan = make(chan closeError, 1)
+	s.statsRequests = make(chan chan ConnectionStats)
+	s.closePathRequests = make(chan protocol.PathID)
+	s.pathPriorityRequests = make(chan pathPriorityRequest)
+	s.pathsRequests = make(chan chan []PathState)
+	s.openPathRequests = make(chan *openPathRequest)
+	s.datagramRequests = make(chan *wire.DatagramFrame)
+	s.receivedDatagrams = make(chan []byte, protocol.MaxDatagramQueueLen)
 	s.sendingScheduled = make(chan struct{}, 1)
 	s.undecryptablePackets = make([]*receivedPacket, 0, protocol.MaxUndecryptablePackets)
 	s.ctx, s.ctxCancel = context.WithCancel(context.Background())
@@ -210,11 +234,8 @@ func (s *session) setup(
 		s.config.IdleTimeout,
 	)
 
-	s.scheduler = &scheduler{SchedulerName: s.config.SchedulerName,
-		Training:          s.config.Training,
-		AllowedCongestion: s.config.AllowedCongestion,
-		DumpExp:           s.config.DumpExperiences}
-	s.scheduler.setup()
+	s.scheduler = &scheduler{}
+	s.scheduler.setup(s.config)
 
 	if pconnMgr == nil && conn != nil {
 		// XXX ONLY VALID FOR BENCHMARK!
@@ -235,6 +256,9 @@ func (s *session) setup(
 	s.flowControlManager = flowcontrol.NewFlowControlManager(s.connectionParameters, s.rttStats, s.remoteRTTs)
 	s.streamsMap = newStreamsMap(s.newStream, s.perspective, s.connectionParameters)
 	s.streamFramer = newStreamFramer(s.streamsMap, s.flowControlManager)
+	// the peer only understands STREAM_GAP frames with the deadline extension
+	s.streamFramer.partialReliability = s.config.PartialReliability && s.version.UsesDeadlines()
+	s.streamFramer.streamOrder = s.config.StreamOrder
 	s.pathTimers = make(chan *path)
 
 	var err error
@@ -356,6 +380,24 @@ runLoop:
 		case <-s.sendingScheduled:
 			// We do all the interesting stuff after the switch statement, so
 			// nothing to see here.
+		case c := <-s.statsRequests:
+			c <- s.connectionStats()
+			continue
+		case pathID := <-s.closePathRequests:
+			if err := s.closePath(pathID, true); err != nil {
+				utils.Errorf("closing path %x: %v", pathID, err)
+			}
+			s.schedulePathsFrame()
+		case c := <-s.pathsRequests:
+			c <- s.pathStates()
+			continue
+		case r := <-s.openPathRequests:
+			r.pathID, r.err = s.openPath(r.local, r.remote)
+			close(r.done)
+		case r := <-s.pathPriorityRequests:
+			r.err <- s.setPathPriority(r.pathID, r.priority, true)
+		case f := <-s.datagramRequests:
+			s.streamFramer.AddDatagramForTransmission(f)
 		case tmpPth := <-s.pathTimers:
 			timerPth = tmpPth
 			// We do all the interesting stuff after the switch statement, so
@@ -391,9 +433,13 @@ runLoop:
 				// This could cause packets to be retransmitted, so check it before trying
 				// to send packets.
 				timerPth.sentPacketHandler.OnAlarm()
+				s.traceCongestionWindow(timerPth)
 			}
 			timerPth = nil
 		}
+		if err := s.probePaths(now); err != nil {
+			s.closeLocal(err)
+		}
 
 		if !s.pathManagerLaunched && s.handshakeComplete {
 			// XXX (QDC): for benchmark tests
@@ -465,6 +511,9 @@ func (s *session) maybeResetTimer() {
 	if !s.receivedTooManyUndecrytablePacketsTime.IsZero() {
 		deadline = utils.MinTime(deadline, s.receivedTooManyUndecrytablePacketsTime.Add(protocol.PublicResetTimeout))
 	}
+	if nextProbe := s.nextPathProbe(); !nextProbe.IsZero() {
+		deadline = utils.MinTime(deadline, nextProbe)
+	}
 
 	s.timer.Reset(deadline)
 }
@@ -512,6 +561,10 @@ func (s *session) handleFrames(fs []wire.Frame, p *path) error {
 		switch frame := ff.(type) {
 		case *wire.StreamFrame:
 			err = s.handleStreamFrame(frame)
+		case *wire.StreamGapFrame:
+			err = s.handleStreamGapFrame(frame)
+		case *wire.DatagramFrame:
+			s.handleDatagramFrame(frame)
 		case *wire.AckFrame:
 			err = s.handleAckFrame(frame)
 		case *wire.ConnectionCloseFrame:
@@ -536,17 +589,25 @@ func (s *session) handleFrames(fs []wire.Frame, p *path) error {
 			}
 		case *wire.ClosePathFrame:
 			s.handleClosePathFrame(frame)
+		case *wire.PathPriorityFrame:
+			err = s.handlePathPriorityFrame(frame)
 		case *wire.PathsFrame:
-			// So far, do nothing
+			var failed []*path
 			s.pathsLock.RLock()
 			for i := 0; i < int(frame.NumPaths); i++ {
 				s.remoteRTTs[frame.PathIDs[i]] = frame.RemoteRTTs[i]
-				if frame.RemoteRTTs[i] >= 30*time.Minute {
+				pth, ok := s.paths[frame.PathIDs[i]]
+				if ok && frame.RemoteRTTs[i] >= 30*time.Minute {
 					// Path is potentially failed
-					s.paths[frame.PathIDs[i]].potentiallyFailed.Set(true)
+					failed = append(failed, pth)
+				} else if ok {
+					pth.receivedPacketHandler.SetRemoteRTT(frame.RemoteRTTs[i])
 				}
 			}
 			s.pathsLock.RUnlock()
+			for _, pth := range failed {
+				pth.suspect(time.Now())
+			}
 		default:
 			return errors.New("Session BUG: unexpected frame type")
 		}
@@ -608,6 +669,18 @@ func (s *session) handleStreamFrame(frame *wire.StreamFrame) error {
 	return str.AddStreamFrame(frame)
 }
 
+func (s *session) handleStreamGapFrame(frame *wire.StreamGapFrame) error {
+	str, err := s.streamsMap.GetOrOpenStream(frame.StreamID)
+	if err != nil {
+		return err
+	}
+	if str == nil {
+		// Stream is closed and already garbage collected
+		return nil
+	}
+	return str.AddStreamGap(frame)
+}
+
 func (s *session) handleWindowUpdateFrame(frame *wire.WindowUpdateFrame) error {
 	if frame.StreamID != 0 {
 		str, err := s.streamsMap.GetOrOpenStream(frame.StreamID)
@@ -637,7 +710,34 @@ func (s *session) handleRstStreamFrame(frame *wire.RstStreamFrame) error {
 
 func (s *session) handleAckFrame(frame *wire.AckFrame) error {
 	pth := s.paths[frame.PathID]
+	alpha := pth.sentPacketHandler.GetPathAlpha()
 	err := pth.sentPacketHandler.ReceivedAck(frame, pth.lastRcvdPacketNumber, pth.lastNetworkActivityTime)
+	var numMeetDeadline uint16
+	for _, sample := range frame.DeadlineSamples {
+		if sample.MetDeadline() {
+			numMeetDeadline++
+		}
+	}
+	s.trace(TraceAckReceived, &AckReceivedEvent{
+		PathID:                 frame.PathID,
+		LargestAcked:           frame.LargestAcked,
+		HasDeadlineInformation: frame.HasDeadlineInformation,
+		NumHasDeadline:         uint16(len(frame.DeadlineSamples)),
+		NumMeetDeadline:        numMeetDeadline,
+	})
+	s.scheduler.duplicates.receivedDeadlineSamples(frame.PathID, frame.DeadlineSamples)
+	for _, miss := range pth.sentPacketHandler.DeadlineMisses() {
+		s.trace(TraceDeadlineMissed, &DeadlineMissedEvent{
+			PathID:       frame.PathID,
+			PacketNumber: miss.PacketNumber,
+			Lateness:     miss.Lateness,
+			StreamIDs:    miss.StreamIDs,
+		})
+	}
+	if newAlpha := pth.sentPacketHandler.GetPathAlpha(); newAlpha != alpha {
+		s.trace(TraceAlphaChanged, &AlphaChangedEvent{PathID: frame.PathID, OldAlpha: alpha, NewAlpha: newAlpha})
+	}
+	s.traceCongestionWindow(pth)
 	if err == nil && pth.rttStats.SmoothedRTT() > s.rttStats.SmoothedRTT() {
 		// Update the session RTT, which comes to take the max RTT on all paths
 		s.rttStats.UpdateSessionRTT(pth.rttStats.SmoothedRTT())
@@ -680,9 +780,14 @@ func (s *session) closePath(pthID protocol.PathID, sendClosePathFrame bool) erro
 		return nil
 	}
 
+	// Don't schedule packets on the path anymore, the CLOSE_PATH frame is sent on another path
+	pth.close()
 	pth.sentPacketHandler.SetInflightAsLost()
 	closePathFrame := pth.GetClosePathFrame()
-	s.streamFramer.AddClosePathFrameForTransmission(closePathFrame)
+	// Without any packet received on the path, there is nothing the frame could acknowledge
+	if closePathFrame != nil {
+		s.streamFramer.AddClosePathFrameForTransmission(closePathFrame)
+	}
 
 	return nil
 }
@@ -692,6 +797,14 @@ func (s *session) schedulePathsFrame() {
 	s.streamFramer.AddPathsFrameForTransmission(s)
 }
 
+// requestClosePath asks the run loop to close a path and to tell the peer with a CLOSE_PATH frame
+func (s *session) requestClosePath(pathID protocol.PathID) {
+	select {
+	case s.closePathRequests <- pathID:
+	case <-s.ctx.Done():
+	}
+}
+
 func (s *session) closePaths() {
 	// XXX (QDC): still for tests
 	if s.pathManager != nil {
@@ -761,6 +874,8 @@ func (s *session) handleCloseError(closeErr closeError) error {
 		return nil
 	}
 
+	s.scheduler.close()
+
 	s.closePaths()
 
 	// If this is a remote close we're done here
@@ -797,6 +912,7 @@ func (s *session) sendPackedPacket(packet *packedPacket, pth *path) error {
 	pth.sentPacket <- struct{}{}
 
 	s.logPacket(packet, pth.pathID)
+	s.tracePacketSent(packet, pth)
 	//czy: only write raw data, where is the PacketNumber and Packet head information
 	return pth.conn.Write(packet.raw)
 }
@@ -884,7 +1000,9 @@ func (s *session) newStream(id protocol.StreamID) *stream {
 	} else {
 		s.flowControlManager.NewStream(id, true)
 	}
-	return newStream(id, s.scheduleSending, s.queueResetStreamFrame, s.flowControlManager)
+	str := newStream(id, s.scheduleSending, s.queueResetStreamFrame, s.flowControlManager)
+	str.onDuplicateData = func() { s.duplicatesReceived++ }
+	return str
 }
 
 // garbageCollectStreams goes through all streams and removes EOF'ed streams
@@ -0,0 +1,148 @@
+package quic
+
+import (
+	"errors"
+	"net"
+	"sort"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+var (
+	errNoPathManager         = errors.New("the session can't manage paths")
+	errHandshakeIncomplete   = errors.New("paths can only be opened once the handshake is complete")
+	errUnknownLocalAddr      = errors.New("no socket is open on the local address")
+	errIPVersionMismatch     = errors.New("the local and remote addresses have different IP versions")
+	errCloseInitialPath      = errors.New("the initial path can't be closed")
+	errPathAddrNotResolvable = errors.New("path addresses must be UDP addresses")
+)
+
+// A PathState describes a path of the session
+type PathState struct {
+	PathID     PathID
+	LocalAddr  net.Addr
+	RemoteAddr net.Addr
+	// Label is the label of the local interface, according to the InterfacePolicy
+	Label string
+	// Open is false once the path was closed, by either peer
+	Open bool
+	// Priority is the priority of the path, set with SetPathPriority or by the peer
+	Priority PathPriority
+	// Health is the state of the failure detection of the path
+	Health PathHealth
+	// PotentiallyFailed is set while the Health is not PathHealthActive
+	PotentiallyFailed bool
+}
+
+// an openPathRequest is answered by the run loop of the session
+type openPathRequest struct {
+	local  net.UDPAddr
+	remote net.UDPAddr
+
+	pathID protocol.PathID
+	err    error
+	done   chan struct{}
+}
+
+// Paths returns the state of every path, sorted by PathID. Closed paths are included.
+func (s *session) Paths() []PathState {
+	c := make(chan []PathState, 1)
+	select {
+	case s.pathsRequests <- c:
+		return <-c
+	case <-s.ctx.Done():
+		// the run loop finished, so the paths don't change anymore
+		return s.pathStates()
+	}
+}
+
+func (s *session) pathStates() []PathState {
+	s.pathsLock.RLock()
+	defer s.pathsLock.RUnlock()
+	states := make([]PathState, 0, len(s.paths))
+	for pathID, pth := range s.paths {
+		states = append(states, PathState{
+			PathID:            pathID,
+			LocalAddr:         pth.conn.LocalAddr(),
+			RemoteAddr:        pth.conn.RemoteAddr(),
+			Label:             pth.label,
+			Open:              pth.open.Get(),
+			Priority:          pth.priority,
+			Health:            pth.health.state,
+			PotentiallyFailed: pth.potentiallyFailed.Get(),
+		})
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].PathID < states[j].PathID })
+	return states
+}
+
+// OpenPath opens a path between a local address of the session and a remote address, and returns its PathID.
+// If an open path already uses these addresses, its PathID is returned.
+func (s *session) OpenPath(local, remote net.Addr) (PathID, error) {
+	localAddr, err := toUDPAddr(local)
+	if err != nil {
+		return 0, err
+	}
+	remoteAddr, err := toUDPAddr(remote)
+	if err != nil {
+		return 0, err
+	}
+	r := &openPathRequest{local: localAddr, remote: remoteAddr, done: make(chan struct{})}
+	select {
+	case s.openPathRequests <- r:
+		<-r.done
+		return r.pathID, r.err
+	case <-s.ctx.Done():
+		return 0, errSessionClosed
+	}
+}
+
+// openPath must be called from the run loop
+func (s *session) openPath(local, remote net.UDPAddr) (protocol.PathID, error) {
+	if s.pathManager == nil || s.pathManager.pconnMgr == nil {
+		return 0, errNoPathManager
+	}
+	if !s.handshakeComplete {
+		return 0, errHandshakeIncomplete
+	}
+	pathID, err := s.pathManager.openPath(local, remote)
+	if err != nil {
+		return 0, err
+	}
+	s.schedulePathsFrame()
+	return pathID, nil
+}
+
+// ClosePath closes an open path. The peer is told with a CLOSE_PATH frame.
+// Data that was in flight on the path is retransmitted on the other paths.
+func (s *session) ClosePath(pathID PathID) error {
+	if pathID == protocol.InitialPathID {
+		return errCloseInitialPath
+	}
+	s.pathsLock.RLock()
+	pth, ok := s.paths[pathID]
+	s.pathsLock.RUnlock()
+	if !ok || !pth.open.Get() {
+		return errUnknownPath
+	}
+	select {
+	case s.closePathRequests <- pathID:
+		return nil
+	case <-s.ctx.Done():
+		return errSessionClosed
+	}
+}
+
+func toUDPAddr(addr net.Addr) (net.UDPAddr, error) {
+	if addr == nil {
+		return net.UDPAddr{}, errPathAddrNotResolvable
+	}
+	if udpAddr, ok := addr.(*net.UDPAddr); ok {
+		return *udpAddr, nil
+	}
+	udpAddr, err := net.ResolveUDPAddr("udp", addr.String())
+	if err != nil {
+		return net.UDPAddr{}, errPathAddrNotResolvable
+	}
+	return *udpAddr, nil
+}
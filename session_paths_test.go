@@ -0,0 +1,182 @@
+package quic
+
+import (
+	"context"
+	"net"
+
+	"github.com/lucas-clemente/quic-go/congestion"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Path management API", func() {
+	var (
+		sess             *session
+		pconnAny, pconn  net.PacketConn
+		remote1, remote3 *net.UDPAddr
+		pcm              *pconnManager
+	)
+
+	listen := func() net.PacketConn {
+		c, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		return c
+	}
+
+	BeforeEach(func() {
+		sess = &session{
+			paths:             make(map[protocol.PathID]*path),
+			closedPaths:       make(map[protocol.PathID]bool),
+			streamFramer:      newStreamFramer(nil, nil),
+			closePathRequests: make(chan protocol.PathID, 1),
+			pathsRequests:     make(chan chan []PathState),
+			openPathRequests:  make(chan *openPathRequest),
+		}
+		sess.ctx, sess.ctxCancel = context.WithCancel(context.Background())
+		pconnAny = listen()
+		pconn = listen()
+		pcm = &pconnManager{
+			perspective: protocol.PerspectiveClient,
+			pconnAny:    pconnAny,
+			pconns:      map[string]net.PacketConn{pconn.LocalAddr().String(): pconn},
+			localAddrs:  []net.UDPAddr{*pconn.LocalAddr().(*net.UDPAddr)},
+		}
+		sess.pathManager = &pathManager{pconnMgr: pcm, sess: sess, nxtPathID: 5}
+		remote1 = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 4433}
+		remote3 = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 4434}
+		newPath := func(pathID protocol.PathID, pconn net.PacketConn, remote *net.UDPAddr) *path {
+			pth := &path{pathID: pathID, sess: sess, conn: &conn{pconn: pconn, currentAddr: remote}, rttStats: &congestion.RTTStats{}}
+			pth.open.Set(true)
+			sess.paths[pathID] = pth
+			return pth
+		}
+		newPath(0, pconnAny, remote1)
+		newPath(3, pconn, remote3).label = "wifi"
+		newPath(1, pconn, remote1).priority = PathPriorityBackup
+	})
+
+	AfterEach(func() {
+		sess.ctxCancel()
+		pconnAny.Close()
+		pconn.Close()
+	})
+
+	It("lists the paths", func() {
+		sess.paths[1].potentiallyFailed.Set(true)
+		sess.paths[3].open.Set(false)
+		go func() {
+			defer GinkgoRecover()
+			c := <-sess.pathsRequests
+			c <- sess.pathStates()
+		}()
+		states := sess.Paths()
+		Expect(states).To(HaveLen(3))
+		Expect(states[0].PathID).To(Equal(PathID(0)))
+		Expect(states[1]).To(Equal(PathState{
+			PathID:            1,
+			LocalAddr:         pconn.LocalAddr(),
+			RemoteAddr:        remote1,
+			Open:              true,
+			Priority:          PathPriorityBackup,
+			PotentiallyFailed: true,
+		}))
+		Expect(states[2].Label).To(Equal("wifi"))
+		Expect(states[2].Open).To(BeFalse())
+	})
+
+	It("lists the paths after the session was closed", func() {
+		sess.ctxCancel()
+		Expect(sess.Paths()).To(HaveLen(3))
+	})
+
+	Context("opening paths", func() {
+		BeforeEach(func() {
+			sess.handshakeComplete = true
+		})
+
+		It("returns the path that already uses the addresses", func() {
+			go func() {
+				defer GinkgoRecover()
+				r := <-sess.openPathRequests
+				r.pathID, r.err = sess.openPath(r.local, r.remote)
+				close(r.done)
+			}()
+			pathID, err := sess.OpenPath(pconn.LocalAddr(), remote3)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pathID).To(Equal(PathID(3)))
+			Expect(sess.streamFramer.PopPathsFrame()).ToNot(BeNil())
+		})
+
+		It("accepts any address that resolves to a UDP address", func() {
+			pathID, err := sess.openPath(*pconn.LocalAddr().(*net.UDPAddr), *remote1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pathID).To(Equal(PathID(1)))
+			addr, err := toUDPAddr(&net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 80})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(addr.String()).To(Equal("10.0.0.1:80"))
+			_, err = sess.OpenPath(nil, remote1)
+			Expect(err).To(MatchError(errPathAddrNotResolvable))
+		})
+
+		It("refuses unknown local addresses", func() {
+			_, err := sess.openPath(net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1}, *remote1)
+			Expect(err).To(MatchError(errUnknownLocalAddr))
+		})
+
+		It("refuses addresses of different IP versions", func() {
+			_, err := sess.openPath(*pconn.LocalAddr().(*net.UDPAddr), net.UDPAddr{IP: net.IPv6loopback, Port: 4433})
+			Expect(err).To(MatchError(errIPVersionMismatch))
+		})
+
+		It("waits for the handshake", func() {
+			sess.handshakeComplete = false
+			_, err := sess.openPath(*pconn.LocalAddr().(*net.UDPAddr), *remote1)
+			Expect(err).To(MatchError(errHandshakeIncomplete))
+		})
+
+		It("needs a path manager", func() {
+			sess.pathManager = nil
+			_, err := sess.openPath(*pconn.LocalAddr().(*net.UDPAddr), *remote1)
+			Expect(err).To(MatchError(errNoPathManager))
+		})
+
+		It("fails once the session is closed", func() {
+			sess.ctxCancel()
+			_, err := sess.OpenPath(pconn.LocalAddr(), remote1)
+			Expect(err).To(MatchError(errSessionClosed))
+		})
+	})
+
+	Context("closing paths", func() {
+		It("asks the run loop to close the path", func() {
+			Expect(sess.ClosePath(3)).To(Succeed())
+			Expect(sess.closePathRequests).To(Receive(Equal(protocol.PathID(3))))
+		})
+
+		It("refuses to close the initial path", func() {
+			Expect(sess.ClosePath(0)).To(MatchError(errCloseInitialPath))
+		})
+
+		It("refuses unknown and closed paths", func() {
+			Expect(sess.ClosePath(7)).To(MatchError(errUnknownPath))
+			sess.paths[3].open.Set(false)
+			Expect(sess.ClosePath(3)).To(MatchError(errUnknownPath))
+		})
+
+		It("fails once the session is closed", func() {
+			sess.ctxCancel()
+			sess.closePathRequests = make(chan protocol.PathID)
+			Expect(sess.ClosePath(3)).To(MatchError(errSessionClosed))
+		})
+
+		It("leaves closed paths out of the PATHS frame", func() {
+			sess.closedPaths[3] = true
+			sess.schedulePathsFrame()
+			f := sess.streamFramer.PopPathsFrame()
+			Expect(f.NumPaths).To(Equal(uint8(2)))
+			Expect(f.PathIDs).To(ConsistOf(protocol.PathID(0), protocol.PathID(1)))
+		})
+	})
+})
@@ -15,6 +15,7 @@ import (
 	. "github.com/onsi/gomega"
 
 	"github.com/lucas-clemente/quic-go/ackhandler"
+	"github.com/lucas-clemente/quic-go/congestion"
 	"github.com/lucas-clemente/quic-go/internal/crypto"
 	"github.com/lucas-clemente/quic-go/internal/handshake"
 	"github.com/lucas-clemente/quic-go/internal/mocks"
@@ -102,6 +103,19 @@ func (h *mockSentPacketHandler) ShouldSendRetransmittablePacket() bool {
 	return b
 }
 func (h *mockSentPacketHandler) GetStatistics() (uint64, uint64, uint64) { panic("not implemented") }
+func (h *mockSentPacketHandler) GetLastPackets() uint64                  { return 0 }
+func (h *mockSentPacketHandler) GetAckedBytes() protocol.ByteCount       { return 0 }
+func (h *mockSentPacketHandler) GetSentBytes() protocol.ByteCount        { return 0 }
+func (h *mockSentPacketHandler) GetCongestionWindow() protocol.ByteCount {
+	return protocol.DefaultTCPMSS * protocol.InitialCongestionWindow
+}
+func (h *mockSentPacketHandler) GetBytesInFlight() protocol.ByteCount           { return 0 }
+func (h *mockSentPacketHandler) GetPathAlpha() float32                          { return 1 }
+func (h *mockSentPacketHandler) GetDeadlineStatistics() (uint64, uint64)        { return 0, 0 }
+func (h *mockSentPacketHandler) DeadlineMisses() []ackhandler.DeadlineMiss      { return nil }
+func (h *mockSentPacketHandler) CalculateMeetRatio() float32                    { return 0 }
+func (h *mockSentPacketHandler) CalculateInstantMeetRatio() float32             { return 0 }
+func (h *mockSentPacketHandler) CalculateHistoryMeetRatio(armIndex int) float32 { return 0 }
 
 func (h *mockSentPacketHandler) GetStopWaitingFrame(force bool) *wire.StopWaitingFrame {
 	h.requestedStopWaiting = true
@@ -145,9 +159,15 @@ func (m *mockReceivedPacketHandler) SetLowerLimit(protocol.PacketNumber) {
 	panic("not implemented")
 }
 func (m *mockReceivedPacketHandler) GetAlarmTimeout() time.Time { return m.ackAlarm }
-func (m *mockReceivedPacketHandler) GetStatistics() uint64 {
+func (m *mockReceivedPacketHandler) GetStatistics() (uint64, uint64, uint64) {
 	panic("not implemented")
 }
+func (m *mockReceivedPacketHandler) StatisticPacketMeet(hdr *wire.PublicHeader, rcvTime time.Time) error {
+	return nil
+}
+func (m *mockReceivedPacketHandler) SetRemoteRTT(rtt time.Duration)                         {}
+func (m *mockReceivedPacketHandler) UpdateCurNotSent(curNotSent uint16, batchLength uint16) {}
+func (m *mockReceivedPacketHandler) UpdateAlpha(alpha uint16)                               {}
 
 func (m *mockReceivedPacketHandler) GetClosePathFrame() *wire.ClosePathFrame {
 	panic("not implemented")
@@ -155,6 +175,25 @@ func (m *mockReceivedPacketHandler) GetClosePathFrame() *wire.ClosePathFrame {
 
 var _ ackhandler.ReceivedPacketHandler = &mockReceivedPacketHandler{}
 
+// fixedBatchScheduler makes the same decisions for every batch, the packets beyond them are held back
+type fixedBatchScheduler struct {
+	paths []PathID
+}
+
+func (s *fixedBatchScheduler) SelectPath(*PathSnapshot) PathID { return s.paths[0] }
+func (s *fixedBatchScheduler) SelectBatch(_ *PathSnapshot, deadlines []time.Duration) []PathID {
+	paths := make([]PathID, len(deadlines))
+	for i := range paths {
+		paths[i] = NoPath
+		if i < len(s.paths) {
+			paths[i] = s.paths[i]
+		}
+	}
+	return paths
+}
+
+var _ BatchPathScheduler = &fixedBatchScheduler{}
+
 func areSessionsRunning() bool {
 	var b bytes.Buffer
 	pprof.Lookup("goroutine").WriteTo(&b, 1)
@@ -1059,6 +1098,161 @@ var _ = Describe("Session", func() {
 		})
 	})
 
+	Context("sending on several paths", func() {
+		newPath := func(pathID protocol.PathID, rtt time.Duration) *path {
+			pth := &path{
+				pathID:                pathID,
+				sess:                  sess,
+				conn:                  mconn,
+				rttStats:              &congestion.RTTStats{},
+				receivedPacketHandler: &mockReceivedPacketHandler{},
+				packetNumberGenerator: newPacketNumberGenerator(protocol.SkipPacketAveragePeriodLength),
+				sentPacket:            make(chan struct{}, 10),
+			}
+			pth.sentPacketHandler = ackhandler.NewSentPacketHandler(pth.rttStats, nil, nil, nil)
+			pth.rttStats.UpdateRTT(rtt, 0, time.Now())
+			pth.open.Set(true)
+			sess.paths[pathID] = pth
+			return pth
+		}
+
+		sendPacket := func() {
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				Expect(sess.sendPacket()).To(Succeed())
+				close(done)
+			}()
+			Eventually(done).Should(BeClosed())
+		}
+
+		packetsSent := func(pth *path) uint64 {
+			sent, _, _ := pth.sentPacketHandler.GetStatistics()
+			return sent
+		}
+
+		BeforeEach(func() {
+			sess.packer.cryptoSetup = &mockCryptoSetup{encLevelSeal: protocol.EncryptionForwardSecure}
+			_, err := sess.GetOrOpenStream(5)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		Context("in batches", func() {
+			BeforeEach(func() {
+				sess.scheduler.pathScheduler = newBatchLinOptScheduler(nil)
+			})
+
+			It("sends the packets that miss their deadline on every path on the path with the lowest delay", func() {
+				slow := newPath(1, 200*time.Millisecond)
+				fast := newPath(3, 40*time.Millisecond)
+				// only the fast path delivers the first frame in time, the second one is late on every path
+				sess.streamFramer.AddFrameForRetransmission(&wire.StreamFrame{StreamID: 5, Data: make([]byte, 1000), Deadline: time.Now().Add(50 * time.Millisecond)})
+				sess.streamFramer.AddFrameForRetransmission(&wire.StreamFrame{StreamID: 5, Offset: 1000, Data: make([]byte, 1000), Deadline: time.Now().Add(time.Millisecond)})
+				sendPacket()
+				Expect(sess.streamFramer.HasFramesForRetransmission()).To(BeFalse())
+				Expect(packetsSent(fast)).To(BeEquivalentTo(2))
+				Expect(packetsSent(slow)).To(BeZero())
+			})
+
+			It("sends the packets in order, up to the first one that is held back", func() {
+				sess.scheduler.pathScheduler = &fixedBatchScheduler{paths: []PathID{3, NoPath, 1}}
+				other := newPath(1, 40*time.Millisecond)
+				chosen := newPath(3, 40*time.Millisecond)
+				str, err := sess.GetOrOpenStream(5)
+				Expect(err).ToNot(HaveOccurred())
+				str.(*stream).dataForWriting = make([]byte, 3*protocol.MaxPacketSize)
+				sendPacket()
+				Expect(str.(*stream).lenOfDataForWriting()).To(BeZero())
+				Expect(packetsSent(chosen)).To(BeNumerically(">", 1))
+				Expect(packetsSent(other)).To(BeZero())
+			})
+
+			It("counts a packet that is held back once", func() {
+				sess.scheduler.pathScheduler = &fixedBatchScheduler{paths: []PathID{3}}
+				newPath(1, 40*time.Millisecond)
+				pth := newPath(3, 40*time.Millisecond)
+				str, err := sess.GetOrOpenStream(5)
+				Expect(err).ToNot(HaveOccurred())
+				str.(*stream).dataForWriting = make([]byte, 3*protocol.MaxPacketSize)
+				sendPacket()
+				Expect(str.(*stream).lenOfDataForWriting()).To(BeZero())
+				// every packet but the first one was held back by the batches before it
+				Expect(sess.scheduler.NotSentPackets).To(Equal(packetsSent(pth) - 1))
+				Expect(sess.scheduler.heldBack).To(BeZero())
+			})
+
+			It("sends the first packet on the path with the lowest delay if it is held back", func() {
+				sess.scheduler.pathScheduler = &fixedBatchScheduler{paths: []PathID{NoPath, 1}}
+				slow := newPath(1, 200*time.Millisecond)
+				fast := newPath(3, 40*time.Millisecond)
+				sess.streamFramer.AddFrameForRetransmission(&wire.StreamFrame{StreamID: 5, Data: make([]byte, 100)})
+				sendPacket()
+				Expect(sess.streamFramer.HasFramesForRetransmission()).To(BeFalse())
+				Expect(packetsSent(fast)).To(BeEquivalentTo(1))
+				Expect(packetsSent(slow)).To(BeZero())
+			})
+
+			It("sends a batch that waits for the low-cost path on that path", func() {
+				budget := 100.0
+				sess.scheduler.cost = newCostTracker(&CostPolicy{Budget: &budget})
+				expensive := newPath(1, 40*time.Millisecond)
+				expensive.price = 1
+				cheap := newPath(3, 200*time.Millisecond)
+				sess.streamFramer.AddFrameForRetransmission(&wire.StreamFrame{StreamID: 5, Data: make([]byte, 1000), Deadline: time.Now().Add(time.Second)})
+				sendPacket()
+				Expect(sess.streamFramer.HasFramesForRetransmission()).To(BeFalse())
+				Expect(packetsSent(cheap)).To(BeEquivalentTo(1))
+				Expect(packetsSent(expensive)).To(BeZero())
+			})
+		})
+
+		Context("with duplicates", func() {
+			var (
+				sch           *redundantScheduler
+				first, second *path
+			)
+
+			BeforeEach(func() {
+				sch = newRedundantScheduler(&Config{}).(*redundantScheduler)
+				sess.scheduler.pathScheduler = sch
+				first = newPath(1, 20*time.Millisecond)
+				second = newPath(3, 30*time.Millisecond)
+				second.price = 2
+				// the duplicates are limited to a fraction of the packets sent
+				for i := 1; i <= 10; i++ {
+					Expect(first.sentPacketHandler.SentPacket(&ackhandler.Packet{PacketNumber: protocol.PacketNumber(i), Length: 1})).To(Succeed())
+				}
+			})
+
+			It("charges the duplicates that were sent", func() {
+				pkt := &ackhandler.Packet{
+					PacketNumber:    0x1337,
+					Frames:          []wire.Frame{&wire.StreamFrame{StreamID: 5, Data: []byte("foobar")}},
+					EncryptionLevel: protocol.EncryptionForwardSecure,
+					Deadline:        time.Now().Add(18 * time.Millisecond),
+				}
+				Expect(sess.scheduler.maybeDuplicate(sess, pkt, first)).To(Succeed())
+				Expect(packetsSent(second)).To(BeEquivalentTo(1))
+				Expect(sch.duplicates).To(BeEquivalentTo(1))
+				Expect(sch.duplicateCost).To(Equal(2.0))
+				Expect(second.totalCost).To(Equal(2.0))
+			})
+
+			It("doesn't duplicate packets without stream data", func() {
+				pkt := &ackhandler.Packet{
+					PacketNumber:    0x1337,
+					Frames:          []wire.Frame{&wire.PingFrame{}},
+					EncryptionLevel: protocol.EncryptionForwardSecure,
+					Deadline:        time.Now().Add(18 * time.Millisecond),
+				}
+				Expect(sess.scheduler.maybeDuplicate(sess, pkt, first)).To(Succeed())
+				Expect(packetsSent(second)).To(BeZero())
+				Expect(sch.duplicates).To(BeZero())
+				Expect(sch.duplicateCost).To(BeZero())
+			})
+		})
+	})
+
 	Context("retransmissions", func() {
 		var sph *mockSentPacketHandler
 		BeforeEach(func() {
@@ -0,0 +1,176 @@
+package quic
+
+import (
+	"net"
+	"sort"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// PathStats is a snapshot of the statistics of a path
+type PathStats struct {
+	PathID     PathID
+	LocalAddr  net.Addr
+	RemoteAddr net.Addr
+	// Label is the label of the local interface, according to the InterfacePolicy
+	Label string
+	// Priority is the priority of the path, set with SetPathPriority or by the peer
+	Priority PathPriority
+	// Health is the state of the failure detection of the path
+	Health PathHealth
+
+	SmoothedRTT      time.Duration
+	CongestionWindow protocol.ByteCount
+	BytesInFlight    protocol.ByteCount
+	// Alpha is the deadline stringency factor currently chosen by the bandit
+	Alpha float32
+
+	PacketsSent          uint64
+	PacketsRetransmitted uint64
+	PacketsLost          uint64
+	// Reinjections is the number of lost packets whose stream data was reinjected on this path,
+	// LateReinjections the number of those that couldn't be delivered by their deadline
+	Reinjections     uint64
+	LateReinjections uint64
+
+	PacketsReceived uint64
+	// PacketsWithDeadline is the number of received packets that carried a deadline,
+	// PacketsMetDeadline the number of those that arrived in time
+	PacketsWithDeadline uint64
+	PacketsMetDeadline  uint64
+	// PacketsReportedWithDeadline is the number of sent packets with a deadline the peer reported on,
+	// PacketsReportedMetDeadline the number of those that arrived in time
+	PacketsReportedWithDeadline uint64
+	PacketsReportedMetDeadline  uint64
+
+	// Price is the price of the path according to the CostPolicy, TotalCost the cost spent on it so far
+	Price     float64
+	TotalCost float64
+}
+
+// DeadlineMeetRatio is the fraction of the received packets with a deadline that arrived in time.
+// It is 0 if no packet with a deadline was received.
+func (s *PathStats) DeadlineMeetRatio() float64 {
+	if s.PacketsWithDeadline == 0 {
+		return 0
+	}
+	return float64(s.PacketsMetDeadline) / float64(s.PacketsWithDeadline)
+}
+
+// ConnectionStats is a snapshot of the statistics of a connection
+type ConnectionStats struct {
+	// Paths are sorted by PathID
+	Paths []PathStats
+
+	PacketsSent          uint64
+	PacketsRetransmitted uint64
+	PacketsLost          uint64
+	PacketsReceived      uint64
+	PacketsWithDeadline  uint64
+	PacketsMetDeadline   uint64
+	// PacketsNotSent is the number of packets that batch schedulers held back at least once.
+	// A packet is counted when it is sent or dropped, however many batches held it back.
+	PacketsNotSent uint64
+	// DatagramsDropped is the number of datagrams that were never sent, because their deadline expired or the send queue was full
+	DatagramsDropped uint64
+	// PacketsDuplicated is the number of copies the redundant scheduler sent on other paths,
+	// DuplicatesSavedDeadline the number of packets that missed their deadline, but one of their copies met it
+	PacketsDuplicated       uint64
+	DuplicatesSavedDeadline uint64
+	// DuplicatesReceived is the number of STREAM frames received with data that had already been received, e.g. in a copy sent on another path
+	DuplicatesReceived uint64
+
+	// TotalCost is the cost spent on all paths, PacketsWithCost the number of packets sent on paths with a price
+	TotalCost       float64
+	PacketsWithCost uint64
+	// RemainingBudget is the cost the connection may still spend, +Inf if the CostPolicy has no budget
+	RemainingBudget float64
+}
+
+// DeadlineMeetRatio is the fraction of the received packets with a deadline that arrived in time, on all paths.
+// It is 0 if no packet with a deadline was received.
+func (s *ConnectionStats) DeadlineMeetRatio() float64 {
+	if s.PacketsWithDeadline == 0 {
+		return 0
+	}
+	return float64(s.PacketsMetDeadline) / float64(s.PacketsWithDeadline)
+}
+
+// connectionStats collects the statistics. It must be called from the run loop, or after it finished.
+func (s *session) connectionStats() ConnectionStats {
+	s.pathsLock.RLock()
+	defer s.pathsLock.RUnlock()
+
+	stats := ConnectionStats{
+		Paths:            make([]PathStats, 0, len(s.paths)),
+		PacketsNotSent:   s.scheduler.GetNotSentPackets(),
+		DatagramsDropped: s.streamFramer.droppedDatagrams,
+		TotalCost:        s.scheduler.GetTotalCost(),
+		PacketsWithCost:  s.scheduler.GetTotalPktWithCost(),
+		RemainingBudget:  s.scheduler.cost.remainingBudget(time.Now()),
+
+		PacketsDuplicated:       s.scheduler.duplicates.sent,
+		DuplicatesSavedDeadline: s.scheduler.duplicates.savedDeadlines,
+		DuplicatesReceived:      s.duplicatesReceived,
+	}
+	for pathID, pth := range s.paths {
+		sent, retrans, lost := pth.sentPacketHandler.GetStatistics()
+		rcv, hasDeadline, metDeadline := pth.receivedPacketHandler.GetStatistics()
+		reportedDeadline, reportedMet := pth.sentPacketHandler.GetDeadlineStatistics()
+		ps := PathStats{
+			PathID:                      pathID,
+			Label:                       pth.label,
+			Priority:                    pth.priority,
+			Health:                      pth.health.state,
+			SmoothedRTT:                 pth.rttStats.SmoothedRTT(),
+			CongestionWindow:            pth.sentPacketHandler.GetCongestionWindow(),
+			BytesInFlight:               pth.sentPacketHandler.GetBytesInFlight(),
+			Alpha:                       pth.sentPacketHandler.GetPathAlpha(),
+			PacketsSent:                 sent,
+			PacketsRetransmitted:        retrans,
+			PacketsLost:                 lost,
+			Reinjections:                pth.reinjections,
+			LateReinjections:            pth.lateReinjections,
+			PacketsReceived:             rcv,
+			PacketsWithDeadline:         hasDeadline,
+			PacketsMetDeadline:          metDeadline,
+			PacketsReportedWithDeadline: reportedDeadline,
+			PacketsReportedMetDeadline:  reportedMet,
+			Price:                       pth.price,
+			TotalCost:                   pth.totalCost,
+		}
+		if pth.conn != nil {
+			ps.LocalAddr = pth.conn.LocalAddr()
+			ps.RemoteAddr = pth.conn.RemoteAddr()
+		}
+		stats.Paths = append(stats.Paths, ps)
+
+		stats.PacketsSent += sent
+		stats.PacketsRetransmitted += retrans
+		stats.PacketsLost += lost
+		stats.PacketsReceived += rcv
+		stats.PacketsWithDeadline += hasDeadline
+		stats.PacketsMetDeadline += metDeadline
+	}
+	sort.Slice(stats.Paths, func(i, j int) bool { return stats.Paths[i].PathID < stats.Paths[j].PathID })
+	return stats
+}
+
+// ConnectionStats returns a snapshot of the statistics of the connection.
+// It can be called at any time, also after the session was closed.
+func (s *session) ConnectionStats() ConnectionStats {
+	c := make(chan ConnectionStats, 1)
+	select {
+	case s.statsRequests <- c:
+		return <-c
+	case <-s.ctx.Done():
+		// the run loop finished, so the statistics don't change anymore
+		return s.connectionStats()
+	}
+}
+
+// PathStats returns a snapshot of the statistics of every path, sorted by PathID
+func (s *session) PathStats() []PathStats {
+	return s.ConnectionStats().Paths
+}
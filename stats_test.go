@@ -0,0 +1,94 @@
+package quic
+
+import (
+	"context"
+	"math"
+
+	"github.com/lucas-clemente/quic-go/ackhandler"
+	"github.com/lucas-clemente/quic-go/congestion"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Statistics", func() {
+	var sess *session
+
+	newStatsPath := func(pathID protocol.PathID, price float64) *path {
+		rttStats := &congestion.RTTStats{}
+		return &path{
+			pathID:                pathID,
+			sess:                  sess,
+			rttStats:              rttStats,
+			sentPacketHandler:     ackhandler.NewSentPacketHandler(rttStats, nil, nil, nil),
+			receivedPacketHandler: ackhandler.NewReceivedPacketHandler(protocol.VersionWhatever, rttStats),
+			price:                 price,
+		}
+	}
+
+	BeforeEach(func() {
+		sess = &session{
+			paths:         make(map[protocol.PathID]*path),
+			scheduler:     &scheduler{},
+			streamFramer:  newStreamFramer(nil, nil),
+			statsRequests: make(chan chan ConnectionStats),
+		}
+		sess.ctx, sess.ctxCancel = context.WithCancel(context.Background())
+		sess.scheduler.setup(&Config{})
+		sess.paths[3] = newStatsPath(3, 2)
+		sess.paths[1] = newStatsPath(1, 0)
+		sess.paths[3].totalCost = 6
+		sess.paths[3].reinjections = 2
+		sess.scheduler.NotSentPackets = 4
+		sess.scheduler.totalCost = 6
+		sess.scheduler.totalPktWithCost = 3
+		sess.streamFramer.droppedDatagrams = 5
+		sess.scheduler.duplicates.sent = 7
+		sess.scheduler.duplicates.savedDeadlines = 2
+		sess.duplicatesReceived = 3
+	})
+
+	It("collects the statistics of every path", func() {
+		stats := sess.connectionStats()
+		Expect(stats.Paths).To(HaveLen(2))
+		Expect(stats.Paths[0].PathID).To(Equal(PathID(1)))
+		Expect(stats.Paths[1].PathID).To(Equal(PathID(3)))
+		Expect(stats.Paths[1].Price).To(Equal(2.0))
+		Expect(stats.Paths[1].TotalCost).To(Equal(6.0))
+		Expect(stats.Paths[1].Reinjections).To(Equal(uint64(2)))
+		Expect(stats.Paths[1].CongestionWindow).ToNot(BeZero())
+	})
+
+	It("collects the statistics of the connection", func() {
+		stats := sess.connectionStats()
+		Expect(stats.PacketsNotSent).To(Equal(uint64(4)))
+		Expect(stats.TotalCost).To(Equal(6.0))
+		Expect(stats.PacketsWithCost).To(Equal(uint64(3)))
+		Expect(stats.DatagramsDropped).To(Equal(uint64(5)))
+		Expect(stats.PacketsDuplicated).To(Equal(uint64(7)))
+		Expect(stats.DuplicatesSavedDeadline).To(Equal(uint64(2)))
+		Expect(stats.DuplicatesReceived).To(Equal(uint64(3)))
+		Expect(math.IsInf(stats.RemainingBudget, 1)).To(BeTrue())
+	})
+
+	It("calculates the deadline meet ratio", func() {
+		stats := &ConnectionStats{PacketsWithDeadline: 4, PacketsMetDeadline: 3}
+		Expect(stats.DeadlineMeetRatio()).To(Equal(0.75))
+		Expect((&PathStats{}).DeadlineMeetRatio()).To(BeZero())
+	})
+
+	It("asks the run loop for the statistics", func() {
+		go func() {
+			defer GinkgoRecover()
+			c := <-sess.statsRequests
+			c <- sess.connectionStats()
+		}()
+		Expect(sess.PathStats()).To(HaveLen(2))
+	})
+
+	It("returns the statistics after the session was closed", func() {
+		sess.ctxCancel()
+		Expect(sess.ConnectionStats().PacketsNotSent).To(Equal(uint64(4)))
+	})
+})
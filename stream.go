@@ -25,6 +25,8 @@ type stream struct {
 
 	streamID protocol.StreamID
 	onData   func()
+	// onDuplicateData is called for STREAM frames that only contain data received before
+	onDuplicateData func()
 	// onReset is a callback that should send a RST_STREAM
 	onReset func(protocol.StreamID, protocol.ByteCount)
 
@@ -56,6 +58,15 @@ type stream struct {
 	writeChan      chan struct{}
 	writeDeadline  time.Time
 
+	// dataDeadline is the delivery deadline of dataForWriting
+	dataDeadline time.Time
+	// relativeDataDeadline is used to compute the dataDeadline of every Write
+	relativeDataDeadline time.Duration
+
+	// priority and urgent are used by the framer to order the streams, see StreamOrder
+	priority uint8
+	urgent   bool
+
 	flowControlManager flowcontrol.FlowControlManager
 }
 
@@ -69,6 +80,25 @@ func (deadlineError) Timeout() bool   { return true }
 
 var errDeadline net.Error = &deadlineError{}
 
+// A StreamGapError is returned by Read if the peer abandoned stream data because its deadline expired.
+// The abandoned bytes are skipped, the next Read continues after the gap.
+type StreamGapError struct {
+	Offset protocol.ByteCount
+	Length protocol.ByteCount
+}
+
+var _ net.Error = &StreamGapError{}
+
+func (e *StreamGapError) Error() string {
+	return fmt.Sprintf("peer abandoned %d bytes at offset %d", e.Length, e.Offset)
+}
+
+// Temporary returns true, since reading can continue after the gap
+func (e *StreamGapError) Temporary() bool { return true }
+
+// Timeout returns false
+func (e *StreamGapError) Timeout() bool { return false }
+
 // newStream creates a new Stream
 func newStream(StreamID protocol.StreamID,
 	onData func(),
@@ -146,6 +176,14 @@ func (s *stream) Read(p []byte) (int, error) {
 			return bytesRead, err
 		}
 
+		if frame.Gap {
+			// return the data read so far, the gap is reported by the next Read
+			if bytesRead > 0 {
+				return bytesRead, nil
+			}
+			return 0, s.skipGap(frame)
+		}
+
 		m := utils.Min(len(p)-bytesRead, int(frame.DataLen())-s.readPosInFrame)
 
 		if bytesRead > len(p) {
@@ -181,7 +219,34 @@ func (s *stream) Read(p []byte) (int, error) {
 	return bytesRead, nil
 }
 
+// skipGap consumes the rest of a gap frame
+func (s *stream) skipGap(frame *wire.StreamFrame) error {
+	length := frame.DataLen() - protocol.ByteCount(s.readPosInFrame)
+	gapErr := &StreamGapError{Offset: s.readOffset, Length: length}
+	s.readOffset += length
+	if !s.resetRemotely.Get() {
+		s.flowControlManager.AddBytesRead(s.streamID, length)
+	}
+	s.onData() // so that a possible WINDOW_UPDATE is sent
+	s.mutex.Lock()
+	s.frameQueue.Pop()
+	s.mutex.Unlock()
+	return gapErr
+}
+
 func (s *stream) Write(p []byte) (int, error) {
+	s.mutex.Lock()
+	d := s.relativeDataDeadline
+	s.mutex.Unlock()
+	var t time.Time
+	if d != 0 {
+		t = time.Now().Add(d)
+	}
+	return s.WriteWithDeadline(p, t)
+}
+
+// WriteWithDeadline writes data that should be delivered to the peer by t
+func (s *stream) WriteWithDeadline(p []byte, t time.Time) (int, error) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
@@ -197,6 +262,7 @@ func (s *stream) Write(p []byte) (int, error) {
 
 	s.dataForWriting = make([]byte, len(p))
 	copy(s.dataForWriting, p)
+	s.dataDeadline = t
 	s.onData()
 
 	var err error
@@ -241,13 +307,27 @@ func (s *stream) lenOfDataForWriting() protocol.ByteCount {
 	return l
 }
 
+// getDeadlineForWriting returns the delivery deadline of the data not yet sent
+func (s *stream) getDeadlineForWriting() time.Time {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.dataDeadline
+}
+
 func (s *stream) getDataForWriting(maxBytes protocol.ByteCount) []byte {
+	data, _ := s.getDataForWritingWithDeadline(maxBytes)
+	return data
+}
+
+// getDataForWritingWithDeadline returns the data and the delivery deadline it was written with
+func (s *stream) getDataForWritingWithDeadline(maxBytes protocol.ByteCount) ([]byte, time.Time) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
 	if s.err != nil || s.dataForWriting == nil {
-		return nil
+		return nil, time.Time{}
 	}
+	deadline := s.dataDeadline
 
 	var ret []byte
 	if protocol.ByteCount(len(s.dataForWriting)) > maxBytes {
@@ -259,7 +339,7 @@ func (s *stream) getDataForWriting(maxBytes protocol.ByteCount) []byte {
 		s.signalWrite()
 	}
 	s.writeOffset += protocol.ByteCount(len(ret))
-	return ret
+	return ret, deadline
 }
 
 // Close implements io.Closer
@@ -299,13 +379,31 @@ func (s *stream) AddStreamFrame(frame *wire.StreamFrame) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 	err = s.frameQueue.Push(frame)
-	if err != nil && err != errDuplicateStreamData {
+	if err == errDuplicateStreamData {
+		if s.onDuplicateData != nil {
+			s.onDuplicateData()
+		}
+	} else if err != nil {
 		return err
 	}
 	s.signalRead()
 	return nil
 }
 
+// AddStreamGap adds data that the peer abandoned. Read returns a StreamGapError when it reaches the gap.
+func (s *stream) AddStreamGap(frame *wire.StreamGapFrame) error {
+	if frame.ByteLen == 0 {
+		return nil
+	}
+	// The gap only takes up its length in the frame sorter, no data is allocated for it
+	return s.AddStreamFrame(&wire.StreamFrame{
+		StreamID: s.streamID,
+		Offset:   frame.Offset,
+		GapLen:   frame.ByteLen,
+		Gap:      true,
+	})
+}
+
 // signalRead performs a non-blocking send on the readChan
 func (s *stream) signalRead() {
 	select {
@@ -345,6 +443,27 @@ func (s *stream) SetWriteDeadline(t time.Time) error {
 	return nil
 }
 
+// SetDataDeadline sets the relative delivery deadline of future Write calls
+func (s *stream) SetDataDeadline(d time.Duration) {
+	s.mutex.Lock()
+	s.relativeDataDeadline = d
+	s.mutex.Unlock()
+}
+
+// SetPriority sets the priority and the urgency of the stream
+func (s *stream) SetPriority(priority uint8, urgent bool) {
+	s.mutex.Lock()
+	s.priority = priority
+	s.urgent = urgent
+	s.mutex.Unlock()
+}
+
+func (s *stream) getPriority() (uint8, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.priority, s.urgent
+}
+
 func (s *stream) SetDeadline(t time.Time) error {
 	_ = s.SetReadDeadline(t)  // SetReadDeadline never errors
 	_ = s.SetWriteDeadline(t) // SetWriteDeadline never errors
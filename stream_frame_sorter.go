@@ -43,8 +43,7 @@ func (s *streamFrameSorter) Push(frame *wire.StreamFrame) error {
 		if frame.DataLen() <= oldFrame.DataLen() {
 			return errDuplicateStreamData
 		}
-		frame.Data = frame.Data[oldFrame.DataLen():]
-		frame.Offset += oldFrame.DataLen()
+		cutFrameFront(frame, oldFrame.DataLen())
 		wasCut = true
 	}
 
@@ -69,9 +68,8 @@ func (s *streamFrameSorter) Push(frame *wire.StreamFrame) error {
 
 	if start < gap.Value.Start {
 		add := gap.Value.Start - start
-		frame.Offset += add
+		cutFrameFront(frame, add)
 		start += add
-		frame.Data = frame.Data[add:]
 		wasCut = true
 	}
 
@@ -95,9 +93,8 @@ func (s *streamFrameSorter) Push(frame *wire.StreamFrame) error {
 
 	if end > endGap.Value.End {
 		cutLen := end - endGap.Value.End
-		len := frame.DataLen() - cutLen
 		end -= cutLen
-		frame.Data = frame.Data[:len]
+		cutFrameBack(frame, cutLen)
 		wasCut = true
 	}
 
@@ -133,7 +130,7 @@ func (s *streamFrameSorter) Push(frame *wire.StreamFrame) error {
 		return errTooManyGapsInReceivedStreamData
 	}
 
-	if wasCut {
+	if wasCut && !frame.Gap {
 		data := make([]byte, frame.DataLen())
 		copy(data, frame.Data)
 		frame.Data = data
@@ -143,6 +140,26 @@ func (s *streamFrameSorter) Push(frame *wire.StreamFrame) error {
 	return nil
 }
 
+// cutFrameFront removes the first n bytes of a frame.
+// Gap frames carry no data, only their length is reduced.
+func cutFrameFront(frame *wire.StreamFrame, n protocol.ByteCount) {
+	frame.Offset += n
+	if frame.Gap {
+		frame.GapLen -= n
+		return
+	}
+	frame.Data = frame.Data[n:]
+}
+
+// cutFrameBack removes the last n bytes of a frame
+func cutFrameBack(frame *wire.StreamFrame, n protocol.ByteCount) {
+	if frame.Gap {
+		frame.GapLen -= n
+		return
+	}
+	frame.Data = frame.Data[:frame.DataLen()-n]
+}
+
 func (s *streamFrameSorter) Pop() *wire.StreamFrame {
 	frame := s.Head()
 	if frame != nil {
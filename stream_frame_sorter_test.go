@@ -571,6 +571,38 @@ var _ = Describe("StreamFrame sorter", func() {
 				})
 			})
 
+			Context("gap frames", func() {
+				It("cuts the beginning of a gap without allocating data", func() {
+					Expect(s.Push(&wire.StreamFrame{Offset: 0, Data: []byte("foob")})).To(Succeed())
+					Expect(s.Push(&wire.StreamFrame{Offset: 2, GapLen: 8, Gap: true})).To(Succeed())
+					Expect(s.Pop().Data).To(Equal([]byte("foob")))
+					gap := s.Pop()
+					Expect(gap.Gap).To(BeTrue())
+					Expect(gap.Offset).To(Equal(protocol.ByteCount(4)))
+					Expect(gap.DataLen()).To(Equal(protocol.ByteCount(6)))
+					Expect(gap.Data).To(BeNil())
+					checkGaps([]utils.ByteInterval{{Start: 10, End: protocol.MaxByteCount}})
+				})
+
+				It("cuts the end of a gap", func() {
+					Expect(s.Push(&wire.StreamFrame{Offset: 6, Data: []byte("foobar")})).To(Succeed())
+					Expect(s.Push(&wire.StreamFrame{Offset: 0, GapLen: 10, Gap: true})).To(Succeed())
+					gap := s.Pop()
+					Expect(gap.DataLen()).To(Equal(protocol.ByteCount(6)))
+					Expect(gap.Data).To(BeNil())
+					Expect(s.Pop().Data).To(Equal([]byte("foobar")))
+				})
+
+				It("extends a shorter gap", func() {
+					Expect(s.Push(&wire.StreamFrame{Offset: 0, GapLen: 4, Gap: true})).To(Succeed())
+					Expect(s.Push(&wire.StreamFrame{Offset: 0, GapLen: 6, Gap: true})).To(Succeed())
+					Expect(s.Pop().DataLen()).To(Equal(protocol.ByteCount(4)))
+					gap := s.Pop()
+					Expect(gap.Offset).To(Equal(protocol.ByteCount(4)))
+					Expect(gap.DataLen()).To(Equal(protocol.ByteCount(2)))
+				})
+			})
+
 			Context("DoS protection", func() {
 				It("errors when too many gaps are created", func() {
 					for i := 0; i < protocol.MaxStreamFrameSorterGaps; i++ {
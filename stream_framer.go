@@ -19,7 +19,18 @@ type streamFramer struct {
 	blockedFrameQueue    []*wire.BlockedFrame
 	addAddressFrameQueue []*wire.AddAddressFrame
 	closePathFrameQueue  []*wire.ClosePathFrame
+	streamGapFrameQueue  []*wire.StreamGapFrame
+	pathPriorityFrames   []*wire.PathPriorityFrame
 	pathsFrame           *wire.PathsFrame
+	// datagramQueue are the datagrams waiting to be sent, in the order they were queued
+	datagramQueue []*wire.DatagramFrame
+	// droppedDatagrams is the number of datagrams dropped because their deadline expired or the queue was full
+	droppedDatagrams uint64
+
+	// partialReliability abandons stream data whose deadline expired instead of retransmitting it
+	partialReliability bool
+	// streamOrder is the order in which the data of the streams is popped
+	streamOrder StreamOrder
 }
 
 func newStreamFramer(streamsMap *streamsMap, flowControlManager flowcontrol.FlowControlManager) *streamFramer {
@@ -38,6 +49,47 @@ func (f *streamFramer) PopStreamFrames(maxLen protocol.ByteCount) []*wire.Stream
 	return append(fs, f.maybePopNormalFrames(maxLen-currentLen)...)
 }
 
+// AddDatagramForTransmission queues a datagram. If the queue is full, the oldest datagram is dropped.
+func (f *streamFramer) AddDatagramForTransmission(frame *wire.DatagramFrame) {
+	if len(f.datagramQueue) >= protocol.MaxDatagramQueueLen {
+		f.datagramQueue = f.datagramQueue[1:]
+		f.droppedDatagrams++
+	}
+	f.datagramQueue = append(f.datagramQueue, frame)
+}
+
+// PopDatagramFrames pops the datagrams that fit into maxLen.
+// Datagrams whose deadline expired are dropped, since they would arrive late anyway.
+func (f *streamFramer) PopDatagramFrames(maxLen protocol.ByteCount) (res []*wire.DatagramFrame, currentLen protocol.ByteCount) {
+	now := time.Now()
+	for len(f.datagramQueue) > 0 {
+		frame := f.datagramQueue[0]
+		if !frame.Deadline.IsZero() && frame.Deadline.Before(now) {
+			utils.Debugf("Dropping datagram of %d bytes, its deadline expired", len(frame.Data))
+			f.datagramQueue = f.datagramQueue[1:]
+			f.droppedDatagrams++
+			continue
+		}
+		frameLen, _ := frame.MinLength(protocol.VersionWhatever) // can never error
+		if currentLen+frameLen > maxLen {
+			break
+		}
+		f.datagramQueue = f.datagramQueue[1:]
+		res = append(res, frame)
+		currentLen += frameLen
+	}
+	return
+}
+
+func (f *streamFramer) PopStreamGapFrame() *wire.StreamGapFrame {
+	if len(f.streamGapFrameQueue) == 0 {
+		return nil
+	}
+	frame := f.streamGapFrameQueue[0]
+	f.streamGapFrameQueue = f.streamGapFrameQueue[1:]
+	return frame
+}
+
 func (f *streamFramer) PopBlockedFrame() *wire.BlockedFrame {
 	if len(f.blockedFrameQueue) == 0 {
 		return nil
@@ -63,17 +115,19 @@ func (f *streamFramer) PopAddAddressFrame() *wire.AddAddressFrame {
 func (f *streamFramer) AddPathsFrameForTransmission(s *session) {
 	s.pathsLock.RLock()
 	defer s.pathsLock.RUnlock()
-	paths := make([]protocol.PathID, len(s.paths))
-	remoteRTTs := make([]time.Duration, len(s.paths))
-	i := 0
-	for pathID := range s.paths {
-		paths[i] = pathID
-		if s.paths[pathID].potentiallyFailed.Get() {
-			remoteRTTs[i] = time.Hour
+	paths := make([]protocol.PathID, 0, len(s.paths))
+	remoteRTTs := make([]time.Duration, 0, len(s.paths))
+	for pathID, pth := range s.paths {
+		// The peer learns about closed paths from the CLOSE_PATH frame
+		if s.closedPaths[pathID] {
+			continue
+		}
+		paths = append(paths, pathID)
+		if pth.potentiallyFailed.Get() {
+			remoteRTTs = append(remoteRTTs, time.Hour)
 		} else {
-			remoteRTTs[i] = s.paths[pathID].rttStats.SmoothedRTT()
+			remoteRTTs = append(remoteRTTs, pth.rttStats.SmoothedRTT())
 		}
-		i++
 	}
 	f.pathsFrame = &wire.PathsFrame{MaxNumPaths: 255, NumPaths: uint8(len(paths)), PathIDs: paths, RemoteRTTs: remoteRTTs}
 }
@@ -100,6 +154,19 @@ func (f *streamFramer) PopClosePathFrame() *wire.ClosePathFrame {
 	return frame
 }
 
+func (f *streamFramer) AddPathPriorityFrameForTransmission(pathID protocol.PathID, priority protocol.PathPriority) {
+	f.pathPriorityFrames = append(f.pathPriorityFrames, &wire.PathPriorityFrame{PathID: pathID, Priority: priority})
+}
+
+func (f *streamFramer) PopPathPriorityFrame() *wire.PathPriorityFrame {
+	if len(f.pathPriorityFrames) == 0 {
+		return nil
+	}
+	frame := f.pathPriorityFrames[0]
+	f.pathPriorityFrames = f.pathPriorityFrames[1:]
+	return frame
+}
+
 func (f *streamFramer) HasFramesForRetransmission() bool {
 	return len(f.retransmissionQueue) > 0
 }
@@ -126,9 +193,67 @@ func (f *streamFramer) PopCryptoStreamFrame(maxLen protocol.ByteCount) *wire.Str
 	return frame
 }
 
+// hasExpired says if the data of a frame should be abandoned instead of retransmitted.
+// The FIN is always retransmitted.
+func (f *streamFramer) hasExpired(frame *wire.StreamFrame, now time.Time) bool {
+	return f.partialReliability && !frame.FinBit && frame.DataLen() > 0 && !frame.Deadline.IsZero() && frame.Deadline.Before(now)
+}
+
+// abandonFrame tells the peer that the data of the frame won't be retransmitted
+func (f *streamFramer) abandonFrame(frame *wire.StreamFrame) {
+	utils.Debugf("Abandoning expired data of stream %d at offset 0x%x, length 0x%x", frame.StreamID, frame.Offset, frame.DataLen())
+	if n := len(f.streamGapFrameQueue); n > 0 {
+		last := f.streamGapFrameQueue[n-1]
+		if last.StreamID == frame.StreamID && last.Offset+last.ByteLen == frame.Offset {
+			last.ByteLen += frame.DataLen()
+			return
+		}
+	}
+	f.streamGapFrameQueue = append(f.streamGapFrameQueue, &wire.StreamGapFrame{
+		StreamID: frame.StreamID,
+		Offset:   frame.Offset,
+		ByteLen:  frame.DataLen(),
+	})
+}
+
+// AbandonExpiredData drops the queued datagrams whose deadline expired, and abandons the stream retransmissions that expired, see hasExpired.
+// This keeps data that can't arrive in time anymore out of a scheduling batch.
+// It returns the number of datagrams and stream retransmissions removed from the queues.
+func (f *streamFramer) AbandonExpiredData(now time.Time) (removed int) {
+	datagrams := f.datagramQueue[:0]
+	for _, frame := range f.datagramQueue {
+		if !frame.Deadline.IsZero() && frame.Deadline.Before(now) {
+			utils.Debugf("Dropping datagram of %d bytes, its deadline expired", len(frame.Data))
+			f.droppedDatagrams++
+			removed++
+			continue
+		}
+		datagrams = append(datagrams, frame)
+	}
+	f.datagramQueue = datagrams
+
+	retransmissions := f.retransmissionQueue[:0]
+	for _, frame := range f.retransmissionQueue {
+		if f.hasExpired(frame, now) {
+			f.abandonFrame(frame)
+			removed++
+			continue
+		}
+		retransmissions = append(retransmissions, frame)
+	}
+	f.retransmissionQueue = retransmissions
+	return
+}
+
 func (f *streamFramer) maybePopFramesForRetransmission(maxLen protocol.ByteCount) (res []*wire.StreamFrame, currentLen protocol.ByteCount) {
+	now := time.Now()
 	for len(f.retransmissionQueue) > 0 {
 		frame := f.retransmissionQueue[0]
+		if f.hasExpired(frame, now) {
+			f.retransmissionQueue = f.retransmissionQueue[1:]
+			f.abandonFrame(frame)
+			continue
+		}
 		frame.DataLenPresent = true
 
 		frameHeaderLen, _ := frame.MinLength(protocol.VersionWhatever) // can never error
@@ -195,7 +320,7 @@ func (f *streamFramer) maybePopNormalFrames(maxBytes protocol.ByteCount) (res []
 		if lenStreamData != 0 {
 			// Only getDataForWriting() if we didn't have data earlier, so that we
 			// don't send without FC approval (if a Write() raced).
-			data = s.getDataForWriting(maxLen)
+			data, frame.Deadline = s.getDataForWritingWithDeadline(maxLen)
 		}
 
 		// This is unlikely, but check it nonetheless, the scheduler might have jumped in. Seems to happen in ~20% of cases in the tests.
@@ -232,11 +357,142 @@ func (f *streamFramer) maybePopNormalFrames(maxBytes protocol.ByteCount) (res []
 		return true, nil
 	}
 
-	f.streamsMap.RoundRobinIterate(fn)
+	if less := f.streamOrder.less(); less != nil {
+		f.streamsMap.SortedIterate(less, fn)
+	} else {
+		f.streamsMap.RoundRobinIterate(fn)
+	}
 
 	return
 }
 
+// A queuedPacket is a packet worth of data queued in the stream framer
+type queuedPacket struct {
+	// Deadline is the earliest deadline of the data in the packet, zero if none of it has a deadline
+	Deadline time.Time
+	Length   protocol.ByteCount
+}
+
+// iterateQueuedData calls fn for every piece of queued data, in the order it would be popped, until fn returns false.
+// Datagrams come first, then stream retransmissions, followed by the data of the open streams,
+// split into pieces of at most MaxPacketSize.
+// The streams are visited like maybePopNormalFrames does: with a StreamOrder, a stream's data comes before the next stream's,
+// otherwise the streams take turns, one piece each.
+// Only the data that flow control allows to be sent is considered.
+// Data without a deadline has a zero deadline.
+func (f *streamFramer) iterateQueuedData(fn func(deadline time.Time, length protocol.ByteCount) bool) {
+	for _, frame := range f.datagramQueue {
+		length, _ := frame.MinLength(protocol.VersionWhatever) // can never error
+		if !fn(frame.Deadline, length) {
+			return
+		}
+	}
+	for _, frame := range f.retransmissionQueue {
+		length, _ := frame.MinLength(protocol.VersionWhatever) // can never error
+		if !fn(frame.Deadline, length+frame.DataLen()) {
+			return
+		}
+	}
+
+	type queuedStreamData struct {
+		deadline time.Time
+		length   protocol.ByteCount
+	}
+	var streams []queuedStreamData
+	var connectionWindow protocol.ByteCount
+	var hasConnectionWindow bool
+	streamFn := func(s *stream) (bool, error) {
+		if s == nil || s.streamID == 1 /* crypto stream is handled separately */ {
+			return true, nil
+		}
+		lenStreamData := s.lenOfDataForWriting()
+		if lenStreamData == 0 {
+			return true, nil
+		}
+		sendWindowSize, _ := f.flowControlManager.SendWindowSize(s.streamID)
+		lenStreamData = utils.MinByteCount(lenStreamData, sendWindowSize)
+		// the header stream doesn't contribute to connection level flow control, see session.newStream
+		if s.streamID != 3 {
+			if !hasConnectionWindow {
+				connectionWindow = f.flowControlManager.RemainingConnectionWindowSize()
+				hasConnectionWindow = true
+			}
+			lenStreamData = utils.MinByteCount(lenStreamData, connectionWindow)
+			connectionWindow -= lenStreamData
+		}
+		if lenStreamData > 0 {
+			streams = append(streams, queuedStreamData{deadline: s.getDeadlineForWriting(), length: lenStreamData})
+		}
+		return true, nil
+	}
+	less := f.streamOrder.less()
+	f.streamsMap.PeekIterate(less, streamFn)
+
+	for {
+		var found bool
+		for i := range streams {
+			for streams[i].length > 0 {
+				length := utils.MinByteCount(streams[i].length, protocol.MaxPacketSize)
+				if !fn(streams[i].deadline, length) {
+					return
+				}
+				streams[i].length -= length
+				found = true
+				if less == nil {
+					break
+				}
+			}
+		}
+		if !found {
+			return
+		}
+	}
+}
+
+// PeekDeadlines returns the delivery deadlines of the next n pieces of queued data, see iterateQueuedData.
+// Every datagram and stream retransmission is counted as one packet.
+// Fewer than n deadlines are returned if not enough data is queued.
+func (f *streamFramer) PeekDeadlines(n int) []time.Time {
+	var deadlines []time.Time
+	if n <= 0 {
+		return deadlines
+	}
+	f.iterateQueuedData(func(deadline time.Time, _ protocol.ByteCount) bool {
+		deadlines = append(deadlines, deadline)
+		return len(deadlines) < n
+	})
+	return deadlines
+}
+
+// PeekPackets returns the next n packets the queued data fills, in the order they would be sent.
+// Small pieces of data share a packet, which then has the earliest of their deadlines.
+// Fewer than n packets are returned if not enough data is queued.
+func (f *streamFramer) PeekPackets(n int) []queuedPacket {
+	var packets []queuedPacket
+	if n <= 0 {
+		return packets
+	}
+	var cur queuedPacket
+	f.iterateQueuedData(func(deadline time.Time, length protocol.ByteCount) bool {
+		if cur.Length > 0 && cur.Length+length > protocol.MaxPacketSize {
+			packets = append(packets, cur)
+			cur = queuedPacket{}
+			if len(packets) >= n {
+				return false
+			}
+		}
+		cur.Length += length
+		if cur.Length == length || earlierDeadline(deadline, cur.Deadline) {
+			cur.Deadline = deadline
+		}
+		return true
+	})
+	if cur.Length > 0 {
+		packets = append(packets, cur)
+	}
+	return packets
+}
+
 // maybeSplitOffFrame removes the first n bytes and returns them as a separate frame. If n >= len(frame), nil is returned and nothing is modified.
 func maybeSplitOffFrame(frame *wire.StreamFrame, n protocol.ByteCount) *wire.StreamFrame {
 	if n >= frame.DataLen() {
@@ -254,5 +510,6 @@ func maybeSplitOffFrame(frame *wire.StreamFrame, n protocol.ByteCount) *wire.Str
 		Offset:         frame.Offset,
 		Data:           frame.Data[:n],
 		DataLenPresent: frame.DataLenPresent,
+		Deadline:       frame.Deadline,
 	}
 }
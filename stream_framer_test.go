@@ -2,7 +2,9 @@ package quic
 
 import (
 	"bytes"
+	"time"
 
+	"github.com/golang/mock/gomock"
 	"github.com/lucas-clemente/quic-go/internal/mocks/mocks_fc"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/wire"
@@ -98,6 +100,18 @@ var _ = Describe("Stream Framer", func() {
 			Expect(framer.PopStreamFrames(1000)).To(BeEmpty())
 		})
 
+		It("sets the deadline of normal frames", func() {
+			mockFcm.EXPECT().SendWindowSize(id1).Return(protocol.MaxByteCount, nil)
+			mockFcm.EXPECT().AddBytesSent(id1, protocol.ByteCount(6))
+			mockFcm.EXPECT().RemainingConnectionWindowSize().Return(protocol.MaxByteCount)
+			deadline := time.Now().Add(20 * time.Millisecond)
+			stream1.dataForWriting = []byte("foobar")
+			stream1.dataDeadline = deadline
+			fs := framer.PopStreamFrames(1000)
+			Expect(fs).To(HaveLen(1))
+			Expect(fs[0].Deadline).To(Equal(deadline))
+		})
+
 		It("returns multiple normal frames", func() {
 			mockFcm.EXPECT().SendWindowSize(id1).Return(protocol.MaxByteCount, nil)
 			mockFcm.EXPECT().AddBytesSent(id1, protocol.ByteCount(6))
@@ -201,6 +215,19 @@ var _ = Describe("Stream Framer", func() {
 				Expect(f.FinBit).To(BeTrue())
 			})
 
+			It("keeps the deadline when splitting", func() {
+				deadline := time.Now().Add(20 * time.Millisecond)
+				f := &wire.StreamFrame{
+					StreamID: 1,
+					Data:     []byte("foobar"),
+					Deadline: deadline,
+				}
+				previous := maybeSplitOffFrame(f, 3)
+				Expect(previous).ToNot(BeNil())
+				Expect(previous.Deadline).To(Equal(deadline))
+				Expect(f.Deadline).To(Equal(deadline))
+			})
+
 			It("splits a frame", func() {
 				mockFcm.EXPECT().AddBytesRetrans(retransmittedFrame2.StreamID, protocol.ByteCount(2))
 				framer.AddFrameForRetransmission(retransmittedFrame2)
@@ -283,6 +310,138 @@ var _ = Describe("Stream Framer", func() {
 			})
 		})
 
+		Context("peeking deadlines", func() {
+			// allowSending lets every stream send sendWindowSize bytes, and all of them together connectionWindowSize bytes
+			allowSending := func(sendWindowSize, connectionWindowSize protocol.ByteCount) {
+				mockFcm.EXPECT().SendWindowSize(gomock.Any()).Return(sendWindowSize, nil).AnyTimes()
+				mockFcm.EXPECT().RemainingConnectionWindowSize().Return(connectionWindowSize).AnyTimes()
+			}
+
+			It("returns nothing for an empty framer", func() {
+				Expect(framer.PeekDeadlines(6)).To(BeEmpty())
+			})
+
+			It("returns the deadlines of retransmissions before normal data", func() {
+				d1 := time.Now().Add(10 * time.Millisecond)
+				d2 := time.Now().Add(40 * time.Millisecond)
+				retransmittedFrame1.Deadline = d1
+				framer.AddFrameForRetransmission(retransmittedFrame1)
+				allowSending(protocol.MaxByteCount, protocol.MaxByteCount)
+				stream1.dataForWriting = []byte("foobar")
+				stream1.dataDeadline = d2
+				Expect(framer.PeekDeadlines(6)).To(Equal([]time.Time{d1, d2}))
+			})
+
+			It("returns one deadline per packet of stream data", func() {
+				deadline := time.Now().Add(10 * time.Millisecond)
+				allowSending(protocol.MaxByteCount, protocol.MaxByteCount)
+				stream1.dataForWriting = make([]byte, 2*protocol.MaxPacketSize+1)
+				stream1.dataDeadline = deadline
+				Expect(framer.PeekDeadlines(6)).To(Equal([]time.Time{deadline, deadline, deadline}))
+				Expect(framer.PeekDeadlines(2)).To(HaveLen(2))
+			})
+
+			It("doesn't pop any data", func() {
+				allowSending(protocol.MaxByteCount, protocol.MaxByteCount)
+				stream1.dataForWriting = []byte("foobar")
+				framer.PeekDeadlines(6)
+				Expect(stream1.lenOfDataForWriting()).To(Equal(protocol.ByteCount(6)))
+			})
+
+			It("packs small pieces of data into one packet, with the earliest deadline", func() {
+				d1 := time.Now().Add(10 * time.Millisecond)
+				d2 := time.Now().Add(40 * time.Millisecond)
+				retransmittedFrame1.Deadline = d2
+				framer.AddFrameForRetransmission(retransmittedFrame1)
+				framer.AddDatagramForTransmission(&wire.DatagramFrame{Data: []byte("foo")})
+				framer.AddDatagramForTransmission(&wire.DatagramFrame{Data: []byte("bar"), Deadline: d1})
+				packets := framer.PeekPackets(6)
+				Expect(packets).To(HaveLen(1))
+				Expect(packets[0].Deadline).To(Equal(d1))
+				Expect(packets[0].Length).To(BeNumerically(">", 6+retransmittedFrame1.DataLen()))
+			})
+
+			It("starts a new packet if the data doesn't fit", func() {
+				deadline := time.Now().Add(time.Second)
+				framer.AddDatagramForTransmission(&wire.DatagramFrame{Data: make([]byte, 1000)})
+				allowSending(protocol.MaxByteCount, protocol.MaxByteCount)
+				stream1.dataForWriting = make([]byte, 2*protocol.MaxPacketSize+10)
+				stream1.dataDeadline = deadline
+				packets := framer.PeekPackets(6)
+				Expect(packets).To(Equal([]queuedPacket{
+					{Length: 1003},
+					{Deadline: deadline, Length: protocol.MaxPacketSize},
+					{Deadline: deadline, Length: protocol.MaxPacketSize},
+					{Deadline: deadline, Length: 10},
+				}))
+				Expect(framer.PeekPackets(2)).To(Equal(packets[:2]))
+				Expect(framer.PeekPackets(0)).To(BeEmpty())
+			})
+
+			It("returns the deadlines of datagrams first", func() {
+				d1 := time.Now().Add(10 * time.Millisecond)
+				d2 := time.Now().Add(40 * time.Millisecond)
+				retransmittedFrame1.Deadline = d2
+				framer.AddFrameForRetransmission(retransmittedFrame1)
+				framer.AddDatagramForTransmission(&wire.DatagramFrame{Data: []byte("foo"), Deadline: d1})
+				framer.AddDatagramForTransmission(&wire.DatagramFrame{Data: []byte("bar")})
+				Expect(framer.PeekDeadlines(6)).To(Equal([]time.Time{d1, {}, d2}))
+			})
+
+			It("lets the streams take turns, like popping them does", func() {
+				d1 := time.Now().Add(10 * time.Millisecond)
+				d2 := time.Now().Add(40 * time.Millisecond)
+				allowSending(protocol.MaxByteCount, protocol.MaxByteCount)
+				stream1.dataForWriting = make([]byte, 2*protocol.MaxPacketSize)
+				stream1.dataDeadline = d1
+				stream2.dataForWriting = make([]byte, 2*protocol.MaxPacketSize)
+				stream2.dataDeadline = d2
+				streamsMap.roundRobinIndex = 1 // stream 2
+				Expect(framer.PeekDeadlines(6)).To(Equal([]time.Time{d2, d1, d2, d1}))
+				Expect(streamsMap.roundRobinIndex).To(Equal(uint32(1)))
+			})
+
+			It("returns the data of a stream before the next one with a StreamOrder", func() {
+				d1 := time.Now().Add(10 * time.Millisecond)
+				d2 := time.Now().Add(40 * time.Millisecond)
+				framer.streamOrder = StreamOrderEDF
+				allowSending(protocol.MaxByteCount, protocol.MaxByteCount)
+				stream1.dataForWriting = make([]byte, 2*protocol.MaxPacketSize)
+				stream1.dataDeadline = d2
+				stream2.dataForWriting = make([]byte, 2*protocol.MaxPacketSize)
+				stream2.dataDeadline = d1
+				Expect(framer.PeekDeadlines(6)).To(Equal([]time.Time{d1, d1, d2, d2}))
+			})
+
+			Context("flow control", func() {
+				BeforeEach(func() {
+					stream1.dataForWriting = make([]byte, 2*protocol.MaxPacketSize)
+					stream2.dataForWriting = make([]byte, 2*protocol.MaxPacketSize)
+				})
+
+				It("only counts the data a stream is allowed to send", func() {
+					allowSending(10, protocol.MaxByteCount)
+					Expect(framer.PeekPackets(6)).To(Equal([]queuedPacket{{Length: 20}}))
+				})
+
+				It("only counts the data the connection is allowed to send", func() {
+					allowSending(protocol.MaxByteCount, protocol.MaxPacketSize+10)
+					Expect(framer.PeekPackets(6)).To(Equal([]queuedPacket{
+						{Length: protocol.MaxPacketSize},
+						{Length: 10},
+					}))
+				})
+
+				It("doesn't count the header stream against the connection window", func() {
+					stream2.dataForWriting = nil
+					headerStream := &stream{streamID: 3, dataForWriting: make([]byte, 10)}
+					streamsMap.putStream(headerStream)
+					allowSending(protocol.MaxByteCount, 5)
+					Expect(framer.PeekPackets(6)).To(Equal([]queuedPacket{{Length: 15}}))
+				})
+			})
+		})
+
 		Context("sending FINs", func() {
 			It("sends FINs when streams are closed", func() {
 				mockFcm.EXPECT().AddBytesSent(id1, protocol.ByteCount(0))
@@ -466,4 +625,158 @@ var _ = Describe("Stream Framer", func() {
 			Expect(framer.PopBlockedFrame()).To(BeNil())
 		})
 	})
+
+	Context("stream order", func() {
+		BeforeEach(func() {
+			mockFcm.EXPECT().SendWindowSize(gomock.Any()).Return(protocol.MaxByteCount, nil).AnyTimes()
+			mockFcm.EXPECT().AddBytesSent(gomock.Any(), gomock.Any()).AnyTimes()
+			mockFcm.EXPECT().RemainingConnectionWindowSize().Return(protocol.MaxByteCount).AnyTimes()
+			stream1.dataForWriting = []byte("foobar")
+			stream1.dataDeadline = time.Now().Add(time.Second)
+			stream2.dataForWriting = []byte("foobaz")
+			stream2.dataDeadline = time.Now().Add(time.Millisecond)
+		})
+
+		It("pops the streams round-robin by default", func() {
+			stream2.priority = 1
+			fs := framer.PopStreamFrames(1000)
+			Expect(fs).To(HaveLen(2))
+			Expect(fs[0].StreamID).To(Equal(id1))
+		})
+
+		It("pops the data with the earliest deadline first", func() {
+			framer.streamOrder = StreamOrderEDF
+			fs := framer.PopStreamFrames(1000)
+			Expect(fs).To(HaveLen(2))
+			Expect(fs[0].StreamID).To(Equal(id2))
+			Expect(fs[1].StreamID).To(Equal(id1))
+		})
+
+		It("pops data without a deadline last", func() {
+			framer.streamOrder = StreamOrderEDF
+			stream2.dataDeadline = time.Time{}
+			fs := framer.PopStreamFrames(1000)
+			Expect(fs[0].StreamID).To(Equal(id1))
+		})
+
+		It("pops the streams with the highest priority first", func() {
+			framer.streamOrder = StreamOrderPriority
+			stream1.priority = 1
+			fs := framer.PopStreamFrames(1000)
+			Expect(fs[0].StreamID).To(Equal(id1))
+		})
+
+		It("pops urgent streams first", func() {
+			framer.streamOrder = StreamOrderEDF
+			stream1.urgent = true
+			fs := framer.PopStreamFrames(1000)
+			Expect(fs[0].StreamID).To(Equal(id1))
+		})
+
+		It("peeks the deadlines in the same order", func() {
+			framer.streamOrder = StreamOrderEDF
+			Expect(framer.PeekDeadlines(2)).To(Equal([]time.Time{stream2.dataDeadline, stream1.dataDeadline}))
+		})
+	})
+
+	Context("datagrams", func() {
+		It("pops the datagrams that fit", func() {
+			d1 := &wire.DatagramFrame{Data: make([]byte, 100)}
+			d2 := &wire.DatagramFrame{Data: make([]byte, 100)}
+			framer.AddDatagramForTransmission(d1)
+			framer.AddDatagramForTransmission(d2)
+			fs, length := framer.PopDatagramFrames(150)
+			Expect(fs).To(Equal([]*wire.DatagramFrame{d1}))
+			Expect(length).To(Equal(protocol.ByteCount(103)))
+			fs, _ = framer.PopDatagramFrames(150)
+			Expect(fs).To(Equal([]*wire.DatagramFrame{d2}))
+			fs, length = framer.PopDatagramFrames(150)
+			Expect(fs).To(BeEmpty())
+			Expect(length).To(BeZero())
+		})
+
+		It("drops datagrams whose deadline expired", func() {
+			framer.AddDatagramForTransmission(&wire.DatagramFrame{Data: []byte("late"), Deadline: time.Now().Add(-time.Millisecond)})
+			d := &wire.DatagramFrame{Data: []byte("in time"), Deadline: time.Now().Add(time.Hour)}
+			framer.AddDatagramForTransmission(d)
+			fs, _ := framer.PopDatagramFrames(1000)
+			Expect(fs).To(Equal([]*wire.DatagramFrame{d}))
+			Expect(framer.droppedDatagrams).To(BeEquivalentTo(1))
+		})
+
+		It("drops the oldest datagram if the queue is full", func() {
+			for i := 0; i <= protocol.MaxDatagramQueueLen; i++ {
+				framer.AddDatagramForTransmission(&wire.DatagramFrame{Data: []byte{byte(i)}})
+			}
+			Expect(framer.datagramQueue).To(HaveLen(protocol.MaxDatagramQueueLen))
+			Expect(framer.datagramQueue[0].Data).To(Equal([]byte{1}))
+			Expect(framer.droppedDatagrams).To(BeEquivalentTo(1))
+		})
+	})
+
+	Context("partial reliability", func() {
+		BeforeEach(func() {
+			framer.partialReliability = true
+			retransmittedFrame1.Deadline = time.Now().Add(-time.Second)
+		})
+
+		It("abandons expired retransmissions and queues a STREAM_GAP frame", func() {
+			retransmittedFrame1.Offset = 0x10
+			framer.AddFrameForRetransmission(retransmittedFrame1)
+			Expect(framer.PopStreamFrames(1000)).To(BeEmpty())
+			Expect(framer.PopStreamGapFrame()).To(Equal(&wire.StreamGapFrame{StreamID: 5, Offset: 0x10, ByteLen: 2}))
+			Expect(framer.PopStreamGapFrame()).To(BeNil())
+		})
+
+		It("merges contiguous gaps of a stream", func() {
+			frame := &wire.StreamFrame{
+				StreamID: 5,
+				Offset:   2,
+				Data:     []byte{0xbe, 0xef},
+				Deadline: retransmittedFrame1.Deadline,
+			}
+			framer.AddFrameForRetransmission(retransmittedFrame1)
+			framer.AddFrameForRetransmission(frame)
+			Expect(framer.PopStreamFrames(1000)).To(BeEmpty())
+			Expect(framer.PopStreamGapFrame()).To(Equal(&wire.StreamGapFrame{StreamID: 5, Offset: 0, ByteLen: 4}))
+			Expect(framer.PopStreamGapFrame()).To(BeNil())
+		})
+
+		It("retransmits data that didn't expire", func() {
+			retransmittedFrame1.Deadline = time.Now().Add(time.Hour)
+			mockFcm.EXPECT().AddBytesRetrans(retransmittedFrame1.StreamID, retransmittedFrame1.DataLen())
+			framer.AddFrameForRetransmission(retransmittedFrame1)
+			Expect(framer.PopStreamFrames(1000)).To(Equal([]*wire.StreamFrame{retransmittedFrame1}))
+			Expect(framer.PopStreamGapFrame()).To(BeNil())
+		})
+
+		It("retransmits FINs", func() {
+			retransmittedFrame1.FinBit = true
+			mockFcm.EXPECT().AddBytesRetrans(retransmittedFrame1.StreamID, retransmittedFrame1.DataLen())
+			framer.AddFrameForRetransmission(retransmittedFrame1)
+			Expect(framer.PopStreamFrames(1000)).To(HaveLen(1))
+			Expect(framer.PopStreamGapFrame()).To(BeNil())
+		})
+
+		It("abandons the expired data before it is scheduled", func() {
+			frame := &wire.StreamFrame{StreamID: 5, Offset: 2, Data: []byte{0xbe, 0xef}, Deadline: time.Now().Add(time.Hour)}
+			framer.AddFrameForRetransmission(retransmittedFrame1)
+			framer.AddFrameForRetransmission(frame)
+			framer.AddDatagramForTransmission(&wire.DatagramFrame{Data: []byte("late"), Deadline: time.Now().Add(-time.Millisecond)})
+			Expect(framer.AbandonExpiredData(time.Now())).To(Equal(2))
+			Expect(framer.retransmissionQueue).To(Equal([]*wire.StreamFrame{frame}))
+			Expect(framer.datagramQueue).To(BeEmpty())
+			Expect(framer.droppedDatagrams).To(BeEquivalentTo(1))
+			Expect(framer.PopStreamGapFrame()).To(Equal(&wire.StreamGapFrame{StreamID: 5, Offset: 0, ByteLen: 2}))
+		})
+
+		It("retransmits expired data if disabled", func() {
+			framer.partialReliability = false
+			mockFcm.EXPECT().AddBytesRetrans(retransmittedFrame1.StreamID, retransmittedFrame1.DataLen())
+			framer.AddFrameForRetransmission(retransmittedFrame1)
+			Expect(framer.AbandonExpiredData(time.Now())).To(BeZero())
+			Expect(framer.PopStreamFrames(1000)).To(HaveLen(1))
+			Expect(framer.PopStreamGapFrame()).To(BeNil())
+		})
+	})
 })
@@ -0,0 +1,73 @@
+package quic
+
+import (
+	"sort"
+	"time"
+)
+
+// A StreamOrder is the order in which the stream framer packs the data of the streams.
+type StreamOrder int
+
+const (
+	// StreamOrderRoundRobin serves the streams round-robin. Priorities are ignored.
+	StreamOrderRoundRobin StreamOrder = iota
+	// StreamOrderEDF serves the urgent streams first, then the stream whose data has the earliest deadline.
+	// Data without a deadline is sent last. Ties are broken by the stream priority.
+	StreamOrderEDF
+	// StreamOrderPriority serves the urgent streams first, then the streams with the highest priority.
+	// Ties are broken by the deadline of the data.
+	StreamOrderPriority
+)
+
+// a streamLess reports if the data of stream a should be sent before the data of stream b
+type streamLess func(a, b *stream) bool
+
+// earlierDeadline reports if a is before b, a zero deadline being the latest
+func earlierDeadline(a, b time.Time) bool {
+	if a.IsZero() {
+		return false
+	}
+	return b.IsZero() || a.Before(b)
+}
+
+func edfStreamLess(a, b *stream) bool {
+	pa, ua := a.getPriority()
+	pb, ub := b.getPriority()
+	if ua != ub {
+		return ua
+	}
+	da, db := a.getDeadlineForWriting(), b.getDeadlineForWriting()
+	if !da.Equal(db) {
+		return earlierDeadline(da, db)
+	}
+	return pa > pb
+}
+
+func priorityStreamLess(a, b *stream) bool {
+	pa, ua := a.getPriority()
+	pb, ub := b.getPriority()
+	if ua != ub {
+		return ua
+	}
+	if pa != pb {
+		return pa > pb
+	}
+	return earlierDeadline(a.getDeadlineForWriting(), b.getDeadlineForWriting())
+}
+
+// less returns the streamLess of the order, or nil for StreamOrderRoundRobin
+func (o StreamOrder) less() streamLess {
+	switch o {
+	case StreamOrderEDF:
+		return edfStreamLess
+	case StreamOrderPriority:
+		return priorityStreamLess
+	default:
+		return nil
+	}
+}
+
+// sortStreams sorts the streams stably, so that streams that rank equally keep their order
+func sortStreams(streams []*stream, less streamLess) {
+	sort.SliceStable(streams, func(i, j int) bool { return less(streams[i], streams[j]) })
+}
@@ -3,6 +3,7 @@ package quic
 import (
 	"errors"
 	"io"
+	"net"
 	"runtime"
 	"strconv"
 	"time"
@@ -159,6 +160,18 @@ var _ = Describe("Stream", func() {
 			Expect(b).To(Equal([]byte{0xDE, 0xAD, 0xBE, 0xEF}))
 		})
 
+		It("counts duplicate StreamFrames", func() {
+			var duplicates int
+			str.onDuplicateData = func() { duplicates++ }
+			mockFcm.EXPECT().UpdateHighestReceived(streamID, protocol.ByteCount(2)).Times(2)
+			frame := wire.StreamFrame{Data: []byte{0xDE, 0xAD}}
+			Expect(str.AddStreamFrame(&frame)).To(Succeed())
+			Expect(duplicates).To(BeZero())
+			duplicate := wire.StreamFrame{Data: []byte{0xDE, 0xAD}}
+			Expect(str.AddStreamFrame(&duplicate)).To(Succeed())
+			Expect(duplicates).To(Equal(1))
+		})
+
 		It("waits until data is available", func() {
 			mockFcm.EXPECT().UpdateHighestReceived(streamID, protocol.ByteCount(2))
 			mockFcm.EXPECT().AddBytesRead(streamID, protocol.ByteCount(2))
@@ -344,6 +357,46 @@ var _ = Describe("Stream", func() {
 			})
 		})
 
+		Context("gaps", func() {
+			It("returns a gap error and continues reading after the gap", func() {
+				mockFcm.EXPECT().UpdateHighestReceived(streamID, protocol.ByteCount(2))
+				mockFcm.EXPECT().UpdateHighestReceived(streamID, protocol.ByteCount(6))
+				mockFcm.EXPECT().UpdateHighestReceived(streamID, protocol.ByteCount(8))
+				mockFcm.EXPECT().AddBytesRead(streamID, protocol.ByteCount(2)).Times(2)
+				mockFcm.EXPECT().AddBytesRead(streamID, protocol.ByteCount(4))
+				err := str.AddStreamFrame(&wire.StreamFrame{Offset: 0, Data: []byte("fo")})
+				Expect(err).ToNot(HaveOccurred())
+				err = str.AddStreamGap(&wire.StreamGapFrame{StreamID: streamID, Offset: 2, ByteLen: 4})
+				Expect(err).ToNot(HaveOccurred())
+				err = str.AddStreamFrame(&wire.StreamFrame{Offset: 6, Data: []byte("ar")})
+				Expect(err).ToNot(HaveOccurred())
+				b := make([]byte, 8)
+				n, err := strWithTimeout.Read(b)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(b[:n]).To(Equal([]byte("fo")))
+				n, err = strWithTimeout.Read(b)
+				Expect(err).To(MatchError(&StreamGapError{Offset: 2, Length: 4}))
+				Expect(err.(net.Error).Temporary()).To(BeTrue())
+				Expect(n).To(BeZero())
+				n, err = strWithTimeout.Read(b)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(b[:n]).To(Equal([]byte("ar")))
+			})
+
+			It("checks gaps against the flow control window", func() {
+				testErr := errors.New("flow control violation")
+				mockFcm.EXPECT().UpdateHighestReceived(streamID, protocol.ByteCount(1<<40+2)).Return(testErr)
+				err := str.AddStreamGap(&wire.StreamGapFrame{StreamID: streamID, Offset: 2, ByteLen: 1 << 40})
+				Expect(err).To(MatchError(testErr))
+				Expect(str.frameQueue.Head()).To(BeNil())
+			})
+
+			It("ignores empty gaps", func() {
+				Expect(str.AddStreamGap(&wire.StreamGapFrame{StreamID: streamID, Offset: 2})).To(Succeed())
+				Expect(str.frameQueue.Head()).To(BeNil())
+			})
+		})
+
 		Context("closing", func() {
 			Context("with FIN bit", func() {
 				It("returns EOFs", func() {
@@ -793,6 +846,60 @@ var _ = Describe("Stream", func() {
 			Expect(str.getDataForWriting(1000)).To(BeNil())
 		})
 
+		Context("data deadlines", func() {
+			It("returns the deadline the data was written with", func() {
+				deadline := time.Now().Add(30 * time.Millisecond)
+				go func() {
+					defer GinkgoRecover()
+					n, err := str.WriteWithDeadline([]byte("foobar"), deadline)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(n).To(Equal(6))
+				}()
+				Eventually(func() protocol.ByteCount { return str.lenOfDataForWriting() }).Should(Equal(protocol.ByteCount(6)))
+				Expect(str.getDeadlineForWriting()).To(Equal(deadline))
+				data, t := str.getDataForWritingWithDeadline(3)
+				Expect(data).To(Equal([]byte("foo")))
+				Expect(t).To(Equal(deadline))
+				data, t = str.getDataForWritingWithDeadline(3)
+				Expect(data).To(Equal([]byte("bar")))
+				Expect(t).To(Equal(deadline))
+			})
+
+			It("uses the relative deadline set by SetDataDeadline", func() {
+				str.SetDataDeadline(50 * time.Millisecond)
+				go func() {
+					defer GinkgoRecover()
+					_, err := strWithTimeout.Write([]byte("foobar"))
+					Expect(err).ToNot(HaveOccurred())
+				}()
+				Eventually(func() protocol.ByteCount { return str.lenOfDataForWriting() }).Should(Equal(protocol.ByteCount(6)))
+				data, t := str.getDataForWritingWithDeadline(1000)
+				Expect(data).To(Equal([]byte("foobar")))
+				Expect(t).To(BeTemporally("~", time.Now().Add(50*time.Millisecond), 20*time.Millisecond))
+			})
+
+			It("sets the priority", func() {
+				priority, urgent := str.getPriority()
+				Expect(priority).To(BeZero())
+				Expect(urgent).To(BeFalse())
+				str.SetPriority(3, true)
+				priority, urgent = str.getPriority()
+				Expect(priority).To(Equal(uint8(3)))
+				Expect(urgent).To(BeTrue())
+			})
+
+			It("has no deadline by default", func() {
+				go func() {
+					defer GinkgoRecover()
+					_, err := strWithTimeout.Write([]byte("foobar"))
+					Expect(err).ToNot(HaveOccurred())
+				}()
+				Eventually(func() protocol.ByteCount { return str.lenOfDataForWriting() }).Should(Equal(protocol.ByteCount(6)))
+				_, t := str.getDataForWritingWithDeadline(1000)
+				Expect(t.IsZero()).To(BeTrue())
+			})
+		})
+
 		It("copies the slice while writing", func() {
 			s := []byte("foo")
 			go func() {
@@ -272,6 +272,93 @@ func (m *streamsMap) RoundRobinIterate(fn streamLambda) error {
 	return nil
 }
 
+// SortedIterate executes the streamLambda for every open stream in the order given by less, until the streamLambda returns false
+// Like RoundRobinIterate, it prioritizes the crypto- and the header-stream (StreamIDs 1 and 3)
+// Streams that rank equally are visited round-robin
+func (m *streamsMap) SortedIterate(less streamLess, fn streamLambda) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, i := range []protocol.StreamID{1, 3} {
+		cont, err := m.iterateFunc(i, fn)
+		if err != nil && err != errMapAccess {
+			return err
+		}
+		if !cont {
+			return nil
+		}
+	}
+
+	numStreams := uint32(len(m.openStreams))
+	streams := make([]*stream, 0, numStreams)
+	for i := uint32(0); i < numStreams; i++ {
+		streamID := m.openStreams[(i+m.roundRobinIndex)%numStreams]
+		if streamID == 1 || streamID == 3 {
+			continue
+		}
+		if str, ok := m.streams[streamID]; ok && str != nil {
+			streams = append(streams, str)
+		}
+	}
+	sortStreams(streams, less)
+
+	for _, str := range streams {
+		cont, err := fn(str)
+		if err != nil {
+			return err
+		}
+		m.roundRobinIndex = (m.roundRobinIndex + 1) % numStreams
+		if !cont {
+			break
+		}
+	}
+	return nil
+}
+
+// PeekIterate executes the streamLambda for every open stream in the order of SortedIterate, or of RoundRobinIterate if less is nil,
+// until the streamLambda returns false
+// It doesn't move the round-robin position, so that looking at the streams doesn't change the order in which they are served
+func (m *streamsMap) PeekIterate(less streamLess, fn streamLambda) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, i := range []protocol.StreamID{1, 3} {
+		cont, err := m.iterateFunc(i, fn)
+		if err != nil && err != errMapAccess {
+			return err
+		}
+		if !cont {
+			return nil
+		}
+	}
+
+	numStreams := uint32(len(m.openStreams))
+	streams := make([]*stream, 0, numStreams)
+	for i := uint32(0); i < numStreams; i++ {
+		streamID := m.openStreams[(i+m.roundRobinIndex)%numStreams]
+		if streamID == 1 || streamID == 3 {
+			continue
+		}
+		if str, ok := m.streams[streamID]; ok && str != nil {
+			streams = append(streams, str)
+		}
+	}
+	if less != nil {
+		sortStreams(streams, less)
+	}
+
+	for _, str := range streams {
+		cont, err := fn(str)
+		if err != nil {
+			return err
+		}
+		if !cont {
+			break
+		}
+	}
+	return nil
+}
+
 func (m *streamsMap) iterateFunc(streamID protocol.StreamID, fn streamLambda) (bool, error) {
 	str, ok := m.streams[streamID]
 	if !ok {
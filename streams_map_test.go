@@ -673,5 +673,110 @@ var _ = Describe("Streams Map", func() {
 				})
 			})
 		})
+
+		Context("SortedIterate", func() {
+			var lambdaCalledForStream []protocol.StreamID
+
+			fn := func(str *stream) (bool, error) {
+				lambdaCalledForStream = append(lambdaCalledForStream, str.StreamID())
+				return true, nil
+			}
+
+			BeforeEach(func() {
+				lambdaCalledForStream = lambdaCalledForStream[:0]
+				for i := 4; i <= 8; i++ {
+					err := m.putStream(&stream{streamID: protocol.StreamID(i)})
+					Expect(err).NotTo(HaveOccurred())
+				}
+			})
+
+			It("visits the streams in the given order", func() {
+				m.streams[6].priority = 2
+				m.streams[8].priority = 1
+				err := m.SortedIterate(priorityStreamLess, fn)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(lambdaCalledForStream).To(Equal([]protocol.StreamID{6, 8, 4, 5, 7}))
+			})
+
+			It("visits equally ranked streams round-robin", func() {
+				m.streams[6].priority = 2
+				fn := func(str *stream) (bool, error) {
+					lambdaCalledForStream = append(lambdaCalledForStream, str.StreamID())
+					return len(lambdaCalledForStream) < 2, nil
+				}
+				err := m.SortedIterate(priorityStreamLess, fn)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(lambdaCalledForStream).To(Equal([]protocol.StreamID{6, 4}))
+				lambdaCalledForStream = lambdaCalledForStream[:0]
+				err = m.SortedIterate(priorityStreamLess, fn)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(lambdaCalledForStream).To(Equal([]protocol.StreamID{6, 7}))
+			})
+
+			It("gets crypto- and header stream first", func() {
+				err := m.putStream(&stream{streamID: 1})
+				Expect(err).NotTo(HaveOccurred())
+				err = m.putStream(&stream{streamID: 3})
+				Expect(err).NotTo(HaveOccurred())
+				m.streams[7].urgent = true
+				err = m.SortedIterate(priorityStreamLess, fn)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(lambdaCalledForStream).To(Equal([]protocol.StreamID{1, 3, 7, 4, 5, 6, 8}))
+			})
+		})
+
+		Context("PeekIterate", func() {
+			var lambdaCalledForStream []protocol.StreamID
+
+			fn := func(str *stream) (bool, error) {
+				lambdaCalledForStream = append(lambdaCalledForStream, str.StreamID())
+				return true, nil
+			}
+
+			BeforeEach(func() {
+				lambdaCalledForStream = lambdaCalledForStream[:0]
+				for i := 4; i <= 8; i++ {
+					err := m.putStream(&stream{streamID: protocol.StreamID(i)})
+					Expect(err).NotTo(HaveOccurred())
+				}
+			})
+
+			It("visits the streams in round-robin order without moving the round-robin position", func() {
+				m.roundRobinIndex = 3 // stream 7
+				err := m.PeekIterate(nil, fn)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(lambdaCalledForStream).To(Equal([]protocol.StreamID{7, 8, 4, 5, 6}))
+				Expect(m.roundRobinIndex).To(Equal(uint32(3)))
+			})
+
+			It("visits the streams in the given order", func() {
+				m.roundRobinIndex = 1 // stream 5
+				m.streams[6].priority = 2
+				err := m.PeekIterate(priorityStreamLess, fn)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(lambdaCalledForStream).To(Equal([]protocol.StreamID{6, 5, 7, 8, 4}))
+				Expect(m.roundRobinIndex).To(Equal(uint32(1)))
+			})
+
+			It("gets crypto- and header stream first", func() {
+				err := m.putStream(&stream{streamID: 1})
+				Expect(err).NotTo(HaveOccurred())
+				err = m.putStream(&stream{streamID: 3})
+				Expect(err).NotTo(HaveOccurred())
+				err = m.PeekIterate(nil, fn)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(lambdaCalledForStream).To(Equal([]protocol.StreamID{1, 3, 4, 5, 6, 7, 8}))
+			})
+
+			It("stops when the lambda returns false", func() {
+				fn := func(str *stream) (bool, error) {
+					lambdaCalledForStream = append(lambdaCalledForStream, str.StreamID())
+					return len(lambdaCalledForStream) < 2, nil
+				}
+				err := m.PeekIterate(nil, fn)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(lambdaCalledForStream).To(Equal([]protocol.StreamID{4, 5}))
+			})
+		})
 	})
 })
@@ -0,0 +1,348 @@
+package quic
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// A Tracer receives structured events of the connections it is configured for.
+// It is called from the goroutines of all these connections, so it must be safe for concurrent use.
+type Tracer interface {
+	Trace(event *TraceEvent)
+}
+
+// TraceEventType is the type of a TraceEvent
+type TraceEventType string
+
+// The types of TraceEvents
+const (
+	// TracePacketSent is emitted for every packet sent, with a PacketSentEvent
+	TracePacketSent TraceEventType = "packet_sent"
+	// TraceAckReceived is emitted for every ACK frame received, with an AckReceivedEvent
+	TraceAckReceived TraceEventType = "ack_received"
+	// TraceAlphaChanged is emitted when the bandit of a path chooses another alpha, with an AlphaChangedEvent
+	TraceAlphaChanged TraceEventType = "alpha_changed"
+	// TraceLPSolved is emitted when BatchLinOpt scheduled a batch, with an LPSolvedEvent
+	TraceLPSolved TraceEventType = "lp_solved"
+	// TraceCongestionWindowChanged is emitted when the congestion window of a path changed, with a CongestionWindowEvent
+	TraceCongestionWindowChanged TraceEventType = "cwnd_changed"
+	// TracePathOpened and TracePathClosed are emitted with a PathEvent
+	TracePathOpened TraceEventType = "path_opened"
+	TracePathClosed TraceEventType = "path_closed"
+	// TracePathHealthChanged is emitted when the failure detection changed the health of a path, with a PathHealthEvent
+	TracePathHealthChanged TraceEventType = "path_health_changed"
+	// TraceDeadlineMissed is emitted for every packet the peer reported to have missed its deadline, with a DeadlineMissedEvent
+	TraceDeadlineMissed TraceEventType = "deadline_missed"
+	// TracePacketDuplicated is emitted when a packet was sent again on another path, with a PacketDuplicatedEvent
+	TracePacketDuplicated TraceEventType = "packet_duplicated"
+)
+
+// A TraceEvent is a single event of a connection
+type TraceEvent struct {
+	Time         time.Time             `json:"time"`
+	ConnectionID protocol.ConnectionID `json:"connection_id"`
+	Type         TraceEventType        `json:"type"`
+	// Data is one of the *...Event types, depending on the Type
+	Data interface{} `json:"data"`
+}
+
+// PacketSentEvent is the data of a TracePacketSent event
+type PacketSentEvent struct {
+	PathID       PathID                `json:"path_id"`
+	PacketNumber protocol.PacketNumber `json:"packet_number"`
+	Length       protocol.ByteCount    `json:"length"`
+	// Deadline is the earliest deadline of the stream data in the packet, if any
+	Deadline *time.Time `json:"deadline,omitempty"`
+	Alpha    float32    `json:"alpha"`
+}
+
+// AckReceivedEvent is the data of a TraceAckReceived event
+type AckReceivedEvent struct {
+	PathID       PathID                `json:"path_id"`
+	LargestAcked protocol.PacketNumber `json:"largest_acked"`
+	// HasDeadlineInformation is false if the peer doesn't use the deadline extension
+	HasDeadlineInformation bool   `json:"has_deadline_information"`
+	NumHasDeadline         uint16 `json:"num_has_deadline"`
+	NumMeetDeadline        uint16 `json:"num_meet_deadline"`
+}
+
+// DeadlineMissedEvent is the data of a TraceDeadlineMissed event
+type DeadlineMissedEvent struct {
+	PathID       PathID                `json:"path_id"`
+	PacketNumber protocol.PacketNumber `json:"packet_number"`
+	// Lateness is how long after its deadline the packet arrived
+	Lateness time.Duration `json:"lateness"`
+	// StreamIDs are only known if the packet wasn't acked or declared lost before the report arrived
+	StreamIDs []protocol.StreamID `json:"stream_ids,omitempty"`
+}
+
+// PacketDuplicatedEvent is the data of a TracePacketDuplicated event
+type PacketDuplicatedEvent struct {
+	PathID                PathID                `json:"path_id"`
+	PacketNumber          protocol.PacketNumber `json:"packet_number"`
+	DuplicatePathID       PathID                `json:"duplicate_path_id"`
+	DuplicatePacketNumber protocol.PacketNumber `json:"duplicate_packet_number"`
+}
+
+// AlphaChangedEvent is the data of a TraceAlphaChanged event
+type AlphaChangedEvent struct {
+	PathID   PathID  `json:"path_id"`
+	OldAlpha float32 `json:"old_alpha"`
+	NewAlpha float32 `json:"new_alpha"`
+}
+
+// LPSolvedEvent is the data of a TraceLPSolved event.
+// Times are in milliseconds, congestion windows in packets.
+type LPSolvedEvent struct {
+	Deadlines []float64 `json:"deadlines"`
+	Paths     []PathID  `json:"paths"`
+	Delays    []float64 `json:"delays"`
+	Cwnds     []float64 `json:"cwnds"`
+	Costs     []float64 `json:"costs,omitempty"`
+	// Budget is only set if the batch has a cost budget
+	Budget *float64 `json:"budget,omitempty"`
+	// Policy is the path of every packet, numbered from 1 in the order of Paths, or 0 if it is not sent
+	Policy []int `json:"policy"`
+}
+
+// CongestionWindowEvent is the data of a TraceCongestionWindowChanged event
+type CongestionWindowEvent struct {
+	PathID           PathID             `json:"path_id"`
+	CongestionWindow protocol.ByteCount `json:"cwnd"`
+	BytesInFlight    protocol.ByteCount `json:"bytes_in_flight"`
+}
+
+// PathEvent is the data of a TracePathOpened or TracePathClosed event
+type PathEvent struct {
+	PathID     PathID `json:"path_id"`
+	LocalAddr  string `json:"local_addr,omitempty"`
+	RemoteAddr string `json:"remote_addr,omitempty"`
+	Label      string `json:"label,omitempty"`
+}
+
+// PathHealthEvent is the data of a TracePathHealthChanged event
+type PathHealthEvent struct {
+	PathID    PathID     `json:"path_id"`
+	Label     string     `json:"label,omitempty"`
+	OldHealth PathHealth `json:"old_health"`
+	NewHealth PathHealth `json:"new_health"`
+}
+
+func newTraceEventData(t TraceEventType) (interface{}, error) {
+	switch t {
+	case TracePacketSent:
+		return &PacketSentEvent{}, nil
+	case TraceAckReceived:
+		return &AckReceivedEvent{}, nil
+	case TraceDeadlineMissed:
+		return &DeadlineMissedEvent{}, nil
+	case TracePacketDuplicated:
+		return &PacketDuplicatedEvent{}, nil
+	case TraceAlphaChanged:
+		return &AlphaChangedEvent{}, nil
+	case TraceLPSolved:
+		return &LPSolvedEvent{}, nil
+	case TraceCongestionWindowChanged:
+		return &CongestionWindowEvent{}, nil
+	case TracePathOpened, TracePathClosed:
+		return &PathEvent{}, nil
+	case TracePathHealthChanged:
+		return &PathHealthEvent{}, nil
+	}
+	return nil, fmt.Errorf("unknown trace event type %q", t)
+}
+
+// UnmarshalJSON decodes the Data into the type that belongs to the Type of the event
+func (e *TraceEvent) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Time         time.Time             `json:"time"`
+		ConnectionID protocol.ConnectionID `json:"connection_id"`
+		Type         TraceEventType        `json:"type"`
+		Data         json.RawMessage       `json:"data"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	data, err := newTraceEventData(raw.Type)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw.Data, data); err != nil {
+		return err
+	}
+	*e = TraceEvent{Time: raw.Time, ConnectionID: raw.ConnectionID, Type: raw.Type, Data: data}
+	return nil
+}
+
+// ReadTraceEvents reads the events written by a JSON tracer, e.g. to replay a run
+func ReadTraceEvents(r io.Reader) ([]TraceEvent, error) {
+	var events []TraceEvent
+	dec := json.NewDecoder(r)
+	for {
+		var e TraceEvent
+		if err := dec.Decode(&e); err != nil {
+			if err == io.EOF {
+				return events, nil
+			}
+			return events, err
+		}
+		events = append(events, e)
+	}
+}
+
+// A JSONTracer writes every event as a line of JSON
+type JSONTracer struct {
+	mutex sync.Mutex
+	w     *bufio.Writer
+	enc   *json.Encoder
+	err   error
+}
+
+var _ Tracer = &JSONTracer{}
+
+// NewJSONTracer creates a tracer that writes to w. Flush must be called to write the buffered events.
+func NewJSONTracer(w io.Writer) *JSONTracer {
+	bw := bufio.NewWriter(w)
+	return &JSONTracer{w: bw, enc: json.NewEncoder(bw)}
+}
+
+// Trace writes the event. After the first error, all events are dropped.
+func (t *JSONTracer) Trace(event *TraceEvent) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.err != nil {
+		return
+	}
+	t.err = t.enc.Encode(event)
+}
+
+// Flush writes the buffered events and returns the first error that occurred
+func (t *JSONTracer) Flush() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.err != nil {
+		return t.err
+	}
+	t.err = t.w.Flush()
+	return t.err
+}
+
+// A FileTracer writes every event as a line of JSON to a file
+type FileTracer struct {
+	*JSONTracer
+	file *os.File
+}
+
+// NewFileTracer creates a tracer that writes to a file. An existing file is truncated.
+func NewFileTracer(path string) (*FileTracer, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &FileTracer{JSONTracer: NewJSONTracer(f), file: f}, nil
+}
+
+// Close writes the buffered events and closes the file
+func (t *FileTracer) Close() error {
+	err := t.Flush()
+	if cerr := t.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// A MemoryTracer keeps all events in memory
+type MemoryTracer struct {
+	mutex  sync.Mutex
+	events []TraceEvent
+}
+
+var _ Tracer = &MemoryTracer{}
+
+// Trace stores the event
+func (t *MemoryTracer) Trace(event *TraceEvent) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.events = append(t.events, *event)
+}
+
+// Events returns the events traced so far
+func (t *MemoryTracer) Events() []TraceEvent {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	events := make([]TraceEvent, len(t.events))
+	copy(events, t.events)
+	return events
+}
+
+// traceEvent sends an event to the tracer, if there is one
+func traceEvent(tracer Tracer, connectionID protocol.ConnectionID, t TraceEventType, data interface{}) {
+	if tracer == nil {
+		return
+	}
+	tracer.Trace(&TraceEvent{Time: time.Now(), ConnectionID: connectionID, Type: t, Data: data})
+}
+
+// trace sends an event to the tracer of the Config, if there is one
+func (s *session) trace(t TraceEventType, data interface{}) {
+	if s == nil || s.config == nil {
+		return
+	}
+	traceEvent(s.config.Tracer, s.connectionID, t, data)
+}
+
+// traceCongestionWindow emits an event if the congestion window of the path changed since the last event
+func (s *session) traceCongestionWindow(pth *path) {
+	if s.config == nil || s.config.Tracer == nil {
+		return
+	}
+	cwnd := pth.sentPacketHandler.GetCongestionWindow()
+	if cwnd == pth.tracedCongestionWindow {
+		return
+	}
+	pth.tracedCongestionWindow = cwnd
+	s.trace(TraceCongestionWindowChanged, &CongestionWindowEvent{
+		PathID:           pth.pathID,
+		CongestionWindow: cwnd,
+		BytesInFlight:    pth.sentPacketHandler.GetBytesInFlight(),
+	})
+}
+
+func (s *session) tracePacketSent(packet *packedPacket, pth *path) {
+	if s.config == nil || s.config.Tracer == nil {
+		return
+	}
+	e := &PacketSentEvent{
+		PathID:       pth.pathID,
+		PacketNumber: packet.number,
+		Length:       protocol.ByteCount(len(packet.raw)),
+		Alpha:        pth.sentPacketHandler.GetPathAlpha(),
+	}
+	if !packet.m_deadline.IsZero() {
+		deadline := packet.m_deadline
+		e.Deadline = &deadline
+	}
+	s.trace(TracePacketSent, e)
+	s.traceCongestionWindow(pth)
+}
+
+// pathEvent describes the path for TracePathOpened and TracePathClosed events
+func pathEvent(pth *path) *PathEvent {
+	e := &PathEvent{PathID: pth.pathID, Label: pth.label}
+	if pth.conn != nil {
+		if addr := pth.conn.LocalAddr(); addr != nil {
+			e.LocalAddr = addr.String()
+		}
+		if addr := pth.conn.RemoteAddr(); addr != nil {
+			e.RemoteAddr = addr.String()
+		}
+	}
+	return e
+}
@@ -0,0 +1,161 @@
+package quic
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/ackhandler"
+	"github.com/lucas-clemente/quic-go/congestion"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tracer", func() {
+	deadline := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	events := []TraceEvent{
+		{Type: TracePacketSent, Data: &PacketSentEvent{PathID: 1, PacketNumber: 7, Length: 1200, Deadline: &deadline, Alpha: 1.5}},
+		{Type: TraceAckReceived, Data: &AckReceivedEvent{PathID: 1, LargestAcked: 7, HasDeadlineInformation: true, NumHasDeadline: 4, NumMeetDeadline: 3}},
+		{Type: TraceDeadlineMissed, Data: &DeadlineMissedEvent{PathID: 1, PacketNumber: 5, Lateness: 3 * time.Millisecond, StreamIDs: []protocol.StreamID{5, 7}}},
+		{Type: TracePacketDuplicated, Data: &PacketDuplicatedEvent{PathID: 1, PacketNumber: 8, DuplicatePathID: 3, DuplicatePacketNumber: 4}},
+		{Type: TraceAlphaChanged, Data: &AlphaChangedEvent{PathID: 1, OldAlpha: 1.5, NewAlpha: 2}},
+		{Type: TraceLPSolved, Data: &LPSolvedEvent{Deadlines: []float64{10, 20}, Paths: []PathID{1, 3}, Delays: []float64{5, 15}, Cwnds: []float64{1, 1}, Policy: []int{1, 2}}},
+		{Type: TraceCongestionWindowChanged, Data: &CongestionWindowEvent{PathID: 3, CongestionWindow: 14000, BytesInFlight: 2400}},
+		{Type: TracePathOpened, Data: &PathEvent{PathID: 3, LocalAddr: "10.0.0.1:4433"}},
+		{Type: TracePathClosed, Data: &PathEvent{PathID: 3}},
+	}
+	for i := range events {
+		events[i].Time = deadline.Add(time.Duration(i) * time.Millisecond)
+		events[i].ConnectionID = 0x1337
+	}
+
+	It("keeps the events in memory", func() {
+		tracer := &MemoryTracer{}
+		for i := range events {
+			tracer.Trace(&events[i])
+		}
+		Expect(tracer.Events()).To(Equal(events))
+	})
+
+	It("writes JSON events that can be read again", func() {
+		buf := &bytes.Buffer{}
+		tracer := NewJSONTracer(buf)
+		for i := range events {
+			tracer.Trace(&events[i])
+		}
+		Expect(tracer.Flush()).To(Succeed())
+		Expect(bytes.Count(buf.Bytes(), []byte("\n"))).To(Equal(len(events)))
+		read, err := ReadTraceEvents(buf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(read).To(HaveLen(len(events)))
+		for i := range read {
+			Expect(read[i].Time.Equal(events[i].Time)).To(BeTrue())
+			read[i].Time = events[i].Time
+		}
+		Expect(read).To(Equal(events))
+	})
+
+	It("rejects unknown event types", func() {
+		_, err := ReadTraceEvents(bytes.NewBufferString(`{"type":"foobar","data":{}}`))
+		Expect(err).To(MatchError(`unknown trace event type "foobar"`))
+	})
+
+	It("reports encoding errors", func() {
+		tracer := NewJSONTracer(&bytes.Buffer{})
+		tracer.Trace(&TraceEvent{Type: TraceLPSolved, Data: &LPSolvedEvent{Delays: []float64{math.Inf(1)}}})
+		Expect(tracer.Flush()).ToNot(Succeed())
+	})
+
+	It("writes the events to a file", func() {
+		dir, err := ioutil.TempDir("", "tracer")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+		filename := filepath.Join(dir, "trace.json")
+		tracer, err := NewFileTracer(filename)
+		Expect(err).ToNot(HaveOccurred())
+		tracer.Trace(&events[1])
+		Expect(tracer.Close()).To(Succeed())
+		f, err := os.Open(filename)
+		Expect(err).ToNot(HaveOccurred())
+		defer f.Close()
+		read, err := ReadTraceEvents(f)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(read).To(HaveLen(1))
+		Expect(read[0].Data).To(Equal(events[1].Data))
+	})
+
+	Context("in the session", func() {
+		var (
+			sess   *session
+			tracer *MemoryTracer
+			pth    *path
+		)
+
+		BeforeEach(func() {
+			tracer = &MemoryTracer{}
+			sess = &session{connectionID: 0x42, config: &Config{Tracer: tracer}}
+			rttStats := &congestion.RTTStats{}
+			pth = &path{
+				pathID:            1,
+				sess:              sess,
+				rttStats:          rttStats,
+				sentPacketHandler: ackhandler.NewSentPacketHandler(rttStats, nil, nil, nil),
+			}
+			pth.open.Set(true)
+		})
+
+		It("traces congestion window changes", func() {
+			sess.traceCongestionWindow(pth)
+			sess.traceCongestionWindow(pth)
+			Expect(tracer.Events()).To(HaveLen(1))
+			e := tracer.Events()[0]
+			Expect(e.ConnectionID).To(Equal(protocol.ConnectionID(0x42)))
+			Expect(e.Type).To(Equal(TraceCongestionWindowChanged))
+			Expect(e.Data.(*CongestionWindowEvent).CongestionWindow).To(Equal(pth.sentPacketHandler.GetCongestionWindow()))
+		})
+
+		It("traces closing a path once", func() {
+			Expect(pth.close()).To(Succeed())
+			Expect(pth.close()).To(Succeed())
+			Expect(tracer.Events()).To(HaveLen(1))
+			Expect(tracer.Events()[0].Type).To(Equal(TracePathClosed))
+			Expect(tracer.Events()[0].Data).To(Equal(&PathEvent{PathID: 1}))
+		})
+
+		It("doesn't trace without a tracer", func() {
+			sess.config = &Config{}
+			sess.traceCongestionWindow(pth)
+			Expect(pth.close()).To(Succeed())
+			Expect(tracer.Events()).To(BeEmpty())
+		})
+	})
+
+	It("traces the LP solved by BatchLinOpt", func() {
+		tracer := &MemoryTracer{}
+		sch := newPathScheduler(&Config{SchedulerName: "BatchLinOpt", Tracer: tracer}).(BatchPathScheduler)
+		snapshot := &PathSnapshot{
+			ConnectionID: 0x42,
+			Paths: []PathInfo{
+				{PathID: 0, SendingAllowed: true},
+				{PathID: 1, SendingAllowed: true, SmoothedRTT: 20 * time.Millisecond, Alpha: 1, CongestionWindow: 10 * protocol.MaxPacketSize},
+			},
+			RemainingBudget: math.Inf(1),
+			BatchBudget:     math.Inf(1),
+		}
+		paths := sch.SelectBatch(snapshot, []time.Duration{50 * time.Millisecond, 60 * time.Millisecond})
+		Expect(paths).To(Equal([]PathID{1, 1}))
+		Expect(tracer.Events()).To(HaveLen(1))
+		e := tracer.Events()[0]
+		Expect(e.Type).To(Equal(TraceLPSolved))
+		lp := e.Data.(*LPSolvedEvent)
+		Expect(lp.Deadlines).To(Equal([]float64{50, 60}))
+		Expect(lp.Paths).To(Equal([]PathID{1}))
+		Expect(lp.Policy).To(Equal([]int{1, 1}))
+		Expect(lp.Budget).To(BeNil())
+	})
+})